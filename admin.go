@@ -0,0 +1,433 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	adminShutdownTimeout = 5 * time.Second
+	defaultTraceDuration = 15 * time.Minute
+)
+
+// AdminMenus is the response of the admin menus endpoint
+type AdminMenus struct {
+	HomeMenu string   `json:"home_menu"`
+	Menus    []string `json:"menus"`
+}
+
+// AdminSessionCount is the response of the admin session count endpoint
+type AdminSessionCount struct {
+	// Live is the number of sessions in progress on this instance
+	Live int `json:"live"`
+	// Active is the number of sessions in progress on all instances, see ActiveSessionCount
+	Active int64 `json:"active"`
+}
+
+// AdminSession is the response of the admin session endpoint
+type AdminSession struct {
+	SessionID string            `json:"session_id"`
+	Msisdn    string            `json:"msisdn"`
+	Data      map[string]string `json:"data"`
+}
+
+// AdminBlockRequest is the body of the admin request that blocks a msisdn
+type AdminBlockRequest struct {
+	Reason string `json:"reason"`
+	// ExpiresIn lifts the block after the duration, e.g 24h. Blocks without it last until removed
+	ExpiresIn string `json:"expires_in"`
+}
+
+// FlushLogs saves the session logs waiting in the buffer without waiting for the next bulk insert
+func (app *UssdApp) FlushLogs(ctx context.Context) error {
+	if !app.savingLogs() {
+		return errors.New("saving logs is disabled")
+	}
+	if app.opt.LogMode == LogModeSync {
+		// Logs are written as they are saved
+		return nil
+	}
+
+	res := make(chan error, 1)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-app.stop:
+		return errors.New("app is closed")
+	case app.flushReqs <- res:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-res:
+		return err
+	}
+}
+
+// AdminAuthFn wraps the admin handler, refusing requests that are not authenticated
+type AdminAuthFn func(next http.Handler) http.Handler
+
+// AdminTokenAuth authenticates admin requests with the token in their Authorization header, e.g
+// "Authorization: Bearer <token>"
+func AdminTokenAuth(token string) AdminAuthFn {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminHandler returns an http handler for inspecting the app at runtime. It serves:
+//
+//	GET  /menus                          registered menus
+//	GET  /graph?format=dot|mermaid       menu graph, see ExportMenuGraph
+//	GET  /sessions                       sessions in progress on this instance, see ActiveSessions
+//	GET  /sessions/count                 number of sessions in progress, see ActiveSessionCount
+//	GET  /sessions/{id}?msisdn={msisdn}  cached data of a session. The msisdn may be left out for sessions on this instance
+//	DELETE /sessions/{id}?msisdn={msisdn} terminates a session, see TerminateSession
+//	GET  /conversations/{id}?format=json|html screens and inputs of a logged session, see GetConversation
+//	POST /logs/flush                     saves buffered session logs, see FlushLogs
+//	GET  /blocks/{msisdn}                block of a msisdn, see Options.Blocklist
+//	PUT  /blocks/{msisdn}                blocks a msisdn, with an AdminBlockRequest body
+//	DELETE /blocks/{msisdn}              unblocks a msisdn
+//	PUT  /trace/{msisdn}?for={duration}  traces requests of a msisdn, for 15 minutes by default. See TraceMsisdn
+//	DELETE /trace/{msisdn}               stops tracing a msisdn
+//
+// The handler is not authenticated, wrap it, e.g with AdminTokenAuth, before mounting it. Options.AdminAddr serves it
+// wrapped with Options.AdminAuth.
+func (app *UssdApp) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/menus", app.adminMenus)
+	mux.HandleFunc("/graph", app.adminGraph)
+	mux.HandleFunc("/sessions", app.adminActiveSessions)
+	mux.HandleFunc("/sessions/", app.adminSession)
+	mux.HandleFunc("/conversations/", app.adminConversation)
+	mux.HandleFunc("/logs/flush", app.adminFlushLogs)
+	mux.HandleFunc("/blocks/", app.adminBlocks)
+	mux.HandleFunc("/trace/", app.adminTrace)
+	return mux
+}
+
+// serveAdmin serves the admin handler on Options.AdminAddr until the app is closed
+func (app *UssdApp) serveAdmin(ctx context.Context) {
+	defer app.workers.Done()
+
+	srv := &http.Server{
+		Addr:              app.opt.AdminAddr,
+		Handler:           app.opt.AdminAuth(app.AdminHandler()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-app.stop:
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	app.opt.Logger.Info("admin api listening", "addr", app.opt.AdminAddr)
+
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		app.opt.Logger.Error("admin api stopped", "error", err)
+	}
+}
+
+func (app *UssdApp) adminMenus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, &AdminMenus{HomeMenu: app.homeMenu, Menus: app.GetMenuNames()})
+}
+
+func (app *UssdApp) adminGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := GraphDOT
+	switch r.URL.Query().Get("format") {
+	case "", "dot":
+	case "mermaid":
+		format = GraphMermaid
+	default:
+		http.Error(w, "unknown graph format", http.StatusBadRequest)
+		return
+	}
+
+	graph, err := app.ExportMenuGraph(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, graph)
+}
+
+func (app *UssdApp) adminActiveSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessions, err := app.ActiveSessions(r.Context())
+	if err != nil {
+		app.opt.Logger.Error("failed to get active sessions", "error", err)
+		http.Error(w, "failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, sessions)
+}
+
+func (app *UssdApp) adminSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	if sessionID == "count" && r.Method == http.MethodGet {
+		active, err := app.ActiveSessionCount(r.Context())
+		if err != nil {
+			app.opt.Logger.Error("failed to count active sessions", "error", err)
+			http.Error(w, "failed to count sessions", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, &AdminSessionCount{Live: app.liveSessions(), Active: active})
+		return
+	}
+	if sessionID == "" || strings.Contains(sessionID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var (
+		msisdn     = r.URL.Query().Get("msisdn")
+		sessionKey = app.sessionKeyOf(sessionID, msisdn)
+	)
+	if msisdn == "" {
+		key, ok := app.trackedSessionKey(sessionID)
+		if !ok {
+			http.Error(w, "session is not on this instance, query it with its msisdn", http.StatusNotFound)
+			return
+		}
+		sessionKey = key
+		_, msisdn, _ = app.ParseSessionKey(key)
+	}
+
+	if r.Method == http.MethodDelete {
+		err := app.terminateSession(r.Context(), sessionID, msisdn)
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, ErrKeyNotFound):
+			http.Error(w, "session not found", http.StatusNotFound)
+		default:
+			app.opt.Logger.Error("failed to terminate session", "session_id", sessionID, "error", err)
+			http.Error(w, "failed to terminate session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	data, err := app.opt.Cache.GetMap(r.Context(), sessionKey)
+	switch {
+	case err == nil && len(data) > 0:
+	case err == nil, errors.Is(err, ErrKeyNotFound):
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	default:
+		app.opt.Logger.Error("failed to get session data", "session_id", sessionID, "error", err)
+		http.Error(w, "failed to get session", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, &AdminSession{SessionID: sessionID, Msisdn: msisdn, Data: data})
+}
+
+func (app *UssdApp) adminConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/conversations/")
+	if sessionID == "" || strings.Contains(sessionID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json", "html":
+	default:
+		http.Error(w, "unknown conversation format", http.StatusBadRequest)
+		return
+	}
+
+	conv, err := app.GetConversation(r.Context(), sessionID)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoSessionLogs):
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	default:
+		app.opt.Logger.Error("failed to get conversation", "session_id", sessionID, "error", err)
+		http.Error(w, "failed to get conversation", http.StatusInternalServerError)
+		return
+	}
+
+	if format != "html" {
+		writeJSON(w, conv)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = conv.WriteHTML(w)
+	if err != nil {
+		app.opt.Logger.Error("failed to write conversation", "session_id", sessionID, "error", err)
+	}
+}
+
+func (app *UssdApp) adminFlushLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := app.FlushLogs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *UssdApp) adminBlocks(w http.ResponseWriter, r *http.Request) {
+	msisdn := strings.TrimPrefix(r.URL.Path, "/blocks/")
+	switch {
+	case app.opt.Blocklist == nil:
+		http.Error(w, "app has no blocklist", http.StatusNotFound)
+		return
+	case msisdn == "" || strings.Contains(msisdn, "/"):
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		block, err := app.opt.Blocklist.GetBlock(r.Context(), msisdn)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrKeyNotFound):
+			http.Error(w, "msisdn is not blocked", http.StatusNotFound)
+			return
+		default:
+			app.opt.Logger.Error("failed to get block", "msisdn", msisdn, "error", err)
+			http.Error(w, "failed to get block", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, block)
+	case http.MethodPut:
+		req := &AdminBlockRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid block request", http.StatusBadRequest)
+			return
+		}
+
+		var ttl time.Duration
+		if req.ExpiresIn != "" {
+			ttl, err = time.ParseDuration(req.ExpiresIn)
+			if err != nil || ttl <= 0 {
+				http.Error(w, "invalid expires_in", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = app.BlockMsisdn(r.Context(), msisdn, req.Reason, ttl)
+		if err != nil {
+			app.opt.Logger.Error("failed to block msisdn", "msisdn", msisdn, "error", err)
+			http.Error(w, "failed to block msisdn", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		err := app.UnblockMsisdn(r.Context(), msisdn)
+		if err != nil {
+			app.opt.Logger.Error("failed to unblock msisdn", "msisdn", msisdn, "error", err)
+			http.Error(w, "failed to unblock msisdn", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (app *UssdApp) adminTrace(w http.ResponseWriter, r *http.Request) {
+	msisdn := strings.TrimPrefix(r.URL.Path, "/trace/")
+	if msisdn == "" || strings.Contains(msisdn, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+
+	switch r.Method {
+	case http.MethodPut:
+		dur := defaultTraceDuration
+		if val := r.URL.Query().Get("for"); val != "" {
+			dur, err = time.ParseDuration(val)
+			if err != nil || dur <= 0 {
+				http.Error(w, "invalid trace duration", http.StatusBadRequest)
+				return
+			}
+		}
+		err = app.TraceMsisdn(r.Context(), msisdn, dur)
+	case http.MethodDelete:
+		err = app.UntraceMsisdn(r.Context(), msisdn)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		app.opt.Logger.Error("failed to update trace", "msisdn", msisdn, "error", err)
+		http.Error(w, "failed to update trace", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
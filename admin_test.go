@@ -0,0 +1,44 @@
+package ussdapp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gidyon/ussdapp"
+)
+
+func TestAdminTokenAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{name: "valid token", token: "secret", header: "Bearer secret", status: http.StatusOK},
+		{name: "missing header", token: "secret", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", header: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "token prefix", token: "secret", header: "Bearer secre", status: http.StatusUnauthorized},
+		{name: "empty token refuses all", header: "Bearer ", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/menus", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			ussdapp.AdminTokenAuth(tt.token)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package ussdapp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// NewAfricasTalkingAdapter returns a gateway adapter for Africa's Talking USSD callbacks.
+//
+// Africa's Talking posts form encoded fields sessionId, phoneNumber, serviceCode and text,
+// where text contains all inputs for the session joined by *. Responses are plain text
+// prefixed with CON to continue the session or END to terminate it.
+func NewAfricasTalkingAdapter() GatewayAdapter {
+	return &africasTalkingAdapter{}
+}
+
+type africasTalkingAdapter struct{}
+
+func (*africasTalkingAdapter) ParseRequest(r *http.Request) (UssdPayload, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse form: %v", err)
+	}
+
+	var (
+		text       = strings.TrimSpace(r.Form.Get("text"))
+		ussdParams = strings.Split(text, "*")
+	)
+
+	payload := &ussdPayload{
+		data: &ussdPayloadInternal{
+			SessionID:        r.Form.Get("sessionId"),
+			ServiceCode:      r.Form.Get("serviceCode"),
+			Msisdn:           strings.TrimPrefix(r.Form.Get("phoneNumber"), "+"),
+			UssdParams:       text,
+			UssdCurrentParam: strings.TrimSpace(ussdParams[len(ussdParams)-1]),
+		},
+	}
+
+	switch {
+	case payload.SessionId() == "":
+		return nil, errors.New("missing sessionId")
+	case payload.Msisdn() == "":
+		return nil, errors.New("missing phoneNumber")
+	}
+
+	return payload, nil
+}
+
+func (*africasTalkingAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	return writeEncoded(w, PlainTextEncoder(), sr)
+}
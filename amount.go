@@ -0,0 +1,257 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	amountSaveStep        = "save"
+	amountFormattedSuffix = "_formatted"
+	defaultAmountPrompt   = "CON Enter amount"
+)
+
+// AmountFormatter formats an amount in minor units, e.g cents, for display in the language
+type AmountFormatter func(lang string, minor int64) string
+
+// AmountMenuOptions contains data for a menu that asks for an amount of money
+type AmountMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Prompt asks for the amount, per language. Defaults to CON Enter amount
+	Prompt Content
+	// Field is the session field the amount is saved in as an integer of minor units. Defaults to the menu name.
+	// The formatted amount is saved in the field with a _formatted suffix, for confirmation menus
+	Field string
+	// Decimals is the number of minor unit digits of the currency, e.g 2 for cents. Defaults to 0
+	Decimals int
+	// Min is the smallest amount allowed, in minor units
+	Min int64
+	// Max is the largest amount allowed, in minor units. Zero means no limit
+	Max int64
+	// Format formats saved amounts. Defaults to FormatAmount without a currency
+	Format AmountFormatter
+	// InvalidMessage is shown above the prompt for invalid amounts, per language. A %s verb is replaced with the
+	// smallest and a second one with the largest amount
+	InvalidMessage Content
+	// NextMenu is rendered once the amount is saved. Defaults to the home menu
+	NextMenu string
+}
+
+// AddAmountMenu registers a menu that asks for an amount, accepting inputs like 1500, 1,500.50 or 1.500,50.
+//
+// The amount is checked against the bounds and saved in the session in minor units, read it with GetAmount.
+// The input is received by a menu named <menu>:save.
+func (app *UssdApp) AddAmountMenu(opt *AmountMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing amount menu options")
+	case opt.MenuName == "":
+		return errors.New("missing amount menu name")
+	case opt.Decimals < 0:
+		return fmt.Errorf("amount menu %s has negative decimals", opt.MenuName)
+	case opt.Max > 0 && opt.Max < opt.Min:
+		return fmt.Errorf("amount menu %s has a maximum below the minimum", opt.MenuName)
+	}
+
+	var (
+		saveMenu = fmt.Sprintf("%s:%s", opt.MenuName, amountSaveStep)
+		nextMenu = firstVal(opt.NextMenu, app.homeMenu)
+		field    = firstVal(opt.Field, opt.MenuName)
+		format   = opt.Format
+	)
+	if format == nil {
+		format = FormatAmount("", opt.Decimals)
+	}
+
+	prompt := opt.Prompt
+	if len(prompt) == 0 {
+		prompt = Content{app.opt.DefaultLanguage: defaultAmountPrompt}
+	}
+
+	invalid := amountInvalidError(opt, format)
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName:    opt.MenuName,
+			NextMenu:    saveMenu,
+			ShortCut:    opt.ShortCut,
+			MenuContent: prompt,
+		}),
+		NewMenu(&MenuOptions{
+			MenuName: saveMenu,
+			NextMenu: nextMenu,
+			Validators: []Validator{func(input string) error {
+				minor, err := ParseAmount(input, opt.Decimals)
+				if err != nil || minor < opt.Min || (opt.Max > 0 && minor > opt.Max) {
+					return invalid
+				}
+				return nil
+			}},
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				minor, err := ParseAmount(payload.UssdCurrentParam(), opt.Decimals)
+				if err != nil {
+					return nil, err
+				}
+
+				lang := app.GetLanguage(ctx, payload)
+
+				err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload),
+					field, minor, field+amountFormattedSuffix, format(lang, minor))
+				if err != nil {
+					return nil, fmt.Errorf("failed to save amount: %v", err)
+				}
+
+				return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+			},
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// amountInvalidError returns the error for amounts that cannot be parsed or are out of bounds, with the bounds
+// formatted in each language of the invalid message
+func amountInvalidError(opt *AmountMenuOptions, format AmountFormatter) *Error {
+	messages := opt.InvalidMessage.clone()
+	if _, ok := messages[""]; !ok {
+		messages[""] = "Enter an amount of at least %s"
+		if opt.Max > 0 {
+			messages[""] = "Enter an amount between %s and %s"
+		}
+	}
+
+	for lang, msg := range messages {
+		args := []interface{}{format(lang, opt.Min)}
+		if opt.Max > 0 {
+			args = append(args, format(lang, opt.Max))
+		}
+		if n := strings.Count(msg, "%s"); n < len(args) {
+			args = args[:n]
+		}
+		messages[lang] = fmt.Sprintf(msg, args...)
+	}
+
+	return NewError(CodeInvalidInput, messages, nil)
+}
+
+// GetAmount returns the amount in minor units saved in the session field by an amount menu
+func (app *UssdApp) GetAmount(ctx context.Context, payload UssdPayload, field string) (int64, error) {
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), field)
+	if err != nil {
+		return 0, err
+	}
+
+	minor, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("session field %s is not an amount: %v", field, err)
+	}
+
+	return minor, nil
+}
+
+// ParseAmount parses an amount entered by a user into minor units of a currency with the given decimals.
+//
+// Both 1,500.50 and 1.500,50 are read as 1500.50: when both separators appear the last one separates decimals, and a
+// single separator separates decimals only if at most decimals digits follow it. Amounts with more decimals than
+// the currency has are rejected.
+func ParseAmount(input string, decimals int) (int64, error) {
+	input = strings.ReplaceAll(strings.TrimSpace(input), " ", "")
+	if input == "" {
+		return 0, errors.New("missing amount")
+	}
+
+	var (
+		whole    = input
+		fraction string
+	)
+
+	sep := strings.LastIndexAny(input, ".,")
+	if sep >= 0 {
+		var (
+			mixed     = strings.Contains(input, ".") && strings.Contains(input, ",")
+			single    = strings.Count(input, input[sep:sep+1]) == 1
+			fracDigit = len(input) - sep - 1
+		)
+		if mixed || (single && fracDigit <= decimals && fracDigit > 0) {
+			whole, fraction = input[:sep], input[sep+1:]
+		}
+	}
+
+	// The remaining separators group thousands
+	if strings.ContainsAny(whole, ".,") {
+		groups := strings.FieldsFunc(whole, func(r rune) bool { return r == '.' || r == ',' })
+		for i, group := range groups {
+			if (i > 0 && len(group) != 3) || group == "" {
+				return 0, fmt.Errorf("invalid amount %s", input)
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+
+	if len(fraction) > decimals || !isDigits(whole) || (fraction != "" && !isDigits(fraction)) {
+		return 0, fmt.Errorf("invalid amount %s", input)
+	}
+
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %s", input)
+	}
+
+	return minor, nil
+}
+
+// FormatAmount returns a formatter that writes amounts with thousands separated by commas, e.g KES 1,500.50
+func FormatAmount(currency string, decimals int) AmountFormatter {
+	return func(lang string, minor int64) string {
+		sign := ""
+		if minor < 0 {
+			sign, minor = "-", -minor
+		}
+
+		var (
+			unit      = int64(math.Pow10(decimals))
+			whole     = strconv.FormatInt(minor/unit, 10)
+			grouped   = make([]string, 0, len(whole)/3+1)
+			formatted string
+		)
+
+		for len(whole) > 3 {
+			grouped = append([]string{whole[len(whole)-3:]}, grouped...)
+			whole = whole[:len(whole)-3]
+		}
+		grouped = append([]string{whole}, grouped...)
+
+		formatted = sign + strings.Join(grouped, ",")
+		if decimals > 0 {
+			formatted += fmt.Sprintf(".%0*d", decimals, minor%unit)
+		}
+
+		return strings.TrimSpace(currency + " " + formatted)
+	}
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
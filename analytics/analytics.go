@@ -0,0 +1,248 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"gorm.io/gorm"
+)
+
+// DailySessions is the number of sessions in a day
+type DailySessions struct {
+	Day           time.Time `json:"day"`
+	Sessions      int64     `json:"sessions"`
+	UniqueMsisdns int64     `json:"unique_msisdns"`
+}
+
+// MenuDropOff is how many sessions that reached a menu ended on it
+type MenuDropOff struct {
+	MenuName string  `json:"menu_name"`
+	Reached  int64   `json:"reached"`
+	DropOffs int64   `json:"drop_offs"`
+	Rate     float64 `json:"rate"`
+}
+
+// FunnelConversion is how many sessions that reached a menu went on to reach another menu
+type FunnelConversion struct {
+	FromMenu  string  `json:"from_menu"`
+	ToMenu    string  `json:"to_menu"`
+	Entered   int64   `json:"entered"`
+	Converted int64   `json:"converted"`
+	Rate      float64 `json:"rate"`
+}
+
+// SessionLength is the average length of sessions
+type SessionLength struct {
+	Sessions        int64         `json:"sessions"`
+	AverageRequests float64       `json:"average_requests"`
+	AverageDuration time.Duration `json:"average_duration"`
+}
+
+// Analytics queries session logs created between from and to
+type Analytics interface {
+	// SessionsPerDay returns the number of sessions and unique msisdns for each day, oldest first
+	SessionsPerDay(ctx context.Context, from, to time.Time) ([]*DailySessions, error)
+	// DropOffRates returns for each menu the sessions that reached it and the sessions whose last request was on it
+	DropOffRates(ctx context.Context, from, to time.Time) ([]*MenuDropOff, error)
+	// Funnel returns the sessions that reached fromMenu and the ones that reached toMenu after it
+	Funnel(ctx context.Context, fromMenu, toMenu string, from, to time.Time) (*FunnelConversion, error)
+	// AverageSessionLength returns the average number of requests and duration of sessions
+	AverageSessionLength(ctx context.Context, from, to time.Time) (*SessionLength, error)
+	// UniqueMsisdns returns the number of distinct msisdns that used the service
+	UniqueMsisdns(ctx context.Context, from, to time.Time) (int64, error)
+}
+
+// NewAnalytics creates analytics over the logs table in the database, e.g the table of an app from
+// UssdApp.LogsTable. Empty table uses the default logs table
+func NewAnalytics(db *gorm.DB, table string) (Analytics, error) {
+	if db == nil {
+		return nil, errors.New("missing sql db")
+	}
+	if table == "" {
+		table = (&ussdapp.SessionRequest{}).TableName()
+	}
+	return &analytics{db: db, table: table}, nil
+}
+
+type analytics struct {
+	db    *gorm.DB
+	table string
+}
+
+func (a *analytics) logs(ctx context.Context, from, to time.Time) *gorm.DB {
+	return a.db.WithContext(ctx).Table(a.table).Where("created_at BETWEEN ? AND ?", from, to)
+}
+
+func (a *analytics) SessionsPerDay(ctx context.Context, from, to time.Time) ([]*DailySessions, error) {
+	rows := make([]*struct {
+		Day           string
+		Sessions      int64
+		UniqueMsisdns int64
+	}, 0)
+
+	err := a.logs(ctx, from, to).
+		Select("DATE(created_at) AS day, COUNT(DISTINCT session_id) AS sessions, COUNT(DISTINCT msisdn) AS unique_msisdns").
+		Group("DATE(created_at)").
+		Order("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions per day: %v", err)
+	}
+
+	res := make([]*DailySessions, 0, len(rows))
+	for _, row := range rows {
+		if len(row.Day) < 10 {
+			return nil, fmt.Errorf("unexpected day %q", row.Day)
+		}
+		day, err := time.Parse("2006-01-02", row.Day[:10])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse day %q: %v", row.Day, err)
+		}
+		res = append(res, &DailySessions{
+			Day:           day,
+			Sessions:      row.Sessions,
+			UniqueMsisdns: row.UniqueMsisdns,
+		})
+	}
+
+	return res, nil
+}
+
+func (a *analytics) DropOffRates(ctx context.Context, from, to time.Time) ([]*MenuDropOff, error) {
+	type menuCount struct {
+		MenuName string
+		Count    int64
+	}
+
+	reached := make([]*menuCount, 0)
+	err := a.logs(ctx, from, to).
+		Select("menu_name, COUNT(DISTINCT session_id) AS count").
+		Group("menu_name").
+		Scan(&reached).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu sessions: %v", err)
+	}
+
+	// The last request of each session
+	last := a.logs(ctx, from, to).
+		Select("session_id, MAX(created_at) AS last_at").
+		Group("session_id")
+
+	dropped := make([]*menuCount, 0)
+	err = a.db.WithContext(ctx).Table(a.table+" AS l").
+		Select("l.menu_name, COUNT(DISTINCT l.session_id) AS count").
+		Joins("JOIN (?) AS t ON l.session_id = t.session_id AND l.created_at = t.last_at", last).
+		Group("l.menu_name").
+		Scan(&dropped).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu drop offs: %v", err)
+	}
+
+	drops := make(map[string]int64, len(dropped))
+	for _, d := range dropped {
+		drops[d.MenuName] = d.Count
+	}
+
+	res := make([]*MenuDropOff, 0, len(reached))
+	for _, r := range reached {
+		res = append(res, &MenuDropOff{
+			MenuName: r.MenuName,
+			Reached:  r.Count,
+			DropOffs: drops[r.MenuName],
+			Rate:     rate(drops[r.MenuName], r.Count),
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Rate > res[j].Rate
+	})
+
+	return res, nil
+}
+
+func (a *analytics) Funnel(ctx context.Context, fromMenu, toMenu string, from, to time.Time) (*FunnelConversion, error) {
+	res := &FunnelConversion{FromMenu: fromMenu, ToMenu: toMenu}
+
+	err := a.logs(ctx, from, to).
+		Where("menu_name = ?", fromMenu).
+		Distinct("session_id").
+		Count(&res.Entered).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions for %s: %v", fromMenu, err)
+	}
+
+	err = a.db.WithContext(ctx).Table(a.table+" AS a").
+		Joins("JOIN "+a.table+" AS b ON a.session_id = b.session_id AND b.created_at > a.created_at").
+		Where("a.menu_name = ? AND b.menu_name = ?", fromMenu, toMenu).
+		Where("a.created_at BETWEEN ? AND ?", from, to).
+		Distinct("a.session_id").
+		Count(&res.Converted).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions from %s to %s: %v", fromMenu, toMenu, err)
+	}
+
+	res.Rate = rate(res.Converted, res.Entered)
+
+	return res, nil
+}
+
+func (a *analytics) AverageSessionLength(ctx context.Context, from, to time.Time) (*SessionLength, error) {
+	rows, err := a.logs(ctx, from, to).
+		Select("COUNT(*) AS requests, MIN(created_at) AS started_at, MAX(created_at) AS ended_at").
+		Group("session_id").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session lengths: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		res      = &SessionLength{}
+		requests int64
+		duration time.Duration
+	)
+
+	for rows.Next() {
+		var (
+			n          int64
+			start, end time.Time
+		)
+		err = rows.Scan(&n, &start, &end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session length: %v", err)
+		}
+		res.Sessions++
+		requests += n
+		duration += end.Sub(start)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session lengths: %v", err)
+	}
+
+	if res.Sessions > 0 {
+		res.AverageRequests = float64(requests) / float64(res.Sessions)
+		res.AverageDuration = duration / time.Duration(res.Sessions)
+	}
+
+	return res, nil
+}
+
+func (a *analytics) UniqueMsisdns(ctx context.Context, from, to time.Time) (int64, error) {
+	var count int64
+	err := a.logs(ctx, from, to).Distinct("msisdn").Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unique msisdns: %v", err)
+	}
+	return count, nil
+}
+
+func rate(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,4 @@
+/*
+Package analytics has query helpers over USSD session logs saved in the logs table, returning typed results for dashboards.
+*/
+package analytics
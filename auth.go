@@ -0,0 +1,137 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	authenticatedKey      = "authenticated"
+	authPendingMenuKey    = "auth_pending_menu"
+	authPendingPayloadKey = "auth_pending_payload"
+)
+
+// Authenticator tells whether the user of a session is logged in, for menus with RequiresAuth
+type Authenticator interface {
+	Authenticated(ctx context.Context, payload UssdPayload) (bool, error)
+}
+
+// protectedMenu is implemented by menus that are only rendered for sessions that are logged in
+type protectedMenu interface {
+	RequiresAuth() bool
+}
+
+func requiresAuth(m Menu) bool {
+	pm, ok := m.(protectedMenu)
+	return ok && pm.RequiresAuth()
+}
+
+// authenticated reports whether the session is logged in after a call to Login, or with the app authenticator.
+//
+// Sessions that called Login are logged in even if the authenticator does not tell so yet, so that the menu resumed
+// after login is not sent back to the login menu.
+func (app *UssdApp) authenticated(ctx context.Context, payload UssdPayload) (bool, error) {
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), authenticatedKey)
+	switch {
+	case err == nil:
+		if val == "true" {
+			return true, nil
+		}
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return false, fmt.Errorf("failed to get session login: %v", err)
+	}
+
+	if app.opt.Authenticator != nil {
+		return app.opt.Authenticator.Authenticated(ctx, payload)
+	}
+
+	return false, nil
+}
+
+// authorizeMenu returns the login menu in place of a protected menu for sessions that are not logged in, keeping
+// the menu and payload to render them once the session logs in
+func (app *UssdApp) authorizeMenu(ctx context.Context, payload UssdPayload, menu Menu) (Menu, error) {
+	if !requiresAuth(menu) {
+		return menu, nil
+	}
+
+	ok, err := app.authenticated(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return menu, nil
+	}
+
+	login, ok := app.getMenu(app.opt.LoginMenu)
+	if !ok {
+		return nil, fmt.Errorf("menu %s requires auth: login menu %q: %w", menu.MenuName(), app.opt.LoginMenu, ErrMenuNotExist)
+	}
+
+	bs, err := payload.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload),
+		authPendingMenuKey, menu.MenuName(), authPendingPayloadKey, bs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save menu pending login: %v", err)
+	}
+
+	return login, nil
+}
+
+// Login logs the session in and renders the menu that required login, with the input it received. Sessions that
+// went to the login menu on their own get nextMenu.
+//
+// Call it at the end of a login flow once the user is verified, or use SecureInputOptions.Login.
+func (app *UssdApp) Login(ctx context.Context, payload UssdPayload, nextMenu string) (SessionResponse, error) {
+	sessionKey := app.GetSessionKey(payload)
+
+	vals, err := app.opt.Cache.GetMapFields(ctx, sessionKey, authPendingMenuKey, authPendingPayloadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu pending login: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, sessionKey, authenticatedKey, "true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session login: %v", err)
+	}
+
+	menu, ok := app.getMenu(vals[authPendingMenuKey])
+	if !ok {
+		return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+	}
+
+	err = app.opt.Cache.DeleteMapField(ctx, sessionKey, authPendingMenuKey, authPendingPayloadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete menu pending login: %v", err)
+	}
+
+	menuPayload, err := rebindPayload([]byte(vals[authPendingPayloadKey]), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := app.ReplaceMenu(ctx, menuPayload, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	// The menu that rendered the login flow does not move the session on
+	SkipSavingPayload(payload)
+
+	return sr, nil
+}
+
+// Logout logs out a session logged in with Login
+func (app *UssdApp) Logout(ctx context.Context, payload UssdPayload) error {
+	err := app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), authenticatedKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete session login: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultBlockedMessage = "END This number is blocked from using this service"
+	defaultBlocklistTable = "ussd_blocklist"
+	blockedMenuName       = "blocked"
+)
+
+// Block turns a msisdn away from the app, e.g for fraud or abuse
+type Block struct {
+	Msisdn string `json:"msisdn" gorm:"primaryKey;type:varchar(15)"`
+	Reason string `json:"reason,omitempty" gorm:"type:varchar(200)"`
+	// ExpiresAt lifts the block at the time. Blocks without it last until removed
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"type:datetime(6);index"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;type:datetime(6)"`
+}
+
+func (*Block) TableName() string {
+	return defaultBlocklistTable
+}
+
+// expired reports whether the block was lifted by its expiry
+func (b *Block) expired(now time.Time) bool {
+	return b.ExpiresAt != nil && !b.ExpiresAt.After(now)
+}
+
+// Blocklist keeps msisdns blocked from using the app
+type Blocklist interface {
+	// GetBlock returns the block of the msisdn. Returns ErrKeyNotFound if the msisdn is not blocked or the block expired
+	GetBlock(ctx context.Context, msisdn string) (*Block, error)
+	// AddBlock blocks the msisdn, replacing an earlier block
+	AddBlock(ctx context.Context, block *Block) error
+	// RemoveBlock unblocks the msisdn
+	RemoveBlock(ctx context.Context, msisdn string) error
+}
+
+// BlockMsisdn blocks the msisdn from the app for the duration, or until unblocked when ttl is zero
+func (app *UssdApp) BlockMsisdn(ctx context.Context, msisdn, reason string, ttl time.Duration) error {
+	switch {
+	case app.opt.Blocklist == nil:
+		return errors.New("app has no blocklist")
+	case msisdn == "":
+		return errors.New("missing msisdn")
+	}
+
+	block := &Block{Msisdn: msisdn, Reason: reason, CreatedAt: time.Now()}
+	if ttl > 0 {
+		expiresAt := block.CreatedAt.Add(ttl)
+		block.ExpiresAt = &expiresAt
+	}
+
+	return app.opt.Blocklist.AddBlock(ctx, block)
+}
+
+// UnblockMsisdn lifts the block of the msisdn
+func (app *UssdApp) UnblockMsisdn(ctx context.Context, msisdn string) error {
+	if app.opt.Blocklist == nil {
+		return errors.New("app has no blocklist")
+	}
+	return app.opt.Blocklist.RemoveBlock(ctx, msisdn)
+}
+
+// blocked returns the blocked message for msisdns in the blocklist
+func (app *UssdApp) blocked(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if app.opt.Blocklist == nil {
+		return nil, false, nil
+	}
+
+	block, err := app.opt.Blocklist.GetBlock(ctx, payload.Msisdn())
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to check blocklist: %v", err)
+	}
+
+	app.opt.Logger.Warn("blocked msisdn dialed the app", "msisdn", payload.Msisdn(), "reason", block.Reason)
+
+	SkipSavingPayload(payload)
+
+	sr := &sessionResponse{
+		response: firstVal(app.opt.BlockedMessage, defaultBlockedMessage),
+		menuName: blockedMenuName,
+	}
+
+	return sr.End(), true, nil
+}
+
+// NewCacheBlocklist creates a blocklist that keeps blocks in the cache, expiring them with the cache
+func NewCacheBlocklist(cache Cacher, appName string) Blocklist {
+	return &cacheBlocklist{cache: cache, appName: appName}
+}
+
+type cacheBlocklist struct {
+	cache   Cacher
+	appName string
+}
+
+func (l *cacheBlocklist) key(msisdn string) string {
+	return fmt.Sprintf("%s:blocklist:%s", l.appName, msisdn)
+}
+
+func (l *cacheBlocklist) GetBlock(ctx context.Context, msisdn string) (*Block, error) {
+	val, err := l.cache.Get(ctx, l.key(msisdn))
+	if err != nil {
+		return nil, err
+	}
+
+	block := &Block{}
+	err = json.Unmarshal([]byte(val), block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block: %v", err)
+	}
+
+	if block.expired(time.Now()) {
+		return nil, ErrKeyNotFound
+	}
+
+	return block, nil
+}
+
+func (l *cacheBlocklist) AddBlock(ctx context.Context, block *Block) error {
+	var ttl time.Duration
+	if block.ExpiresAt != nil {
+		ttl = time.Until(*block.ExpiresAt)
+		if ttl <= 0 {
+			return l.RemoveBlock(ctx, block.Msisdn)
+		}
+	}
+
+	bs, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to marshal block: %v", err)
+	}
+
+	err = l.cache.Set(ctx, l.key(block.Msisdn), string(bs), ttl)
+	if err != nil {
+		return fmt.Errorf("failed to save block: %v", err)
+	}
+
+	return nil
+}
+
+func (l *cacheBlocklist) RemoveBlock(ctx context.Context, msisdn string) error {
+	err := l.cache.Delete(ctx, l.key(msisdn))
+	if err != nil {
+		return fmt.Errorf("failed to remove block: %v", err)
+	}
+	return nil
+}
+
+// NewGormBlocklist creates a blocklist that keeps blocks in the blocklist table of the database.
+//
+// The table is created if it does not exist. Expired blocks are ignored but left in the table.
+func NewGormBlocklist(db *gorm.DB) (Blocklist, error) {
+	if db == nil {
+		return nil, errors.New("missing db")
+	}
+
+	err := db.AutoMigrate(&Block{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to auto migrate %s table: %v", defaultBlocklistTable, err)
+	}
+
+	return &gormBlocklist{db: db}, nil
+}
+
+type gormBlocklist struct {
+	db *gorm.DB
+}
+
+func (l *gormBlocklist) GetBlock(ctx context.Context, msisdn string) (*Block, error) {
+	block := &Block{}
+
+	err := l.db.WithContext(ctx).Where("msisdn = ?", msisdn).First(block).Error
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("failed to get block: %v", err)
+	}
+
+	if block.expired(time.Now()) {
+		return nil, ErrKeyNotFound
+	}
+
+	return block, nil
+}
+
+func (l *gormBlocklist) AddBlock(ctx context.Context, block *Block) error {
+	err := l.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "msisdn"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "expires_at", "created_at"}),
+	}).Create(block).Error
+	if err != nil {
+		return fmt.Errorf("failed to save block: %v", err)
+	}
+
+	return nil
+}
+
+func (l *gormBlocklist) RemoveBlock(ctx context.Context, msisdn string) error {
+	err := l.db.WithContext(ctx).Where("msisdn = ?", msisdn).Delete(&Block{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove block: %v", err)
+	}
+	return nil
+}
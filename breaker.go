@@ -0,0 +1,303 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 30 * time.Second
+	defaultServiceBusyMessage      = "END Service is busy. Please try again later"
+)
+
+// ErrBreakerOpen is returned by CircuitBreaker.Do when calls to the dependency are short-circuited
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single trial call through after the open timeout, closing the breaker if it succeeds
+	BreakerHalfOpen
+	// BreakerOpen short-circuits calls
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOptions configures a circuit breaker
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of failures in a row that opens the breaker. Defaults to 5
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before a trial call is let through. Defaults to 30 seconds
+	OpenTimeout time.Duration
+	// IsFailure reports whether an error is a failure of the dependency. Defaults to IsConnectionFailure
+	IsFailure func(error) bool
+}
+
+func (o *CircuitBreakerOptions) failureThreshold() int {
+	if o == nil || o.FailureThreshold <= 0 {
+		return defaultBreakerFailureThreshold
+	}
+	return o.FailureThreshold
+}
+
+func (o *CircuitBreakerOptions) openTimeout() time.Duration {
+	if o == nil || o.OpenTimeout <= 0 {
+		return defaultBreakerOpenTimeout
+	}
+	return o.OpenTimeout
+}
+
+func (o *CircuitBreakerOptions) isFailure(err error) bool {
+	if o == nil || o.IsFailure == nil {
+		return IsConnectionFailure(err)
+	}
+	return o.IsFailure(err)
+}
+
+// IsConnectionFailure reports whether an error is a failure to reach a backend: network errors, refused or reset
+// connections, timeouts and errors returned with ErrBackendUnavailable
+func IsConnectionFailure(err error) bool {
+	var ae *Error
+	var ne net.Error
+
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, ErrFailedValidation):
+		return false
+	case errors.As(err, &ae) && ae.Code == CodeBackendUnavailable:
+		return true
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne):
+		return true
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	default:
+		return false
+	}
+}
+
+// CircuitBreaker stops calls to a failing dependency, such as a core banking system, so that users get a busy
+// screen at once instead of waiting for calls that time out
+type CircuitBreaker struct {
+	name     string
+	opt      *CircuitBreakerOptions
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// trial is set while the trial call of a half open breaker is in progress
+	trial    bool
+	onChange func(name string, state BreakerState)
+}
+
+// NewCircuitBreaker creates a closed circuit breaker for the dependency
+func NewCircuitBreaker(name string, opt *CircuitBreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{name: name, opt: opt}
+}
+
+// Name returns the name of the dependency of the breaker
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// State returns the state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.opt.openTimeout() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Allow reports whether a call to the dependency may go through. Callers that are allowed must report the outcome
+// of the call with Record
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.opt.openTimeout() {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+	}
+
+	// A single trial call at a time while half open
+	if b.trial {
+		return false
+	}
+	b.trial = true
+
+	return true
+}
+
+// Record reports the outcome of a call allowed by Allow. Errors that are not failures of the dependency, e.g
+// invalid input, count as successes
+func (b *CircuitBreaker) Record(err error) {
+	failed := b.opt.isFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if !failed {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.opt.failureThreshold() {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// release gives up a call allowed by Allow without an outcome
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// Do calls fn if the breaker allows it and records its outcome. It returns ErrBreakerOpen without calling fn when
+// the breaker is open
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !b.Allow() {
+		return fmt.Errorf("%w: %s", ErrBreakerOpen, b.name)
+	}
+
+	err := fn(ctx)
+	b.Record(err)
+
+	return err
+}
+
+// setState changes the state, the mutex must be held
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(b.name, state)
+	}
+}
+
+// AddCircuitBreaker adds a breaker for the dependency. Menus that declare the dependency in MenuOptions.Dependencies
+// render Options.ServiceBusyMessage while the breaker is open, and their connection failures open it.
+//
+// Menu logic can also wrap calls to the dependency with CircuitBreaker.Do.
+func (app *UssdApp) AddCircuitBreaker(dependency string, opt *CircuitBreakerOptions) (*CircuitBreaker, error) {
+	if dependency == "" {
+		return nil, errors.New("missing dependency name")
+	}
+
+	b := NewCircuitBreaker(dependency, opt)
+	b.onChange = func(name string, state BreakerState) {
+		app.opt.Logger.Warn("circuit breaker changed state", "dependency", name, "state", state.String())
+		app.metrics.breakerChanged(name, state)
+	}
+
+	app.breakersMu.Lock()
+	defer app.breakersMu.Unlock()
+
+	if _, ok := app.breakers[dependency]; ok {
+		return nil, fmt.Errorf("circuit breaker for %s is added", dependency)
+	}
+	app.breakers[dependency] = b
+	app.metrics.breakerChanged(dependency, BreakerClosed)
+
+	return b, nil
+}
+
+// CircuitBreaker returns the breaker of the dependency, or nil if it has none
+func (app *UssdApp) CircuitBreaker(dependency string) *CircuitBreaker {
+	app.breakersMu.RLock()
+	defer app.breakersMu.RUnlock()
+
+	return app.breakers[dependency]
+}
+
+// dependentMenu is implemented by menus that call backend dependencies
+type dependentMenu interface {
+	Dependencies() []string
+}
+
+// menuBreakers returns the breakers of the dependencies of the menu
+func (app *UssdApp) menuBreakers(menu Menu) []*CircuitBreaker {
+	dm, ok := menu.(dependentMenu)
+	if !ok {
+		return nil
+	}
+
+	var breakers []*CircuitBreaker
+	for _, dep := range dm.Dependencies() {
+		if b := app.CircuitBreaker(dep); b != nil {
+			breakers = append(breakers, b)
+		}
+	}
+
+	return breakers
+}
+
+// allowDependencies reports whether the breakers of all dependencies of a menu let it render. It returns the
+// breakers that allowed the menu, whose outcome must be recorded, and the dependency that was refused
+func (app *UssdApp) allowDependencies(breakers []*CircuitBreaker) ([]*CircuitBreaker, string) {
+	allowed := make([]*CircuitBreaker, 0, len(breakers))
+
+	for _, b := range breakers {
+		if !b.Allow() {
+			// Release trials of half open breakers that let the menu through
+			for _, a := range allowed {
+				a.release()
+			}
+			return nil, b.Name()
+		}
+		allowed = append(allowed, b)
+	}
+
+	return allowed, ""
+}
+
+// serviceBusyResponse ends the session with the service busy message, leaving the session on the menu
+func (app *UssdApp) serviceBusyResponse(payload UssdPayload, menu Menu, dependency string) SessionResponse {
+	app.debug(payload, "dependency unavailable", "menu", menu.MenuName(), "dependency", dependency)
+	app.metrics.breakerRejected(dependency)
+
+	SkipSavingPayload(payload)
+
+	return NewSessionResponse(&SessionData{
+		Response:      firstVal(app.opt.ServiceBusyMessage, defaultServiceBusyMessage),
+		StatusMessage: fmt.Sprintf("%s unavailable", dependency),
+		MenuName:      menu.MenuName(),
+		SessionId:     payload.SessionId(),
+		Terminal:      true,
+	})
+}
@@ -0,0 +1,210 @@
+package ussdapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MenuBuilder registers a menu with chained calls instead of a MenuOptions literal, e.g
+//
+//	err := app.Menu("name").
+//		Content("en", "Enter your name").
+//		Next("success").
+//		Route("2", "login").
+//		Validate(MinLen(3)).
+//		Handle(fn)
+//
+// Misconfiguration, such as a menu without content or an input routed twice, is returned by Add or Handle.
+type MenuBuilder struct {
+	app *UssdApp
+	// group receives the menu instead of the app for menus of a MenuGroup
+	group *MenuGroup
+	opt   MenuOptions
+	errs  []string
+}
+
+func newMenuBuilder(name string) *MenuBuilder {
+	return &MenuBuilder{
+		opt: MenuOptions{
+			MenuName:    name,
+			MenuContent: make(Content),
+			Routes:      make(map[string]string),
+		},
+	}
+}
+
+// Menu starts building the menu with the name. Register it with Add, or with Handle for menus with logic
+func (app *UssdApp) Menu(name string) *MenuBuilder {
+	b := newMenuBuilder(name)
+	b.app = app
+	return b
+}
+
+// Content sets the text of the menu in the language. Use an empty language for the default text
+func (b *MenuBuilder) Content(lang, text string) *MenuBuilder {
+	if _, ok := b.opt.MenuContent[lang]; ok {
+		b.errs = append(b.errs, fmt.Sprintf("content in language %q is set twice", lang))
+	}
+	b.opt.MenuContent[lang] = text
+	return b
+}
+
+// ContentFn fetches the menu text each time the menu is rendered, see MenuOptions.ContentFn
+func (b *MenuBuilder) ContentFn(fn ContentFn) *MenuBuilder {
+	b.opt.ContentFn = fn
+	return b
+}
+
+// Next sets the menu rendered after this one
+func (b *MenuBuilder) Next(menuName string) *MenuBuilder {
+	b.opt.NextMenu = menuName
+	return b
+}
+
+// Previous sets the previous menu
+func (b *MenuBuilder) Previous(menuName string) *MenuBuilder {
+	b.opt.PreviousMenu = menuName
+	return b
+}
+
+// Route renders the menu when the user enters the input, instead of the next menu
+func (b *MenuBuilder) Route(input, menuName string) *MenuBuilder {
+	if route, ok := b.opt.Routes[input]; ok {
+		b.errs = append(b.errs, fmt.Sprintf("input %q is routed to both %s and %s", input, route, menuName))
+	}
+	b.opt.Routes[input] = menuName
+	return b
+}
+
+// Decide picks the menu rendered after this one with the decision table, see DecisionTable
+func (b *MenuBuilder) Decide(table *DecisionTable) *MenuBuilder {
+	b.opt.Decisions = table
+	return b
+}
+
+// DependsOn declares the backend services the menu calls, see MenuOptions.Dependencies
+func (b *MenuBuilder) DependsOn(dependencies ...string) *MenuBuilder {
+	b.opt.Dependencies = append(b.opt.Dependencies, dependencies...)
+	return b
+}
+
+// ShortCut opens the menu when a session starts with the ussd string, see MenuOptions.ShortCut
+func (b *MenuBuilder) ShortCut(ussdString string) *MenuBuilder {
+	b.opt.ShortCut = ussdString
+	return b
+}
+
+// Validate adds validators run on the user input before the menu is rendered
+func (b *MenuBuilder) Validate(validators ...Validator) *MenuBuilder {
+	for _, v := range validators {
+		if v == nil {
+			b.errs = append(b.errs, "nil validator")
+			continue
+		}
+		b.opt.Validators = append(b.opt.Validators, v)
+	}
+	return b
+}
+
+// ValidationMessage sets the message shown for invalid input in the language, replacing the validator message
+func (b *MenuBuilder) ValidationMessage(lang, text string) *MenuBuilder {
+	if b.opt.ValidationMessage == nil {
+		b.opt.ValidationMessage = make(Content)
+	}
+	b.opt.ValidationMessage[lang] = text
+	return b
+}
+
+// Sensitive redacts the input received by the menu from session logs
+func (b *MenuBuilder) Sensitive() *MenuBuilder {
+	b.opt.SensitiveInput = true
+	return b
+}
+
+// RequiresAuth renders the login menu instead of the menu for sessions that are not logged in
+func (b *MenuBuilder) RequiresAuth() *MenuBuilder {
+	b.opt.RequiresAuth = true
+	return b
+}
+
+// Segments sets the roles or customer segments that see the menu
+func (b *MenuBuilder) Segments(segments ...string) *MenuBuilder {
+	b.opt.Segments = append(b.opt.Segments, segments...)
+	return b
+}
+
+// Flag turns the menu on with the feature flag, rendering the fallback menu when it is off
+func (b *MenuBuilder) Flag(flag, fallbackMenu string) *MenuBuilder {
+	b.opt.Flag = flag
+	b.opt.FallbackMenu = fallbackMenu
+	return b
+}
+
+// Experiment serves variants of the menu content to sessions
+func (b *MenuBuilder) Experiment(experiment *Experiment) *MenuBuilder {
+	b.opt.Experiment = experiment
+	return b
+}
+
+// BeforeRender is called before the menu is rendered
+func (b *MenuBuilder) BeforeRender(fn BeforeRenderFn) *MenuBuilder {
+	b.opt.BeforeRender = fn
+	return b
+}
+
+// AfterRender is called after the menu is rendered
+func (b *MenuBuilder) AfterRender(fn AfterRenderFn) *MenuBuilder {
+	b.opt.AfterRender = fn
+	return b
+}
+
+// Handle sets the logic that renders the menu and registers it
+func (b *MenuBuilder) Handle(fn MenuHandlerFn) error {
+	if fn == nil {
+		return fmt.Errorf("menu %s: nil handler", b.opt.MenuName)
+	}
+	b.opt.GenerateMenuFn = fn
+	return b.Add()
+}
+
+// Build checks the menu and returns it without registering it
+func (b *MenuBuilder) Build() (Menu, error) {
+	errs := append([]string{}, b.errs...)
+
+	if strings.TrimSpace(b.opt.MenuName) == "" {
+		return nil, errors.New("missing menu name")
+	}
+	if b.opt.NextMenu == "" {
+		errs = append(errs, "missing next menu")
+	}
+
+	// Menus rendered from content need text, which may also come from translation files
+	hasContent := len(b.opt.MenuContent) > 0 || b.opt.ContentFn != nil || b.opt.GenerateMenuFn != nil
+	if !hasContent && b.app != nil && len(b.app.translations[b.opt.MenuName]) > 0 {
+		hasContent = true
+	}
+	if !hasContent {
+		errs = append(errs, "missing content")
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("menu %s: %s", b.opt.MenuName, strings.Join(errs, "; "))
+	}
+
+	opt := b.opt
+	return NewMenu(&opt), nil
+}
+
+// Add checks the menu and registers it with the app, or adds it to the group of the builder
+func (b *MenuBuilder) Add() error {
+	m, err := b.Build()
+	if err != nil {
+		return err
+	}
+	if b.group != nil {
+		opt := b.opt
+		return b.group.AddMenu(&opt)
+	}
+	return b.app.AddMenu(m)
+}
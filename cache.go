@@ -2,7 +2,10 @@ package ussdapp
 
 import (
 	"context"
+	"encoding"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -64,3 +67,118 @@ type Cacher interface {
 	// Set expiration for the specified key
 	Expire(ctx context.Context, key string, dur time.Duration) error
 }
+
+// SetCacher is implemented by caches that add values to sets, such as the redis and memory caches. It is required
+// by Options.GuardConcurrentSessions
+type SetCacher interface {
+	// SetUnique adds a unique value to a set, returning false if the value was in the set
+	SetUnique(ctx context.Context, key string, value string) (bool, error)
+}
+
+func isSetCacher(cache Cacher) bool {
+	_, ok := cache.(SetCacher)
+	return ok
+}
+
+// addToSet adds the value to the set of a cache that implements SetCacher
+func addToSet(ctx context.Context, cache Cacher, key, value string) (bool, error) {
+	sc, ok := cache.(SetCacher)
+	if !ok {
+		return false, errors.New("cache does not support adding to sets")
+	}
+	return sc.SetUnique(ctx, key, value)
+}
+
+// IncrCacher is implemented by caches that increment counters atomically, such as the redis, memory and dynamodb
+// caches. It is required by Options.RateLimit and secure input menus
+type IncrCacher interface {
+	// Incr adds one to the counter at key and returns the count. Counters that do not exist start from zero and
+	// expire after dur
+	Incr(ctx context.Context, key string, dur time.Duration) (int64, error)
+}
+
+func isIncrCacher(cache Cacher) bool {
+	_, ok := cache.(IncrCacher)
+	return ok
+}
+
+// incr increments the counter of a cache that implements IncrCacher
+func incr(ctx context.Context, cache Cacher, key string, dur time.Duration) (int64, error) {
+	ic, ok := cache.(IncrCacher)
+	if !ok {
+		return 0, errors.New("cache does not support counters")
+	}
+	return ic.Incr(ctx, key, dur)
+}
+
+// MapFields reads field value pairs in any of the formats accepted by Cacher.SetMapField, i.e pairs of values, a
+// slice of pairs or a map. Cacher implementations use it to accept the same formats as redis
+func MapFields(values []interface{}) (map[string]string, error) {
+	if len(values) == 1 {
+		switch v := values[0].(type) {
+		case map[string]interface{}:
+			fields := make(map[string]string, len(v))
+			for field, val := range v {
+				fields[field] = CacheString(val)
+			}
+			return fields, nil
+		case map[string]string:
+			fields := make(map[string]string, len(v))
+			for field, val := range v {
+				fields[field] = val
+			}
+			return fields, nil
+		case []string:
+			values = make([]interface{}, 0, len(v))
+			for _, val := range v {
+				values = append(values, val)
+			}
+		case []interface{}:
+			values = v
+		}
+	}
+
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("expected field value pairs, got %d values", len(values))
+	}
+
+	fields := make(map[string]string, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		fields[CacheString(values[i])] = CacheString(values[i+1])
+	}
+
+	return fields, nil
+}
+
+// CacheString formats values the same way redis client does when writing arguments
+func CacheString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case encoding.BinaryMarshaler:
+		bs, err := v.MarshalBinary()
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(bs)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,16 @@
+/*
+Package dynamodb implements USSD caching in an Amazon DynamoDB table.
+
+Keys of the cache are kept in the partition key of the table and fields of hashes, members of sets and plain values in
+its sort key, both of type string. Items carry the unix time in seconds they expire at in a number attribute, which
+should be the time to live attribute of the table so that DynamoDB deletes expired items. Reads skip items that have
+expired but are not deleted yet.
+
+	aws dynamodb create-table --table-name ussd-cache \
+		--attribute-definitions AttributeName=hash,AttributeType=S AttributeName=field,AttributeType=S \
+		--key-schema AttributeName=hash,KeyType=HASH AttributeName=field,KeyType=RANGE \
+		--billing-mode PAY_PER_REQUEST
+	aws dynamodb update-time-to-live --table-name ussd-cache \
+		--time-to-live-specification Enabled=true,AttributeName=expires_at
+*/
+package dynamodbcache
@@ -0,0 +1,600 @@
+package dynamodbcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gidyon/ussdapp"
+)
+
+const (
+	defaultPartitionKey = "hash"
+	defaultSortKey      = "field"
+	defaultTTLAttribute = "expires_at"
+	valueAttribute      = "value"
+	// maxBatchWrite and maxBatchGet are the most requests BatchWriteItem and BatchGetItem take
+	maxBatchWrite   = 25
+	maxBatchGet     = 100
+	maxBatchRetries = 5
+	batchRetryDelay = 50 * time.Millisecond
+)
+
+// Sort keys of the items of a cache key. Fields and members are prefixed so that they never collide
+const (
+	valueSortKey = "@value"
+	// headerSortKey keeps the expiry of hashes and sets, which fields and members set later inherit
+	headerSortKey = "@key"
+	fieldPrefix   = "f:"
+	memberPrefix  = "m:"
+)
+
+// API is the part of the DynamoDB client used by the cache
+type API interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// Options contains data required for the dynamodb cache
+type Options struct {
+	// Client is the DynamoDB client, e.g dynamodb.NewFromConfig(cfg). Point its endpoint resolver to DynamoDB Local in
+	// development
+	Client API
+	// Table keeps the cache
+	Table string
+	// PartitionKey is the name of the partition key of the table. Defaults to hash
+	PartitionKey string
+	// SortKey is the name of the sort key of the table. Defaults to field
+	SortKey string
+	// TTLAttribute is the name of the time to live attribute of the table. Defaults to expires_at
+	TTLAttribute string
+}
+
+// NewDynamoDBCache creates a USSD cacher that keeps data in the DynamoDB table.
+//
+// Expiry has a precision of a second, as DynamoDB keeps the time to live of items in seconds.
+func NewDynamoDBCache(opt *Options) (ussdapp.Cacher, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.Client == nil:
+		return nil, errors.New("missing dynamodb client")
+	case opt.Table == "":
+		return nil, errors.New("missing table")
+	}
+
+	dc := &dynamoCache{
+		client: opt.Client,
+		table:  opt.Table,
+		pk:     opt.PartitionKey,
+		sk:     opt.SortKey,
+		ttl:    opt.TTLAttribute,
+	}
+	if dc.pk == "" {
+		dc.pk = defaultPartitionKey
+	}
+	if dc.sk == "" {
+		dc.sk = defaultSortKey
+	}
+	if dc.ttl == "" {
+		dc.ttl = defaultTTLAttribute
+	}
+
+	return dc, nil
+}
+
+type dynamoCache struct {
+	client API
+	table  string
+	pk     string
+	sk     string
+	ttl    string
+}
+
+type item = map[string]types.AttributeValue
+
+func str(s string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: s}
+}
+
+func num(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+// stringOf returns the string of a string attribute, or the number of a number attribute
+func stringOf(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	default:
+		return ""
+	}
+}
+
+func (dc *dynamoCache) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	return dc.putItem(ctx, dc.newItem(key, valueSortKey, value, expiry(dur)), "", nil)
+}
+
+func (dc *dynamoCache) Get(ctx context.Context, key string) (string, error) {
+	it, err := dc.getItem(ctx, key, valueSortKey)
+	if err != nil {
+		return "", err
+	}
+	if it == nil {
+		return "", ussdapp.ErrKeyNotFound
+	}
+
+	return stringOf(it[valueAttribute]), nil
+}
+
+func (dc *dynamoCache) Delete(ctx context.Context, key string) error {
+	items, err := dc.query(ctx, key, "")
+	if err != nil {
+		return err
+	}
+
+	reqs := make([]types.WriteRequest, 0, len(items))
+	for _, it := range items {
+		reqs = append(reqs, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: dc.keyOf(it)}})
+	}
+
+	return dc.batchWrite(ctx, reqs)
+}
+
+func (dc *dynamoCache) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	vals := make(map[string]string, len(fields))
+	for field, val := range fields {
+		vals[field] = ussdapp.CacheString(val)
+	}
+
+	return dc.setFields(ctx, key, vals)
+}
+
+func (dc *dynamoCache) GetMap(ctx context.Context, key string) (map[string]string, error) {
+	items, err := dc.query(ctx, key, fieldPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(items))
+	for _, it := range items {
+		if dc.live(it) {
+			res[strings.TrimPrefix(stringOf(it[dc.sk]), fieldPrefix)] = stringOf(it[valueAttribute])
+		}
+	}
+
+	return res, nil
+}
+
+func (dc *dynamoCache) DeleteMap(ctx context.Context, key string) error {
+	return dc.Delete(ctx, key)
+}
+
+func (dc *dynamoCache) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	fields, err := ussdapp.MapFields(values)
+	if err != nil {
+		return err
+	}
+
+	return dc.setFields(ctx, key, fields)
+}
+
+func (dc *dynamoCache) GetMapField(ctx context.Context, key, field string) (string, error) {
+	it, err := dc.getItem(ctx, key, fieldPrefix+field)
+	if err != nil {
+		return "", err
+	}
+	if it == nil {
+		return "", ussdapp.ErrKeyNotFound
+	}
+
+	return stringOf(it[valueAttribute]), nil
+}
+
+func (dc *dynamoCache) GetMapFields(ctx context.Context, key string, fields ...string) (map[string]string, error) {
+	res := make(map[string]string, len(fields))
+
+	// BatchGetItem fails on duplicate keys
+	keys := make([]item, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := res[field]; ok {
+			continue
+		}
+		res[field] = ""
+		keys = append(keys, dc.itemKey(key, fieldPrefix+field))
+	}
+
+	items, err := dc.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, it := range items {
+		if dc.live(it) {
+			res[strings.TrimPrefix(stringOf(it[dc.sk]), fieldPrefix)] = stringOf(it[valueAttribute])
+		}
+	}
+
+	return res, nil
+}
+
+func (dc *dynamoCache) DeleteMapField(ctx context.Context, key string, fields ...string) error {
+	reqs := make([]types.WriteRequest, 0, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		reqs = append(reqs, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: dc.itemKey(key, fieldPrefix+field)}})
+	}
+
+	return dc.batchWrite(ctx, reqs)
+}
+
+func (dc *dynamoCache) SetUnique(ctx context.Context, key string, value string) (bool, error) {
+	ttl, err := dc.keyExpiry(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	// Members that expired but are not deleted yet are replaced
+	err = dc.putItem(ctx, dc.newItem(key, memberPrefix+value, "", ttl),
+		"attribute_not_exists(#pk) OR #ttl <= :now",
+		item{":now": num(time.Now().Unix())},
+	)
+	switch {
+	case err == nil:
+		return true, nil
+	case isConditionFailed(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (dc *dynamoCache) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	now := num(time.Now().Unix())
+
+	out, err := dc.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(dc.table),
+		Key:                       dc.itemKey(key, valueSortKey),
+		UpdateExpression:          aws.String("ADD #value :one"),
+		ConditionExpression:       aws.String("attribute_exists(#pk) AND (attribute_not_exists(#ttl) OR #ttl > :now)"),
+		ExpressionAttributeNames:  map[string]string{"#pk": dc.pk, "#ttl": dc.ttl, "#value": valueAttribute},
+		ExpressionAttributeValues: item{":one": num(1), ":now": now},
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+	switch {
+	case err == nil:
+		return strconv.ParseInt(stringOf(out.Attributes[valueAttribute]), 10, 64)
+	case !isConditionFailed(err):
+		return 0, fmt.Errorf("failed to update item: %w", err)
+	}
+
+	// Counters that expired but are not deleted yet start again
+	it := dc.itemKey(key, valueSortKey)
+	it[valueAttribute] = num(1)
+	if ttl := expiry(dur); ttl > 0 {
+		it[dc.ttl] = num(ttl)
+	}
+
+	err = dc.putItem(ctx, it, "attribute_not_exists(#pk) OR #ttl <= :now", item{":now": now})
+	switch {
+	case err == nil:
+		return 1, nil
+	case isConditionFailed(err):
+		// Another request started the counter
+		return dc.Incr(ctx, key, dur)
+	default:
+		return 0, err
+	}
+}
+
+func (dc *dynamoCache) ExistInSet(ctx context.Context, key, value string) (bool, error) {
+	it, err := dc.getItem(ctx, key, memberPrefix+value)
+	if err != nil {
+		return false, err
+	}
+
+	return it != nil, nil
+}
+
+func (dc *dynamoCache) DeleteSetValue(ctx context.Context, key, value string) error {
+	_, err := dc.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(dc.table),
+		Key:       dc.itemKey(key, memberPrefix+value),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+
+	return nil
+}
+
+func (dc *dynamoCache) Expire(ctx context.Context, key string, dur time.Duration) error {
+	if dur <= 0 {
+		return dc.Delete(ctx, key)
+	}
+
+	items, err := dc.query(ctx, key, "")
+	if err != nil {
+		return err
+	}
+
+	ttl := expiry(dur)
+	exists := false
+
+	for _, it := range items {
+		if !dc.live(it) || stringOf(it[dc.sk]) == headerSortKey {
+			continue
+		}
+		exists = true
+
+		_, err = dc.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(dc.table),
+			Key:                       dc.keyOf(it),
+			UpdateExpression:          aws.String("SET #ttl = :ttl"),
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			ExpressionAttributeNames:  map[string]string{"#pk": dc.pk, "#ttl": dc.ttl},
+			ExpressionAttributeValues: item{":ttl": num(ttl)},
+		})
+		// Items deleted since the query stay deleted
+		if err != nil && !isConditionFailed(err) {
+			return fmt.Errorf("failed to update item: %w", err)
+		}
+	}
+
+	// Like redis, keys that do not exist are not given an expiry
+	if !exists {
+		return nil
+	}
+
+	return dc.putItem(ctx, dc.newItem(key, headerSortKey, "", ttl), "", nil)
+}
+
+// setFields puts the fields of the hash, which expire with the hash
+func (dc *dynamoCache) setFields(ctx context.Context, key string, fields map[string]string) error {
+	ttl, err := dc.keyExpiry(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	reqs := make([]types.WriteRequest, 0, len(fields))
+	for field, val := range fields {
+		reqs = append(reqs, types.WriteRequest{PutRequest: &types.PutRequest{Item: dc.newItem(key, fieldPrefix+field, val, ttl)}})
+	}
+
+	return dc.batchWrite(ctx, reqs)
+}
+
+// keyExpiry returns the expiry of the hash or set, zero if it has none
+func (dc *dynamoCache) keyExpiry(ctx context.Context, key string) (int64, error) {
+	it, err := dc.getItem(ctx, key, headerSortKey)
+	if err != nil || it == nil {
+		return 0, err
+	}
+
+	ttl, _ := strconv.ParseInt(stringOf(it[dc.ttl]), 10, 64)
+
+	return ttl, nil
+}
+
+// live reports whether the item has not expired. DynamoDB deletes expired items some time after they expire
+func (dc *dynamoCache) live(it item) bool {
+	av, ok := it[dc.ttl]
+	if !ok {
+		return true
+	}
+
+	ttl, err := strconv.ParseInt(stringOf(av), 10, 64)
+	if err != nil || ttl == 0 {
+		return true
+	}
+
+	return time.Now().Unix() < ttl
+}
+
+func (dc *dynamoCache) itemKey(key, sortKey string) item {
+	return item{dc.pk: str(key), dc.sk: str(sortKey)}
+}
+
+// keyOf returns the primary key of the item
+func (dc *dynamoCache) keyOf(it item) item {
+	return item{dc.pk: it[dc.pk], dc.sk: it[dc.sk]}
+}
+
+func (dc *dynamoCache) newItem(key, sortKey, value string, ttl int64) item {
+	it := dc.itemKey(key, sortKey)
+	it[valueAttribute] = str(value)
+	if ttl > 0 {
+		it[dc.ttl] = num(ttl)
+	}
+	return it
+}
+
+// getItem returns the item, or nil if it does not exist or has expired
+func (dc *dynamoCache) getItem(ctx context.Context, key, sortKey string) (item, error) {
+	out, err := dc.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(dc.table),
+		Key:            dc.itemKey(key, sortKey),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if len(out.Item) == 0 || !dc.live(out.Item) {
+		return nil, nil
+	}
+
+	return out.Item, nil
+}
+
+// putItem puts the item if the condition, which may use #pk and #ttl, holds
+func (dc *dynamoCache) putItem(ctx context.Context, it item, condition string, values item) error {
+	in := &dynamodb.PutItemInput{
+		TableName: aws.String(dc.table),
+		Item:      it,
+	}
+	if condition != "" {
+		in.ConditionExpression = aws.String(condition)
+		in.ExpressionAttributeNames = map[string]string{"#pk": dc.pk, "#ttl": dc.ttl}
+		if len(values) > 0 {
+			in.ExpressionAttributeValues = values
+		}
+	}
+
+	_, err := dc.client.PutItem(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to put item: %w", err)
+	}
+
+	return nil
+}
+
+// query returns the items of the key whose sort key starts with prefix, including expired items
+func (dc *dynamoCache) query(ctx context.Context, key, prefix string) ([]item, error) {
+	in := &dynamodb.QueryInput{
+		TableName:                 aws.String(dc.table),
+		KeyConditionExpression:    aws.String("#pk = :pk"),
+		ExpressionAttributeNames:  map[string]string{"#pk": dc.pk},
+		ExpressionAttributeValues: item{":pk": str(key)},
+		ConsistentRead:            aws.Bool(true),
+	}
+	if prefix != "" {
+		in.KeyConditionExpression = aws.String("#pk = :pk AND begins_with(#sk, :prefix)")
+		in.ExpressionAttributeNames["#sk"] = dc.sk
+		in.ExpressionAttributeValues[":prefix"] = str(prefix)
+	}
+
+	items := make([]item, 0)
+
+	pages := dynamodb.NewQueryPaginator(dc.client, in)
+	for pages.HasMorePages() {
+		out, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query items: %w", err)
+		}
+		items = append(items, out.Items...)
+	}
+
+	return items, nil
+}
+
+// batchGet gets the items in batches, retrying keys DynamoDB leaves unprocessed
+func (dc *dynamoCache) batchGet(ctx context.Context, keys []item) ([]item, error) {
+	items := make([]item, 0, len(keys))
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGet {
+			n = maxBatchGet
+		}
+		batch := keys[:n]
+		keys = keys[n:]
+
+		for attempt := 0; len(batch) > 0; attempt++ {
+			err := dc.waitRetry(ctx, attempt)
+			if err != nil {
+				return nil, err
+			}
+
+			out, err := dc.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					dc.table: {Keys: batch, ConsistentRead: aws.Bool(true)},
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to batch get items: %w", err)
+			}
+
+			items = append(items, out.Responses[dc.table]...)
+			batch = out.UnprocessedKeys[dc.table].Keys
+		}
+	}
+
+	return items, nil
+}
+
+// batchWrite sends the requests in batches, retrying requests DynamoDB leaves unprocessed
+func (dc *dynamoCache) batchWrite(ctx context.Context, reqs []types.WriteRequest) error {
+	for len(reqs) > 0 {
+		n := len(reqs)
+		if n > maxBatchWrite {
+			n = maxBatchWrite
+		}
+		batch := reqs[:n]
+		reqs = reqs[n:]
+
+		for attempt := 0; len(batch) > 0; attempt++ {
+			err := dc.waitRetry(ctx, attempt)
+			if err != nil {
+				return err
+			}
+
+			out, err := dc.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{dc.table: batch},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to batch write items: %w", err)
+			}
+
+			batch = out.UnprocessedItems[dc.table]
+		}
+	}
+
+	return nil
+}
+
+// waitRetry waits before retrying unprocessed requests of a batch, backing off exponentially
+func (dc *dynamoCache) waitRetry(ctx context.Context, attempt int) error {
+	switch {
+	case attempt == 0:
+		return nil
+	case attempt > maxBatchRetries:
+		return fmt.Errorf("batch has unprocessed requests after %d retries", maxBatchRetries)
+	}
+
+	timer := time.NewTimer(batchRetryDelay << (attempt - 1))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isConditionFailed(err error) bool {
+	var cfe *types.ConditionalCheckFailedException
+	return errors.As(err, &cfe)
+}
+
+// expiry returns the unix time in seconds an item set now expires at after dur, rounded up. Zero means no expiry
+func expiry(dur time.Duration) int64 {
+	if dur <= 0 {
+		return 0
+	}
+
+	at := time.Now().Add(dur)
+	if at.Nanosecond() > 0 {
+		return at.Unix() + 1
+	}
+
+	return at.Unix()
+}
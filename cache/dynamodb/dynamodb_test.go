@@ -0,0 +1,173 @@
+package dynamodbcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// stubAPI answers the calls made by a test. Calls it has no function for panic
+type stubAPI struct {
+	API
+	updateItem   func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	putItem      func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	batchGetItem func(*dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error)
+}
+
+func (s *stubAPI) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return s.updateItem(in)
+}
+
+func (s *stubAPI) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return s.putItem(in)
+}
+
+func (s *stubAPI) BatchGetItem(ctx context.Context, in *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	return s.batchGetItem(in)
+}
+
+func newTestCache(t *testing.T, api API) *dynamoCache {
+	t.Helper()
+
+	cache, err := NewDynamoDBCache(&Options{Client: api, Table: "ussd"})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	return cache.(*dynamoCache)
+}
+
+func TestNewDynamoDBCache(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  *Options
+	}{
+		{name: "missing options"},
+		{name: "missing client", opt: &Options{Table: "ussd"}},
+		{name: "missing table", opt: &Options{Client: &stubAPI{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDynamoDBCache(tt.opt)
+			if err == nil {
+				t.Fatal("expected options to be refused")
+			}
+		})
+	}
+
+	dc := newTestCache(t, &stubAPI{})
+	if dc.pk != defaultPartitionKey || dc.sk != defaultSortKey || dc.ttl != defaultTTLAttribute {
+		t.Fatalf("expected default attribute names, got %s, %s and %s", dc.pk, dc.sk, dc.ttl)
+	}
+}
+
+func TestIncr(t *testing.T) {
+	conditionFailed := &types.ConditionalCheckFailedException{}
+
+	counted := func(n string) (*dynamodb.UpdateItemOutput, error) {
+		return &dynamodb.UpdateItemOutput{Attributes: item{valueAttribute: &types.AttributeValueMemberN{Value: n}}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		updates []func() (*dynamodb.UpdateItemOutput, error)
+		puts    []error
+		count   int64
+	}{
+		{
+			name:    "counter exists",
+			updates: []func() (*dynamodb.UpdateItemOutput, error){func() (*dynamodb.UpdateItemOutput, error) { return counted("5") }},
+			count:   5,
+		},
+		{
+			name:    "counter starts",
+			updates: []func() (*dynamodb.UpdateItemOutput, error){func() (*dynamodb.UpdateItemOutput, error) { return nil, conditionFailed }},
+			puts:    []error{nil},
+			count:   1,
+		},
+		{
+			name: "counter started by another request",
+			updates: []func() (*dynamodb.UpdateItemOutput, error){
+				func() (*dynamodb.UpdateItemOutput, error) { return nil, conditionFailed },
+				func() (*dynamodb.UpdateItemOutput, error) { return counted("2") },
+			},
+			puts:  []error{conditionFailed},
+			count: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updates, puts int
+
+			api := &stubAPI{
+				updateItem: func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+					if updates == len(tt.updates) {
+						t.Fatalf("unexpected update %d", updates+1)
+					}
+					updates++
+					return tt.updates[updates-1]()
+				},
+				putItem: func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					if puts == len(tt.puts) {
+						t.Fatalf("unexpected put %d", puts+1)
+					}
+					if got := stringOf(in.Item[valueAttribute]); got != "1" {
+						t.Fatalf("expected counter to start at 1, got %s", got)
+					}
+					if _, ok := in.Item[defaultTTLAttribute]; !ok {
+						t.Fatal("expected counter to expire")
+					}
+					puts++
+					return &dynamodb.PutItemOutput{}, tt.puts[puts-1]
+				},
+			}
+
+			count, err := newTestCache(t, api).Incr(context.Background(), "counter", time.Minute)
+			if err != nil {
+				t.Fatalf("failed to increment: %v", err)
+			}
+			if count != tt.count {
+				t.Fatalf("expected count %d, got %d", tt.count, count)
+			}
+			if updates != len(tt.updates) || puts != len(tt.puts) {
+				t.Fatalf("expected %d updates and %d puts, got %d and %d", len(tt.updates), len(tt.puts), updates, puts)
+			}
+		})
+	}
+}
+
+func TestGetMapFields(t *testing.T) {
+	var dc *dynamoCache
+
+	api := &stubAPI{
+		batchGetItem: func(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+			if n := len(in.RequestItems["ussd"].Keys); n != 3 {
+				t.Fatalf("expected duplicate fields to be requested once, got %d keys", n)
+			}
+
+			live := dc.newItem("session", fieldPrefix+"menu", "home", 0)
+			expired := dc.newItem("session", fieldPrefix+"pin", "1234", time.Now().Add(-time.Minute).Unix())
+
+			return &dynamodb.BatchGetItemOutput{Responses: map[string][]item{"ussd": {live, expired}}}, nil
+		},
+	}
+
+	dc = newTestCache(t, api)
+
+	vals, err := dc.GetMapFields(context.Background(), "session", "menu", "pin", "language", "menu")
+	if err != nil {
+		t.Fatalf("failed to get fields: %v", err)
+	}
+
+	expected := map[string]string{"menu": "home", "pin": "", "language": ""}
+	for field, val := range expected {
+		if vals[field] != val {
+			t.Errorf("expected field %s to be %q, got %q", field, val, vals[field])
+		}
+	}
+}
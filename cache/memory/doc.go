@@ -0,0 +1,6 @@
+/*
+Package memorycache implements USSD caching in process memory.
+
+It is meant for local development and tests where running redis is not practical.
+*/
+package memorycache
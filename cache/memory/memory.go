@@ -0,0 +1,326 @@
+package memorycache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const sweepInterval = time.Minute
+
+// NewMemoryCache creates a USSD cacher that keeps data in memory
+func NewMemoryCache() ussdapp.Cacher {
+	mc := &memoryCache{
+		items:     make(map[string]*item),
+		lastSweep: time.Now(),
+	}
+	return mc
+}
+
+type item struct {
+	value     string
+	hash      map[string]string
+	set       map[string]struct{}
+	expiresAt time.Time
+}
+
+func (it *item) expired(now time.Time) bool {
+	return !it.expiresAt.IsZero() && now.After(it.expiresAt)
+}
+
+type memoryCache struct {
+	mu        sync.Mutex
+	items     map[string]*item
+	lastSweep time.Time
+}
+
+// get returns a live item for the key. Callers must hold the lock
+func (mc *memoryCache) get(key string) (*item, bool) {
+	it, ok := mc.items[key]
+	if !ok {
+		return nil, false
+	}
+	if it.expired(time.Now()) {
+		delete(mc.items, key)
+		return nil, false
+	}
+	return it, true
+}
+
+// sweep removes expired items from memory at most once every sweep interval. Callers must hold the lock
+func (mc *memoryCache) sweep() {
+	now := time.Now()
+	if now.Sub(mc.lastSweep) < sweepInterval {
+		return
+	}
+	for key, it := range mc.items {
+		if it.expired(now) {
+			delete(mc.items, key)
+		}
+	}
+	mc.lastSweep = now
+}
+
+// hashItem returns the hash item for the key, creating it if it doesn't exist. Callers must hold the lock
+func (mc *memoryCache) hashItem(key string) *item {
+	it, ok := mc.get(key)
+	if !ok || it.hash == nil {
+		it = &item{hash: make(map[string]string), expiresAt: expiresAt(it)}
+		mc.items[key] = it
+	}
+	return it
+}
+
+// setItem returns the set item for the key, creating it if it doesn't exist. Callers must hold the lock
+func (mc *memoryCache) setItem(key string) *item {
+	it, ok := mc.get(key)
+	if !ok || it.set == nil {
+		it = &item{set: make(map[string]struct{}), expiresAt: expiresAt(it)}
+		mc.items[key] = it
+	}
+	return it
+}
+
+func expiresAt(it *item) time.Time {
+	if it == nil {
+		return time.Time{}
+	}
+	return it.expiresAt
+}
+
+func (mc *memoryCache) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sweep()
+
+	it := &item{value: value}
+	if dur > 0 {
+		it.expiresAt = time.Now().Add(dur)
+	}
+	mc.items[key] = it
+
+	return nil
+}
+
+func (mc *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok || it.hash != nil || it.set != nil {
+		return "", ussdapp.ErrKeyNotFound
+	}
+
+	return it.value, nil
+}
+
+func (mc *memoryCache) Delete(ctx context.Context, key string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	delete(mc.items, key)
+
+	return nil
+}
+
+func (mc *memoryCache) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sweep()
+
+	it := mc.hashItem(key)
+	for field, val := range fields {
+		it.hash[field] = ussdapp.CacheString(val)
+	}
+
+	return nil
+}
+
+func (mc *memoryCache) GetMap(ctx context.Context, key string) (map[string]string, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	res := make(map[string]string)
+
+	it, ok := mc.get(key)
+	if !ok {
+		return res, nil
+	}
+
+	for field, val := range it.hash {
+		res[field] = val
+	}
+
+	return res, nil
+}
+
+func (mc *memoryCache) DeleteMap(ctx context.Context, key string) error {
+	return mc.Delete(ctx, key)
+}
+
+func (mc *memoryCache) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	fields, err := ussdapp.MapFields(values)
+	if err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sweep()
+
+	it := mc.hashItem(key)
+	for field, val := range fields {
+		it.hash[field] = val
+	}
+
+	return nil
+}
+
+func (mc *memoryCache) GetMapField(ctx context.Context, key, field string) (string, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok {
+		return "", ussdapp.ErrKeyNotFound
+	}
+
+	val, ok := it.hash[field]
+	if !ok {
+		return "", ussdapp.ErrKeyNotFound
+	}
+
+	return val, nil
+}
+
+func (mc *memoryCache) GetMapFields(ctx context.Context, key string, fields ...string) (map[string]string, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	res := make(map[string]string, len(fields))
+
+	it, _ := mc.get(key)
+	for _, field := range fields {
+		if it != nil {
+			res[field] = it.hash[field]
+		} else {
+			res[field] = ""
+		}
+	}
+
+	return res, nil
+}
+
+func (mc *memoryCache) DeleteMapField(ctx context.Context, key string, fields ...string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok {
+		return nil
+	}
+
+	for _, field := range fields {
+		delete(it.hash, field)
+	}
+
+	return nil
+}
+
+func (mc *memoryCache) SetUnique(ctx context.Context, key string, value string) (bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sweep()
+
+	it := mc.setItem(key)
+	_, ok := it.set[value]
+	if ok {
+		return false, nil
+	}
+	it.set[value] = struct{}{}
+
+	return true, nil
+}
+
+func (mc *memoryCache) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sweep()
+
+	it, ok := mc.get(key)
+	if !ok {
+		it = &item{value: "0"}
+		if dur > 0 {
+			it.expiresAt = time.Now().Add(dur)
+		}
+		mc.items[key] = it
+	}
+	if it.hash != nil || it.set != nil {
+		return 0, errors.New("value is not a counter")
+	}
+
+	n, err := strconv.ParseInt(it.value, 10, 64)
+	if err != nil {
+		return 0, errors.New("value is not a counter")
+	}
+	n++
+	it.value = strconv.FormatInt(n, 10)
+
+	return n, nil
+}
+
+func (mc *memoryCache) ExistInSet(ctx context.Context, key, value string) (bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok {
+		return false, nil
+	}
+
+	_, ok = it.set[value]
+
+	return ok, nil
+}
+
+func (mc *memoryCache) DeleteSetValue(ctx context.Context, key, value string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok {
+		return nil
+	}
+
+	delete(it.set, value)
+
+	return nil
+}
+
+func (mc *memoryCache) Expire(ctx context.Context, key string, dur time.Duration) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	it, ok := mc.get(key)
+	if !ok {
+		return nil
+	}
+
+	if dur <= 0 {
+		delete(mc.items, key)
+		return nil
+	}
+
+	it.expiresAt = time.Now().Add(dur)
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/go-redis/redis/v8"
+)
+
+// NewSessionCounter creates a counter of the sessions in progress on all instances of the app, kept in redis
+func NewSessionCounter(conn *redis.Client, appName string) ussdapp.SessionCounter {
+	return &sessionCounter{cc: conn, key: fmt.Sprintf("%s:active_sessions", appName)}
+}
+
+type sessionCounter struct {
+	cc  *redis.Client
+	key string
+}
+
+func (c *sessionCounter) AddSessions(ctx context.Context, delta int64) error {
+	return c.cc.IncrBy(ctx, c.key, delta).Err()
+}
+
+func (c *sessionCounter) CountSessions(ctx context.Context) (int64, error) {
+	n, err := c.cc.Get(ctx, c.key).Int64()
+	switch {
+	case err == nil:
+		return n, nil
+	case errors.Is(err, redis.Nil):
+		return 0, nil
+	default:
+		return 0, err
+	}
+}
@@ -0,0 +1,95 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/go-redis/redis/v8"
+)
+
+const expiryClaimDuration = time.Minute
+
+// EnableExpiredEvents configures redis to publish events for expired keys, keeping the events already enabled.
+//
+// Managed redis services usually disallow CONFIG SET, in which case enable the Ex flags of notify-keyspace-events
+// in the service settings instead.
+func EnableExpiredEvents(ctx context.Context, conn *redis.Client) error {
+	res, err := conn.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("failed to get keyspace events config: %v", err)
+	}
+
+	flags := ""
+	if len(res) == 2 {
+		flags, _ = res[1].(string)
+	}
+
+	if !strings.Contains(flags, "E") {
+		flags += "E"
+	}
+	if !strings.Contains(flags, "x") && !strings.Contains(flags, "A") {
+		flags += "x"
+	}
+
+	err = conn.ConfigSet(ctx, "notify-keyspace-events", flags).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set keyspace events config: %v", err)
+	}
+
+	return nil
+}
+
+// ListenSessionExpiry reports sessions of the app whose keys expire in redis to Options.OnSessionTimeout.
+// It blocks until ctx is done.
+//
+// It subscribes to expired key events of the database of conn, so redis must publish them, see EnableExpiredEvents.
+// All instances of the app may listen, each session is reported by one of them. Set Options.DisableSessionSweeper
+// so that the app does not report timeouts on its own as well.
+func ListenSessionExpiry(ctx context.Context, app *ussdapp.UssdApp, conn *redis.Client) error {
+	channel := fmt.Sprintf("__keyevent@%d__:expired", conn.Options().DB)
+
+	pubsub := conn.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	// Wait for the subscription to be confirmed
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %v", channel, err)
+	}
+
+	ch := pubsub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			sessionID, msisdn, ok := app.ParseSessionKey(msg.Payload)
+			if !ok {
+				continue
+			}
+
+			// Every listener receives the event, only the one that claims it reports the session
+			claimed, err := conn.SetNX(ctx, "expired:"+msg.Payload, 1, expiryClaimDuration).Result()
+			if err != nil {
+				app.Logger().Warn("failed to claim expired session", "session_id", sessionID, "error", err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+
+			err = app.SessionExpired(ctx, sessionID, msisdn)
+			if err != nil {
+				app.Logger().Warn("failed to report expired session", "session_id", sessionID, "error", err)
+			}
+		}
+	}
+}
@@ -71,7 +71,6 @@ func (rc *redisCache) GetMapField(ctx context.Context, key, field string) (strin
 	case err == nil:
 		return res, nil
 	case errors.Is(err, redis.Nil):
-		fmt.Println("KEY not found", key)
 		return "", ussdapp.ErrKeyNotFound
 	default:
 		return "", err
@@ -84,11 +83,15 @@ func (rc *redisCache) GetMapFields(ctx context.Context, key string, fields ...st
 	case err == nil:
 		v := make(map[string]string, len(res))
 		for index, val := range res {
+			// Fields that are not set are empty, as in the other caches
+			if val == nil {
+				v[fields[index]] = ""
+				continue
+			}
 			v[fields[index]] = fmt.Sprint(val)
 		}
 		return v, nil
 	case errors.Is(err, redis.Nil):
-		fmt.Println("KEY not found", key)
 		return nil, ussdapp.ErrKeyNotFound
 	default:
 		return nil, err
@@ -107,6 +110,19 @@ func (rc *redisCache) SetUnique(ctx context.Context, key string, value string) (
 	return res == 1, nil
 }
 
+// incrScript increments the counter, setting the expiry of counters it creates
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (rc *redisCache) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	return incrScript.Run(ctx, rc.cc, []string{key}, dur.Milliseconds()).Int64()
+}
+
 func (rc *redisCache) ExistInSet(ctx context.Context, key, value string) (bool, error) {
 	return rc.cc.SIsMember(ctx, key, value).Result()
 }
@@ -0,0 +1,14 @@
+/*
+Package chat has gateway adapters that serve ussdapp menus over chat channels, WhatsApp Cloud API and Telegram bots.
+
+Each message of a user is an input to the session of the user, so numbered replies select menu options as they do
+over USSD:
+
+	adapter, err := chat.NewTelegramAdapter(&chat.TelegramOptions{SecretToken: secret})
+	if err != nil {
+		// handle error
+	}
+
+	http.Handle("/telegram", app.GatewayHandler(adapter))
+*/
+package chat
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const telegramChannel = "telegram"
+
+// TelegramOptions configures the Telegram adapter
+type TelegramOptions struct {
+	// SecretToken set when registering the webhook. When set, requests without it in
+	// X-Telegram-Bot-Api-Secret-Token are rejected
+	SecretToken string
+	// SessionDuration is how long a conversation is kept without replies, defaults to 24 hours
+	SessionDuration time.Duration
+}
+
+// TelegramUpdate is the part of Telegram webhook updates read by the adapter
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message,omitempty"`
+}
+
+// TelegramMessage is a message sent to the bot
+type TelegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	Chat      TelegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+// TelegramChat is the chat of a message
+type TelegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramReply is the sendMessage call written in the webhook response
+type TelegramReply struct {
+	Method string `json:"method"`
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// NewTelegramAdapter returns a gateway adapter for Telegram bot webhooks.
+//
+// The chat id is used as the session id and msisdn of the payload. Menus are sent back in the webhook response
+// as a sendMessage call. Updates other than text messages are acknowledged without running the menus.
+func NewTelegramAdapter(opt *TelegramOptions) (ussdapp.GatewayAdapter, error) {
+	if opt == nil {
+		return nil, errors.New("missing options")
+	}
+
+	a := &telegramAdapter{opt: *opt}
+	if a.opt.SessionDuration <= 0 {
+		a.opt.SessionDuration = defaultSessionDuration
+	}
+
+	return a, nil
+}
+
+type telegramAdapter struct {
+	opt TelegramOptions
+}
+
+func (a *telegramAdapter) ParseRequest(r *http.Request) (ussdapp.UssdPayload, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	if a.opt.SecretToken != "" {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.opt.SecretToken)) != 1 {
+			return nil, errors.New("invalid telegram secret token")
+		}
+	}
+
+	update := &TelegramUpdate{}
+	err := json.NewDecoder(r.Body).Decode(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode telegram update: %v", err)
+	}
+
+	if update.Message == nil || update.Message.Text == "" {
+		return nil, ussdapp.ErrSkipRequest
+	}
+
+	return ussdapp.NewConversationPayload(&ussdapp.ConversationMessage{
+		Channel:         telegramChannel,
+		Sender:          strconv.FormatInt(update.Message.Chat.ID, 10),
+		Text:            update.Message.Text,
+		SessionDuration: a.opt.SessionDuration,
+	}), nil
+}
+
+func (*telegramAdapter) WriteResponse(w http.ResponseWriter, sr ussdapp.SessionResponse) error {
+	return errors.New("telegram replies need the chat of the request")
+}
+
+// WritePayloadResponse replies to the chat of the payload
+func (*telegramAdapter) WritePayloadResponse(
+	_ context.Context, w http.ResponseWriter, payload ussdapp.UssdPayload, sr ussdapp.SessionResponse,
+) error {
+	chatID, err := strconv.ParseInt(payload.Msisdn(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid chat id %s: %v", payload.Msisdn(), err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err = json.NewEncoder(w).Encode(&TelegramReply{
+		Method: "sendMessage",
+		ChatID: chatID,
+		Text:   ussdapp.ResponseMessage(sr),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const (
+	defaultSessionDuration = 24 * time.Hour
+	defaultGraphURL        = "https://graph.facebook.com/v19.0"
+)
+
+// WhatsAppOptions configures the WhatsApp Cloud API adapter
+type WhatsAppOptions struct {
+	// AccessToken used to send messages through the Graph API
+	AccessToken string
+	// VerifyToken set when registering the webhook, it is checked by the verification request
+	VerifyToken string
+	// AppSecret of the Meta app. When set, requests without a valid X-Hub-Signature-256 are rejected
+	AppSecret string
+	// GraphURL is the versioned url of the Graph API, defaults to https://graph.facebook.com/v19.0
+	GraphURL string
+	// HTTPClient sends messages, defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+	// SessionDuration is how long a conversation is kept without replies, defaults to 24 hours
+	SessionDuration time.Duration
+}
+
+// WhatsAppWebhook is the part of WhatsApp webhook notifications read by the adapter
+type WhatsAppWebhook struct {
+	Object string          `json:"object"`
+	Entry  []WhatsAppEntry `json:"entry"`
+}
+
+// WhatsAppEntry is an entry of a webhook notification
+type WhatsAppEntry struct {
+	ID      string           `json:"id"`
+	Changes []WhatsAppChange `json:"changes"`
+}
+
+// WhatsAppChange is a change of a webhook entry
+type WhatsAppChange struct {
+	Field string              `json:"field"`
+	Value WhatsAppChangeValue `json:"value"`
+}
+
+// WhatsAppChangeValue holds the messages received by a business number
+type WhatsAppChangeValue struct {
+	Metadata struct {
+		PhoneNumberID string `json:"phone_number_id"`
+	} `json:"metadata"`
+	Messages []WhatsAppMessage `json:"messages"`
+}
+
+// WhatsAppMessage is a message sent by a user. Replies to interactive buttons and lists carry the id of the option
+type WhatsAppMessage struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	Type string `json:"type"`
+	Text struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Interactive struct {
+		ButtonReply struct {
+			ID string `json:"id"`
+		} `json:"button_reply"`
+		ListReply struct {
+			ID string `json:"id"`
+		} `json:"list_reply"`
+	} `json:"interactive"`
+}
+
+// input returns the text of the message or the id of the selected option
+func (m *WhatsAppMessage) input() string {
+	switch m.Type {
+	case "text":
+		return m.Text.Body
+	case "interactive":
+		if m.Interactive.ButtonReply.ID != "" {
+			return m.Interactive.ButtonReply.ID
+		}
+		return m.Interactive.ListReply.ID
+	default:
+		return ""
+	}
+}
+
+// NewWhatsAppAdapter returns a gateway adapter for WhatsApp Cloud API webhooks.
+//
+// The msisdn of the user is used as the session id and the business phone number id as the service code.
+// Webhooks are acknowledged straight away and menus are sent to the user through the Graph API.
+// Notifications without a text or interactive reply, like delivery statuses, do not run the menus.
+//
+// WhatsApp verifies the webhook with a GET request, serve it using NewWhatsAppHandler.
+func NewWhatsAppAdapter(opt *WhatsAppOptions) (ussdapp.GatewayAdapter, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.AccessToken == "":
+		return nil, errors.New("missing access token")
+	}
+
+	a := &whatsAppAdapter{opt: *opt}
+	if a.opt.GraphURL == "" {
+		a.opt.GraphURL = defaultGraphURL
+	}
+	if a.opt.HTTPClient == nil {
+		a.opt.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if a.opt.SessionDuration <= 0 {
+		a.opt.SessionDuration = defaultSessionDuration
+	}
+
+	return a, nil
+}
+
+// NewWhatsAppHandler returns an http handler for the WhatsApp webhook. It answers the verification request and
+// runs the menus of the app for message notifications.
+func NewWhatsAppHandler(app *ussdapp.UssdApp, opt *WhatsAppOptions) (http.Handler, error) {
+	adapter, err := NewWhatsAppAdapter(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := app.GatewayHandler(adapter)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			gateway.ServeHTTP(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		token := q.Get("hub.verify_token")
+		if q.Get("hub.mode") != "subscribe" || opt.VerifyToken == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(opt.VerifyToken)) != 1 {
+			http.Error(w, "verification failed", http.StatusForbidden)
+			return
+		}
+
+		io.WriteString(w, q.Get("hub.challenge"))
+	}), nil
+}
+
+type whatsAppAdapter struct {
+	opt WhatsAppOptions
+}
+
+func (a *whatsAppAdapter) ParseRequest(r *http.Request) (ussdapp.UssdPayload, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	if a.opt.AppSecret != "" && !a.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		return nil, errors.New("invalid whatsapp signature")
+	}
+
+	webhook := &WhatsAppWebhook{}
+	err = json.Unmarshal(body, webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode whatsapp webhook: %v", err)
+	}
+
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			for _, msg := range change.Value.Messages {
+				input := msg.input()
+				if input == "" || msg.From == "" {
+					continue
+				}
+
+				return ussdapp.NewConversationPayload(&ussdapp.ConversationMessage{
+					Channel:         change.Value.Metadata.PhoneNumberID,
+					Sender:          msg.From,
+					Text:            input,
+					SessionDuration: a.opt.SessionDuration,
+				}), nil
+			}
+		}
+	}
+
+	return nil, ussdapp.ErrSkipRequest
+}
+
+// validSignature checks the signature of the body, which is sha256= followed by the hex HMAC-SHA256 of the body
+func (a *whatsAppAdapter) validSignature(header string, body []byte) bool {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.opt.AppSecret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), signature)
+}
+
+func (*whatsAppAdapter) WriteResponse(w http.ResponseWriter, sr ussdapp.SessionResponse) error {
+	return errors.New("whatsapp replies need the sender of the request")
+}
+
+// WritePayloadResponse acknowledges the webhook and sends the menu to the sender of the payload
+func (a *whatsAppAdapter) WritePayloadResponse(
+	ctx context.Context, w http.ResponseWriter, payload ussdapp.UssdPayload, sr ussdapp.SessionResponse,
+) error {
+	// WhatsApp retries webhooks that are not acknowledged, which would repeat the input
+	w.WriteHeader(http.StatusOK)
+
+	bs, err := json.Marshal(map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                payload.Msisdn(),
+		"type":              "text",
+		"text":              map[string]string{"body": ussdapp.ResponseMessage(sr)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", strings.TrimSuffix(a.opt.GraphURL, "/"), payload.ServiceCode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.opt.AccessToken)
+
+	res, err := a.opt.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send whatsapp message: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("failed to send whatsapp message: %s: %s", res.Status, msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Command ussdsim drives a running ussd app from the terminal.
+//
+// Usage:
+//
+//	ussdsim -url http://localhost:8080/ussd -msisdn 254700000000 -code '*123#' -protocol africastalking
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/gidyon/ussdapp/simulator"
+)
+
+var (
+	appURL      = flag.String("url", "http://localhost:8080/", "URL of the ussd app")
+	msisdn      = flag.String("msisdn", "254700000000", "Phone number sessions are dialed from")
+	serviceCode = flag.String("code", "*123#", "USSD code dialed")
+	protocol    = flag.String("protocol", "generic", "Request format: generic or africastalking")
+)
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	opt := &simulator.Options{
+		URL:         *appURL,
+		Msisdn:      *msisdn,
+		ServiceCode: *serviceCode,
+	}
+
+	switch *protocol {
+	case "generic":
+		opt.Protocol = simulator.Generic
+	case "africastalking":
+		opt.Protocol = simulator.AfricasTalking
+	default:
+		fmt.Fprintf(os.Stderr, "unknown protocol %s\n", *protocol)
+		os.Exit(2)
+	}
+
+	sim, err := simulator.NewSimulator(opt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = sim.Run(ctx, os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,179 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultConcurrentSessionMessage = "END You have another session in progress. Please try again later"
+	defaultEndedSessionMessage      = "END This session ended because you started a new session"
+	concurrentSessionMenuName       = "concurrent_session"
+)
+
+// ConcurrentSessionAction is what happens to a session started while the msisdn has another session in progress
+type ConcurrentSessionAction int
+
+const (
+	// EndOldSession discards the data of the session in progress and continues with the new one. Later requests of
+	// the old session are ended
+	EndOldSession ConcurrentSessionAction = iota
+	// RejectNewSession ends the new session with Options.ConcurrentSessionMessage
+	RejectNewSession
+	// AllowConcurrentSession lets both sessions continue
+	AllowConcurrentSession
+)
+
+// ConcurrentSessionFn decides what to do with a new session when the msisdn has the session with activeSessionID in progress
+type ConcurrentSessionFn func(ctx context.Context, payload UssdPayload, activeSessionID string) (ConcurrentSessionAction, error)
+
+func (app *UssdApp) activeSessionKey(msisdn string) string {
+	return fmt.Sprintf("%s:active:%s", app.opt.AppName, msisdn)
+}
+
+// endedSessionsKey is the set of sessions of the msisdn ended by a newer session
+func (app *UssdApp) endedSessionsKey(msisdn string) string {
+	return fmt.Sprintf("%s:ended_sessions:%s", app.opt.AppName, msisdn)
+}
+
+// guardSession checks new sessions against the session in progress for the msisdn and marks the new session as active.
+//
+// It returns false if the new session can continue.
+func (app *UssdApp) guardSession(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if !app.opt.GuardConcurrentSessions {
+		return nil, false, nil
+	}
+
+	// Requests of a session ended by a newer session look like new sessions, as the data of the session is gone
+	endedKey := app.endedSessionsKey(payload.Msisdn())
+
+	ended, err := app.opt.Cache.ExistInSet(ctx, endedKey, payload.SessionId())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check ended sessions: %v", err)
+	}
+	if ended {
+		err = app.opt.Cache.DeleteMap(ctx, app.GetSessionKey(payload))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to delete ended session: %v", err)
+		}
+
+		// The session is told it ended, so it sends no more requests
+		err = app.opt.Cache.DeleteSetValue(ctx, endedKey, payload.SessionId())
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to remove ended session: %v", err)
+		}
+
+		SkipSavingPayload(payload)
+
+		sr := &sessionResponse{response: defaultEndedSessionMessage, menuName: concurrentSessionMenuName}
+
+		return sr.End(), true, nil
+	}
+
+	activeKey := app.activeSessionKey(payload.Msisdn())
+
+	activeID, err := app.opt.Cache.Get(ctx, activeKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return nil, false, fmt.Errorf("failed to get active session: %v", err)
+	}
+
+	if activeID != "" && activeID != payload.SessionId() {
+		activeSessionKey := app.sessionKeyOf(activeID, payload.Msisdn())
+
+		// The session may have expired before the key
+		_, err = app.opt.Cache.GetMapField(ctx, activeSessionKey, "new")
+		switch {
+		case err == nil:
+			action := EndOldSession
+			if app.opt.OnConcurrentSession != nil {
+				action, err = app.opt.OnConcurrentSession(ctx, payload, activeID)
+				if err != nil {
+					return nil, false, err
+				}
+			}
+
+			switch action {
+			case RejectNewSession:
+				err = app.opt.Cache.DeleteMap(ctx, app.GetSessionKey(payload))
+				if err != nil {
+					return nil, false, fmt.Errorf("failed to delete rejected session: %v", err)
+				}
+
+				SkipSavingPayload(payload)
+
+				sr := &sessionResponse{
+					response: firstVal(app.opt.ConcurrentSessionMessage, defaultConcurrentSessionMessage),
+					menuName: concurrentSessionMenuName,
+				}
+
+				return sr.End(), true, nil
+			case EndOldSession:
+				err = app.opt.Cache.DeleteMap(ctx, activeSessionKey)
+				if err != nil {
+					return nil, false, fmt.Errorf("failed to end session %s: %v", activeID, err)
+				}
+
+				_, err = addToSet(ctx, app.opt.Cache, endedKey, activeID)
+				if err == nil {
+					err = app.opt.Cache.Expire(ctx, endedKey, app.opt.SessionDuration)
+				}
+				if err != nil {
+					return nil, false, fmt.Errorf("failed to save ended session %s: %v", activeID, err)
+				}
+			}
+		case errors.Is(err, ErrKeyNotFound):
+		default:
+			return nil, false, fmt.Errorf("failed to get active session data: %v", err)
+		}
+	}
+
+	err = app.opt.Cache.Set(ctx, activeKey, payload.SessionId(), app.opt.SessionDuration)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save active session: %v", err)
+	}
+
+	return nil, false, nil
+}
+
+// releaseSession clears the active session of the msisdn once the session ends. Sessions in progress keep the msisdn
+// active for the session duration after their latest request, so sessions dropped by the network are released
+// once idle instead of a session duration after they started
+func (app *UssdApp) releaseSession(ctx context.Context, payload UssdPayload, sr SessionResponse) error {
+	if !app.opt.GuardConcurrentSessions {
+		return nil
+	}
+
+	activeKey := app.activeSessionKey(payload.Msisdn())
+
+	activeID, err := app.opt.Cache.Get(ctx, activeKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil
+	default:
+		return fmt.Errorf("failed to get active session: %v", err)
+	}
+
+	if activeID != payload.SessionId() {
+		return nil
+	}
+
+	if !sr.Terminal() {
+		err = app.opt.Cache.Expire(ctx, activeKey, app.opt.SessionDuration)
+		if err != nil {
+			return fmt.Errorf("failed to extend active session: %v", err)
+		}
+		return nil
+	}
+
+	err = app.opt.Cache.Delete(ctx, activeKey)
+	if err != nil {
+		return fmt.Errorf("failed to clear active session: %v", err)
+	}
+
+	return nil
+}
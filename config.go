@@ -0,0 +1,128 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MenuHandlerFn is the logic that renders a menu. It has the same signature as MenuOptions.GenerateMenuFn
+type MenuHandlerFn func(context.Context, UssdPayload, Menu) (SessionResponse, error)
+
+// MenuConfig is the declarative definition of a menu
+type MenuConfig struct {
+	Name     string            `json:"name" yaml:"name"`
+	Previous string            `json:"previous,omitempty" yaml:"previous,omitempty"`
+	Next     string            `json:"next" yaml:"next"`
+	ShortCut string            `json:"shortcut,omitempty" yaml:"shortcut,omitempty"`
+	Content  Content           `json:"content" yaml:"content"`
+	Routes   map[string]string `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// Decisions picks the next menu from the input and session data, see DecisionTable
+	Decisions *DecisionTable `json:"decisions,omitempty" yaml:"decisions,omitempty"`
+	// Dependencies are the backend services the menu calls, see MenuOptions.Dependencies
+	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	// Handler is the name of a handler registered with RegisterMenuHandler.
+	//
+	// Menus without a handler render their content in the session language.
+	Handler string `json:"handler,omitempty" yaml:"handler,omitempty"`
+}
+
+// MenusConfig is the declarative definition of a menu tree
+type MenusConfig struct {
+	Menus []*MenuConfig `json:"menus" yaml:"menus"`
+}
+
+// RegisterMenuHandler registers menu logic with the given name so that menus defined in config can bind to it
+func (app *UssdApp) RegisterMenuHandler(name string, fn MenuHandlerFn) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("missing handler name")
+	case fn == nil:
+		return fmt.Errorf("nil handler %s not allowed", name)
+	}
+
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	_, ok := app.handlers[name]
+	if ok {
+		return fmt.Errorf("handler %s is registered", name)
+	}
+
+	app.handlers[name] = fn
+
+	return nil
+}
+
+// AddMenusFromFile reads menus defined in a YAML or JSON file and registers them.
+//
+// The format is detected from the file extension.
+func (app *UssdApp) AddMenusFromFile(fileName string) error {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read menus file: %v", err)
+	}
+
+	cfg := &MenusConfig{}
+
+	switch ext := strings.ToLower(filepath.Ext(fileName)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bs, cfg)
+	case ".json":
+		err = json.Unmarshal(bs, cfg)
+	default:
+		return fmt.Errorf("unsupported menus file format %s", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse menus file %s: %v", fileName, err)
+	}
+
+	return app.AddMenusFromConfig(cfg)
+}
+
+// AddMenusFromConfig registers menus in the config, binding each menu to its registered handler
+func (app *UssdApp) AddMenusFromConfig(cfg *MenusConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("nil menus config not allowed")
+	}
+
+	for _, mc := range cfg.Menus {
+		if mc == nil {
+			continue
+		}
+
+		// Menus without a handler render their content
+		var handler MenuHandlerFn
+		if mc.Handler != "" {
+			app.registryMu.Lock()
+			fn, ok := app.handlers[mc.Handler]
+			app.registryMu.Unlock()
+			if !ok {
+				return fmt.Errorf("handler %s for %s menu is not registered", mc.Handler, mc.Name)
+			}
+			handler = fn
+		}
+
+		err := app.AddMenu(NewMenu(&MenuOptions{
+			MenuName:       mc.Name,
+			PreviousMenu:   mc.Previous,
+			NextMenu:       mc.Next,
+			ShortCut:       mc.ShortCut,
+			MenuContent:    mc.Content,
+			Routes:         mc.Routes,
+			Decisions:      mc.Decisions,
+			Dependencies:   mc.Dependencies,
+			GenerateMenuFn: handler,
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
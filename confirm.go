@@ -0,0 +1,143 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	confirmAnswerStep    = "answer"
+	confirmInput         = "1"
+	cancelInput          = "2"
+	defaultConfirmText   = "Confirm"
+	defaultCancelText    = "Cancel"
+	defaultConfirmPrompt = "Confirm?"
+)
+
+// ConfirmFn is called when the user confirms
+type ConfirmFn func(ctx context.Context, payload UssdPayload) error
+
+// ConfirmMenuOptions contains data for a menu that asks the user to confirm a summary of what they entered
+type ConfirmMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Summary is shown above the choices, per language. Placeholders like {{.amount}} are replaced with session data
+	Summary Content
+	// SummaryFn builds the summary each time the menu is rendered, replacing Summary
+	SummaryFn ContentFn
+	// ConfirmText labels the confirm choice, per language. Defaults to Confirm
+	ConfirmText Content
+	// CancelText labels the cancel choice, per language. Defaults to Cancel
+	CancelText Content
+	// OnConfirm is called when the user confirms, before the success menu is rendered
+	OnConfirm ConfirmFn
+	// SuccessMenu is rendered once OnConfirm succeeds. Defaults to the home menu
+	SuccessMenu string
+	// CancelMenu is rendered when the user cancels. Defaults to the home menu
+	CancelMenu string
+}
+
+// AddConfirmMenu registers a menu that renders a summary followed by 1. Confirm 2. Cancel.
+//
+// Confirming calls OnConfirm then renders the success menu, cancelling renders the cancel menu.
+// The answer is received by a menu named <menu>:answer.
+func (app *UssdApp) AddConfirmMenu(opt *ConfirmMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing confirm menu options")
+	case opt.MenuName == "":
+		return errors.New("missing confirm menu name")
+	}
+
+	var (
+		answerMenu  = fmt.Sprintf("%s:%s", opt.MenuName, confirmAnswerStep)
+		successMenu = firstVal(opt.SuccessMenu, app.homeMenu)
+		cancelMenu  = firstVal(opt.CancelMenu, app.homeMenu)
+	)
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName: opt.MenuName,
+			NextMenu: answerMenu,
+			ShortCut: opt.ShortCut,
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				summary, err := app.confirmSummary(ctx, payload, opt)
+				if err != nil {
+					return nil, err
+				}
+
+				lang := app.GetLanguage(ctx, payload)
+				lines := []string{
+					summary,
+					fmt.Sprintf("%s. %s", confirmInput, firstVal(opt.ConfirmText.Text(lang, app.opt.DefaultLanguage), defaultConfirmText)),
+					fmt.Sprintf("%s. %s", cancelInput, firstVal(opt.CancelText.Text(lang, app.opt.DefaultLanguage), defaultCancelText)),
+				}
+
+				return &sessionResponse{
+					response: fmt.Sprintf("%s %s", conPrefix, strings.Join(lines, "\n")),
+					menuName: m.MenuName(),
+				}, nil
+			},
+		}),
+		NewMenu(&MenuOptions{
+			MenuName:   answerMenu,
+			NextMenu:   successMenu,
+			Validators: []Validator{OneOf(confirmInput, cancelInput)},
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				if payload.UssdCurrentParam() == cancelInput {
+					return app.ReplaceMenuWithName(ctx, cancelMenu, payload)
+				}
+
+				if opt.OnConfirm != nil {
+					err := opt.OnConfirm(ctx, payload)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				return app.ReplaceMenuWithName(ctx, successMenu, payload)
+			},
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// confirmSummary renders the summary of a confirm menu in the session language
+func (app *UssdApp) confirmSummary(ctx context.Context, payload UssdPayload, opt *ConfirmMenuOptions) (string, error) {
+	lang := app.GetLanguage(ctx, payload)
+
+	if opt.SummaryFn != nil {
+		summary, err := opt.SummaryFn(ctx, payload, lang)
+		if err != nil {
+			return "", fmt.Errorf("failed to get summary of menu %s: %w", opt.MenuName, err)
+		}
+		return strings.TrimSpace(summary), nil
+	}
+
+	summary := firstVal(opt.Summary.Text(lang, app.opt.DefaultLanguage), defaultConfirmPrompt)
+	if !strings.Contains(summary, "{{") {
+		return summary, nil
+	}
+
+	data, err := app.opt.Cache.GetMap(ctx, app.GetSessionKey(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to get session data: %v", err)
+	}
+
+	summary, err = executeTemplate(summary, data)
+	if err != nil {
+		return "", fmt.Errorf("menu %s: %v", opt.MenuName, err)
+	}
+
+	return strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,48 @@
+package ussdapp
+
+import "context"
+
+// RequestContext is the request being processed, carried in the context passed to menus and hooks so that business
+// code called from them can read session data without taking the payload as a parameter
+type RequestContext struct {
+	App     *UssdApp
+	Payload UssdPayload
+	Session Session
+	// Menu is the menu being rendered. It is nil before the menu of the request is resolved, e.g in middlewares
+	Menu Menu
+}
+
+type requestContextKey struct{}
+
+// FromContext returns the request set in the context by ProcessPayload. It returns false outside of requests
+func FromContext(ctx context.Context) (*RequestContext, bool) {
+	rc, ok := ctx.Value(requestContextKey{}).(*RequestContext)
+	return rc, ok && rc != nil
+}
+
+// NewContext returns a copy of ctx carrying the request, e.g for tests of code that reads it with FromContext
+func NewContext(ctx context.Context, rc *RequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, rc)
+}
+
+// withRequest returns ctx carrying the payload and session of the request
+func (app *UssdApp) withRequest(ctx context.Context, payload UssdPayload) context.Context {
+	return NewContext(ctx, &RequestContext{
+		App:     app,
+		Payload: payload,
+		Session: app.Session(payload),
+	})
+}
+
+// withMenu returns ctx carrying the request with the menu being rendered
+func withMenu(ctx context.Context, menu Menu) context.Context {
+	rc, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	cp := *rc
+	cp.Menu = menu
+
+	return NewContext(ctx, &cp)
+}
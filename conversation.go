@@ -0,0 +1,96 @@
+package ussdapp
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"time"
+)
+
+// Conversation is the screens a user was shown and the inputs they entered in a session, rebuilt from session logs
+// for support investigations
+type Conversation struct {
+	SessionID string `json:"session_id"`
+	// Msisdn is the msisdn as saved in the logs, so it is hashed or masked under a PIIPolicy
+	Msisdn    string              `json:"msisdn"`
+	StartedAt time.Time           `json:"started_at"`
+	Turns     []*ConversationTurn `json:"turns"`
+}
+
+// ConversationTurn is an input of the user and the screen the app responded with
+type ConversationTurn struct {
+	Time time.Time `json:"time"`
+	// Input is what the user entered, redacted for sensitive menus
+	Input    string `json:"input"`
+	MenuName string `json:"menu_name"`
+	// Screen is the text shown to the user. It is empty unless Options.LogResponseText is set
+	Screen        string `json:"screen,omitempty"`
+	Succeeded     bool   `json:"succeeded"`
+	Ended         bool   `json:"ended"`
+	StatusMessage string `json:"status_message,omitempty"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// GetConversation rebuilds the conversation of a session from its logs in Options.SQLDB
+func (app *UssdApp) GetConversation(ctx context.Context, sessionID string) (*Conversation, error) {
+	trail, err := app.GetSessionTrail(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &Conversation{
+		SessionID: trail.SessionID,
+		Msisdn:    trail.Msisdn,
+		StartedAt: trail.StartedAt,
+		Turns:     make([]*ConversationTurn, 0, len(trail.Steps)),
+	}
+
+	for _, step := range trail.Steps {
+		conv.Turns = append(conv.Turns, &ConversationTurn{
+			Time:          step.Time,
+			Input:         step.UserInput,
+			MenuName:      step.MenuName,
+			Screen:        step.ResponseText,
+			Succeeded:     step.Succeeded,
+			Ended:         step.Ended,
+			StatusMessage: step.StatusMessage,
+			DurationMs:    step.DurationMs,
+		})
+	}
+
+	return conv, nil
+}
+
+var conversationTemplate = template.Must(template.New("conversation").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Session {{.SessionID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; color: #222; }
+.turn { margin: 1em 0; }
+.input { text-align: right; }
+.input span { background: #dcf8c6; padding: .4em .8em; border-radius: .6em; display: inline-block; }
+.screen { background: #f1f1f1; padding: .6em .8em; border-radius: .6em; white-space: pre-wrap; font-family: monospace; }
+.failed .screen { border-left: 3px solid #c0392b; }
+.meta { color: #888; font-size: .8em; margin-top: .3em; }
+</style>
+</head>
+<body>
+<h2>Session {{.SessionID}}</h2>
+<p class="meta">{{.Msisdn}} &middot; started {{.StartedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Turns}}
+<div class="turn{{if not .Succeeded}} failed{{end}}">
+{{if .Input}}<div class="input"><span>{{.Input}}</span></div>{{end}}
+<div class="screen">{{if .Screen}}{{.Screen}}{{else}}[{{.MenuName}}]{{end}}</div>
+<div class="meta">{{.Time.Format "15:04:05.000"}} &middot; {{.MenuName}} &middot; {{.DurationMs}}ms{{if .Ended}} &middot; ended{{end}}{{if .StatusMessage}} &middot; {{.StatusMessage}}{{end}}</div>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the conversation as a simple chat-like html page
+func (conv *Conversation) WriteHTML(w io.Writer) error {
+	return conversationTemplate.Execute(w, conv)
+}
@@ -0,0 +1,180 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateSaveStep       = "save"
+	dateLayout         = "2006-01-02"
+	defaultDatePrompt  = "CON Enter date as DD/MM/YYYY"
+	defaultInvalidDate = "Enter a valid date as DD/MM/YYYY"
+)
+
+// defaultDateFormats are the layouts dates are read with when a date menu has none
+var defaultDateFormats = []string{"02/01/2006", "2/1/2006", "02-01-2006", "02.01.2006", "02012006"}
+
+// DateMenuOptions contains data for a menu that asks for a date
+type DateMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Prompt asks for the date, per language. Defaults to CON Enter date as DD/MM/YYYY
+	Prompt Content
+	// Field is the session field the date is saved in, formatted as 2006-01-02. Defaults to the menu name
+	Field string
+	// Formats are the time layouts accepted, tried in order. Defaults to day first layouts such as 02/01/2006
+	Formats []string
+	// Location dates are read in. Defaults to the local time zone
+	Location *time.Location
+	// Min is the earliest date allowed when set
+	Min time.Time
+	// Max is the latest date allowed when set
+	Max time.Time
+	// NoFuture rejects dates after today, e.g for dates of birth
+	NoFuture bool
+	// NoPast rejects dates before today, e.g for appointments
+	NoPast bool
+	// InvalidMessage is shown above the prompt for invalid dates, per language
+	InvalidMessage Content
+	// NextMenu is rendered once the date is saved. Defaults to the home menu
+	NextMenu string
+}
+
+// AddDateMenu registers a menu that asks for a date, checks it against the range and saves it in the session.
+//
+// The input is received by a menu named <menu>:save, read the date with GetDate.
+func (app *UssdApp) AddDateMenu(opt *DateMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing date menu options")
+	case opt.MenuName == "":
+		return errors.New("missing date menu name")
+	case !opt.Min.IsZero() && !opt.Max.IsZero() && opt.Max.Before(opt.Min):
+		return fmt.Errorf("date menu %s has a maximum before the minimum", opt.MenuName)
+	case opt.NoFuture && opt.NoPast:
+		return fmt.Errorf("date menu %s only allows today", opt.MenuName)
+	}
+
+	var (
+		saveMenu = fmt.Sprintf("%s:%s", opt.MenuName, dateSaveStep)
+		nextMenu = firstVal(opt.NextMenu, app.homeMenu)
+		field    = firstVal(opt.Field, opt.MenuName)
+	)
+
+	prompt := opt.Prompt
+	if len(prompt) == 0 {
+		prompt = Content{app.opt.DefaultLanguage: defaultDatePrompt}
+	}
+
+	messages := opt.InvalidMessage.clone()
+	if _, ok := messages[""]; !ok {
+		messages[""] = defaultInvalidDate
+	}
+	invalid := NewError(CodeInvalidInput, messages, nil)
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName:    opt.MenuName,
+			NextMenu:    saveMenu,
+			ShortCut:    opt.ShortCut,
+			MenuContent: prompt,
+		}),
+		NewMenu(&MenuOptions{
+			MenuName: saveMenu,
+			NextMenu: nextMenu,
+			Validators: []Validator{func(input string) error {
+				if _, err := parseMenuDate(opt, input, time.Now()); err != nil {
+					return invalid
+				}
+				return nil
+			}},
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				date, err := parseMenuDate(opt, payload.UssdCurrentParam(), time.Now())
+				if err != nil {
+					return nil, err
+				}
+
+				err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), field, date.Format(dateLayout))
+				if err != nil {
+					return nil, fmt.Errorf("failed to save date: %v", err)
+				}
+
+				return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+			},
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseMenuDate reads input with the formats of a date menu and checks it is in range on the day of now
+func parseMenuDate(opt *DateMenuOptions, input string, now time.Time) (time.Time, error) {
+	loc := opt.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	formats := opt.Formats
+	if len(formats) == 0 {
+		formats = defaultDateFormats
+	}
+
+	var (
+		date time.Time
+		err  error
+	)
+	for _, format := range formats {
+		date, err = time.ParseInLocation(format, strings.TrimSpace(input), loc)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s", input)
+	}
+
+	var (
+		day   = date.Format(dateLayout)
+		today = now.In(loc).Format(dateLayout)
+	)
+
+	// Dates are compared by day, ignoring the time of the bounds
+	switch {
+	case !opt.Min.IsZero() && day < opt.Min.In(loc).Format(dateLayout):
+		return time.Time{}, fmt.Errorf("date %s is before the minimum", day)
+	case !opt.Max.IsZero() && day > opt.Max.In(loc).Format(dateLayout):
+		return time.Time{}, fmt.Errorf("date %s is after the maximum", day)
+	case opt.NoFuture && day > today:
+		return time.Time{}, fmt.Errorf("date %s is in the future", day)
+	case opt.NoPast && day < today:
+		return time.Time{}, fmt.Errorf("date %s is in the past", day)
+	}
+
+	return date, nil
+}
+
+// GetDate returns the date saved in the session field by a date menu, in the local time zone
+func (app *UssdApp) GetDate(ctx context.Context, payload UssdPayload, field string) (time.Time, error) {
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), field)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	date, err := time.ParseInLocation(dateLayout, val, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("session field %s is not a date: %v", field, err)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,73 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (app *UssdApp) traceKey(msisdn string) string {
+	return fmt.Sprintf("%s:trace:%s", app.opt.AppName, msisdn)
+}
+
+// TraceMsisdn writes debug logs for requests of the msisdn for the duration, even when Options.Debug is off, to
+// troubleshoot the sessions of a user in production. It applies to all instances sharing the cache.
+//
+// Traced requests are logged at info level, so they are written by loggers that drop debug logs.
+func (app *UssdApp) TraceMsisdn(ctx context.Context, msisdn string, dur time.Duration) error {
+	switch {
+	case msisdn == "":
+		return errors.New("missing msisdn")
+	case dur <= 0:
+		return errors.New("trace duration must be positive")
+	}
+
+	err := app.opt.Cache.Set(ctx, app.traceKey(msisdn), "true", dur)
+	if err != nil {
+		return fmt.Errorf("failed to trace msisdn: %v", err)
+	}
+
+	return nil
+}
+
+// UntraceMsisdn stops tracing the requests of the msisdn
+func (app *UssdApp) UntraceMsisdn(ctx context.Context, msisdn string) error {
+	err := app.opt.Cache.Delete(ctx, app.traceKey(msisdn))
+	if err != nil {
+		return fmt.Errorf("failed to untrace msisdn: %v", err)
+	}
+	return nil
+}
+
+// loadTrace marks the payload of a traced msisdn. Requests are not traced when the trace cannot be read
+func (app *UssdApp) loadTrace(ctx context.Context, payload UssdPayload) {
+	p, ok := payload.(*ussdPayload)
+	if !ok || app.opt.Debug {
+		return
+	}
+
+	_, err := app.opt.Cache.Get(ctx, app.traceKey(payload.Msisdn()))
+	p.data.traced = err == nil
+}
+
+func isTraced(payload UssdPayload) bool {
+	p, ok := payload.(*ussdPayload)
+	return ok && p.data.traced
+}
+
+// debug writes a debug log of the request when Options.Debug is on or the msisdn is traced
+func (app *UssdApp) debug(payload UssdPayload, msg string, keyvals ...interface{}) {
+	if !app.opt.Debug && !isTraced(payload) {
+		return
+	}
+
+	keyvals = append([]interface{}{"session_id", payload.SessionId(), "msisdn", payload.Msisdn()}, keyvals...)
+
+	if isTraced(payload) {
+		app.opt.Logger.Info(msg, append(keyvals, "trace", true)...)
+		return
+	}
+
+	app.opt.Logger.Debug(msg, keyvals...)
+}
@@ -0,0 +1,262 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DecisionInput is the condition field of the input the user entered on the menu with the decision table
+const DecisionInput = "input"
+
+// ConditionOp compares a field of a condition with its values
+type ConditionOp string
+
+const (
+	// OpEq holds when the field equals the value
+	OpEq ConditionOp = "eq"
+	// OpNe holds when the field does not equal the value
+	OpNe ConditionOp = "ne"
+	// OpIn holds when the field is one of the values
+	OpIn ConditionOp = "in"
+	// OpNotIn holds when the field is none of the values
+	OpNotIn ConditionOp = "not_in"
+	// OpGt, OpGte, OpLt and OpLte compare the field and the value as numbers. They do not hold for fields that are not
+	// numbers
+	OpGt  ConditionOp = "gt"
+	OpGte ConditionOp = "gte"
+	OpLt  ConditionOp = "lt"
+	OpLte ConditionOp = "lte"
+	// OpMatches holds when the field matches the regular expression in the value
+	OpMatches ConditionOp = "matches"
+	// OpExists holds when the field is set
+	OpExists ConditionOp = "exists"
+	// OpMissing holds when the field is not set
+	OpMissing ConditionOp = "missing"
+)
+
+// Condition is a test of a field of the session data, or of the input with the field DecisionInput
+type Condition struct {
+	Field string      `json:"field" yaml:"field"`
+	Op    ConditionOp `json:"op" yaml:"op"`
+	// Value is compared with the field by the eq, ne, numeric and matches operators
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Values are compared with the field by the in and not_in operators
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// DecisionRule renders its menu when all of its conditions hold
+type DecisionRule struct {
+	// Name identifies the rule in debug logs and menu graphs
+	Name string       `json:"name,omitempty" yaml:"name,omitempty"`
+	When []*Condition `json:"when" yaml:"when"`
+	Menu string       `json:"menu" yaml:"menu"`
+}
+
+// DecisionTable picks the menu rendered after a menu from the input of the user and the session data, e.g
+//
+//	rules:
+//	  - name: minors
+//	    when: [{field: age, op: lt, value: "18"}]
+//	    menu: not_eligible
+//	  - name: premium loan
+//	    when: [{field: segment, op: eq, value: premium}, {field: input, op: eq, value: "1"}]
+//	    menu: premium_loan
+//	default: standard_loan
+//
+// Rules are tried in order and the menu of the first rule whose conditions all hold is rendered. Routes of the menu
+// take precedence over the table.
+type DecisionTable struct {
+	Rules []*DecisionRule `json:"rules" yaml:"rules"`
+	// Default is rendered when no rule matches. Without a default, the next menu of the menu is rendered
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// decisionMenu is implemented by menus whose next menu is picked by a decision table
+type decisionMenu interface {
+	decisionTable() *DecisionTable
+}
+
+// Validate checks the operators and values of the conditions in the table
+func (t *DecisionTable) Validate() error {
+	for i, rule := range t.Rules {
+		name := firstVal(rule.Name, strconv.Itoa(i+1))
+
+		switch {
+		case rule.Menu == "":
+			return fmt.Errorf("rule %s has no menu", name)
+		case len(rule.When) == 0:
+			return fmt.Errorf("rule %s has no conditions", name)
+		}
+
+		for _, cond := range rule.When {
+			if cond == nil || cond.Field == "" {
+				return fmt.Errorf("rule %s has a condition without a field", name)
+			}
+
+			switch cond.Op {
+			case OpEq, OpNe, OpExists, OpMissing:
+			case OpIn, OpNotIn:
+				if len(cond.Values) == 0 {
+					return fmt.Errorf("rule %s: condition on %s has no values", name, cond.Field)
+				}
+			case OpGt, OpGte, OpLt, OpLte:
+				if _, err := strconv.ParseFloat(cond.Value, 64); err != nil {
+					return fmt.Errorf("rule %s: value %q of condition on %s is not a number", name, cond.Value, cond.Field)
+				}
+			case OpMatches:
+				if _, err := regexp.Compile(cond.Value); err != nil {
+					return fmt.Errorf("rule %s: condition on %s: %v", name, cond.Field, err)
+				}
+			default:
+				return fmt.Errorf("rule %s: unknown operator %q", name, cond.Op)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decide returns the menu of the first rule whose conditions hold for the data, or the default menu.
+//
+// It returns false when no rule matches and the table has no default.
+func (t *DecisionTable) Decide(data map[string]string) (menuName, rule string, ok bool) {
+	for i, r := range t.Rules {
+		if r.matches(data) {
+			return r.Menu, firstVal(r.Name, strconv.Itoa(i+1)), true
+		}
+	}
+	if t.Default != "" {
+		return t.Default, "default", true
+	}
+	return "", "", false
+}
+
+// rename returns a copy of the table with its menus renamed
+func (t *DecisionTable) rename(fn func(string) string) *DecisionTable {
+	cp := &DecisionTable{
+		Rules:   make([]*DecisionRule, 0, len(t.Rules)),
+		Default: t.Default,
+	}
+	if cp.Default != "" {
+		cp.Default = fn(cp.Default)
+	}
+	for _, r := range t.Rules {
+		rule := *r
+		rule.Menu = fn(r.Menu)
+		cp.Rules = append(cp.Rules, &rule)
+	}
+	return cp
+}
+
+func (r *DecisionRule) matches(data map[string]string) bool {
+	for _, cond := range r.When {
+		if !cond.holds(data) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Condition) holds(data map[string]string) bool {
+	val, set := data[c.Field]
+
+	switch c.Op {
+	case OpExists:
+		return set && val != ""
+	case OpMissing:
+		return !set || val == ""
+	case OpEq:
+		return set && val == c.Value
+	case OpNe:
+		return val != c.Value
+	case OpIn:
+		return set && contains(c.Values, val)
+	case OpNotIn:
+		return !contains(c.Values, val)
+	case OpGt, OpGte, OpLt, OpLte:
+		return set && compareNumbers(c.Op, val, c.Value)
+	case OpMatches:
+		ok, err := regexp.MatchString(c.Value, val)
+		return set && err == nil && ok
+	default:
+		return false
+	}
+}
+
+func compareNumbers(op ConditionOp, field, value string) bool {
+	a, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(field), ",", ""), 64)
+	if err != nil {
+		return false
+	}
+	b, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	switch op {
+	case OpGt:
+		return a > b
+	case OpGte:
+		return a >= b
+	case OpLt:
+		return a < b
+	default:
+		return a <= b
+	}
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDecidedMenu returns the menu picked by the decision table of the menu shown to the user, from the input and
+// the session data.
+//
+// Returns nil if the menu has no decision table or no rule matches.
+func (app *UssdApp) GetDecidedMenu(ctx context.Context, payload UssdPayload) (Menu, error) {
+	curr, err := app.shownMenu(ctx, payload)
+	if err != nil || curr == nil {
+		return nil, err
+	}
+
+	return app.decidedMenu(ctx, curr, payload)
+}
+
+// decidedMenu returns the menu picked by the decision table of the menu, or nil if no rule matches
+func (app *UssdApp) decidedMenu(ctx context.Context, curr Menu, payload UssdPayload) (Menu, error) {
+	dm, ok := curr.(decisionMenu)
+	if !ok || dm.decisionTable() == nil {
+		return nil, nil
+	}
+
+	data, err := app.opt.Cache.GetMap(ctx, app.GetSessionKey(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session data: %v", err)
+	}
+	if data == nil {
+		data = make(map[string]string, 1)
+	}
+	data[DecisionInput] = payload.UssdCurrentParam()
+
+	name, rule, ok := dm.decisionTable().Decide(data)
+	if !ok {
+		return nil, nil
+	}
+
+	app.debug(payload, "decision table matched", "menu", curr.MenuName(), "rule", rule, "next", name)
+
+	decided, ok := app.getMenu(name)
+	if !ok {
+		return nil, fmt.Errorf("%v: %s", ErrMenuNotExist, name)
+	}
+
+	return decided, nil
+}
@@ -0,0 +1,49 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// requestHash identifies a request as sent by the gateway, retries of the request having the same hash
+func requestHash(payload UssdPayload) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		payload.SessionId(), payload.Msisdn(), payload.ServiceCode(), payload.UssdParams(),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// isDuplicate reports whether the payload is a retry answered with an earlier response
+func isDuplicate(payload UssdPayload) bool {
+	p, ok := payload.(*ussdPayload)
+	return ok && p.data.duplicate
+}
+
+// duplicateResponse returns the last response of the session if it answered the same request within the dedup
+// window, marking the payload as a duplicate so it is not logged or reported again
+func (app *UssdApp) duplicateResponse(ctx context.Context, request string, payload UssdPayload) (SessionResponse, bool, error) {
+	last, err := app.getLastResponse(ctx, payload)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to check for duplicate request: %v", err)
+	}
+
+	if last.Request != request || time.Since(time.Unix(0, last.SentAt)) > app.opt.DedupWindow {
+		return nil, false, nil
+	}
+
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.duplicate = true
+		p.data.ValidationFailed = last.ValidationFailed
+	}
+
+	return last.sessionResponse(payload.SessionId()), true, nil
+}
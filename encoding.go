@@ -0,0 +1,158 @@
+package ussdapp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResponseEncoder writes session responses in the format expected by a gateway
+type ResponseEncoder interface {
+	// ContentType is the content type of encoded responses
+	ContentType() string
+	// Encode writes the session response
+	Encode(w io.Writer, sr SessionResponse) error
+}
+
+// NewEncodingAdapter returns a gateway adapter that reads requests using adapter and writes responses using encoder.
+//
+// Use it for gateways that expect XML or JSON responses instead of CON/END text.
+func NewEncodingAdapter(adapter GatewayAdapter, encoder ResponseEncoder) GatewayAdapter {
+	return &encodingAdapter{GatewayAdapter: adapter, encoder: encoder}
+}
+
+type encodingAdapter struct {
+	GatewayAdapter
+	encoder ResponseEncoder
+}
+
+func (a *encodingAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	return writeEncoded(w, a.encoder, sr)
+}
+
+// writeEncoded sets the content type of the encoder and writes the encoded response
+func writeEncoded(w http.ResponseWriter, encoder ResponseEncoder, sr SessionResponse) error {
+	w.Header().Set("Content-Type", encoder.ContentType())
+
+	err := encoder.Encode(w, sr)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return nil
+}
+
+// ResponseMessage returns the text of the response without the CON/END prefix, for channels that end sessions
+// in other ways
+func ResponseMessage(sr SessionResponse) string {
+	text := ussdResponseText(sr, false)
+
+	prefix, body := splitPrefix(text)
+	if prefix == uprPrefix {
+		return text
+	}
+
+	return body
+}
+
+// PlainTextEncoder writes responses as text using the CON/END prefix convention
+func PlainTextEncoder() ResponseEncoder {
+	return plainTextEncoder{}
+}
+
+type plainTextEncoder struct{}
+
+func (plainTextEncoder) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (plainTextEncoder) Encode(w io.Writer, sr SessionResponse) error {
+	_, err := io.WriteString(w, ussdResponseText(sr, false))
+	return err
+}
+
+// JSONResponse is the envelope written by JSONEncoder
+type JSONResponse struct {
+	SessionID string `json:"sessionId"`
+	Message   string `json:"message"`
+	End       bool   `json:"end"`
+}
+
+// JSONEncoder writes responses as a JSONResponse
+func JSONEncoder() ResponseEncoder {
+	return jsonEncoder{}
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (jsonEncoder) Encode(w io.Writer, sr SessionResponse) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(&JSONResponse{
+		SessionID: sr.SessionId(),
+		Message:   ResponseMessage(sr),
+		End:       sr.Terminal(),
+	})
+}
+
+const (
+	// FreeflowContinue is the freeflow state of responses that expect more input
+	FreeflowContinue = "FC"
+	// FreeflowBreak is the freeflow state of responses that end the session
+	FreeflowBreak = "FB"
+)
+
+// XMLResponse is the envelope written by XMLEncoder. The freeflow state tells the gateway whether the session continues
+type XMLResponse struct {
+	XMLName             xml.Name `xml:"response"`
+	SessionID           string   `xml:"sessionId"`
+	ApplicationResponse string   `xml:"applicationResponse"`
+	Freeflow            Freeflow `xml:"freeflow"`
+}
+
+// Freeflow holds the session fields of an XMLResponse
+type Freeflow struct {
+	FreeflowState          string `xml:"freeflowState"`
+	FreeflowCharging       string `xml:"freeflowCharging"`
+	FreeflowChargingAmount string `xml:"freeflowChargingAmount"`
+}
+
+// XMLEncoder writes responses as an XMLResponse, without charging the user
+func XMLEncoder() ResponseEncoder {
+	return xmlEncoder{}
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (xmlEncoder) Encode(w io.Writer, sr SessionResponse) error {
+	res := &XMLResponse{
+		SessionID:           sr.SessionId(),
+		ApplicationResponse: ResponseMessage(sr),
+		Freeflow: Freeflow{
+			FreeflowState:          FreeflowContinue,
+			FreeflowCharging:       "N",
+			FreeflowChargingAmount: "0.0",
+		},
+	}
+	if sr.Terminal() {
+		res.Freeflow.FreeflowState = FreeflowBreak
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(res)
+}
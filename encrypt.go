@@ -0,0 +1,180 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// encryptedPrefix marks encrypted values so that values written before encryption was enabled are still read
+const encryptedPrefix = "enc:v1:"
+
+// encryptingCacher encrypts values and map field values with AES-GCM before they are written to the cache.
+//
+// Keys, field names and set values are written as they are, since they are used for lookups. Values are bound to
+// their key and field, so a value copied to another field or session fails to decrypt.
+type encryptingCacher struct {
+	Cacher
+	aead cipher.AEAD
+}
+
+func newEncryptingCacher(cache Cacher, key []byte) (*encryptingCacher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session encryption key: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	return &encryptingCacher{Cacher: cache, aead: aead}, nil
+}
+
+// additionalData binds encrypted values to the key and field they are written to. Plain values have no field
+func additionalData(key, field string) []byte {
+	return []byte(key + "\x00" + field)
+}
+
+func (c *encryptingCacher) encrypt(key, field, val string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(val), additionalData(key, field))
+
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *encryptingCacher) decrypt(key, field, val string) (string, error) {
+	if !strings.HasPrefix(val, encryptedPrefix) {
+		return val, nil
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(val, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %v", err)
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	bs, err := c.aead.Open(nil, nonce, ciphertext, additionalData(key, field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+
+	return string(bs), nil
+}
+
+func (c *encryptingCacher) encryptFields(key string, fields map[string]string) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(fields))
+	for field, val := range fields {
+		enc, err := c.encrypt(key, field, val)
+		if err != nil {
+			return nil, err
+		}
+		res[field] = enc
+	}
+	return res, nil
+}
+
+func (c *encryptingCacher) decryptFields(key string, fields map[string]string) (map[string]string, error) {
+	for field, val := range fields {
+		dec, err := c.decrypt(key, field, val)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field, err)
+		}
+		fields[field] = dec
+	}
+	return fields, nil
+}
+
+func (c *encryptingCacher) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	enc, err := c.encrypt(key, "", value)
+	if err != nil {
+		return err
+	}
+	return c.Cacher.Set(ctx, key, enc, dur)
+}
+
+func (c *encryptingCacher) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.Cacher.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return c.decrypt(key, "", val)
+}
+
+func (c *encryptingCacher) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	values := make(map[string]string, len(fields))
+	for field, val := range fields {
+		values[field] = CacheString(val)
+	}
+
+	enc, err := c.encryptFields(key, values)
+	if err != nil {
+		return err
+	}
+
+	return c.Cacher.SetMap(ctx, key, enc)
+}
+
+func (c *encryptingCacher) GetMap(ctx context.Context, key string) (map[string]string, error) {
+	val, err := c.Cacher.GetMap(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decryptFields(key, val)
+}
+
+func (c *encryptingCacher) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	fields, err := MapFields(values)
+	if err != nil {
+		return err
+	}
+
+	enc, err := c.encryptFields(key, fields)
+	if err != nil {
+		return err
+	}
+
+	return c.Cacher.SetMapField(ctx, key, enc)
+}
+
+func (c *encryptingCacher) GetMapField(ctx context.Context, key, field string) (string, error) {
+	val, err := c.Cacher.GetMapField(ctx, key, field)
+	if err != nil {
+		return "", err
+	}
+	return c.decrypt(key, field, val)
+}
+
+func (c *encryptingCacher) GetMapFields(ctx context.Context, key string, fields ...string) (map[string]string, error) {
+	val, err := c.Cacher.GetMapFields(ctx, key, fields...)
+	if err != nil {
+		return nil, err
+	}
+	return c.decryptFields(key, val)
+}
+
+// SetUnique adds the value to the set as it is, like the other set operations
+func (c *encryptingCacher) SetUnique(ctx context.Context, key string, value string) (bool, error) {
+	return addToSet(ctx, c.Cacher, key, value)
+}
+
+// Incr keeps counters as they are, the cache adds to them
+func (c *encryptingCacher) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	return incr(ctx, c.Cacher, key, dur)
+}
@@ -0,0 +1,120 @@
+package ussdapp_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gidyon/ussdapp"
+	memorycache "github.com/gidyon/ussdapp/cache/memory"
+	"github.com/gidyon/ussdapp/ussdtest"
+)
+
+func TestSessionEncryption(t *testing.T) {
+	ctx := context.Background()
+
+	raw := memorycache.NewMemoryCache()
+	app := ussdtest.NewApp(t, &ussdapp.Options{Cache: raw, SessionEncryptionKey: []byte("0123456789abcdef")})
+	cache := app.Cache()
+
+	tests := []struct {
+		name  string
+		write func() error
+		read  func() (string, error)
+		// stored reads the value as written to the cache
+		stored func() (string, error)
+	}{
+		{
+			name:   "Set",
+			write:  func() error { return cache.Set(ctx, "value", "1234", 0) },
+			read:   func() (string, error) { return cache.Get(ctx, "value") },
+			stored: func() (string, error) { return raw.Get(ctx, "value") },
+		},
+		{
+			name:   "SetMap",
+			write:  func() error { return cache.SetMap(ctx, "map", map[string]interface{}{"pin": "1234"}) },
+			read:   func() (string, error) { return cache.GetMapField(ctx, "map", "pin") },
+			stored: func() (string, error) { return raw.GetMapField(ctx, "map", "pin") },
+		},
+		{
+			name:  "SetMapField",
+			write: func() error { return cache.SetMapField(ctx, "fields", "pin", "1234") },
+			read: func() (string, error) {
+				vals, err := cache.GetMapFields(ctx, "fields", "pin")
+				return vals["pin"], err
+			},
+			stored: func() (string, error) { return raw.GetMapField(ctx, "fields", "pin") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.write()
+			if err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+
+			stored, err := tt.stored()
+			if err != nil {
+				t.Fatalf("failed to read stored value: %v", err)
+			}
+			if strings.Contains(stored, "1234") {
+				t.Fatalf("expected value to be encrypted, got %q", stored)
+			}
+
+			val, err := tt.read()
+			if err != nil {
+				t.Fatalf("failed to read: %v", err)
+			}
+			if val != "1234" {
+				t.Fatalf("expected 1234, got %q", val)
+			}
+		})
+	}
+
+	t.Run("moved values fail to decrypt", func(t *testing.T) {
+		val, err := raw.Get(ctx, "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = raw.Set(ctx, "other", val, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = cache.Get(ctx, "other"); err == nil {
+			t.Fatal("expected value moved to another key to fail")
+		}
+
+		field, err := raw.GetMapField(ctx, "map", "pin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = raw.SetMapField(ctx, "map", "otp", field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = cache.GetMapField(ctx, "map", "otp"); err == nil {
+			t.Fatal("expected value moved to another field to fail")
+		}
+	})
+
+	t.Run("plain values are read", func(t *testing.T) {
+		err := raw.Set(ctx, "plain", "hello", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = raw.SetMapField(ctx, "plain_map", "greeting", "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		val, err := cache.Get(ctx, "plain")
+		if err != nil || val != "hello" {
+			t.Fatalf("expected hello, got %q: %v", val, err)
+		}
+		val, err = cache.GetMapField(ctx, "plain_map", "greeting")
+		if err != nil || val != "hello" {
+			t.Fatalf("expected hello, got %q: %v", val, err)
+		}
+	})
+}
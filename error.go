@@ -0,0 +1,78 @@
+package ussdapp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes of the errors created by the helpers in this package
+const (
+	CodeInvalidInput       = "invalid_input"
+	CodeBackendUnavailable = "backend_unavailable"
+)
+
+// Error is an error with a message for the subscriber and an internal cause that is only logged.
+//
+// Return it from menu functions to control what the subscriber sees when the menu fails. The framework renders
+// the user message in the language of the session and keeps the internal cause in the session log.
+// Errors with CodeInvalidInput match ErrFailedValidation, so the menu is shown again with the message on top.
+type Error struct {
+	// Code identifies the kind of error in logs
+	Code string
+	// Messages shown to the subscriber, per language
+	Messages Content
+	// Err is the internal cause. It is never shown to the subscriber
+	Err error
+}
+
+// NewError creates an error with the code, messages for the subscriber and internal cause
+func NewError(code string, messages Content, err error) *Error {
+	return &Error{Code: code, Messages: messages, Err: err}
+}
+
+// ErrInvalidInput returns an error for input the subscriber should correct, showing message to the subscriber
+func ErrInvalidInput(message string) *Error {
+	return NewError(CodeInvalidInput, Content{"": message}, nil)
+}
+
+// ErrBackendUnavailable returns an error for a failed call to a backend service.
+//
+// The subscriber is shown the error message of the app, set in Options.ErrorMessage.
+func ErrBackendUnavailable(err error) *Error {
+	return NewError(CodeBackendUnavailable, nil, err)
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is a validation error
+func (e *Error) Is(target error) bool {
+	return target == ErrFailedValidation && e.Code == CodeInvalidInput
+}
+
+// UserMessage returns the message for the subscriber in the language, or a fallback language.
+//
+// It is empty if the error has no messages.
+func (e *Error) UserMessage(lang string, fallbacks ...string) string {
+	return e.Messages.Text(lang, fallbacks...)
+}
+
+// userMessage returns the message for the subscriber of err if it is an Error with messages
+func userMessage(err error, lang string, fallbacks ...string) (string, bool) {
+	var uerr *Error
+	if !errors.As(err, &uerr) {
+		return "", false
+	}
+
+	msg := uerr.UserMessage(lang, fallbacks...)
+
+	return msg, msg != ""
+}
@@ -0,0 +1,82 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Sources of reported errors
+const (
+	// ErrorSourceMenu is a menu that failed to generate its response
+	ErrorSourceMenu = "menu"
+	// ErrorSourceRequest is a request that failed outside of menus, e.g a middleware that panicked
+	ErrorSourceRequest = "request"
+	// ErrorSourceWorker is a background worker that failed, e.g writing session logs
+	ErrorSourceWorker = "worker"
+)
+
+// ErrorReport is a failure of the app sent to Options.ErrorReporter
+type ErrorReport struct {
+	Err error
+	// Source is where the error happened, one of the ErrorSource constants
+	Source string
+	// Operation is the work that failed for worker errors, e.g save_logs
+	Operation string
+	// Menu is the menu that failed for menu errors
+	Menu        string
+	SessionID   string
+	ServiceCode string
+	// Msisdn is protected by Options.PIIPolicy like in session logs
+	Msisdn string
+	// Stack is the stack of the panic for requests that panicked
+	Stack []byte
+	Time  time.Time
+}
+
+// ErrorReporter sends errors of the app to an error tracking service, e.g the Sentry reporter in the reporter/sentry
+// package. It is called in the request path, so it must not block on the service
+type ErrorReporter interface {
+	ReportError(ctx context.Context, report *ErrorReport)
+}
+
+// reportRequestError reports an error of a request to the error reporter
+func (app *UssdApp) reportRequestError(ctx context.Context, payload UssdPayload, source, menuName string, err error) {
+	if app.opt.ErrorReporter == nil || err == nil {
+		return
+	}
+
+	report := &ErrorReport{
+		Err:         err,
+		Source:      source,
+		Menu:        menuName,
+		SessionID:   payload.SessionId(),
+		ServiceCode: payload.ServiceCode(),
+		Msisdn:      payload.Msisdn(),
+		Time:        time.Now(),
+	}
+	if app.opt.PIIPolicy != nil {
+		report.Msisdn = app.opt.PIIPolicy.logMsisdn(report.Msisdn)
+	}
+
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		report.Stack = perr.Stack
+	}
+
+	app.opt.ErrorReporter.ReportError(ctx, report)
+}
+
+// reportWorkerError reports a failure of a background worker to the error reporter
+func (app *UssdApp) reportWorkerError(operation string, err error) {
+	if app.opt.ErrorReporter == nil || err == nil {
+		return
+	}
+
+	app.opt.ErrorReporter.ReportError(context.Background(), &ErrorReport{
+		Err:       err,
+		Source:    ErrorSourceWorker,
+		Operation: operation,
+		Time:      time.Now(),
+	})
+}
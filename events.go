@@ -0,0 +1,246 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultSessionSweepInterval = 30 * time.Second
+
+// SessionEvent describes a change in the lifecycle of a session
+type SessionEvent struct {
+	SessionID   string
+	Msisdn      string
+	ServiceCode string
+	// MenuName is the last menu rendered in the session. It is empty for started sessions
+	MenuName string
+	Time     time.Time
+}
+
+// SessionEventFn is called when a session starts, ends or times out.
+//
+// It is called while the request is processed, so slow work like sending an SMS should run in a goroutine.
+type SessionEventFn func(ctx context.Context, event *SessionEvent)
+
+// trackedSession is a session in progress on this instance, watched for timeouts when the timeout hook is set
+type trackedSession struct {
+	event     SessionEvent
+	expiresAt time.Time
+}
+
+func (app *UssdApp) sessionEndedKey(sessionID, msisdn string) string {
+	return fmt.Sprintf("%s:ended:%s:%s", app.opt.AppName, sessionID, msisdn)
+}
+
+func newSessionEvent(payload UssdPayload, menuName string) *SessionEvent {
+	return &SessionEvent{
+		SessionID:   payload.SessionId(),
+		Msisdn:      payload.Msisdn(),
+		ServiceCode: payload.ServiceCode(),
+		MenuName:    menuName,
+		Time:        time.Now(),
+	}
+}
+
+// startSession calls the session start hook and tracks the session
+func (app *UssdApp) startSession(ctx context.Context, payload UssdPayload) {
+	app.trackedMu.Lock()
+	app.tracked[app.GetSessionKey(payload)] = &trackedSession{
+		event:     *newSessionEvent(payload, ""),
+		expiresAt: time.Now().Add(app.sessionDuration(payload)),
+	}
+	app.trackedMu.Unlock()
+
+	app.countSessions(ctx, 1)
+
+	if app.opt.OnSessionStart != nil {
+		app.opt.OnSessionStart(ctx, newSessionEvent(payload, ""))
+	}
+}
+
+// sessionResponded records the menu sent to the user, calling the session end hook if the response ends the session
+func (app *UssdApp) sessionResponded(ctx context.Context, payload UssdPayload, sr SessionResponse) {
+	if isDuplicate(payload) {
+		return
+	}
+
+	sessionKey := app.GetSessionKey(payload)
+
+	if !sr.Terminal() {
+		app.trackedMu.Lock()
+		if ts, ok := app.tracked[sessionKey]; ok {
+			ts.event.MenuName = sr.MenuName()
+		}
+		app.trackedMu.Unlock()
+		return
+	}
+
+	app.trackedMu.Lock()
+	delete(app.tracked, sessionKey)
+	app.trackedMu.Unlock()
+
+	app.countSessionEnd(ctx, sessionKey)
+
+	// Before the session of a conversation is cleared, for the hook to read session data
+	app.sendEndSMS(ctx, payload, sr)
+
+	if isConversation(payload) {
+		// The next message of the msisdn starts a new session
+		err := app.opt.Cache.Delete(ctx, sessionKey)
+		if err != nil {
+			app.opt.Logger.Warn("failed to clear session", "session_id", payload.SessionId(), "error", err)
+		}
+	} else if app.markEnded() {
+		// The session expires later, so other instances and expiry listeners must not report it as timed out
+		err := app.opt.Cache.Set(ctx, app.sessionEndedKey(payload.SessionId(), payload.Msisdn()), "true", app.opt.SessionDuration)
+		if err != nil {
+			app.opt.Logger.Warn("failed to mark session as ended", "session_id", payload.SessionId(), "error", err)
+		}
+	}
+
+	if app.opt.OnSessionEnd != nil {
+		app.opt.OnSessionEnd(ctx, newSessionEvent(payload, sr.MenuName()))
+	}
+}
+
+// sessionsSweeper drops tracked sessions that expired, reporting them to the session timeout hook and the session
+// counter when set
+func (app *UssdApp) sessionsSweeper(ctx context.Context) {
+	defer app.workers.Done()
+
+	interval := app.opt.SessionSweepInterval
+	if interval <= 0 {
+		interval = defaultSessionSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.stop:
+			return
+		case <-ticker.C:
+			app.sweepSessions(ctx, interval)
+		}
+	}
+}
+
+func (app *UssdApp) sweepSessions(ctx context.Context, interval time.Duration) {
+	now := time.Now()
+
+	expired := make(map[string]*trackedSession)
+
+	app.trackedMu.Lock()
+	for key, ts := range app.tracked {
+		if ts.expiresAt.Before(now) {
+			expired[key] = ts
+		}
+	}
+	app.trackedMu.Unlock()
+
+	for key, ts := range expired {
+		if !app.markEnded() || app.opt.DisableSessionSweeper {
+			app.trackedMu.Lock()
+			delete(app.tracked, key)
+			app.trackedMu.Unlock()
+			continue
+		}
+
+		_, err := app.opt.Cache.GetMapField(ctx, key, "new")
+		switch {
+		case err == nil:
+			// Session data outlived the expected duration, check again later
+			app.trackedMu.Lock()
+			ts.expiresAt = now.Add(interval)
+			app.trackedMu.Unlock()
+			continue
+		case errors.Is(err, ErrKeyNotFound):
+		default:
+			app.opt.Logger.Warn("failed to check session expiry", "session_id", ts.event.SessionID, "error", err)
+			continue
+		}
+
+		app.trackedMu.Lock()
+		delete(app.tracked, key)
+		app.trackedMu.Unlock()
+
+		err = app.reportTimeout(ctx, &ts.event)
+		if err != nil {
+			app.opt.Logger.Warn("failed to report session timeout", "session_id", ts.event.SessionID, "error", err)
+		}
+	}
+}
+
+// SessionExpired calls Options.OnSessionTimeout for a session whose data expired, unless the session ended, and
+// counts the session out of Options.SessionCounter.
+//
+// It is called by listeners of cache expiry events, such as rediscache.ListenSessionExpiry. The last menu of the
+// session is not known to listeners, so it is missing from the event.
+func (app *UssdApp) SessionExpired(ctx context.Context, sessionID, msisdn string) error {
+	if !app.markEnded() {
+		return nil
+	}
+
+	return app.reportTimeout(ctx, &SessionEvent{
+		SessionID: sessionID,
+		Msisdn:    msisdn,
+	})
+}
+
+// reportTimeout calls the session timeout hook and counts the session out unless the session ended
+func (app *UssdApp) reportTimeout(ctx context.Context, event *SessionEvent) error {
+	_, err := app.opt.Cache.Get(ctx, app.sessionEndedKey(event.SessionID, event.Msisdn))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return fmt.Errorf("failed to check if session ended: %v", err)
+	}
+
+	event.Time = time.Now()
+
+	app.countSessions(ctx, -1)
+
+	if app.opt.OnSessionTimeout != nil {
+		app.opt.OnSessionTimeout(ctx, event)
+	}
+
+	return nil
+}
+
+// liveSessions returns the number of sessions in progress on this instance
+func (app *UssdApp) liveSessions() int {
+	now := time.Now()
+
+	app.trackedMu.Lock()
+	defer app.trackedMu.Unlock()
+
+	n := 0
+	for _, ts := range app.tracked {
+		if ts.expiresAt.After(now) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// trackedSessionKey returns the key of a session in progress on this instance
+func (app *UssdApp) trackedSessionKey(sessionID string) (string, bool) {
+	app.trackedMu.Lock()
+	defer app.trackedMu.Unlock()
+
+	for key, ts := range app.tracked {
+		if ts.event.SessionID == sessionID {
+			return key, true
+		}
+	}
+
+	return "", false
+}
@@ -2,11 +2,8 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
-	"log"
 	"net/http"
-	"net/http/httputil"
 	"time"
 
 	"github.com/gidyon/gomicro/pkg/conn"
@@ -65,24 +62,36 @@ func main() {
 	})
 
 	// Ussd App Instance
-	ussdApp, err := ussdapp.NewUssdApp(ctx, &ussdapp.Options{
+	var ussdApp *ussdapp.UssdApp
+	ussdApp, err = ussdapp.NewUssdApp(ctx, &ussdapp.Options{
 		AppName:         "test",
 		HomeMenu:        homeUnregisteredMenu,
 		SQLDB:           sqlDB,
 		Cache:           rediscache.NewRedisCache(redisDB),
-		Logger:          appLogger,
+		Logger:          ussdapp.NewGrpcLogger(appLogger),
 		TableName:       viper.GetString("LOGS_TABLE"),
 		DefaultLanguage: english,
 		SaveLogs:        false,
 		Handler:         nil,
 		SessionDuration: 3 * time.Minute,
+		HomeMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload) (string, error) {
+			// Check if user is registered
+			// exists := true
+
+			// if exists {
+			// 	// Registered users start on the login menu
+			// 	return loginMenu, nil
+			// }
+
+			return homeUnregisteredMenu, nil
+		},
 	})
 	handleErr(err)
 
 	// Register menus
 	registerUserMenus(ussdApp)
 
-	http.HandleFunc("/ussd", ussdHandler(ussdApp))
+	http.Handle("/ussd", ussdApp.HTTPHandler())
 	handleErr(http.ListenAndServe(viper.GetString("httpPort"), nil))
 }
 
@@ -91,87 +100,3 @@ func handleErr(err error) {
 		panic(err)
 	}
 }
-
-func ussdHandler(ussdApp *ussdapp.UssdApp) http.HandlerFunc {
-	handler := func(rw http.ResponseWriter, r *http.Request) (int, error) {
-		bs, err := httputil.DumpRequest(r, true)
-		if err != nil {
-			return http.StatusInternalServerError, err
-		}
-
-		log.Printf("INCOMING REQUEST: %s\n\n", string(bs))
-
-		var (
-			ctx           = r.Context()
-			payload       = ussdapp.UssdPayloadFromRequest(r)
-			sessionResp   ussdapp.SessionResponse
-			sessionFailed = func(status string) {
-				ussdapp.SetSessionFailed(sessionResp, status)
-			}
-			currentMenu ussdapp.Menu
-		)
-
-		// Save session response at end of function
-		defer func() {
-			if sessionResp != nil {
-				ussdApp.SaveLog(ctx, payload, sessionResp)
-			}
-		}()
-
-		// Check if user is registered
-		exists, err := true, nil
-
-		// Check if session is new
-		currentMenu, newSession, err := ussdApp.GetSessionMenu(ctx, payload)
-		if err != nil {
-			sessionFailed(err.Error())
-			return http.StatusInternalServerError, err
-		}
-
-		_, _ = newSession, exists
-
-		// switch {
-		// case exists && newSession:
-		// 	// Save user details to cache
-		// 	err = ussdApp.Cache().SetMap(ctx, ussdApp.GetSessionKey(payload), map[string]interface{}{
-		// 		"full_names": "Test User",
-		// 	})
-		// 	if err != nil {
-		// 		return http.StatusInternalServerError, fmt.Errorf("failed to save user to cache: %v", err)
-		// 	}
-
-		// 	// Update current menu in this case
-		// 	currentMenu, err = ussdApp.SaveMenuNameAsCurrent(ctx, loginMenu, payload)
-		// 	if err != nil {
-		// 		return http.StatusInternalServerError, fmt.Errorf("failed to save %s menu as current: %v", loginMenu, err)
-		// 	}
-		// }
-
-		// Generate response to send to user
-		sessionResp, err = currentMenu.GenerateResponse(ctx, payload)
-		switch {
-		case err == nil:
-			// Write response back to user: IMPORTANT
-			err = ussdapp.WriteUSSDResponse(rw, payload, sessionResp)
-			if err != nil {
-				return http.StatusInternalServerError, err
-			}
-
-		case errors.Is(err, ussdapp.ErrFailedValidation):
-			sessionFailed(err.Error())
-		default:
-			sessionFailed(err.Error())
-			return http.StatusInternalServerError, err
-		}
-
-		return http.StatusOK, nil
-	}
-
-	return func(rw http.ResponseWriter, r *http.Request) {
-		c, err := handler(rw, r)
-		if err != nil {
-			log.Printf("ussd request failed with code %v %v", c, err)
-			http.Error(rw, "END Service is not available try again later", http.StatusOK)
-		}
-	}
-}
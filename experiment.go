@@ -0,0 +1,90 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+)
+
+// AssignmentStrategy decides how sessions are assigned a variant of an experiment
+type AssignmentStrategy int
+
+const (
+	// AssignByMsisdn picks the variant by a hash of the msisdn, so users are served the same variant in every session
+	AssignByMsisdn AssignmentStrategy = iota
+	// AssignPerSession picks a random variant the first time the session reaches the menu
+	AssignPerSession
+)
+
+// Experiment serves variants of the content of a menu to compare how users respond to them.
+//
+// The variant of a session is kept in the session and recorded in the variant column of session logs as
+// <experiment>/<variant>, and counted in the experiment exposures metric.
+type Experiment struct {
+	// Name identifies the experiment in logs and metrics
+	Name string
+	// Variants are the menu content of each variant by language. Languages missing from a variant use MenuContent
+	Variants map[string]Content
+	// Assignment decides which sessions are served a variant. Defaults to AssignByMsisdn
+	Assignment AssignmentStrategy
+}
+
+// variantNames returns the names of the variants in a stable order
+func (e *Experiment) variantNames() []string {
+	names := make([]string, 0, len(e.Variants))
+	for name := range e.Variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// experimentMenu is implemented by menus that run experiments. The app sets the function that assigns variants
+type experimentMenu interface {
+	setVariantFn(fn variantFn)
+}
+
+type variantFn func(ctx context.Context, payload UssdPayload, e *Experiment) (string, error)
+
+func experimentKey(name string) string {
+	return "experiment:" + name
+}
+
+// assignVariant returns the variant of the experiment for the session, assigning one when the session has none
+func (app *UssdApp) assignVariant(ctx context.Context, payload UssdPayload, e *Experiment) (string, error) {
+	session := app.Session(payload)
+
+	variant, err := session.Get(ctx, experimentKey(e.Name))
+	switch {
+	case err == nil:
+		if _, ok := e.Variants[variant]; ok {
+			return variant, nil
+		}
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return "", fmt.Errorf("failed to get variant of experiment %s: %v", e.Name, err)
+	}
+
+	names := e.variantNames()
+
+	switch e.Assignment {
+	case AssignPerSession:
+		variant = names[rand.Intn(len(names))]
+	default:
+		h := fnv.New32a()
+		h.Write([]byte(e.Name + ":" + payload.Msisdn()))
+		variant = names[h.Sum32()%uint32(len(names))]
+	}
+
+	err = session.Set(ctx, experimentKey(e.Name), variant)
+	if err != nil {
+		return "", err
+	}
+
+	app.metrics.experimentExposed(e.Name, variant)
+
+	return variant, nil
+}
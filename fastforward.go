@@ -0,0 +1,62 @@
+package ussdapp
+
+import (
+	"context"
+	"strings"
+)
+
+// skipDialedInputs makes a new session start on the home menu, keeping the inputs dialed with the service code,
+// e.g 1, 1 and John for *123*1*1*John#, to be run through the menus by fastForward
+func skipDialedInputs(payload UssdPayload) {
+	p, ok := payload.(*ussdPayload)
+	if !ok || p.data.UssdParams == "" {
+		return
+	}
+
+	p.data.dialedInputs = strings.Split(p.data.UssdParams, "*")
+	p.data.UssdParams, p.data.UssdCurrentParam = "", ""
+}
+
+// fastForward feeds the inputs dialed with the service code to the menus one after the other, as if the user had
+// entered them, starting from the response of the home menu.
+//
+// It stops early when a menu ends the session or rejects an input, returning that menu to the user.
+func (app *UssdApp) fastForward(ctx context.Context, payload UssdPayload, sr SessionResponse) (SessionResponse, error) {
+	p, ok := payload.(*ussdPayload)
+	if !ok || len(p.data.dialedInputs) == 0 {
+		return sr, nil
+	}
+
+	var (
+		inputs = p.data.dialedInputs
+		step   = p
+		err    error
+	)
+
+	for i, input := range inputs {
+		if sr.Terminal() || sr.Failed() || step.data.ValidationFailed {
+			break
+		}
+
+		data := *p.data
+		data.dialedInputs = nil
+		data.UssdParams = strings.Join(inputs[:i+1], "*")
+		data.UssdCurrentParam = strings.TrimSpace(input)
+		step = &ussdPayload{data: &data}
+
+		sr, err = app.processMenu(ctx, step)
+		if err != nil {
+			return nil, err
+		}
+		p.data.jobs = step.data.jobs
+	}
+
+	// The request is logged with all the inputs dialed and the outcome of the last menu
+	p.data.UssdParams = strings.Join(inputs, "*")
+	p.data.UssdCurrentParam = step.data.UssdCurrentParam
+	p.data.ValidationFailed = step.data.ValidationFailed
+	p.data.skip = step.data.skip
+	p.data.dialedInputs = nil
+
+	return sr, nil
+}
@@ -0,0 +1,52 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlagProvider tells whether a feature flag is on for a user, e.g backed by a flag service or a config per market
+type FlagProvider interface {
+	IsEnabled(ctx context.Context, flag, msisdn string) bool
+}
+
+// flaggedMenu is implemented by menus turned on by a feature flag
+type flaggedMenu interface {
+	// featureFlag returns the flag of the menu and the menu rendered when the flag is off
+	featureFlag() (flag, fallbackMenu string)
+}
+
+// enabledMenu returns the menu, or its fallback menu when the feature flag of the menu is off for the msisdn.
+//
+// Menus with a flag are off when the app has no flag provider.
+func (app *UssdApp) enabledMenu(ctx context.Context, payload UssdPayload, m Menu) (Menu, error) {
+	seen := make(map[string]bool)
+
+	for {
+		fm, ok := m.(flaggedMenu)
+		if !ok {
+			return m, nil
+		}
+
+		flag, fallback := fm.featureFlag()
+		if flag == "" {
+			return m, nil
+		}
+		if app.opt.FlagProvider != nil && app.opt.FlagProvider.IsEnabled(ctx, flag, payload.Msisdn()) {
+			return m, nil
+		}
+
+		seen[m.MenuName()] = true
+
+		fallback = firstVal(fallback, app.homeMenuOf(payload))
+		if seen[fallback] {
+			return nil, fmt.Errorf("fallback menus of %s menu are all turned off", m.MenuName())
+		}
+
+		next, ok := app.getMenu(fallback)
+		if !ok {
+			return nil, fmt.Errorf("%w: fallback menu %s", ErrMenuNotExist, fallback)
+		}
+		m = next
+	}
+}
@@ -0,0 +1,225 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const flowCompleteStep = "complete"
+
+// FlowStep is a single question in a flow
+type FlowStep struct {
+	// Field is the name the answer is collected under
+	Field string
+	// Prompt is the question shown to the user, per language
+	Prompt Content
+	// Validators are run on the answer. Invalid answers show the prompt again with the error message
+	Validators []Validator
+	// ValidationMessage is the message shown for invalid answers per language, replacing the validator message
+	ValidationMessage Content
+	// Sensitive redacts the answer, such as a PIN, from session logs
+	Sensitive bool
+}
+
+// FlowCompleteFn is called with the answers collected in a flow. The returned response is sent to the user
+type FlowCompleteFn func(ctx context.Context, payload UssdPayload, fields map[string]string) (SessionResponse, error)
+
+// FlowOptions contains data for a flow that asks the user a sequence of questions
+type FlowOptions struct {
+	// Name is the name of the menu that starts the flow
+	Name     string
+	ShortCut string
+	// NextMenu is the menu that receives input on the response of OnComplete. Defaults to the home menu of the session
+	NextMenu   string
+	Steps      []*FlowStep
+	OnComplete FlowCompleteFn
+}
+
+// AddFlow registers menus that ask the user each step in order and collect the answers in the session.
+//
+// The flow starts from the menu with the flow name. The menu of each later step is named <flow>:<field>,
+// so routes can point to them. Once the last step is answered OnComplete is called with all the answers. The menus
+// are registered together, none of them is registered if any name is taken.
+func (app *UssdApp) AddFlow(opt *FlowOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing flow options")
+	case opt.Name == "":
+		return errors.New("missing flow name")
+	case len(opt.Steps) == 0:
+		return fmt.Errorf("flow %s has no steps", opt.Name)
+	case opt.OnComplete == nil:
+		return fmt.Errorf("flow %s has no complete callback", opt.Name)
+	}
+
+	seen := make(map[string]bool, len(opt.Steps))
+	for _, step := range opt.Steps {
+		switch {
+		case step == nil || step.Field == "":
+			return fmt.Errorf("flow %s has a step without a field", opt.Name)
+		case seen[step.Field]:
+			return fmt.Errorf("flow %s has duplicate field %s", opt.Name, step.Field)
+		case step.Field == flowCompleteStep:
+			return fmt.Errorf("flow %s has field %s, which names the menu that completes the flow", opt.Name, step.Field)
+		}
+		seen[step.Field] = true
+	}
+
+	menus := make([]Menu, 0, len(opt.Steps)+1)
+
+	for i, step := range opt.Steps {
+		var (
+			i          = i
+			validators []Validator
+			messages   Content
+			shortCut   string
+			sensitive  bool
+		)
+
+		// A menu validates the answer to the step before it
+		if i > 0 {
+			validators = opt.Steps[i-1].Validators
+			messages = opt.Steps[i-1].ValidationMessage
+			sensitive = opt.Steps[i-1].Sensitive
+		} else {
+			shortCut = opt.ShortCut
+		}
+
+		menus = append(menus, NewMenu(&MenuOptions{
+			MenuName:          flowStepMenu(opt, i),
+			NextMenu:          flowStepMenu(opt, i+1),
+			ShortCut:          shortCut,
+			MenuContent:       step.Prompt,
+			Validators:        validators,
+			ValidationMessage: messages,
+			SensitiveInput:    sensitive,
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				if i == 0 {
+					// Starting the flow discards earlier answers
+					err := app.Session(payload).Del(ctx, flowKey(opt.Name))
+					if err != nil {
+						return nil, err
+					}
+				} else {
+					_, err := app.saveFlowAnswer(ctx, payload, opt.Name, opt.Steps[i-1].Field)
+					if err != nil {
+						return nil, err
+					}
+				}
+				return m.ExecuteMenuArgs(app.GetLanguage(ctx, payload)), nil
+			},
+		}))
+	}
+
+	last := opt.Steps[len(opt.Steps)-1]
+
+	menus = append(menus, NewMenu(&MenuOptions{
+		MenuName:          flowStepMenu(opt, len(opt.Steps)),
+		NextMenu:          firstVal(opt.NextMenu, app.homeMenu),
+		Validators:        last.Validators,
+		ValidationMessage: last.ValidationMessage,
+		SensitiveInput:    last.Sensitive,
+		GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+			fields, err := app.saveFlowAnswer(ctx, payload, opt.Name, last.Field)
+			if err != nil {
+				return nil, err
+			}
+
+			sr, err := opt.OnComplete(ctx, payload, fields)
+			if err != nil {
+				return nil, err
+			}
+
+			err = app.Session(payload).Del(ctx, flowKey(opt.Name))
+			if err != nil {
+				return nil, err
+			}
+
+			// The home menu differs per route and session, so it is resolved once the flow completes
+			if opt.NextMenu == "" {
+				home, ok := app.getMenu(app.homeMenuOf(payload))
+				if !ok {
+					return nil, fmt.Errorf("%w: home menu %s", ErrMenuNotExist, app.homeMenuOf(payload))
+				}
+
+				err = app.saveNextMenu(ctx, payload, m, home)
+				if err != nil {
+					return nil, err
+				}
+
+				SkipSavingPayload(payload)
+			}
+
+			return sr, nil
+		},
+	}))
+
+	err := app.addMenus(menus...)
+	if err != nil {
+		return err
+	}
+
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	app.menuRegistry.Store(app.registry().withFlow(opt.Name))
+
+	return nil
+}
+
+// withFlow returns a copy of the registry with the flow added
+func (r *menuRegistry) withFlow(flowName string) *menuRegistry {
+	next := *r
+	next.flows = append(append(make([]string, 0, len(r.flows)+1), r.flows...), flowName)
+
+	return &next
+}
+
+// FlowData returns the answers collected so far in the flow with the given name
+func (app *UssdApp) FlowData(ctx context.Context, payload UssdPayload, flowName string) (map[string]string, error) {
+	fields := make(map[string]string)
+
+	err := app.Session(payload).GetJSON(ctx, flowKey(flowName), &fields)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// saveFlowAnswer saves the user input as the answer for field and returns all the answers
+func (app *UssdApp) saveFlowAnswer(ctx context.Context, payload UssdPayload, flowName, field string) (map[string]string, error) {
+	fields, err := app.FlowData(ctx, payload, flowName)
+	if err != nil {
+		return nil, err
+	}
+
+	fields[field] = payload.UssdCurrentParam()
+
+	err = app.Session(payload).SetJSON(ctx, flowKey(flowName), fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func flowKey(flowName string) string {
+	return fmt.Sprintf("flow:%s", flowName)
+}
+
+// flowStepMenu is the name of the menu that shows step i, the menu after the last step completes the flow
+func flowStepMenu(opt *FlowOptions, i int) string {
+	switch {
+	case i == 0:
+		return opt.Name
+	case i == len(opt.Steps):
+		return fmt.Sprintf("%s:%s", opt.Name, flowCompleteStep)
+	default:
+		return fmt.Sprintf("%s:%s", opt.Name, opt.Steps[i].Field)
+	}
+}
@@ -0,0 +1,133 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// ownNumberInput is entered for the phone number to buy airtime for the number of the session
+const ownNumberInput = "0"
+
+// AirtimePurchase is a confirmed airtime purchase
+type AirtimePurchase struct {
+	// Phone receives the airtime. It is the msisdn of the session when the subscriber buys for their own number
+	Phone  string
+	Amount float64
+	// PIN is empty unless the purchase requires one
+	PIN string
+}
+
+// BuyAirtimeFn buys airtime in the backend. Return an ussdapp.Error to show the subscriber why the purchase failed
+type BuyAirtimeFn func(ctx context.Context, payload ussdapp.UssdPayload, purchase *AirtimePurchase) error
+
+// AirtimeOptions configures the airtime purchase flow
+type AirtimeOptions struct {
+	// Name is the name of the menu that starts the flow. Defaults to buy_airtime
+	Name     string
+	ShortCut string
+	// NextMenu receives input after the flow, or when the purchase is cancelled. Defaults to the home menu
+	NextMenu string
+	// MinAmount and MaxAmount bound the amount. They default to 5 and 10000
+	MinAmount float64
+	MaxAmount float64
+	// RequirePIN asks for the PIN of the subscriber before the purchase is confirmed
+	RequirePIN bool
+	// PINLength is the number of digits in a PIN. Defaults to DefaultPINLength
+	PINLength    int
+	PhonePrompt  ussdapp.Content
+	AmountPrompt ussdapp.Content
+	PINPrompt    ussdapp.Content
+	// Summary is shown with the confirm choices. The answers are available as {{.phone}} and {{.amount}}
+	Summary        ussdapp.Content
+	SuccessMessage ussdapp.Content
+	BuyAirtime     BuyAirtimeFn
+}
+
+// AddBuyAirtime registers a flow that asks for the phone number and amount of airtime, confirms the purchase and
+// buys the airtime with BuyAirtime
+func AddBuyAirtime(app *ussdapp.UssdApp, opt *AirtimeOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing airtime options")
+	case opt.BuyAirtime == nil:
+		return errors.New("missing buy airtime function")
+	}
+
+	var (
+		name      = firstVal(opt.Name, "buy_airtime")
+		minAmount = opt.MinAmount
+		maxAmount = opt.MaxAmount
+	)
+	if minAmount <= 0 {
+		minAmount = 5
+	}
+	if maxAmount <= 0 {
+		maxAmount = 10000
+	}
+
+	steps := []*ussdapp.FlowStep{
+		{
+			Field:      "phone",
+			Prompt:     content(opt.PhonePrompt, "Enter phone number or 0 for your number"),
+			Validators: []ussdapp.Validator{phoneOrOwn},
+		},
+		{
+			Field:      "amount",
+			Prompt:     content(opt.AmountPrompt, "Enter amount"),
+			Validators: []ussdapp.Validator{ussdapp.Amount(minAmount, maxAmount)},
+		},
+	}
+	if opt.RequirePIN {
+		steps = append(steps, &ussdapp.FlowStep{
+			Field:      "pin",
+			Prompt:     content(opt.PINPrompt, "Enter your PIN"),
+			Validators: pinValidators(opt.PINLength),
+			Sensitive:  true,
+		})
+	}
+
+	onComplete, err := confirmed(app, name, opt.NextMenu,
+		content(opt.Summary, "Buy airtime of {{.amount}} for {{.phone}}"),
+		content(opt.SuccessMessage, "Your airtime purchase is being processed"),
+		func(ctx context.Context, payload ussdapp.UssdPayload, answers map[string]string) error {
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(answers["amount"], ",", ""), 64)
+			if err != nil {
+				return ussdapp.ErrInvalidInput("Invalid amount")
+			}
+
+			return opt.BuyAirtime(ctx, payload, &AirtimePurchase{
+				Phone:  answers["phone"],
+				Amount: amount,
+				PIN:    answers["pin"],
+			})
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return app.AddFlow(&ussdapp.FlowOptions{
+		Name:     name,
+		ShortCut: opt.ShortCut,
+		NextMenu: opt.NextMenu,
+		Steps:    steps,
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			if fields["phone"] == ownNumberInput {
+				fields["phone"] = payload.Msisdn()
+			}
+			return onComplete(ctx, payload, fields)
+		},
+	})
+}
+
+// phoneOrOwn validates that input is a phone number or the input for the number of the session
+func phoneOrOwn(input string) error {
+	if input == ownNumberInput {
+		return nil
+	}
+	return ussdapp.PhoneNumber()(input)
+}
@@ -0,0 +1,86 @@
+package flows
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// BalanceFn returns the balance of the subscriber as shown to them, e.g KES 1,250.00. The PIN is empty unless the
+// inquiry requires one
+type BalanceFn func(ctx context.Context, payload ussdapp.UssdPayload, pin string) (string, error)
+
+// BalanceOptions configures the balance inquiry flow
+type BalanceOptions struct {
+	// Name is the name of the menu that starts the flow. Defaults to balance
+	Name     string
+	ShortCut string
+	// NextMenu receives input after the flow. Defaults to the home menu
+	NextMenu string
+	// RequirePIN asks for the PIN of the subscriber before the balance is fetched
+	RequirePIN bool
+	// PINLength is the number of digits in a PIN. Defaults to DefaultPINLength
+	PINLength int
+	PINPrompt ussdapp.Content
+	// Message shows the balance, which is available as {{.balance}}
+	Message ussdapp.Content
+	Balance BalanceFn
+}
+
+// AddBalanceInquiry registers a flow that shows the balance of the subscriber from Balance and ends the session
+func AddBalanceInquiry(app *ussdapp.UssdApp, opt *BalanceOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing balance options")
+	case opt.Balance == nil:
+		return errors.New("missing balance function")
+	}
+
+	var (
+		name    = firstVal(opt.Name, "balance")
+		message = content(opt.Message, "Your balance is {{.balance}}")
+	)
+
+	show := func(ctx context.Context, payload ussdapp.UssdPayload, pin string) (ussdapp.SessionResponse, error) {
+		balance, err := opt.Balance(ctx, payload, pin)
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := render(ctx, app, payload, message, map[string]string{"balance": balance})
+		if err != nil {
+			return nil, err
+		}
+
+		return end(name, text), nil
+	}
+
+	if !opt.RequirePIN {
+		return app.AddMenu(ussdapp.NewMenu(&ussdapp.MenuOptions{
+			MenuName: name,
+			ShortCut: opt.ShortCut,
+			NextMenu: firstVal(opt.NextMenu, name),
+			GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+				return show(ctx, payload, "")
+			},
+		}))
+	}
+
+	return app.AddFlow(&ussdapp.FlowOptions{
+		Name:     name,
+		ShortCut: opt.ShortCut,
+		NextMenu: opt.NextMenu,
+		Steps: []*ussdapp.FlowStep{
+			{
+				Field:      "pin",
+				Prompt:     content(opt.PINPrompt, "Enter your PIN"),
+				Validators: pinValidators(opt.PINLength),
+				Sensitive:  true,
+			},
+		},
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			return show(ctx, payload, fields["pin"])
+		},
+	})
+}
@@ -0,0 +1,8 @@
+/*
+Package flows provides ready-made USSD flows that apps register instead of writing the menus themselves: PIN change,
+balance inquiry, airtime purchase, customer registration and feedback surveys.
+
+Each flow is built on UssdApp.AddFlow and UssdApp.AddConfirmMenu. Prompts and messages have English defaults and are
+set per language through the flow options, while calls to backend services are left to callbacks of the app.
+*/
+package flows
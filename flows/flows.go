@@ -0,0 +1,152 @@
+package flows
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// DefaultPINLength is the number of digits in a PIN when the flow options leave it unset
+const DefaultPINLength = 4
+
+const (
+	confirmStep = "confirm"
+	doneStep    = "done"
+)
+
+// content returns c, or the default text for all languages when c is empty
+func content(c ussdapp.Content, def string) ussdapp.Content {
+	if len(c) > 0 {
+		return c
+	}
+	return ussdapp.Content{"": def}
+}
+
+// render executes the text of the content in the session language with the flow answers as data
+func render(ctx context.Context, app *ussdapp.UssdApp, payload ussdapp.UssdPayload, c ussdapp.Content, data interface{}) (string, error) {
+	t, err := template.New("").Option("missingkey=zero").Parse(c.Text(app.GetLanguage(ctx, payload)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = t.Execute(buf, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// end returns a response that ends the session with the text
+func end(menuName, text string) ussdapp.SessionResponse {
+	return ussdapp.NewSessionResponse(&ussdapp.SessionData{
+		Response: text,
+		MenuName: menuName,
+		Terminal: true,
+	})
+}
+
+// pinValidators validate that input is a PIN of length digits
+func pinValidators(length int) []ussdapp.Validator {
+	if length <= 0 {
+		length = DefaultPINLength
+	}
+	return []ussdapp.Validator{ussdapp.Numeric(), ussdapp.MinLen(length), ussdapp.MaxLen(length)}
+}
+
+func stepMenu(flowName, step string) string {
+	return fmt.Sprintf("%s:%s", flowName, step)
+}
+
+// answersKey is the session field that keeps the answers of a flow until they are confirmed
+func answersKey(flowName string) string {
+	return fmt.Sprintf("flows:%s", flowName)
+}
+
+// confirmed registers a confirm menu for the answers of a flow, and a menu that ends the session with the success
+// message once they are confirmed.
+//
+// The OnComplete callback of the flow returned by confirmed keeps the answers in the session and renders the confirm
+// menu, where summary is executed with the answers.
+func confirmed(
+	app *ussdapp.UssdApp,
+	flowName, nextMenu string,
+	summary, success ussdapp.Content,
+	onConfirm func(ctx context.Context, payload ussdapp.UssdPayload, answers map[string]string) error,
+) (ussdapp.FlowCompleteFn, error) {
+	var (
+		confirmMenu = stepMenu(flowName, confirmStep)
+		doneMenu    = stepMenu(flowName, doneStep)
+	)
+
+	answers := func(ctx context.Context, payload ussdapp.UssdPayload) (map[string]string, error) {
+		fields := make(map[string]string)
+		err := app.Session(payload).GetJSON(ctx, answersKey(flowName), &fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get answers of flow %s: %w", flowName, err)
+		}
+		return fields, nil
+	}
+
+	err := app.AddConfirmMenu(&ussdapp.ConfirmMenuOptions{
+		MenuName: confirmMenu,
+		SummaryFn: func(ctx context.Context, payload ussdapp.UssdPayload, lang string) (string, error) {
+			fields, err := answers(ctx, payload)
+			if err != nil {
+				return "", err
+			}
+			return render(ctx, app, payload, summary, fields)
+		},
+		OnConfirm: func(ctx context.Context, payload ussdapp.UssdPayload) error {
+			fields, err := answers(ctx, payload)
+			if err != nil {
+				return err
+			}
+
+			err = onConfirm(ctx, payload, fields)
+			if err != nil {
+				return err
+			}
+
+			return app.Session(payload).Del(ctx, answersKey(flowName))
+		},
+		SuccessMenu: doneMenu,
+		CancelMenu:  nextMenu,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.AddMenu(ussdapp.NewMenu(&ussdapp.MenuOptions{
+		MenuName: doneMenu,
+		// The session ends on the menu, so the next menu only starts the flow again for gateways that keep it open
+		NextMenu: flowName,
+		GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+			return end(m.MenuName(), success.Text(app.GetLanguage(ctx, payload))), nil
+		},
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+		err := app.Session(payload).SetJSON(ctx, answersKey(flowName), fields)
+		if err != nil {
+			return nil, err
+		}
+		return app.ReplaceMenuWithName(ctx, confirmMenu, payload)
+	}, nil
+}
+
+func firstVal(vals ...string) string {
+	for _, val := range vals {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
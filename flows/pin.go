@@ -0,0 +1,88 @@
+package flows
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// ChangePINFn changes the PIN of the subscriber in the backend. Return an ussdapp.Error to show the subscriber why the
+// change failed, e.g a wrong current PIN
+type ChangePINFn func(ctx context.Context, payload ussdapp.UssdPayload, currentPIN, newPIN string) error
+
+// ChangePINOptions configures the PIN change flow
+type ChangePINOptions struct {
+	// Name is the name of the menu that starts the flow. Defaults to change_pin
+	Name     string
+	ShortCut string
+	// NextMenu receives input after the flow. Defaults to the home menu
+	NextMenu string
+	// PINLength is the number of digits in a PIN. Defaults to DefaultPINLength
+	PINLength        int
+	CurrentPINPrompt ussdapp.Content
+	NewPINPrompt     ussdapp.Content
+	ConfirmPINPrompt ussdapp.Content
+	// MismatchMessage ends the session when the new PIN and its confirmation differ
+	MismatchMessage ussdapp.Content
+	SuccessMessage  ussdapp.Content
+	ChangePIN       ChangePINFn
+}
+
+// AddChangePIN registers a flow that asks for the current PIN and a new PIN twice, then changes the PIN with
+// ChangePIN. PINs are redacted from session logs.
+func AddChangePIN(app *ussdapp.UssdApp, opt *ChangePINOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing change pin options")
+	case opt.ChangePIN == nil:
+		return errors.New("missing change pin function")
+	}
+
+	var (
+		name       = firstVal(opt.Name, "change_pin")
+		validators = pinValidators(opt.PINLength)
+		mismatch   = content(opt.MismatchMessage, "The PINs do not match. Please try again")
+		success    = content(opt.SuccessMessage, "Your PIN has been changed")
+	)
+
+	return app.AddFlow(&ussdapp.FlowOptions{
+		Name:     name,
+		ShortCut: opt.ShortCut,
+		NextMenu: opt.NextMenu,
+		Steps: []*ussdapp.FlowStep{
+			{
+				Field:      "current_pin",
+				Prompt:     content(opt.CurrentPINPrompt, "Enter your current PIN"),
+				Validators: validators,
+				Sensitive:  true,
+			},
+			{
+				Field:      "new_pin",
+				Prompt:     content(opt.NewPINPrompt, "Enter your new PIN"),
+				Validators: validators,
+				Sensitive:  true,
+			},
+			{
+				Field:      "confirm_pin",
+				Prompt:     content(opt.ConfirmPINPrompt, "Confirm your new PIN"),
+				Validators: validators,
+				Sensitive:  true,
+			},
+		},
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			lang := app.GetLanguage(ctx, payload)
+
+			if fields["new_pin"] != fields["confirm_pin"] {
+				return end(name, mismatch.Text(lang)), nil
+			}
+
+			err := opt.ChangePIN(ctx, payload, fields["current_pin"], fields["new_pin"])
+			if err != nil {
+				return nil, err
+			}
+
+			return end(name, success.Text(lang)), nil
+		},
+	})
+}
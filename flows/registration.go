@@ -0,0 +1,130 @@
+package flows
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// Customer is a confirmed customer registration
+type Customer struct {
+	Msisdn    string
+	FirstName string
+	LastName  string
+	IDNumber  string
+	// PIN is empty unless the registration sets one
+	PIN string
+}
+
+// RegisterFn registers the customer in the backend. Return an ussdapp.Error to show the subscriber why the
+// registration failed, e.g an ID number that is registered
+type RegisterFn func(ctx context.Context, payload ussdapp.UssdPayload, customer *Customer) error
+
+// RegistrationOptions configures the customer registration flow
+type RegistrationOptions struct {
+	// Name is the name of the menu that starts the flow. Defaults to register
+	Name     string
+	ShortCut string
+	// NextMenu receives input after the flow, or when the registration is cancelled. Defaults to the home menu
+	NextMenu string
+	// SetPIN asks the customer to choose a PIN and confirm it
+	SetPIN bool
+	// PINLength is the number of digits in a PIN. Defaults to DefaultPINLength
+	PINLength        int
+	FirstNamePrompt  ussdapp.Content
+	LastNamePrompt   ussdapp.Content
+	IDNumberPrompt   ussdapp.Content
+	PINPrompt        ussdapp.Content
+	ConfirmPINPrompt ussdapp.Content
+	// MismatchMessage ends the session when the PIN and its confirmation differ
+	MismatchMessage ussdapp.Content
+	// Summary is shown with the confirm choices. The answers are available as {{.first_name}}, {{.last_name}} and
+	// {{.id_number}}
+	Summary        ussdapp.Content
+	SuccessMessage ussdapp.Content
+	Register       RegisterFn
+}
+
+// AddRegistration registers a flow that asks for the names and ID number of a customer, and optionally a PIN,
+// confirms the details and registers the customer with Register
+func AddRegistration(app *ussdapp.UssdApp, opt *RegistrationOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing registration options")
+	case opt.Register == nil:
+		return errors.New("missing register function")
+	}
+
+	var (
+		name     = firstVal(opt.Name, "register")
+		mismatch = content(opt.MismatchMessage, "The PINs do not match. Please try again")
+		names    = []ussdapp.Validator{ussdapp.MinLen(2), ussdapp.MaxLen(50)}
+	)
+
+	steps := []*ussdapp.FlowStep{
+		{
+			Field:      "first_name",
+			Prompt:     content(opt.FirstNamePrompt, "Enter your first name"),
+			Validators: names,
+		},
+		{
+			Field:      "last_name",
+			Prompt:     content(opt.LastNamePrompt, "Enter your last name"),
+			Validators: names,
+		},
+		{
+			Field:      "id_number",
+			Prompt:     content(opt.IDNumberPrompt, "Enter your ID number"),
+			Validators: []ussdapp.Validator{ussdapp.Numeric(), ussdapp.MinLen(5), ussdapp.MaxLen(20)},
+		},
+	}
+	if opt.SetPIN {
+		validators := pinValidators(opt.PINLength)
+		steps = append(steps,
+			&ussdapp.FlowStep{
+				Field:      "pin",
+				Prompt:     content(opt.PINPrompt, "Choose a PIN"),
+				Validators: validators,
+				Sensitive:  true,
+			},
+			&ussdapp.FlowStep{
+				Field:      "confirm_pin",
+				Prompt:     content(opt.ConfirmPINPrompt, "Confirm your PIN"),
+				Validators: validators,
+				Sensitive:  true,
+			},
+		)
+	}
+
+	onComplete, err := confirmed(app, name, opt.NextMenu,
+		content(opt.Summary, "Register {{.first_name}} {{.last_name}}, ID {{.id_number}}"),
+		content(opt.SuccessMessage, "Your registration has been received"),
+		func(ctx context.Context, payload ussdapp.UssdPayload, answers map[string]string) error {
+			return opt.Register(ctx, payload, &Customer{
+				Msisdn:    payload.Msisdn(),
+				FirstName: answers["first_name"],
+				LastName:  answers["last_name"],
+				IDNumber:  answers["id_number"],
+				PIN:       answers["pin"],
+			})
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return app.AddFlow(&ussdapp.FlowOptions{
+		Name:     name,
+		ShortCut: opt.ShortCut,
+		NextMenu: opt.NextMenu,
+		Steps:    steps,
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			if fields["pin"] != fields["confirm_pin"] {
+				return end(name, mismatch.Text(app.GetLanguage(ctx, payload))), nil
+			}
+			delete(fields, "confirm_pin")
+			return onComplete(ctx, payload, fields)
+		},
+	})
+}
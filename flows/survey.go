@@ -0,0 +1,106 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gidyon/ussdapp"
+)
+
+// SurveyQuestion is a question of a feedback survey
+type SurveyQuestion struct {
+	// Field is the name the answer is submitted under
+	Field  string
+	Prompt ussdapp.Content
+	// Choices are the valid answers, e.g 1 to 5 for a rating. Questions without choices take free text
+	Choices []string
+	// MaxLength limits free text answers. Defaults to 160
+	MaxLength int
+}
+
+// SubmitFeedbackFn saves the answers of a survey, keyed by question field
+type SubmitFeedbackFn func(ctx context.Context, payload ussdapp.UssdPayload, answers map[string]string) error
+
+// SurveyOptions configures the feedback survey flow
+type SurveyOptions struct {
+	// Name is the name of the menu that starts the flow. Defaults to feedback
+	Name     string
+	ShortCut string
+	// NextMenu receives input after the flow. Defaults to the home menu
+	NextMenu string
+	// Questions are asked in order. Defaults to a rating from 1 to 5 followed by a comment
+	Questions       []*SurveyQuestion
+	ThankYouMessage ussdapp.Content
+	SubmitFeedback  SubmitFeedbackFn
+}
+
+// defaultQuestions are asked by surveys without questions
+var defaultQuestions = []*SurveyQuestion{
+	{
+		Field:   "rating",
+		Prompt:  ussdapp.Content{"": "How would you rate our service?\n1. Very poor\n2. Poor\n3. Fair\n4. Good\n5. Excellent"},
+		Choices: []string{"1", "2", "3", "4", "5"},
+	},
+	{
+		Field:  "comment",
+		Prompt: ussdapp.Content{"": "Tell us how we can improve"},
+	},
+}
+
+// AddFeedbackSurvey registers a flow that asks the survey questions and submits the answers with SubmitFeedback
+func AddFeedbackSurvey(app *ussdapp.UssdApp, opt *SurveyOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing survey options")
+	case opt.SubmitFeedback == nil:
+		return errors.New("missing submit feedback function")
+	}
+
+	var (
+		name      = firstVal(opt.Name, "feedback")
+		thankYou  = content(opt.ThankYouMessage, "Thank you for your feedback")
+		questions = opt.Questions
+	)
+	if len(questions) == 0 {
+		questions = defaultQuestions
+	}
+
+	steps := make([]*ussdapp.FlowStep, 0, len(questions))
+	for _, q := range questions {
+		if q == nil {
+			return fmt.Errorf("survey %s has a nil question", name)
+		}
+
+		var validators []ussdapp.Validator
+		if len(q.Choices) > 0 {
+			validators = []ussdapp.Validator{ussdapp.OneOf(q.Choices...)}
+		} else {
+			maxLength := q.MaxLength
+			if maxLength <= 0 {
+				maxLength = 160
+			}
+			validators = []ussdapp.Validator{ussdapp.MinLen(1), ussdapp.MaxLen(maxLength)}
+		}
+
+		steps = append(steps, &ussdapp.FlowStep{
+			Field:      q.Field,
+			Prompt:     q.Prompt,
+			Validators: validators,
+		})
+	}
+
+	return app.AddFlow(&ussdapp.FlowOptions{
+		Name:     name,
+		ShortCut: opt.ShortCut,
+		NextMenu: opt.NextMenu,
+		Steps:    steps,
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			err := opt.SubmitFeedback(ctx, payload, fields)
+			if err != nil {
+				return nil, err
+			}
+			return end(name, thankYou.Text(app.GetLanguage(ctx, payload))), nil
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FunnelStats shows how sessions moved through the menus and where they stopped
+type FunnelStats struct {
+	// Sessions is the number of sessions started in the period
+	Sessions int64
+	// Completed is the number of sessions that ended with an END response
+	Completed int64
+	// Abandoned is the number of sessions that stopped without an END response
+	Abandoned int64
+	// Menus has the stats of each menu visited, most visited first
+	Menus []*MenuFunnel
+}
+
+// MenuFunnel contains the number of sessions that reached a menu and how many of them stopped there
+type MenuFunnel struct {
+	MenuName string
+	// Sessions is the number of sessions that visited the menu
+	Sessions int64
+	// Completed is the number of sessions that ended on the menu with an END response
+	Completed int64
+	// Abandoned is the number of sessions whose last menu was this one without an END response
+	Abandoned   int64
+	AbandonRate float64
+}
+
+// sessionTrail is the ordered list of menus visited in a session
+type sessionTrail struct {
+	menus []string
+	ended bool
+}
+
+func (t *sessionTrail) visit(menuName string, ended bool) {
+	if menuName != "" && (len(t.menus) == 0 || t.menus[len(t.menus)-1] != menuName) {
+		t.menus = append(t.menus, menuName)
+	}
+	t.ended = ended
+}
+
+// FunnelStats computes, from the session logs of sessions started between from and to, the menus each
+// session visited and whether it ended with an END response.
+//
+// It requires session logs to be saved in Options.SQLDB. Sessions still in progress are counted as abandoned.
+func (app *UssdApp) FunnelStats(ctx context.Context, from, to time.Time) (*FunnelStats, error) {
+	if app.opt.SQLDB == nil {
+		return nil, errors.New("funnel stats require sql database")
+	}
+
+	tableName := app.logsTable
+
+	rows, err := app.opt.SQLDB.WithContext(ctx).Table(tableName).
+		Select("session_id, menu_name, ended").
+		Where("session_id IN (?)", app.opt.SQLDB.Table(tableName).
+			Select("DISTINCT session_id").
+			Where("created_at BETWEEN ? AND ?", from, to)).
+		Order("session_id, created_at, id").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session logs: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		stats    = &FunnelStats{}
+		menus    = make(map[string]*MenuFunnel)
+		trail    *sessionTrail
+		lastSess string
+	)
+
+	addTrail := func() {
+		if trail == nil || len(trail.menus) == 0 {
+			return
+		}
+
+		stats.Sessions++
+
+		seen := make(map[string]bool, len(trail.menus))
+		for _, menuName := range trail.menus {
+			if seen[menuName] {
+				continue
+			}
+			seen[menuName] = true
+
+			mf, ok := menus[menuName]
+			if !ok {
+				mf = &MenuFunnel{MenuName: menuName}
+				menus[menuName] = mf
+			}
+			mf.Sessions++
+		}
+
+		last := menus[trail.menus[len(trail.menus)-1]]
+		if trail.ended {
+			stats.Completed++
+			last.Completed++
+		} else {
+			stats.Abandoned++
+			last.Abandoned++
+		}
+	}
+
+	for rows.Next() {
+		var (
+			sessionID, menuName string
+			ended               bool
+		)
+		err = rows.Scan(&sessionID, &menuName, &ended)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session log: %v", err)
+		}
+
+		if trail == nil || sessionID != lastSess {
+			addTrail()
+			trail = &sessionTrail{}
+			lastSess = sessionID
+		}
+
+		trail.visit(menuName, ended)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session logs: %v", err)
+	}
+
+	addTrail()
+
+	stats.Menus = make([]*MenuFunnel, 0, len(menus))
+	for _, mf := range menus {
+		mf.AbandonRate = float64(mf.Abandoned) / float64(mf.Sessions)
+		stats.Menus = append(stats.Menus, mf)
+	}
+
+	sort.Slice(stats.Menus, func(i, j int) bool {
+		if stats.Menus[i].Sessions != stats.Menus[j].Sessions {
+			return stats.Menus[i].Sessions > stats.Menus[j].Sessions
+		}
+		return stats.Menus[i].MenuName < stats.Menus[j].MenuName
+	})
+
+	return stats, nil
+}
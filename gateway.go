@@ -0,0 +1,161 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GatewayAdapter parses incoming requests from a USSD gateway and writes responses in the format expected by the gateway.
+//
+// Implement this interface to serve the same menus to a different aggregator.
+type GatewayAdapter interface {
+	// ParseRequest reads the gateway request and returns the ussd payload
+	ParseRequest(*http.Request) (UssdPayload, error)
+	// WriteResponse writes the session response back to the gateway
+	WriteResponse(http.ResponseWriter, SessionResponse) error
+}
+
+// ErrSkipRequest is returned by gateway adapters for requests that carry no user input, such as delivery reports.
+// The request is acknowledged without running the menus.
+var ErrSkipRequest = errors.New("request has no user input")
+
+// PayloadWriter is implemented by gateway adapters whose responses need data of the request, such as channels that
+// reply to the sender through an API. It is used instead of WriteResponse.
+type PayloadWriter interface {
+	WritePayloadResponse(ctx context.Context, w http.ResponseWriter, payload UssdPayload, sr SessionResponse) error
+}
+
+// ConversationMessage is a message received from a messaging channel
+type ConversationMessage struct {
+	// Channel identifies the channel or number the message was sent to, it is used as the service code
+	Channel string
+	// Sender is the msisdn or chat id of the user, it is also the session id
+	Sender string
+	// Text of the message
+	Text string
+	// SessionDuration is how long the session is kept without replies, defaults to Options.SessionDuration
+	SessionDuration time.Duration
+}
+
+// NewConversationPayload returns the payload for a message from a messaging channel, for use by gateway adapters
+// of chat and SMS channels.
+//
+// The sender is used as the session id, so each message of the sender is an input to its session until a menu ends
+// the session or the session expires. The first message of a session opens the home menu whatever its text.
+func NewConversationPayload(msg *ConversationMessage) UssdPayload {
+	text := strings.TrimSpace(msg.Text)
+
+	return &ussdPayload{
+		data: &ussdPayloadInternal{
+			SessionID:        msg.Sender,
+			ServiceCode:      msg.Channel,
+			Msisdn:           msg.Sender,
+			UssdParams:       text,
+			UssdCurrentParam: text,
+			incremental:      true,
+			conversation:     true,
+			sessionDuration:  msg.SessionDuration,
+		},
+	}
+}
+
+// NewGenericAdapter returns the default gateway adapter.
+//
+// It reads the request using UssdPayloadFromRequest and writes plain text responses using the CON/END prefix convention.
+func NewGenericAdapter() GatewayAdapter {
+	return &genericAdapter{}
+}
+
+type genericAdapter struct{}
+
+func (*genericAdapter) ParseRequest(r *http.Request) (UssdPayload, error) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	payload := UssdPayloadFromRequest(r)
+	if payload.SessionId() == "" {
+		return nil, errors.New("missing session id")
+	}
+
+	return payload, nil
+}
+
+func (*genericAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	return writeEncoded(w, PlainTextEncoder(), sr)
+}
+
+// Gateway returns the gateway adapter used by the app to read requests and write responses
+func (app *UssdApp) Gateway() GatewayAdapter {
+	return app.opt.Gateway
+}
+
+// ParseRequest reads the incoming request using the app gateway adapter
+func (app *UssdApp) ParseRequest(r *http.Request) (UssdPayload, error) {
+	return app.opt.Gateway.ParseRequest(r)
+}
+
+// WriteResponse writes the session response using the app gateway adapter
+func (app *UssdApp) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	return app.opt.Gateway.WriteResponse(w, sr)
+}
+
+// splitShortCode separates inputs dialed after the base code, e.g *123*1*2# has code *123# and inputs 1 and 2
+func splitShortCode(code string) (string, []string) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(code, "*"), "#"), "*")
+	if len(parts) < 2 {
+		return code, nil
+	}
+	return "*" + parts[0] + "#", parts[1:]
+}
+
+// joinInputs prefixes the input of payloads from gateways that send only the latest input with the earlier inputs
+// of the session, keeping the inputs in the session cache
+func (app *UssdApp) joinInputs(ctx context.Context, payload UssdPayload) error {
+	p, ok := payload.(*ussdPayload)
+	if !ok || !p.data.incremental {
+		return nil
+	}
+
+	sessionKey := app.GetSessionKey(payload)
+
+	prev, err := app.opt.Cache.GetMapField(ctx, sessionKey, ussdParamsKey)
+	switch {
+	case err == nil:
+		p.data.UssdParams = strings.TrimPrefix(prev+"*"+p.data.UssdCurrentParam, "*")
+	case errors.Is(err, ErrKeyNotFound):
+		if p.data.conversation {
+			// The first message of a conversation opens the home menu
+			p.data.UssdParams, p.data.UssdCurrentParam = "", ""
+		}
+	default:
+		return fmt.Errorf("failed to get session inputs: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, sessionKey, ussdParamsKey, p.data.UssdParams)
+	if err != nil {
+		return fmt.Errorf("failed to save session inputs: %v", err)
+	}
+
+	return nil
+}
+
+// sessionDuration returns how long data of the payload session is kept
+func (app *UssdApp) sessionDuration(payload UssdPayload) time.Duration {
+	if p, ok := payload.(*ussdPayload); ok && p.data.sessionDuration > 0 {
+		return p.data.sessionDuration
+	}
+	return app.opt.SessionDuration
+}
+
+// isConversation reports whether the payload is from a messaging channel whose sessions are keyed by msisdn
+func isConversation(payload UssdPayload) bool {
+	p, ok := payload.(*ussdPayload)
+	return ok && p.data.conversation
+}
@@ -0,0 +1,161 @@
+package ussdapp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GraphFormat is the text format of an exported menu graph
+type GraphFormat int
+
+const (
+	// GraphDOT exports the graph in the graphviz DOT language
+	GraphDOT GraphFormat = iota
+	// GraphMermaid exports the graph as a mermaid flowchart
+	GraphMermaid
+)
+
+// graphStart is the node of the ussd code dialed by the user
+const graphStart = "*dial*"
+
+type graphEdge struct {
+	from, to, label string
+	kind            TransitionKind
+	// shortCut edges go from the dial node to menus opened by shortcuts
+	shortCut bool
+}
+
+// menuGraph returns the nodes in registration order and the edges between them
+func (app *UssdApp) menuGraph() ([]string, []*graphEdge) {
+	var (
+		reg   = app.registry()
+		nodes = []string{graphStart}
+		seen  = map[string]bool{graphStart: true}
+		edges = []*graphEdge{{from: graphStart, to: app.homeMenu, kind: TransitionNext}}
+	)
+
+	addNode := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			nodes = append(nodes, name)
+		}
+	}
+
+	addNode(app.homeMenu)
+	for _, name := range reg.names {
+		addNode(name)
+	}
+
+	for _, name := range reg.names {
+		if sc := reg.menus[name].ShortCut(); sc != "" {
+			edges = append(edges, &graphEdge{from: graphStart, to: name, label: sc, shortCut: true})
+		}
+
+		for _, t := range app.Transitions(name) {
+			addNode(t.To)
+			edges = append(edges, &graphEdge{from: t.From, to: t.To, label: t.Label, kind: t.Kind})
+		}
+	}
+
+	return nodes, edges
+}
+
+// ExportMenuGraph returns the registered menus as a graph in DOT or mermaid text.
+//
+// Edges are the transitions out of each menu, see Transitions. Shortcuts are edges from the dial node.
+func (app *UssdApp) ExportMenuGraph(format GraphFormat) (string, error) {
+	nodes, edges := app.menuGraph()
+
+	switch format {
+	case GraphDOT:
+		return dotGraph(app.opt.AppName, nodes, edges), nil
+	case GraphMermaid:
+		return mermaidGraph(nodes, edges), nil
+	default:
+		return "", fmt.Errorf("unknown graph format %d", format)
+	}
+}
+
+func dotGraph(name string, nodes []string, edges []*graphEdge) string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "digraph %s {\n", strconv.Quote(name))
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	for _, node := range nodes {
+		if node == graphStart {
+			fmt.Fprintf(b, "\t%s [shape=circle, label=\"dial\"];\n", strconv.Quote(node))
+			continue
+		}
+		fmt.Fprintf(b, "\t%s;\n", strconv.Quote(node))
+	}
+
+	for _, e := range edges {
+		var attrs []string
+		if e.label != "" {
+			attrs = append(attrs, "label="+strconv.Quote(e.label))
+		}
+		switch {
+		case e.shortCut:
+			attrs = append(attrs, "style=dotted")
+		case e.kind == TransitionBack, e.kind == TransitionEvent, e.kind == TransitionFallback:
+			attrs = append(attrs, "style=dashed")
+		case e.kind == TransitionDecision, e.kind == TransitionGuarded:
+			attrs = append(attrs, "style=bold")
+		}
+
+		fmt.Fprintf(b, "\t%s -> %s", strconv.Quote(e.from), strconv.Quote(e.to))
+		if len(attrs) > 0 {
+			fmt.Fprintf(b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+func mermaidGraph(nodes []string, edges []*graphEdge) string {
+	b := &strings.Builder{}
+
+	// Menu names may have characters mermaid does not allow in ids
+	ids := make(map[string]string, len(nodes))
+	for i, node := range nodes {
+		ids[node] = fmt.Sprintf("m%d", i)
+	}
+
+	b.WriteString("flowchart LR\n")
+
+	for _, node := range nodes {
+		if node == graphStart {
+			fmt.Fprintf(b, "\t%s((dial))\n", ids[node])
+			continue
+		}
+		fmt.Fprintf(b, "\t%s[\"%s\"]\n", ids[node], mermaidEscape(node))
+	}
+
+	for _, e := range edges {
+		arrow := "-->"
+		switch {
+		case e.shortCut, e.kind == TransitionBack, e.kind == TransitionEvent, e.kind == TransitionFallback:
+			arrow = "-.->"
+		case e.kind == TransitionDecision, e.kind == TransitionGuarded:
+			arrow = "==>"
+		}
+
+		if e.label != "" {
+			fmt.Fprintf(b, "\t%s %s|\"%s\"| %s\n", ids[e.from], arrow, mermaidEscape(e.label), ids[e.to])
+			continue
+		}
+		fmt.Fprintf(b, "\t%s %s %s\n", ids[e.from], arrow, ids[e.to])
+	}
+
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
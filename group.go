@@ -0,0 +1,126 @@
+package ussdapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// mountSeparator joins the prefix of a mounted group and the names of its menus
+const mountSeparator = "."
+
+// MenuGroup is a set of menus shipped together, such as an airtime purchase or bill payment flow in a library, that
+// apps register with Mount.
+//
+// Menus of a group refer to each other by their names in the group. Names of menus outside the group, e.g the home
+// menu of the app, are left as they are when the group is mounted.
+type MenuGroup struct {
+	entry string
+	menus []*MenuOptions
+	names map[string]bool
+}
+
+// NewMenuGroup creates a group that is entered through the menu with the name entry
+func NewMenuGroup(entry string) *MenuGroup {
+	return &MenuGroup{entry: entry, names: make(map[string]bool)}
+}
+
+// AddMenu adds a menu to the group
+func (g *MenuGroup) AddMenu(opt *MenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("nil menu options")
+	case opt.MenuName == "":
+		return errors.New("missing menu name")
+	case g.names[opt.MenuName]:
+		return fmt.Errorf("%w: %s", ErrMenuExist, opt.MenuName)
+	}
+
+	cp := *opt
+	g.menus = append(g.menus, &cp)
+	g.names[opt.MenuName] = true
+
+	return nil
+}
+
+// Menu starts building a menu of the group, which is added to the group by Add or Handle
+func (g *MenuGroup) Menu(name string) *MenuBuilder {
+	b := newMenuBuilder(name)
+	b.group = g
+	return b
+}
+
+// EntryMenu returns the name of the entry menu of the group when mounted with the prefix, for routes from app menus
+func (g *MenuGroup) EntryMenu(prefix string) string {
+	return prefix + mountSeparator + g.entry
+}
+
+// Mount registers the menus of the group with their names prefixed, e.g the menu amount of a group mounted as
+// airtime is registered as airtime.amount. A group may be mounted more than once with different prefixes.
+//
+// No menu is registered when a name is taken or a menu is invalid.
+func (app *UssdApp) Mount(prefix string, g *MenuGroup) error {
+	switch {
+	case g == nil:
+		return errors.New("nil menu group")
+	case strings.TrimSpace(prefix) == "":
+		return errors.New("missing mount prefix")
+	case !g.names[g.entry]:
+		return fmt.Errorf("entry menu %s of group %s is not in the group", g.entry, prefix)
+	}
+
+	rename := func(name string) string {
+		if g.names[name] {
+			return prefix + mountSeparator + name
+		}
+		return name
+	}
+
+	menus := make([]Menu, 0, len(g.menus))
+	for _, opt := range g.menus {
+		mounted := *opt
+		mounted.MenuName = rename(opt.MenuName)
+		mounted.PreviousMenu = rename(opt.PreviousMenu)
+		mounted.NextMenu = rename(opt.NextMenu)
+		mounted.FallbackMenu = rename(opt.FallbackMenu)
+		mounted.Routes = make(map[string]string, len(opt.Routes))
+		for input, route := range opt.Routes {
+			mounted.Routes[input] = rename(route)
+		}
+		if opt.Decisions != nil {
+			mounted.Decisions = opt.Decisions.rename(rename)
+		}
+
+		m := NewMenu(&mounted)
+		m.(*menu).mountPrefix = prefix
+
+		err := ValidateMenu(m)
+		if err != nil {
+			return fmt.Errorf("group %s: %v", prefix, err)
+		}
+		if _, ok := app.getMenu(m.MenuName()); ok {
+			return fmt.Errorf("group %s: %w: %s", prefix, ErrMenuExist, m.MenuName())
+		}
+
+		menus = append(menus, m)
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return fmt.Errorf("group %s: %v", prefix, err)
+		}
+	}
+
+	return nil
+}
+
+// SiblingMenu returns the registered name of the menu with the name in the group of m, so that handlers of group menus
+// can redirect to other menus of the group wherever it is mounted. Names are returned as they are for menus that are
+// not mounted
+func SiblingMenu(m Menu, name string) string {
+	if mm, ok := m.(*menu); ok && mm.mountPrefix != "" {
+		return mm.mountPrefix + mountSeparator + name
+	}
+	return name
+}
@@ -0,0 +1,73 @@
+package ussdapp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gidyon/ussdapp/ussdpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCService returns the app as a gRPC service so that gateways or internal APIs can run requests through
+// the menus without HTTP. Register it using ussdpb.RegisterUssdServiceServer.
+func (app *UssdApp) GRPCService() ussdpb.UssdServiceServer {
+	return &grpcService{app: app}
+}
+
+type grpcService struct {
+	ussdpb.UnimplementedUssdServiceServer
+	app *UssdApp
+}
+
+func (s *grpcService) ProcessUssd(ctx context.Context, req *ussdpb.UssdRequest) (*ussdpb.UssdResponse, error) {
+	switch {
+	case req == nil:
+		return nil, status.Error(codes.InvalidArgument, "missing ussd request")
+	case req.SessionId == "":
+		return nil, status.Error(codes.InvalidArgument, "missing session id")
+	case req.Msisdn == "":
+		return nil, status.Error(codes.InvalidArgument, "missing msisdn")
+	}
+
+	ussdParams := strings.Split(req.UssdParams, "*")
+
+	payload := &ussdPayload{
+		data: &ussdPayloadInternal{
+			SessionID:        req.SessionId,
+			ServiceCode:      req.ServiceCode,
+			Msisdn:           req.Msisdn,
+			UssdParams:       req.UssdParams,
+			UssdCurrentParam: strings.TrimSpace(ussdParams[len(ussdParams)-1]),
+		},
+	}
+
+	sr, err := s.app.ProcessPayload(ctx, payload)
+	if err != nil {
+		s.app.opt.Logger.Error("ussd request failed", "session_id", payload.SessionId(), "msisdn", payload.Msisdn(), "error", err)
+		sr = s.app.errorResponse(ctx, payload, err)
+	}
+
+	if !isDuplicate(payload) {
+		s.app.metrics.sessionCompleted(sr, err)
+	}
+
+	res := &ussdpb.UssdResponse{
+		SessionId: payload.SessionId(),
+		Text:      ResponseMessage(sr),
+		End:       sr.Terminal(),
+		MenuName:  sr.MenuName(),
+	}
+
+	s.app.sessionResponded(ctx, payload, sr)
+
+	s.app.EnqueuePendingJobs(ctx, payload)
+
+	if err != nil {
+		SetSessionFailed(sr, err.Error())
+	}
+
+	s.app.SaveLog(ctx, payload, sr)
+
+	return res, nil
+}
@@ -0,0 +1,589 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultErrorMessage       = "END Service is not available try again later"
+	defaultMenuTimeoutMessage = "END Your request is still being processed. Please dial again shortly"
+)
+
+// SessionHookFn is called after the menu for the session has been resolved and before it is rendered.
+//
+// The returned menu is the one that will be rendered, so the hook can send users to a different menu e.g registered users to a login menu.
+type SessionHookFn func(ctx context.Context, payload UssdPayload, menu Menu, isNew bool) (Menu, error)
+
+// HandlerFunc processes a ussd payload and returns the response for the user
+type HandlerFunc func(ctx context.Context, payload UssdPayload) (SessionResponse, error)
+
+// Middleware wraps a HandlerFunc to add behaviour around menu resolution and response generation
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use adds middlewares that run around every ussd request processed by the app.
+//
+// Middlewares run in the order they are added, the first one being the outermost. Middlewares may be added while
+// requests are served, requests already in progress keep the middlewares they started with.
+func (app *UssdApp) Use(middleware ...Middleware) {
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	app.menuRegistry.Store(app.registry().withMiddlewares(middleware))
+}
+
+// ProcessPayload runs the full menu lifecycle for the payload and returns the response for the user.
+//
+// It runs the middlewares around resolving the session menu, running the session hook,
+// generating the menu response and saving the next menu for the session.
+func (app *UssdApp) ProcessPayload(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	ctx, span := app.tracer.Start(ctx, "ussdapp.ProcessPayload", trace.WithAttributes(
+		attribute.String("ussd.session_id", payload.SessionId()),
+		attribute.String("ussd.service_code", payload.ServiceCode()),
+	))
+
+	// Payload and session for code called from menus, see FromContext
+	ctx = app.withRequest(ctx, payload)
+
+	// Retries of a request by the gateway get the earlier response, hashed before inputs are joined
+	request := requestHash(payload)
+	if app.opt.DedupWindow > 0 {
+		sr, ok, err := app.duplicateResponse(ctx, request, payload)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		if ok {
+			span.SetAttributes(attribute.Bool("ussd.duplicate", true))
+			endSpan(span, nil)
+			return sr, nil
+		}
+	}
+
+	if p, ok := payload.(*ussdPayload); ok && p.data.receivedAt.IsZero() {
+		p.data.receivedAt = time.Now()
+	}
+
+	// Debug logs of a user troubleshot in production
+	app.loadTrace(ctx, payload)
+
+	// Inputs of the session for gateways that send only the latest input
+	err := app.joinInputs(ctx, payload)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	middlewares := app.registry().middlewares
+
+	handler := HandlerFunc(app.process)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	sr, err := app.runHandler(ctx, payload, handler)
+	if sr != nil {
+		span.SetAttributes(attribute.String("ussd.menu", sr.MenuName()))
+	}
+	if err != nil {
+		app.debug(payload, "ussd request failed", "error", err)
+
+		// Follow up work of a failed request is not done
+		if p, ok := payload.(*ussdPayload); ok {
+			p.data.jobs = nil
+		}
+	} else {
+		app.debug(payload, "ussd response", "menu", sr.MenuName(), "terminal", sr.Terminal(), "failed", sr.Failed(),
+			"status", sr.StatusMessage())
+
+		// The menus already ran, so the request does not fail when the response cannot be kept
+		if serr := app.saveLastResponse(ctx, payload, request, sr); serr != nil {
+			app.opt.Logger.Warn("failed to keep last response", "session_id", payload.SessionId(), "error", serr)
+		}
+	}
+	endSpan(span, err)
+
+	return sr, err
+}
+
+// process runs the menu lifecycle for the payload
+func (app *UssdApp) process(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	// Blocked and abusive msisdns are turned away before any menu runs
+	sr, ok, err := app.blocked(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		sr.setSessionId(payload.SessionId())
+		return sr, nil
+	}
+
+	sr, ok, err = app.rateLimit(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		sr.setSessionId(payload.SessionId())
+		return sr, nil
+	}
+
+	// Remaining pages of a long response
+	sr, ok, err = app.nextOverflowPage(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		sr.setSessionId(payload.SessionId())
+		return sr, nil
+	}
+
+	sr, err = app.processMenu(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inputs dialed with the service code of a new session
+	sr, err = app.fastForward(ctx, payload, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.setSessionId(payload.SessionId())
+
+	err = app.saveResumeState(ctx, payload, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.releaseSession(ctx, payload, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.limitResponse(ctx, payload, sr)
+}
+
+// processMenu resolves the menu for the session and renders it
+func (app *UssdApp) processMenu(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	menu, isNew, err := app.GetSessionMenu(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if isNew {
+		app.metrics.sessionStarted()
+
+		// Another session of the msisdn in progress
+		sr, ok, err := app.guardSession(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return sr, nil
+		}
+
+		app.startSession(ctx, payload)
+
+		// Language selected in earlier sessions
+		err = app.loadPreferences(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		// Expired session the user may continue
+		sr, ok, err = app.offerResume(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return sr, nil
+		}
+
+		if m, params := app.shortCutMenu(payload); m != nil {
+			err = app.saveShortCutParams(ctx, payload, params)
+			if err != nil {
+				return nil, err
+			}
+			payload.(*ussdPayload).data.IsShortCut = true
+			menu = m
+		} else {
+			skipDialedInputs(payload)
+		}
+	} else {
+		// Answer to the resume prompt
+		sr, ok, err := app.resume(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return sr, nil
+		}
+
+		// Back and home navigation
+		sr, ok, err = app.navigate(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return sr, nil
+		}
+
+		// Page inputs for paginated menus
+		sr, ok, err = app.turnPage(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return sr, nil
+		}
+
+		// Transitions out of the menu shown to the user on the input
+		next, err := app.nextState(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			menu = next
+		}
+	}
+
+	if app.opt.SessionHook != nil {
+		menu, err = app.opt.SessionHook(ctx, payload, menu, isNew)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if menu == nil {
+		return nil, ErrMenuNotExist
+	}
+
+	// Menus turned off by feature flags
+	menu, err = app.enabledMenu(ctx, payload, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	// Menus hidden from the segments of the msisdn
+	visible, err := app.menuVisible(ctx, payload, menu)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		if !isNew {
+			return app.PreviousMenuWithError(ctx, payload, menu, app.hiddenMenuMessage(ctx, payload))
+		}
+		menu, err = app.homeMenuFor(ctx, payload, menu)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Version of the menu rolled out to the msisdn
+	menu, version := app.servedVersion(payload, menu)
+
+	if !isNew && isSensitive(menu) {
+		err = app.markSensitiveInput(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.opt.Debug || isTraced(payload) {
+		input := payload.UssdCurrentParam()
+		if isSensitive(menu) || (app.opt.PIIPolicy != nil && app.opt.PIIPolicy.RedactInputs) {
+			input = defaultRedactionText
+		}
+		app.debug(payload, "menu resolved", "menu", menu.MenuName(), "new_session", isNew, "input", input)
+	}
+
+	// Validate input received by the menu
+	if !isNew {
+		err = validateInput(menu, app.GetLanguage(ctx, payload), payload.UssdCurrentParam())
+		if err != nil {
+			app.metrics.validationFailed(menu.MenuName())
+			msg, ok := userMessage(err, app.GetLanguage(ctx, payload), app.opt.DefaultLanguage)
+			if !ok {
+				msg = err.Error()
+			}
+			return app.PreviousMenuWithError(ctx, payload, menu, msg)
+		}
+	}
+
+	// Protected menus of sessions that are not logged in
+	protected := menu
+	menu, err = app.authorizeMenu(ctx, payload, menu)
+	if err != nil {
+		return nil, err
+	}
+	if menu != protected {
+		version = ""
+	}
+
+	sr, err := app.renderMenu(ctx, payload, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sr.Failed() {
+		err = app.UpdateNextMenu(ctx, payload, menu)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sr.MenuName() == "" {
+		sr.setMenu(menu.MenuName())
+	}
+
+	if version != "" {
+		sr.setMenuVersion(version)
+	}
+
+	return sr, nil
+}
+
+// ServeHTTP implements http.Handler. It reads the request using the app gateway adapter, runs the menu lifecycle,
+// writes the response and saves the session log.
+func (app *UssdApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	app.serveGateway(app.opt.Gateway, w, r)
+}
+
+// HTTPHandler returns an http handler that runs the full menu lifecycle for incoming ussd requests
+func (app *UssdApp) HTTPHandler() http.Handler {
+	return app
+}
+
+// GatewayHandler returns an http handler that runs the full menu lifecycle for requests from the given gateway.
+//
+// Use it to serve the same menus to several aggregators from one app.
+func (app *UssdApp) GatewayHandler(gateway GatewayAdapter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.serveGateway(gateway, w, r)
+	})
+}
+
+func (app *UssdApp) serveGateway(gateway GatewayAdapter, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	payload, err := gateway.ParseRequest(r)
+	if errors.Is(err, ErrSkipRequest) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		app.opt.Logger.Error("failed to read ussd request", "error", err)
+		http.Error(w, "bad ussd request", http.StatusBadRequest)
+		return
+	}
+
+	app.servePayload(ctx, gateway, w, payload)
+}
+
+// servePayload runs the menus for a parsed request, writing the response with the gateway and saving the log
+func (app *UssdApp) servePayload(ctx context.Context, gateway GatewayAdapter, w http.ResponseWriter, payload UssdPayload) {
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.receivedAt = time.Now()
+	}
+
+	sr, err := app.ProcessPayload(ctx, payload)
+	if err != nil {
+		app.opt.Logger.Error("ussd request failed", "session_id", payload.SessionId(), "msisdn", payload.Msisdn(), "error", err)
+		sr = app.errorResponse(ctx, payload, err)
+	}
+
+	if !isDuplicate(payload) {
+		app.metrics.sessionCompleted(sr, err)
+	}
+
+	sw := &statusWriter{ResponseWriter: w}
+
+	var werr error
+	if pw, ok := gateway.(PayloadWriter); ok {
+		werr = pw.WritePayloadResponse(ctx, sw, payload, sr)
+	} else {
+		werr = gateway.WriteResponse(sw, sr)
+	}
+	if werr != nil {
+		app.opt.Logger.Error("failed to write ussd response", "session_id", payload.SessionId(), "error", werr)
+	}
+
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.httpStatus = sw.status()
+	}
+
+	app.EnqueuePendingJobs(ctx, payload)
+
+	app.sessionResponded(ctx, payload, sr)
+
+	if err != nil {
+		SetSessionFailed(sr, err.Error())
+	}
+
+	app.SaveLog(ctx, payload, sr)
+}
+
+// statusWriter records the status of the response written by a gateway
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(bs)
+}
+
+// status returns the status written, which is 200 when the gateway wrote nothing
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// menuTimedOut reports whether a menu failed because it overran Options.MenuTimeout rather than the request
+// being cancelled. Errors of backend calls are often not wrapped, so the deadline of the menu is checked instead
+func (app *UssdApp) menuTimedOut(ctx, menuCtx context.Context) bool {
+	return app.opt.MenuTimeout > 0 && errors.Is(menuCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// menuTimeoutResponse ends the session with the menu timeout message, leaving the session on the menu
+func (app *UssdApp) menuTimeoutResponse(payload UssdPayload, menu Menu) SessionResponse {
+	app.opt.Logger.Warn("menu overran the menu timeout",
+		"session_id", payload.SessionId(), "menu", menu.MenuName(), "timeout", app.opt.MenuTimeout)
+
+	SkipSavingPayload(payload)
+
+	return NewSessionResponse(&SessionData{
+		Response:      firstVal(app.opt.MenuTimeoutMessage, defaultMenuTimeoutMessage),
+		StatusMessage: "menu timed out",
+		MenuName:      menu.MenuName(),
+		SessionId:     payload.SessionId(),
+		Terminal:      true,
+	})
+}
+
+// errorResponse ends the session after a failed request, showing the user message of err if it is an Error.
+//
+// Other errors render the error menu when set, or the error message.
+func (app *UssdApp) errorResponse(ctx context.Context, payload UssdPayload, err error) SessionResponse {
+	text := app.opt.ErrorMessage
+	if msg, ok := userMessage(err, app.GetLanguage(ctx, payload), app.opt.DefaultLanguage); ok {
+		text = endPrefix + " " + msg
+	} else if sr, ok := app.renderErrorMenu(ctx, payload); ok {
+		return sr
+	}
+
+	return NewSessionResponse(&SessionData{
+		Response:  text,
+		SessionId: payload.SessionId(),
+	})
+}
+
+// renderErrorMenu renders the error menu set in options as an END response
+func (app *UssdApp) renderErrorMenu(ctx context.Context, payload UssdPayload) (SessionResponse, bool) {
+	if app.opt.ErrorMenu == "" {
+		return nil, false
+	}
+
+	menu, ok := app.getMenu(app.opt.ErrorMenu)
+	if !ok {
+		app.opt.Logger.Error("error menu is not registered", "menu", app.opt.ErrorMenu)
+		return nil, false
+	}
+
+	sr, err := menu.GenerateResponse(ctx, payload)
+	if err != nil {
+		app.opt.Logger.Error("failed to render error menu", "menu", app.opt.ErrorMenu, "error", err)
+		return nil, false
+	}
+
+	sr.setMenu(menu.MenuName())
+	sr.setSessionId(payload.SessionId())
+
+	return sr.End(), true
+}
+
+// renderMenu generates the menu response, running the render hooks set in options around it
+func (app *UssdApp) renderMenu(ctx context.Context, payload UssdPayload, menu Menu) (SessionResponse, error) {
+	ctx = withMenu(ctx, menu)
+
+	if app.opt.BeforeRender != nil {
+		sr, err := app.opt.BeforeRender(ctx, payload, menu)
+		if err != nil {
+			return nil, err
+		}
+		if sr != nil {
+			return sr, nil
+		}
+	}
+
+	// Dependencies of the menu that are failing
+	breakers, refused := app.allowDependencies(app.menuBreakers(menu))
+	if refused != "" {
+		return app.serviceBusyResponse(payload, menu, refused), nil
+	}
+
+	start := time.Now()
+
+	spanCtx, span := app.tracer.Start(ctx, "ussdapp.GenerateResponse", trace.WithAttributes(
+		attribute.String("ussd.menu", menu.MenuName()),
+	))
+	if app.opt.MenuTimeout > 0 {
+		var cancel context.CancelFunc
+		spanCtx, cancel = context.WithTimeout(spanCtx, app.opt.MenuTimeout)
+		defer cancel()
+	}
+	sr, err := app.generateResponse(spanCtx, payload, menu)
+	endSpan(span, err)
+	timedOut := err != nil && app.menuTimedOut(ctx, spanCtx)
+	for _, b := range breakers {
+		if timedOut {
+			b.Record(context.DeadlineExceeded)
+		} else {
+			b.Record(err)
+		}
+	}
+	if err != nil {
+		if timedOut {
+			return app.menuTimeoutResponse(payload, menu), nil
+		}
+		app.reportRequestError(ctx, payload, ErrorSourceMenu, menu.MenuName(), err)
+		return nil, err
+	}
+
+	app.metrics.menuRendered(menu.MenuName(), time.Since(start))
+
+	// Options leading to menus hidden from the user
+	err = app.hideOptions(ctx, payload, menu, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.opt.AfterRender != nil {
+		res, err := app.opt.AfterRender(ctx, payload, menu, sr)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			sr = res
+		}
+	}
+
+	return sr, nil
+}
@@ -0,0 +1,123 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	payloadHistoryKey     = "payload_history"
+	maxPayloadHistorySize = 10
+)
+
+// ErrNoPayloadHistory is returned when the session has fewer accepted payloads than requested
+var ErrNoPayloadHistory = errors.New("no payload history")
+
+// getPayloadHistory reads the payloads accepted in the session, oldest first
+func (app *UssdApp) getPayloadHistory(ctx context.Context, payload UssdPayload) ([]json.RawMessage, error) {
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), payloadHistoryKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return []json.RawMessage{}, nil
+	default:
+		return nil, fmt.Errorf("failed to get payload history: %v", err)
+	}
+
+	history := make([]json.RawMessage, 0)
+	if val == "" {
+		return history, nil
+	}
+
+	err = json.Unmarshal([]byte(val), &history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload history: %v", err)
+	}
+
+	return history, nil
+}
+
+// pushPayload adds a payload accepted by its menu to the session payload history, keeping the latest ones
+func (app *UssdApp) pushPayload(ctx context.Context, payload UssdPayload) error {
+	history, err := app.getPayloadHistory(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	bs, err := payload.JSON()
+	if err != nil {
+		return err
+	}
+
+	history = append(history, bs)
+	if len(history) > maxPayloadHistorySize {
+		history = history[len(history)-maxPayloadHistorySize:]
+	}
+
+	bs, err = json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload history: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), payloadHistoryKey, bs)
+	if err != nil {
+		return fmt.Errorf("failed to save payload history: %v", err)
+	}
+
+	return nil
+}
+
+// dropPayloads removes the latest count payloads from the session payload history
+func (app *UssdApp) dropPayloads(ctx context.Context, payload UssdPayload, count int) error {
+	history, err := app.getPayloadHistory(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	if count > len(history) {
+		count = len(history)
+	}
+
+	bs, err := json.Marshal(history[:len(history)-count])
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload history: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), payloadHistoryKey, bs)
+	if err != nil {
+		return fmt.Errorf("failed to save payload history: %v", err)
+	}
+
+	return nil
+}
+
+// PayloadAt returns the payload accepted stepsBack requests before payload in the session.
+//
+// A steps back of 0 returns payload, 1 returns the payload of the menu before it and so on. Payloads that failed
+// validation are not counted. Only the last 10 payloads of a session are kept, older ones return ErrNoPayloadHistory.
+//
+// Use it to read inputs entered a few menus earlier, e.g the amount on a confirmation menu:
+//
+//	amountPayload, err := app.PayloadAt(ctx, payload, 2)
+func (app *UssdApp) PayloadAt(ctx context.Context, payload UssdPayload, stepsBack int) (UssdPayload, error) {
+	switch {
+	case stepsBack < 0:
+		return nil, fmt.Errorf("invalid steps back %d", stepsBack)
+	case stepsBack == 0:
+		return payload, nil
+	}
+
+	history, err := app.getPayloadHistory(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if stepsBack > len(history) {
+		return nil, fmt.Errorf("%w: %d steps back", ErrNoPayloadHistory, stepsBack)
+	}
+
+	// Payloads of a resumed session belong to the expired session
+	return rebindPayload(history[len(history)-stepsBack], payload)
+}
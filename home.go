@@ -0,0 +1,66 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// homeMenuKey is the session field with the home menu returned by Options.HomeMenuFn
+const homeMenuKey = "home_menu"
+
+// HomeMenuFn returns the name of the menu a session starts on, e.g to greet registered, unregistered and blocked
+// users with different menus
+type HomeMenuFn func(ctx context.Context, payload UssdPayload) (string, error)
+
+// resolveHomeMenu sets the home menu of a new session with the home menu function, saving it for later requests
+func (app *UssdApp) resolveHomeMenu(ctx context.Context, payload UssdPayload) error {
+	if app.opt.HomeMenuFn == nil {
+		return nil
+	}
+
+	name, err := app.opt.HomeMenuFn(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to get home menu: %w", err)
+	}
+	if name == "" {
+		return nil
+	}
+
+	if _, ok := app.getMenu(name); !ok {
+		return fmt.Errorf("%w: home menu %s", ErrMenuNotExist, name)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), homeMenuKey, name)
+	if err != nil {
+		return fmt.Errorf("failed to save home menu: %v", err)
+	}
+
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.homeMenu = name
+	}
+
+	return nil
+}
+
+// loadHomeMenu sets the home menu of a session in progress to the one returned by the home menu function
+func (app *UssdApp) loadHomeMenu(ctx context.Context, payload UssdPayload) error {
+	if app.opt.HomeMenuFn == nil {
+		return nil
+	}
+
+	name, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), homeMenuKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil
+	default:
+		return fmt.Errorf("failed to get home menu: %v", err)
+	}
+
+	if p, ok := payload.(*ussdPayload); ok && name != "" {
+		p.data.homeMenu = name
+	}
+
+	return nil
+}
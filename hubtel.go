@@ -0,0 +1,125 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Hubtel request and response types
+const (
+	HubtelTypeInitiation = "Initiation"
+	HubtelTypeResponse   = "Response"
+	HubtelTypeRelease    = "Release"
+	HubtelTypeTimeout    = "Timeout"
+)
+
+// HubtelRequest is the request posted by Hubtel for each step of a session
+type HubtelRequest struct {
+	Mobile      string `json:"Mobile"`
+	SessionID   string `json:"SessionId"`
+	ServiceCode string `json:"ServiceCode"`
+	Type        string `json:"Type"`
+	Message     string `json:"Message"`
+	Operator    string `json:"Operator"`
+	Sequence    int    `json:"Sequence"`
+	ClientState string `json:"ClientState"`
+}
+
+// HubtelResponse is the response to a Hubtel request
+type HubtelResponse struct {
+	Type        string `json:"Type"`
+	Message     string `json:"Message"`
+	ClientState string `json:"ClientState"`
+}
+
+// NewHubtelAdapter returns a gateway adapter for Hubtel's USSD JSON protocol.
+//
+// Hubtel sends the code dialed in the Initiation request and only the latest input in later requests. The inputs of
+// the session are sent back to Hubtel in ClientState, which Hubtel returns in the next request, so that menus see all
+// inputs joined by * as with other gateways. Responses have Type Response to continue the session or Release to end it.
+func NewHubtelAdapter() GatewayAdapter {
+	return &hubtelAdapter{}
+}
+
+type hubtelAdapter struct{}
+
+func (*hubtelAdapter) ParseRequest(r *http.Request) (UssdPayload, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	req := &HubtelRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode hubtel request: %v", err)
+	}
+
+	var params []string
+
+	switch req.Type {
+	case HubtelTypeInitiation:
+		_, params = splitShortCode(req.Message)
+	case HubtelTypeResponse:
+		if req.ClientState != "" {
+			params = strings.Split(req.ClientState, "*")
+		}
+		params = append(params, strings.TrimSpace(req.Message))
+	default:
+		return nil, fmt.Errorf("unsupported hubtel request type %q", req.Type)
+	}
+
+	currentParam := ""
+	if len(params) > 0 {
+		currentParam = params[len(params)-1]
+	}
+
+	payload := &ussdPayload{
+		data: &ussdPayloadInternal{
+			SessionID:        req.SessionID,
+			ServiceCode:      req.ServiceCode,
+			Msisdn:           strings.TrimPrefix(req.Mobile, "+"),
+			UssdParams:       strings.Join(params, "*"),
+			UssdCurrentParam: currentParam,
+		},
+	}
+
+	switch {
+	case payload.SessionId() == "":
+		return nil, errors.New("missing SessionId")
+	case payload.Msisdn() == "":
+		return nil, errors.New("missing Mobile")
+	}
+
+	return payload, nil
+}
+
+func (a *hubtelAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	return a.WritePayloadResponse(context.Background(), w, nil, sr)
+}
+
+// WritePayloadResponse keeps the inputs of the session in ClientState for the next request
+func (*hubtelAdapter) WritePayloadResponse(_ context.Context, w http.ResponseWriter, payload UssdPayload, sr SessionResponse) error {
+	res := &HubtelResponse{
+		Type:    HubtelTypeResponse,
+		Message: ResponseMessage(sr),
+	}
+	if sr.Terminal() {
+		res.Type = HubtelTypeRelease
+	}
+	if payload != nil {
+		res.ClientState = payload.UssdParams()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(res)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Content is the text of a menu in each language, keyed by language
+type Content map[string]string
+
+// Text returns the text in lang. When missing, the text in the first fallback language that has it is returned,
+// then the text of the first language in alphabetical order.
+func (c Content) Text(lang string, fallbacks ...string) string {
+	if text, ok := c[lang]; ok {
+		return text
+	}
+
+	for _, fallback := range fallbacks {
+		if text, ok := c[fallback]; ok {
+			return text
+		}
+	}
+
+	langs := make([]string, 0, len(c))
+	for k := range c {
+		langs = append(langs, k)
+	}
+	sort.Strings(langs)
+
+	if len(langs) > 0 {
+		return c[langs[0]]
+	}
+
+	return ""
+}
+
+func (c Content) clone() Content {
+	content := make(Content, len(c))
+	for k, v := range c {
+		content[k] = v
+	}
+	return content
+}
+
+// Translations are the contents of menus, keyed by menu name
+type Translations map[string]Content
+
+// LoadTranslations reads translations from a JSON or TOML file. The format is detected from the file extension.
+//
+// The file maps menu names to the menu text in each language, e.g {"home": {"en": "CON Welcome", "sw": "CON Karibu"}}.
+func LoadTranslations(fileName string) (Translations, error) {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translations file: %v", err)
+	}
+
+	translations := make(Translations)
+
+	switch ext := strings.ToLower(filepath.Ext(fileName)); ext {
+	case ".json":
+		err = json.Unmarshal(bs, &translations)
+	case ".toml":
+		err = toml.Unmarshal(bs, &translations)
+	default:
+		return nil, fmt.Errorf("unsupported translations file format %s", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse translations file %s: %v", fileName, err)
+	}
+
+	return translations, nil
+}
+
+// AddTranslations sets the content of menus from the translations, replacing text in the same language.
+//
+// Translations of menus not registered yet are applied once the menus are added.
+func (app *UssdApp) AddTranslations(translations Translations) {
+	for menuName, content := range translations {
+		existing, ok := app.translations[menuName]
+		if !ok {
+			existing = make(Content, len(content))
+			app.translations[menuName] = existing
+		}
+		for lang, text := range content {
+			existing[lang] = text
+		}
+
+		if m, ok := app.registry().menus[menuName].(translatable); ok {
+			m.translate(content)
+		}
+	}
+}
+
+// AddTranslationsFromFile loads translations from a JSON or TOML file and adds them to the menus
+func (app *UssdApp) AddTranslationsFromFile(fileName string) error {
+	translations, err := LoadTranslations(fileName)
+	if err != nil {
+		return err
+	}
+
+	app.AddTranslations(translations)
+
+	return nil
+}
+
+// translatable is implemented by menus whose content can be localized by the app
+type translatable interface {
+	translate(content Content)
+	setDefaultLanguage(lang string)
+}
+
+// languageAware is implemented by menus that look up the session language when rendering
+type languageAware interface {
+	setLanguageFn(fn func(context.Context, UssdPayload) string)
+}
+
+// Plural returns one when n is 1 and other otherwise. Verbs for n in the forms are formatted with n,
+// e.g Plural(n, "%d item", "%d items").
+func Plural(n int, one, other string) string {
+	form := other
+	if n == 1 {
+		form = one
+	}
+	if strings.Contains(form, "%") {
+		return fmt.Sprintf(form, n)
+	}
+	return form
+}
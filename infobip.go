@@ -0,0 +1,104 @@
+package ussdapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// InfobipRequest is the body of requests sent by Infobip when a session starts and for each input
+type InfobipRequest struct {
+	Msisdn    string `json:"msisdn"`
+	ShortCode string `json:"shortCode"`
+	Text      string `json:"text"`
+}
+
+// InfobipResponse is the response to an Infobip request
+type InfobipResponse struct {
+	ShouldClose      bool   `json:"shouldClose"`
+	UssdMenu         string `json:"ussdMenu"`
+	ResponseExitCode int    `json:"responseExitCode"`
+	ResponseMessage  string `json:"responseMessage"`
+}
+
+// NewInfobipAdapter returns a gateway adapter for Infobip's USSD API.
+//
+// Infobip posts to {url}/session/{sessionId}/start when a session starts and to {url}/session/{sessionId}/response
+// with each input, so serve the adapter under a path prefix. Only the latest input is sent, the inputs of the session
+// are kept in the session cache so that menus see all inputs joined by * as with other gateways.
+// Responses set shouldClose to end the session.
+func NewInfobipAdapter() GatewayAdapter {
+	return &infobipAdapter{}
+}
+
+type infobipAdapter struct{}
+
+func (*infobipAdapter) ParseRequest(r *http.Request) (UssdPayload, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	var (
+		action    = path.Base(r.URL.Path)
+		sessionID = path.Base(path.Dir(r.URL.Path))
+	)
+
+	req := &InfobipRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode infobip request: %v", err)
+	}
+
+	serviceCode, params := splitShortCode(req.ShortCode)
+
+	switch action {
+	case "start":
+	case "response":
+		params = []string{strings.TrimSpace(req.Text)}
+	default:
+		return nil, fmt.Errorf("unsupported infobip request %s", action)
+	}
+
+	currentParam := ""
+	if len(params) > 0 {
+		currentParam = params[len(params)-1]
+	}
+
+	payload := &ussdPayload{
+		data: &ussdPayloadInternal{
+			SessionID:        sessionID,
+			ServiceCode:      serviceCode,
+			Msisdn:           strings.TrimPrefix(req.Msisdn, "+"),
+			UssdParams:       strings.Join(params, "*"),
+			UssdCurrentParam: currentParam,
+			incremental:      true,
+		},
+	}
+
+	switch {
+	case sessionID == "" || sessionID == "." || sessionID == "/":
+		return nil, errors.New("missing session id in path")
+	case payload.Msisdn() == "":
+		return nil, errors.New("missing msisdn")
+	}
+
+	return payload, nil
+}
+
+func (*infobipAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(&InfobipResponse{
+		ShouldClose:      sr.Terminal(),
+		UssdMenu:         ResponseMessage(sr),
+		ResponseExitCode: http.StatusOK,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrNoJobEnqueuer is returned when enqueueing jobs on an app without Options.JobEnqueuer
+var ErrNoJobEnqueuer = errors.New("no job enqueuer")
+
+// Job is follow up work of a session, e.g sending an SMS receipt, done by workers outside the app
+type Job struct {
+	// Type tells workers how to process the job, e.g sms:receipt
+	Type string
+	// Payload is the json encoded data of the job
+	Payload []byte
+	// SessionID and Msisdn are of the session that enqueued the job
+	SessionID string
+	Msisdn    string
+}
+
+// JobEnqueuer sends jobs to a queue. See the jobs packages for asynq and SQS implementations
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, job *Job) error
+}
+
+// EnqueueJob adds a job to be enqueued once the response to the request has been written, so that the user does not
+// wait for the queue. Data is encoded as json.
+//
+// Jobs of a request that fails are dropped. Enqueue failures are logged.
+func (app *UssdApp) EnqueueJob(ctx context.Context, payload UssdPayload, jobType string, data interface{}) error {
+	if app.opt.JobEnqueuer == nil {
+		return ErrNoJobEnqueuer
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job %s: %v", jobType, err)
+	}
+
+	p, ok := payload.(*ussdPayload)
+	if !ok {
+		return fmt.Errorf("unsupported payload type %T", payload)
+	}
+
+	p.data.jobs = append(p.data.jobs, &Job{
+		Type:      jobType,
+		Payload:   bs,
+		SessionID: payload.SessionId(),
+		Msisdn:    payload.Msisdn(),
+	})
+
+	return nil
+}
+
+// EnqueuePendingJobs enqueues the jobs added with EnqueueJob in the background. The app calls it after writing the
+// response, servers that call ProcessPayload and write the response themselves should call it after writing.
+func (app *UssdApp) EnqueuePendingJobs(ctx context.Context, payload UssdPayload) {
+	p, ok := payload.(*ussdPayload)
+	if !ok || len(p.data.jobs) == 0 || app.opt.JobEnqueuer == nil {
+		return
+	}
+
+	jobs := p.data.jobs
+	p.data.jobs = nil
+
+	if atomic.LoadInt32(&app.closed) == 1 {
+		app.opt.Logger.Error("jobs not enqueued: app is closed", "session_id", payload.SessionId(), "count", len(jobs))
+		return
+	}
+
+	app.workers.Add(1)
+
+	// The request context is done once the response is sent
+	go func() {
+		defer app.workers.Done()
+
+		for _, job := range jobs {
+			err := app.opt.JobEnqueuer.Enqueue(context.Background(), job)
+			if err != nil {
+				app.opt.Logger.Error("failed to enqueue job", "session_id", job.SessionID, "job_type", job.Type, "error", err)
+				app.reportWorkerError("enqueue_job", fmt.Errorf("failed to enqueue %s job of session %s: %w", job.Type, job.SessionID, err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package asynqjobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/hibiken/asynq"
+)
+
+// NewAsynqEnqueuer creates a job enqueuer that enqueues jobs as asynq tasks of the job type with the options, e.g
+// the queue or max retries. The task payload is the job payload.
+func NewAsynqEnqueuer(client *asynq.Client, opts ...asynq.Option) ussdapp.JobEnqueuer {
+	return &asynqEnqueuer{client: client, opts: opts}
+}
+
+type asynqEnqueuer struct {
+	client *asynq.Client
+	opts   []asynq.Option
+}
+
+func (ae *asynqEnqueuer) Enqueue(ctx context.Context, job *ussdapp.Job) error {
+	_, err := ae.client.EnqueueContext(ctx, asynq.NewTask(job.Type, job.Payload), ae.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %v", err)
+	}
+	return nil
+}
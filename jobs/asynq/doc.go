@@ -0,0 +1,4 @@
+/*
+Package asynq implements a USSD job enqueuer that sends jobs to asynq, a redis backed task queue.
+*/
+package asynqjobs
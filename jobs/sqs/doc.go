@@ -0,0 +1,4 @@
+/*
+Package sqs implements a USSD job enqueuer that sends jobs to an Amazon SQS queue.
+*/
+package sqsjobs
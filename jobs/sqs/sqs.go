@@ -0,0 +1,70 @@
+package sqsjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/gidyon/ussdapp"
+)
+
+// SendMessageAPI is the part of the SQS client used by the enqueuer
+type SendMessageAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// NewSQSEnqueuer creates a job enqueuer that sends jobs as messages to the queue. The message body is the job
+// payload, with the job type, session id and msisdn as message attributes.
+//
+// Jobs sent to FIFO queues are grouped by session so jobs of a session are processed in order.
+func NewSQSEnqueuer(client SendMessageAPI, queueURL string) (ussdapp.JobEnqueuer, error) {
+	switch {
+	case client == nil:
+		return nil, errors.New("missing sqs client")
+	case queueURL == "":
+		return nil, errors.New("missing queue url")
+	}
+
+	return &sqsEnqueuer{
+		client:   client,
+		queueURL: queueURL,
+		fifo:     strings.HasSuffix(queueURL, ".fifo"),
+	}, nil
+}
+
+type sqsEnqueuer struct {
+	client   SendMessageAPI
+	queueURL string
+	fifo     bool
+}
+
+func (se *sqsEnqueuer) Enqueue(ctx context.Context, job *ussdapp.Job) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(se.queueURL),
+		MessageBody: aws.String(string(job.Payload)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"type":       stringAttribute(job.Type),
+			"session_id": stringAttribute(job.SessionID),
+			"msisdn":     stringAttribute(job.Msisdn),
+		},
+	}
+	if se.fifo {
+		// Deduplication uses the message body, which queues must have enabled
+		input.MessageGroupId = aws.String(job.SessionID)
+	}
+
+	_, err := se.client.SendMessage(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+
+	return nil
+}
+
+func stringAttribute(val string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(val)}
+}
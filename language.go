@@ -0,0 +1,105 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultChangeLanguageMenu  = "change_language"
+	defaultChangeLanguageTitle = "Select language"
+	changeLanguageSaveStep     = "save"
+)
+
+// Language is a language users can select
+type Language struct {
+	// Code is the language saved in the session, e.g en
+	Code string
+	// Name is shown to the user, e.g English
+	Name string
+}
+
+// ChangeLanguageOptions contains data for the menu that lets users select their language
+type ChangeLanguageOptions struct {
+	// MenuName is the name of the selection menu. Defaults to change_language
+	MenuName string
+	ShortCut string
+	// Title is shown above the languages, per language. Defaults to Select language
+	Title     Content
+	Languages []*Language
+	// NextMenu is rendered once the language is saved. Defaults to the home menu
+	NextMenu string
+}
+
+// AddChangeLanguageMenu registers a menu that lists the languages numbered in order. The selected language is saved
+// with SaveLanguage and the next menu is rendered in it.
+//
+// The selection is received by a menu named <menu>:save.
+func (app *UssdApp) AddChangeLanguageMenu(opt *ChangeLanguageOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing change language options")
+	case len(opt.Languages) == 0:
+		return errors.New("missing languages")
+	}
+
+	var (
+		menuName = firstVal(opt.MenuName, defaultChangeLanguageMenu)
+		saveMenu = fmt.Sprintf("%s:%s", menuName, changeLanguageSaveStep)
+		nextMenu = firstVal(opt.NextMenu, app.homeMenu)
+		inputs   = make([]string, 0, len(opt.Languages))
+		items    = make([]string, 0, len(opt.Languages))
+	)
+
+	for i, lang := range opt.Languages {
+		if lang == nil || lang.Code == "" {
+			return fmt.Errorf("menu %s has a language without a code", menuName)
+		}
+		input := strconv.Itoa(i + 1)
+		inputs = append(inputs, input)
+		items = append(items, fmt.Sprintf("%s. %s", input, firstVal(lang.Name, lang.Code)))
+	}
+
+	list := strings.Join(items, "\n")
+
+	selectMenu := NewMenu(&MenuOptions{
+		MenuName: menuName,
+		NextMenu: saveMenu,
+		ShortCut: opt.ShortCut,
+		GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+			title := firstVal(opt.Title.Text(app.GetLanguage(ctx, payload), app.opt.DefaultLanguage), defaultChangeLanguageTitle)
+			return &sessionResponse{
+				response: fmt.Sprintf("CON %s\n%s", title, list),
+				menuName: m.MenuName(),
+			}, nil
+		},
+	})
+
+	save := NewMenu(&MenuOptions{
+		MenuName:   saveMenu,
+		NextMenu:   nextMenu,
+		Validators: []Validator{OneOf(inputs...)},
+		GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+			i, _ := strconv.Atoi(payload.UssdCurrentParam())
+
+			err := app.SaveLanguage(ctx, payload, opt.Languages[i-1].Code)
+			if err != nil {
+				return nil, err
+			}
+
+			return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+		},
+	})
+
+	for _, m := range []Menu{selectMenu, save} {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
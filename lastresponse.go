@@ -0,0 +1,88 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// lastResponse is the last response sent in a session, with the request it answered
+type lastResponse struct {
+	Response         string `json:"response"`
+	Failed           bool   `json:"failed,omitempty"`
+	StatusMessage    string `json:"status_message,omitempty"`
+	MenuName         string `json:"menu_name,omitempty"`
+	Terminal         bool   `json:"terminal,omitempty"`
+	ValidationFailed bool   `json:"validation_failed,omitempty"`
+	// Request is a hash of the request as sent by the gateway, see requestHash
+	Request string `json:"request,omitempty"`
+	SentAt  int64  `json:"sent_at,omitempty"`
+}
+
+func (r *lastResponse) sessionResponse(sessionID string) SessionResponse {
+	return NewSessionResponse(&SessionData{
+		Response:      r.Response,
+		Failed:        r.Failed,
+		StatusMessage: r.StatusMessage,
+		MenuName:      r.MenuName,
+		SessionId:     sessionID,
+		Terminal:      r.Terminal,
+	})
+}
+
+func (app *UssdApp) lastResponseKey(payload UssdPayload) string {
+	return fmt.Sprintf("%s:last_response:%s:%s", app.opt.AppName, payload.SessionId(), payload.Msisdn())
+}
+
+// saveLastResponse keeps the response sent for a request for as long as the session lasts
+func (app *UssdApp) saveLastResponse(ctx context.Context, payload UssdPayload, request string, sr SessionResponse) error {
+	bs, err := json.Marshal(&lastResponse{
+		Response:         sr.Response(),
+		Failed:           sr.Failed(),
+		StatusMessage:    sr.StatusMessage(),
+		MenuName:         sr.MenuName(),
+		Terminal:         sr.Terminal(),
+		ValidationFailed: payload.ValidationFailed(),
+		Request:          request,
+		SentAt:           time.Now().UnixNano(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal last response: %v", err)
+	}
+
+	err = app.opt.Cache.Set(ctx, app.lastResponseKey(payload), string(bs), app.sessionDuration(payload))
+	if err != nil {
+		return fmt.Errorf("failed to save last response: %v", err)
+	}
+
+	return nil
+}
+
+// getLastResponse reads the last response sent in the session of the payload
+func (app *UssdApp) getLastResponse(ctx context.Context, payload UssdPayload) (*lastResponse, error) {
+	val, err := app.opt.Cache.Get(ctx, app.lastResponseKey(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	last := &lastResponse{}
+	err = json.Unmarshal([]byte(val), last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last response: %v", err)
+	}
+
+	return last, nil
+}
+
+// LastResponse returns the last response sent to the user in the session of the payload, to send the same screen
+// again e.g for a repeat command or after recovering from an error.
+//
+// Returns ErrKeyNotFound if no response has been sent in the session.
+func (app *UssdApp) LastResponse(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	last, err := app.getLastResponse(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return last.sessionResponse(payload.SessionId()), nil
+}
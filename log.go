@@ -1,13 +1,104 @@
 package ussdapp
 
-// Logger represent common interface for logging function
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// Logger writes leveled logs with key value pairs, e.g
+//
+//	logger.Error("failed to enqueue job", "session_id", sessionID, "error", err)
+//
+// The key values are compatible with logr and slog, so those loggers are adapted with a few lines. See NewGrpcLogger
+// and NewStdLogger for the loggers adapted by the package.
 type Logger interface {
-	Errorf(format string, args ...interface{})
-	Fatalf(format string, args ...interface{})
-	Fatal(args ...interface{})
-	Infof(format string, args ...interface{})
-	Info(args ...interface{})
-	Warnf(format string, args ...interface{})
-	Debugf(format string, args ...interface{})
-	Debug(args ...interface{})
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NewGrpcLogger adapts a grpc logger, writing key values after the message as key=value. Debug logs are written
+// when the verbosity of the logger is at least 2
+func NewGrpcLogger(logger grpclog.LoggerV2) Logger {
+	return &grpcLogger{logger: logger}
+}
+
+type grpcLogger struct {
+	logger grpclog.LoggerV2
+}
+
+func (l *grpcLogger) Debug(msg string, keyvals ...interface{}) {
+	if l.logger.V(2) {
+		l.logger.Info(formatLog(msg, keyvals))
+	}
+}
+
+func (l *grpcLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.Info(formatLog(msg, keyvals))
+}
+
+func (l *grpcLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logger.Warning(formatLog(msg, keyvals))
+}
+
+func (l *grpcLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.Error(formatLog(msg, keyvals))
+}
+
+// NewStdLogger adapts a logger of the standard library, writing the level before the message and key values after
+// it as key=value
+func NewStdLogger(logger *log.Logger) Logger {
+	return &stdLogger{logger: logger}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+}
+
+func (l *stdLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logger.Print("DEBUG: " + formatLog(msg, keyvals))
+}
+
+func (l *stdLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.Print("INFO: " + formatLog(msg, keyvals))
+}
+
+func (l *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logger.Print("WARNING: " + formatLog(msg, keyvals))
+}
+
+func (l *stdLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.Print("ERROR: " + formatLog(msg, keyvals))
+}
+
+// formatLog writes the key values after the message as key=value, quoting values with spaces
+func formatLog(msg string, keyvals []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key, val := fmt.Sprint(keyvals[i]), interface{}("")
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		} else {
+			key, val = "!BADKEY", keyvals[i]
+		}
+
+		s := fmt.Sprint(val)
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			s = strconv.Quote(s)
+		}
+
+		b.WriteString(" ")
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(s)
+	}
+
+	return b.String()
 }
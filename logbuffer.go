@@ -0,0 +1,98 @@
+package ussdapp
+
+import (
+	"context"
+	"time"
+)
+
+// LogOverflowPolicy decides what happens to session logs saved while the buffer of logs waiting to be written is full
+type LogOverflowPolicy int
+
+const (
+	// LogOverflowBlock waits for room in the buffer until the request is done, which slows requests down while sinks
+	// are slow
+	LogOverflowBlock LogOverflowPolicy = iota
+	// LogOverflowDropOldest drops the oldest log in the buffer to make room for the new one
+	LogOverflowDropOldest
+	// LogOverflowDropNewest drops the new log
+	LogOverflowDropNewest
+)
+
+// LogBuffer sets how session logs are buffered before they are written to the sinks in batches
+type LogBuffer struct {
+	// Size is the most logs waiting to be written. Defaults to 1000
+	Size int
+	// BatchSize is the number of logs that are written once they are buffered. Defaults to 1000
+	BatchSize int
+	// FlushInterval is how long logs wait before they are written when a batch is not full. Defaults to 5 seconds
+	FlushInterval time.Duration
+	// OverflowPolicy decides what happens to logs saved while the buffer is full. Dropped logs are counted by the
+	// logs_dropped_total metric. Defaults to LogOverflowBlock
+	OverflowPolicy LogOverflowPolicy
+}
+
+func (b *LogBuffer) size() int {
+	if b == nil || b.Size <= 0 {
+		return bulkInsertSize
+	}
+	return b.Size
+}
+
+func (b *LogBuffer) batchSize() int {
+	if b == nil || b.BatchSize <= 0 {
+		return bulkInsertSize
+	}
+	return b.BatchSize
+}
+
+func (b *LogBuffer) flushInterval() time.Duration {
+	if b == nil || b.FlushInterval <= 0 {
+		return tickerInterval
+	}
+	return b.FlushInterval
+}
+
+func (b *LogBuffer) overflowPolicy() LogOverflowPolicy {
+	if b == nil {
+		return LogOverflowBlock
+	}
+	return b.OverflowPolicy
+}
+
+// queueLog sends the log to the workers saving logs, following the overflow policy when the buffer is full
+func (app *UssdApp) queueLog(ctx context.Context, log *SessionRequest) {
+	switch app.opt.LogBuffer.overflowPolicy() {
+	case LogOverflowDropNewest:
+		select {
+		case app.logsChan <- log:
+		default:
+			app.metrics.logDropped()
+			app.opt.Logger.Debug("session logs buffer is full, dropped the log", "session_id", log.SessionID)
+		}
+
+	case LogOverflowDropOldest:
+		// The worker receives from the buffer concurrently, so room is made until the send succeeds
+		for {
+			select {
+			case app.logsChan <- log:
+				return
+			default:
+			}
+
+			select {
+			case old := <-app.logsChan:
+				app.metrics.logDropped()
+				app.opt.Logger.Debug("session logs buffer is full, dropped the oldest log", "session_id", old.SessionID)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case <-ctx.Done():
+			app.metrics.logDropped()
+		case <-app.stop:
+		case app.logsChan <- log:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ussdapp
+
+import (
+	"context"
+	"time"
+)
+
+const defaultSyncLogTimeout = 2 * time.Second
+
+// LogMode decides how session logs are written to the sinks
+type LogMode int
+
+const (
+	// LogModeAsync buffers logs and writes them in batches in the background, see Options.LogBuffer
+	LogModeAsync LogMode = iota
+	// LogModeSync writes the log of each request to the sinks before the request returns, within
+	// Options.SyncLogTimeout. Logs that fail to be written are kept by the failed logs spool
+	LogModeSync
+	// LogModeDisabled saves no logs, as when Options.SaveLogs is off
+	LogModeDisabled
+)
+
+// savingLogs reports whether session logs are saved
+func (app *UssdApp) savingLogs() bool {
+	return app.opt.SaveLogs && app.opt.LogMode != LogModeDisabled
+}
+
+// persistLog writes the log in the request path or queues it for the workers, following Options.LogMode
+func (app *UssdApp) persistLog(ctx context.Context, log *SessionRequest) {
+	if app.opt.LogMode != LogModeSync {
+		app.queueLog(ctx, log)
+		return
+	}
+
+	timeout := app.opt.SyncLogTimeout
+	if timeout <= 0 {
+		timeout = defaultSyncLogTimeout
+	}
+
+	// The log is written even when the request was cancelled after the response
+	writeCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := app.writeLogs(writeCtx, []*SessionRequest{log})
+	if err != nil {
+		app.metrics.logFlushFailed()
+		app.opt.Logger.Error("failed to save session log", "session_id", log.SessionID, "error", err)
+	}
+}
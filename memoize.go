@@ -0,0 +1,110 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// memoized is a backend result kept in the session hash
+type memoized struct {
+	Value json.RawMessage `json:"v"`
+	// ExpiresAt is the unix time in milliseconds the result expires at. Zero keeps it for the session
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+func memoKey(key string) string {
+	return "memo:" + key
+}
+
+// Memoize reads the result of an expensive backend lookup, such as the accounts or balance of the user, into dest.
+//
+// The result of fetch is kept in the session for ttl, or for the rest of the session when ttl is zero, so menus later
+// in the session get it without calling the backend again. Results are kept as json, so dest is filled the same way
+// whether the result was fetched or kept. Failed lookups are not kept.
+func (app *UssdApp) Memoize(
+	ctx context.Context,
+	payload UssdPayload,
+	key string,
+	ttl time.Duration,
+	dest interface{},
+	fetch func(ctx context.Context) (interface{}, error),
+) error {
+	sess := app.Session(payload)
+
+	memo := &memoized{}
+	err := sess.GetJSON(ctx, memoKey(key), memo)
+	switch {
+	case err == nil && (memo.ExpiresAt == 0 || time.Now().UnixMilli() < memo.ExpiresAt):
+		uerr := json.Unmarshal(memo.Value, dest)
+		if uerr == nil {
+			return nil
+		}
+		// Results kept by an older version of the app may not fit dest, so they are fetched again
+		app.opt.Logger.Warn("failed to read memoized result", "key", key, "error", uerr)
+	case err == nil, errors.Is(err, ErrKeyNotFound):
+	default:
+		app.opt.Logger.Warn("failed to get memoized result", "key", key, "error", err)
+	}
+
+	val, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result of %s: %v", key, err)
+	}
+
+	memo = &memoized{Value: bs}
+	if ttl > 0 {
+		memo.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+	}
+
+	// The result is returned even if it cannot be kept
+	err = sess.SetJSON(ctx, memoKey(key), memo)
+	if err != nil {
+		app.opt.Logger.Warn("failed to memoize result", "key", key, "error", err)
+	}
+
+	err = json.Unmarshal(bs, dest)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal result of %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// Forget removes memoized results from the session, e.g the balance after a transfer
+func (app *UssdApp) Forget(ctx context.Context, payload UssdPayload, keys ...string) error {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, memoKey(key))
+	}
+	return app.Session(payload).Del(ctx, fields...)
+}
+
+// Memoize is the typed form of UssdApp.Memoize, e.g
+//
+//	accounts, err := ussdapp.Memoize(ctx, app, payload, "accounts", 5*time.Minute, func(ctx context.Context) ([]*Account, error) {
+//		return bank.Accounts(ctx, payload.Msisdn())
+//	})
+func Memoize[T any](
+	ctx context.Context,
+	app *UssdApp,
+	payload UssdPayload,
+	key string,
+	ttl time.Duration,
+	fetch func(ctx context.Context) (T, error),
+) (T, error) {
+	var v T
+
+	err := app.Memoize(ctx, payload, key, ttl, &v, func(ctx context.Context) (interface{}, error) {
+		return fetch(ctx)
+	})
+
+	return v, err
+}
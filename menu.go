@@ -28,15 +28,101 @@ type Menu interface {
 	ExecuteMenuArgs(key string, args ...interface{}) SessionResponse
 }
 
+// ContentMenu is implemented by menus with content per language, such as menus created with NewMenu. Menu logic
+// gets it from the menu it is passed, e.g
+//
+//	sr, err := menu.(ussdapp.ContentMenu).ExecuteMenuTemplate(lang, account)
+type ContentMenu interface {
+	Menu
+	// Text returns the menu content in the language formatted with args.
+	//
+	// Missing languages fall back to the default language of the app, then to the first language available.
+	Text(lang string, args ...interface{}) string
+	// ExecuteMenuTemplate applies data to the named placeholders in the menu content for the language,
+	// e.g "Welcome {{.Name}}", returning the resulting session response.
+	ExecuteMenuTemplate(key string, data interface{}) (SessionResponse, error)
+}
+
+// routingMenu is implemented by menus that route user inputs to other menus
+type routingMenu interface {
+	// Routes returns user inputs on this menu mapped to the menu rendered next, overriding the next menu
+	Routes() map[string]string
+}
+
+func menuRoutes(m Menu) map[string]string {
+	rm, ok := m.(routingMenu)
+	if !ok {
+		return nil
+	}
+	return rm.Routes()
+}
+
+// validatingMenu is implemented by menus that validate the input they receive
+type validatingMenu interface {
+	// ValidateInput runs the menu validators on input, returning an error with a message for the user in the given language
+	ValidateInput(lang, input string) error
+}
+
+func validateInput(m Menu, lang, input string) error {
+	vm, ok := m.(validatingMenu)
+	if !ok {
+		return nil
+	}
+	return vm.ValidateInput(lang, input)
+}
+
 type generateMenuFn func(context.Context, UssdPayload) (SessionResponse, error)
 
+// BeforeRenderFn is called before a menu is rendered.
+//
+// Returning a non nil response skips rendering the menu and sends the response instead, e.g to redirect users to a login menu.
+type BeforeRenderFn func(ctx context.Context, payload UssdPayload, menu Menu) (SessionResponse, error)
+
+// AfterRenderFn is called after a menu is rendered with the generated response. The returned response is sent to the user.
+type AfterRenderFn func(ctx context.Context, payload UssdPayload, menu Menu, sr SessionResponse) (SessionResponse, error)
+
+// ContentFn returns the text of a menu in the language, for menus whose text is fetched when rendered, e.g balances or offers
+type ContentFn func(ctx context.Context, payload UssdPayload, lang string) (string, error)
+
 // MenuOptions contains data for a USSD menu.
 type MenuOptions struct {
-	MenuName       string
-	PreviousMenu   string
-	NextMenu       string
-	ShortCut       string
-	MenuContent    map[string]string
+	MenuName     string
+	PreviousMenu string
+	NextMenu     string
+	// ShortCut opens the menu when a session starts with the ussd string, e.g 1*2. Inputs in braces like
+	// 1*2*{amount} match any input, which is read with UssdApp.ShortCutParam
+	ShortCut    string
+	MenuContent Content
+	// ContentFn fetches the menu text each time the menu is rendered, replacing MenuContent
+	ContentFn ContentFn
+	Routes    map[string]string
+	// Decisions picks the menu rendered after the menu from the input and session data, replacing the next menu.
+	// Routes take precedence over it
+	Decisions *DecisionTable
+	// Dependencies are the backend services the menu calls, e.g core-banking. The menu renders the service busy
+	// message while the circuit breaker of a dependency is open, see UssdApp.AddCircuitBreaker
+	Dependencies []string
+	// Validators are run on the user input before GenerateMenuFn. Invalid input re-renders the previous menu with the error message
+	Validators []Validator
+	// ValidationMessage is the message shown for invalid input per language, replacing the validator message
+	ValidationMessage Content
+	// SensitiveInput redacts the input received by the menu, such as a PIN, from session logs
+	SensitiveInput bool
+	// RequiresAuth renders the login menu instead of the menu for sessions that are not logged in, see
+	// Options.LoginMenu
+	RequiresAuth bool
+	// Segments are the roles or customer segments, e.g agent or premium, that see the menu, see
+	// Options.SegmentResolver. Menus without segments are seen by everyone
+	Segments []string
+	// Experiment serves variants of MenuContent to sessions when set
+	Experiment *Experiment
+	// Flag is the feature flag that turns the menu on, see Options.FlagProvider. Menus without a flag are always on
+	Flag string
+	// FallbackMenu is rendered instead of the menu when its flag is off. Defaults to the home menu
+	FallbackMenu string
+	BeforeRender BeforeRenderFn
+	AfterRender  AfterRenderFn
+	// GenerateMenuFn generates the menu response. When nil, the menu renders ContentFn or MenuContent in the session language
 	GenerateMenuFn func(context.Context, UssdPayload, Menu) (SessionResponse, error)
 }
 
@@ -51,34 +137,79 @@ func wrap(in fn1, m Menu) generateMenuFn {
 // NewMenu will create a new menu instance
 func NewMenu(opt *MenuOptions) Menu {
 	m := &menu{
-		menuName:    opt.MenuName,
-		nextMenu:    opt.NextMenu,
-		shortCut:    opt.ShortCut,
-		menuContent: make(map[string]string, len(opt.MenuContent)),
+		menuName:     opt.MenuName,
+		previousMenu: opt.PreviousMenu,
+		nextMenu:     opt.NextMenu,
+		shortCut:     opt.ShortCut,
+		menuContent:  opt.MenuContent.clone(),
 	}
-	data := make(map[string]string, len(opt.MenuContent))
-	for k, v := range opt.MenuContent {
-		data[k] = v
+	m.routes = make(map[string]string, len(opt.Routes))
+	for k, v := range opt.Routes {
+		m.routes[k] = v
+	}
+	m.decisions = opt.Decisions
+	m.dependencies = append([]string{}, opt.Dependencies...)
+	m.validators = append([]Validator{}, opt.Validators...)
+	m.validationMessage = opt.ValidationMessage.clone()
+	m.sensitiveInput = opt.SensitiveInput
+	m.requiresAuth = opt.RequiresAuth
+	m.segments = append([]string{}, opt.Segments...)
+	m.beforeRender = opt.BeforeRender
+	m.afterRender = opt.AfterRender
+	m.contentFn = opt.ContentFn
+	if opt.Experiment != nil && len(opt.Experiment.Variants) > 0 {
+		m.experiment = opt.Experiment
+	}
+	m.flag = opt.Flag
+	m.fallbackMenu = opt.FallbackMenu
+	m.generateFn = opt.GenerateMenuFn
+	if opt.GenerateMenuFn != nil {
+		m.generateMenuFn = wrap(opt.GenerateMenuFn, m)
+	} else {
+		m.generateMenuFn = m.renderContent
 	}
-	m.menuContent = data
-	m.generateMenuFn = wrap(opt.GenerateMenuFn, m)
 
 	return m
 }
 
 type menu struct {
-	menuName string
-	// previousMenu   string
-	nextMenu       string
-	shortCut       string
-	generateMenuFn func(context.Context, UssdPayload) (SessionResponse, error)
-	menuContent    map[string]string
+	menuName          string
+	previousMenu      string
+	nextMenu          string
+	shortCut          string
+	generateMenuFn    func(context.Context, UssdPayload) (SessionResponse, error)
+	generateFn        fn1
+	menuContent       Content
+	contentFn         ContentFn
+	defaultLanguage   string
+	languageFn        func(context.Context, UssdPayload) string
+	routes            map[string]string
+	decisions         *DecisionTable
+	dependencies      []string
+	validators        []Validator
+	validationMessage Content
+	sensitiveInput    bool
+	requiresAuth      bool
+	segments          []string
+	beforeRender      BeforeRenderFn
+	afterRender       AfterRenderFn
+	experiment        *Experiment
+	variantFn         variantFn
+	flag              string
+	fallbackMenu      string
+	// mountPrefix is the prefix of the group the menu was mounted with, see Mount
+	mountPrefix string
 }
 
 func (m *menu) MenuName() string {
 	return m.menuName
 }
 
+// PreviousMenu returns the menu set as previous in the menu options
+func (m *menu) PreviousMenu() string {
+	return m.previousMenu
+}
+
 func (m *menu) NextMenu() string {
 	return m.nextMenu
 }
@@ -87,7 +218,92 @@ func (m *menu) ShortCut() string {
 	return m.shortCut
 }
 
+func (m *menu) Routes() map[string]string {
+	return m.routes
+}
+
+// Dependencies returns the backend services the menu calls
+func (m *menu) Dependencies() []string {
+	return m.dependencies
+}
+
+func (m *menu) decisionTable() *DecisionTable {
+	return m.decisions
+}
+
+// SensitiveInput reports whether the input received by the menu is redacted from logs
+func (m *menu) SensitiveInput() bool {
+	return m.sensitiveInput
+}
+
+// RequiresAuth reports whether the menu is only rendered for sessions that are logged in
+func (m *menu) RequiresAuth() bool {
+	return m.requiresAuth
+}
+
+// Segments returns the segments that see the menu
+func (m *menu) Segments() []string {
+	return m.segments
+}
+
+func (m *menu) ValidateInput(lang, input string) error {
+	return runValidators(m.validators, m.validationMessage, input, lang, m.defaultLanguage)
+}
+
 func (m *menu) GenerateResponse(ctx context.Context, p UssdPayload) (SessionResponse, error) {
+	v, variant, err := m.variant(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := v.generateResponse(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if res != nil && variant != "" {
+		res.setVariant(m.experiment.Name + "/" + variant)
+	}
+
+	return res, nil
+}
+
+// variant returns the menu with the content of the experiment variant assigned to the session
+func (m *menu) variant(ctx context.Context, p UssdPayload) (*menu, string, error) {
+	if m.experiment == nil || m.variantFn == nil {
+		return m, "", nil
+	}
+
+	variant, err := m.variantFn(ctx, p, m.experiment)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v := *m
+	v.menuContent = m.menuContent.clone()
+	for lang, text := range m.experiment.Variants[variant] {
+		v.menuContent[lang] = text
+	}
+	if m.generateFn != nil {
+		v.generateMenuFn = wrap(m.generateFn, &v)
+	} else {
+		v.generateMenuFn = v.renderContent
+	}
+
+	return &v, variant, nil
+}
+
+func (m *menu) generateResponse(ctx context.Context, p UssdPayload) (SessionResponse, error) {
+	if m.beforeRender != nil {
+		res, err := m.beforeRender(ctx, p, m)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			return res, nil
+		}
+	}
+
 	res, err := m.generateMenuFn(ctx, p)
 	switch {
 	case err == nil:
@@ -96,29 +312,108 @@ func (m *menu) GenerateResponse(ctx context.Context, p UssdPayload) (SessionResp
 			res = &sessionResponse{}
 		}
 		res.setFailed()
-		res.setStatusMessage(firstVal(res.StatusMessage(), ErrFailedValidation.Error()))
+		msg, _ := userMessage(err, m.language(ctx, p), m.defaultLanguage)
+		res.setStatusMessage(firstVal(res.StatusMessage(), msg, ErrFailedValidation.Error()))
 		res.setMenu(m.menuName)
 	default:
 		return nil, err
 	}
 
+	if m.afterRender != nil {
+		sr, err := m.afterRender(ctx, p, m, res)
+		if err != nil {
+			return nil, err
+		}
+		if sr != nil {
+			res = sr
+		}
+	}
+
 	return res, nil
 }
 
-// menuText returns the menu text
-func (m *menu) menuText(lang string) string {
-	return m.menuContent[lang]
+func (m *menu) Text(lang string, args ...interface{}) string {
+	text := m.menuContent.Text(lang, m.defaultLanguage)
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	return text
 }
 
-func (m *menu) ExecuteMenuArgs(key string, args ...interface{}) SessionResponse {
-	res := m.menuText(key)
-	if len(args) > 0 {
-		res = fmt.Sprintf(res, args...)
+func (m *menu) translate(content Content) {
+	for lang, text := range content {
+		m.menuContent[lang] = text
+	}
+}
+
+func (m *menu) setDefaultLanguage(lang string) {
+	m.defaultLanguage = lang
+}
+
+func (m *menu) setLanguageFn(fn func(context.Context, UssdPayload) string) {
+	m.languageFn = fn
+}
+
+func (m *menu) setVariantFn(fn variantFn) {
+	m.variantFn = fn
+}
+
+func (m *menu) featureFlag() (string, string) {
+	return m.flag, m.fallbackMenu
+}
+
+// content returns the menu text in the language, fetching it with the content function when set
+func (m *menu) content(ctx context.Context, payload UssdPayload, lang string) (string, error) {
+	if m.contentFn == nil {
+		return m.Text(lang), nil
+	}
+
+	text, err := m.contentFn(ctx, payload, lang)
+	if err != nil {
+		return "", fmt.Errorf("failed to get content of menu %s: %w", m.menuName, err)
+	}
+
+	return text, nil
+}
+
+// language returns the language of the session, or the default language of the menu
+func (m *menu) language(ctx context.Context, payload UssdPayload) string {
+	if m.languageFn != nil {
+		return m.languageFn(ctx, payload)
+	}
+	return m.defaultLanguage
+}
+
+// renderContent renders the menu text in the session language for menus without a generate function
+func (m *menu) renderContent(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	text, err := m.content(ctx, payload, m.language(ctx, payload))
+	if err != nil {
+		return nil, err
 	}
+
+	return &sessionResponse{
+		response: text,
+		menuName: m.menuName,
+	}, nil
+}
+
+func (m *menu) ExecuteMenuArgs(key string, args ...interface{}) SessionResponse {
 	return &sessionResponse{
-		response:      res,
+		response:      m.Text(key, args...),
 		failed:        false,
 		statusMessage: "",
 		menuName:      m.menuName,
 	}
 }
+
+func (m *menu) ExecuteMenuTemplate(key string, data interface{}) (SessionResponse, error) {
+	text, err := executeTemplate(m.menuContent.Text(key, m.defaultLanguage), data)
+	if err != nil {
+		return nil, fmt.Errorf("menu %s: %v", m.menuName, err)
+	}
+
+	return &sessionResponse{
+		response: text,
+		menuName: m.menuName,
+	}, nil
+}
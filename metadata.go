@@ -0,0 +1,69 @@
+package ussdapp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxLogDataLength is the size of the Data column of session logs
+const maxLogDataLength = 500
+
+// SetPayloadMetadata attaches business context to the request, e.g the account a middleware resolved, which is saved
+// as JSON in the Data column of the session log together with values set with SessionResponse.SetData. Values of the
+// response replace values of the payload with the same key.
+//
+// Metadata lasts for the request only
+func SetPayloadMetadata(payload UssdPayload, key string, value interface{}) error {
+	p, ok := payload.(*ussdPayload)
+	if !ok {
+		return fmt.Errorf("unsupported payload type %T", payload)
+	}
+
+	if p.data.metadata == nil {
+		p.data.metadata = make(map[string]interface{})
+	}
+	p.data.metadata[key] = value
+
+	return nil
+}
+
+// PayloadMetadata returns the value attached to the request with SetPayloadMetadata
+func PayloadMetadata(payload UssdPayload, key string) (interface{}, bool) {
+	p, ok := payload.(*ussdPayload)
+	if !ok {
+		return nil, false
+	}
+
+	val, ok := p.data.metadata[key]
+	return val, ok
+}
+
+// logData encodes the metadata of the payload and data of the response for the Data column of the session log
+func (app *UssdApp) logData(payload UssdPayload, sr SessionResponse) string {
+	data := make(map[string]interface{})
+	if p, ok := payload.(*ussdPayload); ok {
+		for key, val := range p.data.metadata {
+			data[key] = val
+		}
+	}
+	for key, val := range sr.Data() {
+		data[key] = val
+	}
+	if len(data) == 0 {
+		return ""
+	}
+
+	bs, err := json.Marshal(data)
+	if err != nil {
+		app.opt.Logger.Warn("failed to encode session log data", "session_id", payload.SessionId(), "error", err)
+		return ""
+	}
+
+	// Cut json is invalid, so data that does not fit is left out rather than cut
+	if len(bs) > maxLogDataLength {
+		app.opt.Logger.Warn("session log data is too long, leaving it out", "session_id", payload.SessionId(), "length", len(bs))
+		return ""
+	}
+
+	return string(bs)
+}
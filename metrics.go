@@ -0,0 +1,339 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "ussdapp"
+
+// metrics contains the prometheus collectors for the app. A nil metrics records nothing.
+type metrics struct {
+	registry            *prometheus.Registry
+	labels              prometheus.Labels
+	sessionsStarted     prometheus.Counter
+	sessionsCompleted   *prometheus.CounterVec
+	menuHits            *prometheus.CounterVec
+	menuLatency         *prometheus.HistogramVec
+	validationFailures  *prometheus.CounterVec
+	logFlushFailures    prometheus.Counter
+	logsDropped         prometheus.Counter
+	cacheErrors         *prometheus.CounterVec
+	experimentExposures *prometheus.CounterVec
+	panics              *prometheus.CounterVec
+	breakerState        *prometheus.GaugeVec
+	breakerRejections   *prometheus.CounterVec
+}
+
+func newMetrics(appName string, registry *prometheus.Registry) (*metrics, error) {
+	labels := prometheus.Labels{"app": appName}
+
+	m := &metrics{
+		registry: registry,
+		labels:   labels,
+		sessionsStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "sessions_started_total",
+			Help:        "Number of ussd sessions started.",
+			ConstLabels: labels,
+		}),
+		sessionsCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "sessions_completed_total",
+			Help:        "Number of ussd sessions ended by the app, by status.",
+			ConstLabels: labels,
+		}, []string{"status"}),
+		menuHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "menu_hits_total",
+			Help:        "Number of times a menu was rendered.",
+			ConstLabels: labels,
+		}, []string{"menu"}),
+		menuLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "menu_generation_seconds",
+			Help:        "Time taken to generate menu responses.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"menu"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "validation_failures_total",
+			Help:        "Number of inputs rejected by menu validators.",
+			ConstLabels: labels,
+		}, []string{"menu"}),
+		logFlushFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "log_flush_failures_total",
+			Help:        "Number of failed attempts to save session logs.",
+			ConstLabels: labels,
+		}),
+		logsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "logs_dropped_total",
+			Help:        "Number of session logs dropped because the buffer of logs waiting to be written was full.",
+			ConstLabels: labels,
+		}),
+		cacheErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "cache_errors_total",
+			Help:        "Number of cache operations that failed, by operation.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		experimentExposures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "experiment_exposures_total",
+			Help:        "Number of sessions assigned a variant of an experiment.",
+			ConstLabels: labels,
+		}, []string{"experiment", "variant"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "panics_total",
+			Help:        "Number of panics recovered while processing requests, by menu.",
+			ConstLabels: labels,
+		}, []string{"menu"}),
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   metricsNamespace,
+			Name:        "circuit_breaker_state",
+			Help:        "State of the circuit breaker of a menu dependency: 0 closed, 1 half open, 2 open.",
+			ConstLabels: labels,
+		}, []string{"dependency"}),
+		breakerRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "circuit_breaker_rejections_total",
+			Help:        "Number of menus not rendered because the circuit breaker of a dependency was open.",
+			ConstLabels: labels,
+		}, []string{"dependency"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.sessionsStarted, m.sessionsCompleted, m.menuHits, m.menuLatency, m.validationFailures, m.logFlushFailures, m.logsDropped,
+		m.cacheErrors, m.experimentExposures, m.panics, m.breakerState, m.breakerRejections,
+	} {
+		err := registry.Register(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %v", err)
+		}
+	}
+
+	return m, nil
+}
+
+// watchActiveSessions reports the number of sessions in progress when metrics are scraped
+func (m *metrics) watchActiveSessions(count func(context.Context) (int64, error)) error {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "sessions_active",
+		Help:        "Number of ussd sessions in progress.",
+		ConstLabels: m.labels,
+	}, func() float64 {
+		n, err := count(context.Background())
+		if err != nil {
+			return 0
+		}
+		return float64(n)
+	})
+
+	err := m.registry.Register(gauge)
+	if err != nil {
+		return fmt.Errorf("failed to register metrics: %v", err)
+	}
+
+	return nil
+}
+
+func (m *metrics) sessionStarted() {
+	if m == nil {
+		return
+	}
+	m.sessionsStarted.Inc()
+}
+
+// sessionCompleted records sessions that end with the response or with an error
+func (m *metrics) sessionCompleted(sr SessionResponse, err error) {
+	if m == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		m.sessionsCompleted.WithLabelValues("failed").Inc()
+	case sr != nil && sr.Terminal():
+		m.sessionsCompleted.WithLabelValues("ok").Inc()
+	}
+}
+
+func (m *metrics) menuRendered(menuName string, took time.Duration) {
+	if m == nil {
+		return
+	}
+	m.menuHits.WithLabelValues(menuName).Inc()
+	m.menuLatency.WithLabelValues(menuName).Observe(took.Seconds())
+}
+
+func (m *metrics) validationFailed(menuName string) {
+	if m == nil {
+		return
+	}
+	m.validationFailures.WithLabelValues(menuName).Inc()
+}
+
+func (m *metrics) logFlushFailed() {
+	if m == nil {
+		return
+	}
+	m.logFlushFailures.Inc()
+}
+
+func (m *metrics) logDropped() {
+	if m == nil {
+		return
+	}
+	m.logsDropped.Inc()
+}
+
+func (m *metrics) experimentExposed(experiment, variant string) {
+	if m == nil {
+		return
+	}
+	m.experimentExposures.WithLabelValues(experiment, variant).Inc()
+}
+
+func (m *metrics) panicked(menuName string) {
+	if m == nil {
+		return
+	}
+	m.panics.WithLabelValues(menuName).Inc()
+}
+
+func (m *metrics) breakerChanged(dependency string, state BreakerState) {
+	if m == nil {
+		return
+	}
+	m.breakerState.WithLabelValues(dependency).Set(float64(state))
+}
+
+func (m *metrics) breakerRejected(dependency string) {
+	if m == nil {
+		return
+	}
+	m.breakerRejections.WithLabelValues(dependency).Inc()
+}
+
+func (m *metrics) cacheError(operation string, err error) {
+	if m == nil || err == nil || errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrValueNotFound) {
+		return
+	}
+	m.cacheErrors.WithLabelValues(operation).Inc()
+}
+
+// MetricsHandler returns an http handler that serves the app metrics for prometheus to scrape.
+//
+// It responds with not found when Options.MetricsRegistry is not set.
+func (app *UssdApp) MetricsHandler() http.Handler {
+	if app.metrics == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(app.metrics.registry, promhttp.HandlerOpts{})
+}
+
+// metricsCacher counts errors returned by the cache
+type metricsCacher struct {
+	Cacher
+	metrics *metrics
+}
+
+func (c *metricsCacher) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	err := c.Cacher.Set(ctx, key, value, dur)
+	c.metrics.cacheError("set", err)
+	return err
+}
+
+func (c *metricsCacher) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.Cacher.Get(ctx, key)
+	c.metrics.cacheError("get", err)
+	return val, err
+}
+
+func (c *metricsCacher) Delete(ctx context.Context, key string) error {
+	err := c.Cacher.Delete(ctx, key)
+	c.metrics.cacheError("delete", err)
+	return err
+}
+
+func (c *metricsCacher) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	err := c.Cacher.SetMap(ctx, key, fields)
+	c.metrics.cacheError("set_map", err)
+	return err
+}
+
+func (c *metricsCacher) GetMap(ctx context.Context, key string) (map[string]string, error) {
+	val, err := c.Cacher.GetMap(ctx, key)
+	c.metrics.cacheError("get_map", err)
+	return val, err
+}
+
+func (c *metricsCacher) DeleteMap(ctx context.Context, key string) error {
+	err := c.Cacher.DeleteMap(ctx, key)
+	c.metrics.cacheError("delete_map", err)
+	return err
+}
+
+func (c *metricsCacher) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	err := c.Cacher.SetMapField(ctx, key, values...)
+	c.metrics.cacheError("set_map_field", err)
+	return err
+}
+
+func (c *metricsCacher) GetMapField(ctx context.Context, key, field string) (string, error) {
+	val, err := c.Cacher.GetMapField(ctx, key, field)
+	c.metrics.cacheError("get_map_field", err)
+	return val, err
+}
+
+func (c *metricsCacher) GetMapFields(ctx context.Context, key string, fields ...string) (map[string]string, error) {
+	val, err := c.Cacher.GetMapFields(ctx, key, fields...)
+	c.metrics.cacheError("get_map_fields", err)
+	return val, err
+}
+
+func (c *metricsCacher) DeleteMapField(ctx context.Context, key string, fields ...string) error {
+	err := c.Cacher.DeleteMapField(ctx, key, fields...)
+	c.metrics.cacheError("delete_map_field", err)
+	return err
+}
+
+func (c *metricsCacher) SetUnique(ctx context.Context, key string, value string) (bool, error) {
+	ok, err := addToSet(ctx, c.Cacher, key, value)
+	c.metrics.cacheError("set_unique", err)
+	return ok, err
+}
+
+func (c *metricsCacher) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	n, err := incr(ctx, c.Cacher, key, dur)
+	c.metrics.cacheError("incr", err)
+	return n, err
+}
+
+func (c *metricsCacher) ExistInSet(ctx context.Context, key string, value string) (bool, error) {
+	ok, err := c.Cacher.ExistInSet(ctx, key, value)
+	c.metrics.cacheError("exist_in_set", err)
+	return ok, err
+}
+
+func (c *metricsCacher) DeleteSetValue(ctx context.Context, key string, value string) error {
+	err := c.Cacher.DeleteSetValue(ctx, key, value)
+	c.metrics.cacheError("delete_set_value", err)
+	return err
+}
+
+func (c *metricsCacher) Expire(ctx context.Context, key string, dur time.Duration) error {
+	err := c.Cacher.Expire(ctx, key, dur)
+	c.metrics.cacheError("expire", err)
+	return err
+}
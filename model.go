@@ -4,26 +4,32 @@ import (
 	"time"
 )
 
-var sessionLogsTable = ""
-
 const defaultSessionsLogsTable = "ussd_logs"
 
 type SessionRequest struct {
-	ID            uint      `gorm:"primaryKey;autoIncrement"`
-	SessionID     string    `gorm:"index;type:varchar(100);not null"`
-	Msisdn        string    `gorm:"index;type:varchar(13);not null"`
-	MenuName      string    `gorm:"index;type:varchar(50);not null"`
-	USSDParams    string    `gorm:"type:varchar(500);"`
-	UserInput     string    `gorm:"type:varchar(100);"`
-	Data          string    `gorm:"index;type:varchar(500);"`
-	Succeeded     bool      `gorm:"index;type:tinyint(1)"`
-	StatusMessage string    `gorm:"type:varchar(500);"`
-	CreatedAt     time.Time `gorm:"primaryKey;not null;type:datetime(6)"`
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	SessionID   string `gorm:"index;type:varchar(100);not null"`
+	Msisdn      string `gorm:"index;type:varchar(13);not null"`
+	MenuName    string `gorm:"index;type:varchar(50);not null"`
+	MenuVersion string `gorm:"index;type:varchar(50)"`
+	Variant     string `gorm:"index;type:varchar(100)"`
+	USSDParams  string `gorm:"type:varchar(500);"`
+	UserInput   string `gorm:"type:varchar(100);"`
+	// ResponseText is the text of the response shown to the user, logged with Options.LogResponseText
+	ResponseText  string `gorm:"type:varchar(1000);"`
+	Data          string `gorm:"index;type:varchar(500);"`
+	Succeeded     bool   `gorm:"index;type:tinyint(1)"`
+	Ended         bool   `gorm:"index;type:tinyint(1);not null;default:0"`
+	StatusMessage string `gorm:"type:varchar(500);"`
+	// DurationMs is the time taken to answer the request, from receiving it to writing the response
+	DurationMs int64 `gorm:"index"`
+	// HTTPStatus is the status of the response written by the built-in handler. It is zero for gRPC requests
+	HTTPStatus int       `gorm:"type:smallint"`
+	CreatedAt  time.Time `gorm:"primaryKey;not null;type:datetime(6)"`
 }
 
+// TableName returns the default logs table. Apps save logs to Options.TableName instead when set, see
+// UssdApp.LogsTable
 func (*SessionRequest) TableName() string {
-	if sessionLogsTable != "" {
-		return sessionLogsTable
-	}
 	return defaultSessionsLogsTable
 }
@@ -0,0 +1,223 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	menuStackKey     = "menu_stack"
+	defaultBackInput = "0"
+	defaultHomeInput = "00"
+	maxMenuStackSize = 20
+)
+
+// ErrNoMenuHistory is returned when the session has no rendered menus
+var ErrNoMenuHistory = errors.New("no menu history")
+
+// menuStackEntry is a menu rendered during the session together with the payload that rendered it
+type menuStackEntry struct {
+	Menu    string          `json:"menu"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// getMenuStack reads the menus rendered in the session, oldest first
+func (app *UssdApp) getMenuStack(ctx context.Context, payload UssdPayload) ([]*menuStackEntry, error) {
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), menuStackKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return []*menuStackEntry{}, nil
+	default:
+		return nil, fmt.Errorf("failed to get menu stack: %v", err)
+	}
+
+	stack := make([]*menuStackEntry, 0)
+	if val == "" {
+		return stack, nil
+	}
+
+	err = json.Unmarshal([]byte(val), &stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal menu stack: %v", err)
+	}
+
+	return stack, nil
+}
+
+// saveMenuStack saves the menus rendered in the session
+func (app *UssdApp) saveMenuStack(ctx context.Context, payload UssdPayload, stack []*menuStackEntry) error {
+	if len(stack) > maxMenuStackSize {
+		stack = stack[len(stack)-maxMenuStackSize:]
+	}
+
+	bs, err := json.Marshal(stack)
+	if err != nil {
+		return fmt.Errorf("failed to marshal menu stack: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), menuStackKey, bs)
+	if err != nil {
+		return fmt.Errorf("failed to save menu stack: %v", err)
+	}
+
+	return nil
+}
+
+// PushMenu adds the menu rendered using payload on top of the session menu history.
+//
+// The framework pushes every menu it renders, so callers only need it for menus rendered outside the normal flow.
+func (app *UssdApp) PushMenu(ctx context.Context, payload UssdPayload, menu Menu) error {
+	stack, err := app.getMenuStack(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	bs, err := payload.JSON()
+	if err != nil {
+		return err
+	}
+
+	entry := &menuStackEntry{Menu: menu.MenuName(), Payload: bs}
+
+	// A menu rendered again replaces itself rather than growing the stack
+	if len(stack) > 0 && stack[len(stack)-1].Menu == menu.MenuName() {
+		stack[len(stack)-1] = entry
+	} else {
+		stack = append(stack, entry)
+	}
+
+	return app.saveMenuStack(ctx, payload, stack)
+}
+
+// PopMenu removes the menu on top of the session menu history and returns it.
+//
+// Returns ErrNoMenuHistory if no menu has been rendered in the session.
+func (app *UssdApp) PopMenu(ctx context.Context, payload UssdPayload) (Menu, error) {
+	stack, err := app.getMenuStack(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stack) == 0 {
+		return nil, ErrNoMenuHistory
+	}
+
+	top := stack[len(stack)-1]
+
+	err = app.saveMenuStack(ctx, payload, stack[:len(stack)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	menu, ok := app.getMenu(top.Menu)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMenuNotExist, top.Menu)
+	}
+
+	return menu, nil
+}
+
+// MenuHistory returns names of menus rendered in the session, oldest first.
+//
+// The last menu is the one currently shown to the user.
+func (app *UssdApp) MenuHistory(ctx context.Context, payload UssdPayload) ([]string, error) {
+	stack, err := app.getMenuStack(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(stack))
+	for _, entry := range stack {
+		names = append(names, entry.Menu)
+	}
+
+	return names, nil
+}
+
+// navigate handles back and home inputs for ongoing sessions.
+//
+// It returns false if the input is not a navigation input.
+func (app *UssdApp) navigate(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if app.opt.DisableNavigation {
+		return nil, false, nil
+	}
+
+	switch payload.UssdCurrentParam() {
+	case app.opt.HomeInput:
+		sr, err := app.navigateHome(ctx, payload)
+		return sr, true, err
+	case app.opt.BackInput:
+		sr, err := app.navigateBack(ctx, payload)
+		return sr, true, err
+	}
+
+	return nil, false, nil
+}
+
+// navigateHome clears the menu stack and renders the home menu
+func (app *UssdApp) navigateHome(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	err := app.saveMenuStack(ctx, payload, []*menuStackEntry{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), payloadHistoryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear payload history: %v", err)
+	}
+
+	return app.ReplaceMenuWithName(ctx, app.homeMenuOf(payload), payload)
+}
+
+// navigateBack renders the menu that was shown before the current one using the payload that rendered it
+func (app *UssdApp) navigateBack(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	stack, err := app.getMenuStack(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// The top of the stack is the menu currently shown to the user
+	if len(stack) > 0 {
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) == 0 {
+		return app.navigateHome(ctx, payload)
+	}
+
+	prev := stack[len(stack)-1]
+
+	prevMenu, ok := app.getMenu(prev.Menu)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMenuNotExist, prev.Menu)
+	}
+
+	prevPayload, err := UssdPayloadFromJSON(prev.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous payload: %v", err)
+	}
+
+	// The previous menu is pushed again once it is rendered
+	err = app.saveMenuStack(ctx, payload, stack[:len(stack)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	// So is the payload that rendered it, after the payload of the current menu
+	err = app.dropPayloads(ctx, payload, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := app.ReplaceMenu(ctx, prevPayload, prevMenu)
+	if err != nil {
+		return nil, err
+	}
+
+	SkipSavingPayload(payload)
+
+	return sr, nil
+}
@@ -0,0 +1,256 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otpVerifyStep      = "verify"
+	defaultOTPDigits   = 6
+	defaultOTPAttempts = 3
+	defaultOTPTTL      = 5 * time.Minute
+	defaultOTPPrompt   = "CON Enter the code sent to you"
+	defaultInvalidOTP  = "Enter the %d digit code"
+	defaultWrongOTP    = "Wrong code"
+	defaultExpiredOTP  = "The code has expired, try again"
+	defaultLockedOTP   = "Too many wrong codes, try again later"
+	// maxOTPDigits is the longest code that fits in an int64
+	maxOTPDigits = 18
+)
+
+// OTPSendFn delivers a one time code to the user, e.g by SMS
+type OTPSendFn func(ctx context.Context, payload UssdPayload, code string) error
+
+// OTPMenuOptions contains data for a menu that verifies a one time code sent to the user
+type OTPMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Prompt asks for the code, per language. Defaults to CON Enter the code sent to you
+	Prompt Content
+	// Send delivers the code. A code is generated and sent when the prompt is rendered without a valid code
+	Send OTPSendFn
+	// Digits is the length of the code, at most 18. Defaults to 6
+	Digits int
+	// MaxAttempts is the number of wrong codes allowed before the session ends. Defaults to 3
+	MaxAttempts int
+	// TTL is how long a code is valid. Defaults to 5 minutes
+	TTL time.Duration
+	// InvalidMessage is shown above the prompt for inputs that are not a code, per language. A %d verb is replaced
+	// with the digits
+	InvalidMessage Content
+	// WrongMessage is shown above the prompt for wrong codes, per language
+	WrongMessage Content
+	// ExpiredMessage ends the session when the code expired, per language
+	ExpiredMessage Content
+	// LockedMessage ends the session after too many wrong codes, per language
+	LockedMessage Content
+	// NextMenu is rendered once the code is verified. Defaults to the home menu
+	NextMenu string
+}
+
+// otpFields are the session fields holding the code of an otp menu, its expiry and the wrong attempts
+type otpFields struct {
+	code, expires, attempts string
+}
+
+func newOTPFields(menuName string) otpFields {
+	return otpFields{
+		code:     fmt.Sprintf("otp:%s:code", menuName),
+		expires:  fmt.Sprintf("otp:%s:expires", menuName),
+		attempts: fmt.Sprintf("otp:%s:attempts", menuName),
+	}
+}
+
+// AddOTPMenu registers a menu that sends a one time code to the user and asks for it.
+//
+// The code is kept hashed in the session with its expiry, re-rendering the prompt after a wrong code does not send a
+// new one. The session ends once the code expires or after MaxAttempts wrong codes. The input is received by a menu
+// named <menu>:verify.
+func (app *UssdApp) AddOTPMenu(opt *OTPMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing otp menu options")
+	case opt.MenuName == "":
+		return errors.New("missing otp menu name")
+	case opt.Send == nil:
+		return fmt.Errorf("otp menu %s is missing a send function", opt.MenuName)
+	case opt.Digits < 0 || opt.MaxAttempts < 0 || opt.TTL < 0:
+		return fmt.Errorf("otp menu %s has negative options", opt.MenuName)
+	case opt.Digits > maxOTPDigits:
+		return fmt.Errorf("otp menu %s has more than %d digits", opt.MenuName, maxOTPDigits)
+	}
+
+	var (
+		verifyMenu  = fmt.Sprintf("%s:%s", opt.MenuName, otpVerifyStep)
+		nextMenu    = firstVal(opt.NextMenu, app.homeMenu)
+		fields      = newOTPFields(opt.MenuName)
+		digits      = opt.Digits
+		maxAttempts = opt.MaxAttempts
+		ttl         = opt.TTL
+	)
+	if digits == 0 {
+		digits = defaultOTPDigits
+	}
+	if maxAttempts == 0 {
+		maxAttempts = defaultOTPAttempts
+	}
+	if ttl == 0 {
+		ttl = defaultOTPTTL
+	}
+
+	prompt := opt.Prompt
+	if len(prompt) == 0 {
+		prompt = Content{app.opt.DefaultLanguage: defaultOTPPrompt}
+	}
+
+	messages := opt.InvalidMessage.clone()
+	if _, ok := messages[""]; !ok {
+		messages[""] = defaultInvalidOTP
+	}
+	for lang, msg := range messages {
+		if strings.Contains(msg, "%d") {
+			messages[lang] = fmt.Sprintf(msg, digits)
+		}
+	}
+	invalid := NewError(CodeInvalidInput, messages, nil)
+
+	// end renders a message ending the session, forgetting the code
+	end := func(ctx context.Context, payload UssdPayload, m Menu, msg Content, fallback string) (SessionResponse, error) {
+		err := app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), fields.code, fields.expires, fields.attempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete otp: %v", err)
+		}
+
+		text := firstVal(msg.Text(app.GetLanguage(ctx, payload), app.opt.DefaultLanguage), fallback)
+
+		return (&sessionResponse{response: endPrefix + " " + text, menuName: m.MenuName()}).End(), nil
+	}
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName:    opt.MenuName,
+			NextMenu:    verifyMenu,
+			ShortCut:    opt.ShortCut,
+			MenuContent: prompt,
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				err := app.issueOTP(ctx, payload, fields, opt, digits, ttl)
+				if err != nil {
+					return nil, err
+				}
+
+				return m.ExecuteMenuArgs(app.GetLanguage(ctx, payload)), nil
+			},
+		}),
+		NewMenu(&MenuOptions{
+			MenuName: verifyMenu,
+			NextMenu: nextMenu,
+			Validators: []Validator{func(input string) error {
+				if len(input) != digits || !isDigits(input) {
+					return invalid
+				}
+				return nil
+			}},
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				vals, err := app.opt.Cache.GetMapFields(ctx, app.GetSessionKey(payload), fields.code, fields.expires, fields.attempts)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get otp: %v", err)
+				}
+
+				expires, _ := strconv.ParseInt(vals[fields.expires], 10, 64)
+				if vals[fields.code] == "" || time.Now().Unix() >= expires {
+					return end(ctx, payload, m, opt.ExpiredMessage, defaultExpiredOTP)
+				}
+
+				if subtle.ConstantTimeCompare([]byte(hashOTP(payload.UssdCurrentParam())), []byte(vals[fields.code])) == 1 {
+					err = app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), fields.code, fields.expires, fields.attempts)
+					if err != nil {
+						return nil, fmt.Errorf("failed to delete otp: %v", err)
+					}
+					return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+				}
+
+				attempts, _ := strconv.Atoi(vals[fields.attempts])
+				attempts++
+				if attempts >= maxAttempts {
+					return end(ctx, payload, m, opt.LockedMessage, defaultLockedOTP)
+				}
+
+				err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), fields.attempts, attempts)
+				if err != nil {
+					return nil, fmt.Errorf("failed to save otp attempts: %v", err)
+				}
+
+				msg := firstVal(opt.WrongMessage.Text(app.GetLanguage(ctx, payload), app.opt.DefaultLanguage), defaultWrongOTP)
+
+				return app.PreviousMenuWithError(ctx, payload, m, msg)
+			},
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// issueOTP generates and sends a code unless the session has one that has not expired
+func (app *UssdApp) issueOTP(
+	ctx context.Context, payload UssdPayload, fields otpFields, opt *OTPMenuOptions, digits int, ttl time.Duration,
+) error {
+	vals, err := app.opt.Cache.GetMapFields(ctx, app.GetSessionKey(payload), fields.code, fields.expires)
+	if err != nil {
+		return fmt.Errorf("failed to get otp: %v", err)
+	}
+
+	expires, _ := strconv.ParseInt(vals[fields.expires], 10, 64)
+	if vals[fields.code] != "" && time.Now().Unix() < expires {
+		return nil
+	}
+
+	code, err := generateOTP(digits)
+	if err != nil {
+		return err
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload),
+		fields.code, hashOTP(code), fields.expires, time.Now().Add(ttl).Unix(), fields.attempts, 0)
+	if err != nil {
+		return fmt.Errorf("failed to save otp: %v", err)
+	}
+
+	err = opt.Send(ctx, payload, code)
+	if err != nil {
+		return fmt.Errorf("failed to send otp: %w", err)
+	}
+
+	return nil
+}
+
+// generateOTP returns a random code of the given digits
+func generateOTP(digits int) (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate otp: %v", err)
+	}
+	return fmt.Sprintf("%0*d", digits, n.Int64()), nil
+}
+
+// hashOTP hashes codes kept in the session so they cannot be read from the cache
+func hashOTP(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,439 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize          = 5
+	defaultMaxResponseLength = 160
+	defaultNextPageInput     = "98"
+	defaultPreviousPageInput = "99"
+	defaultNextPageText      = "Next"
+	defaultPreviousPageText  = "Previous"
+)
+
+// PaginatedMenuOptions contains data for a menu that renders a long list of items across several pages.
+//
+// Items are numbered from 1 on each page, so the next menu receives the number of the selected item on the page shown
+// as input. A page ends before an item whose number is a page, back or home input. Use GetPaginatedItem in the next
+// menu to get the selected item.
+type PaginatedMenuOptions struct {
+	MenuName string
+	NextMenu string
+	ShortCut string
+	// MenuContent is the header rendered above the items on every page, per language
+	MenuContent Content
+	// ContentFn fetches the header each time a page is rendered, replacing MenuContent
+	ContentFn ContentFn
+	// Items to render. Ignored if ItemsFn is set
+	Items []string
+	// ItemsFn fetches items each time the menu is rendered afresh
+	ItemsFn func(context.Context, UssdPayload) ([]string, error)
+	// PageSize is the maximum number of items in a page. Pages are shortened further to fit MaxLength
+	PageSize int
+	// MaxLength is the maximum number of characters in a page, including the CON prefix
+	MaxLength         int
+	NextPageInput     string
+	PreviousPageInput string
+	NextPageText      string
+	PreviousPageText  string
+	// Flag is the feature flag that turns the menu on, see Options.FlagProvider. Menus without a flag are always on
+	Flag string
+	// FallbackMenu is rendered instead of the menu when its flag is off. Defaults to the home menu
+	FallbackMenu string
+}
+
+// NewPaginatedMenu creates a menu that renders items across pages, turning pages when the user enters the next or previous page input.
+//
+// The current page and items are kept in the session cache.
+func NewPaginatedMenu(app *UssdApp, opt *PaginatedMenuOptions) Menu {
+	pm := &paginatedMenu{
+		app:               app,
+		menuName:          opt.MenuName,
+		nextMenu:          opt.NextMenu,
+		shortCut:          opt.ShortCut,
+		menuContent:       opt.MenuContent.clone(),
+		contentFn:         opt.ContentFn,
+		items:             append([]string{}, opt.Items...),
+		itemsFn:           opt.ItemsFn,
+		pageSize:          opt.PageSize,
+		maxLength:         opt.MaxLength,
+		nextPageInput:     firstVal(opt.NextPageInput, defaultNextPageInput),
+		previousPageInput: firstVal(opt.PreviousPageInput, defaultPreviousPageInput),
+		nextPageText:      firstVal(opt.NextPageText, defaultNextPageText),
+		previousPageText:  firstVal(opt.PreviousPageText, defaultPreviousPageText),
+		flag:              opt.Flag,
+		fallbackMenu:      opt.FallbackMenu,
+	}
+	if pm.pageSize <= 0 {
+		pm.pageSize = defaultPageSize
+	}
+	if pm.maxLength <= 0 {
+		pm.maxLength = defaultMaxResponseLength
+	}
+
+	return pm
+}
+
+type paginatedMenu struct {
+	app               *UssdApp
+	menuName          string
+	nextMenu          string
+	shortCut          string
+	menuContent       Content
+	contentFn         ContentFn
+	defaultLanguage   string
+	items             []string
+	itemsFn           func(context.Context, UssdPayload) ([]string, error)
+	pageSize          int
+	maxLength         int
+	nextPageInput     string
+	previousPageInput string
+	nextPageText      string
+	previousPageText  string
+	flag              string
+	fallbackMenu      string
+}
+
+// pageTurner is implemented by menus that handle page inputs on their own screen
+type pageTurner interface {
+	turnPage(context.Context, UssdPayload) (SessionResponse, bool, error)
+}
+
+func (pm *paginatedMenu) MenuName() string {
+	return pm.menuName
+}
+
+func (pm *paginatedMenu) NextMenu() string {
+	return pm.nextMenu
+}
+
+func (pm *paginatedMenu) ShortCut() string {
+	return pm.shortCut
+}
+
+func (pm *paginatedMenu) featureFlag() (string, string) {
+	return pm.flag, pm.fallbackMenu
+}
+
+func (pm *paginatedMenu) itemsKey() string {
+	return fmt.Sprintf("paginator:%s:items", pm.menuName)
+}
+
+func (pm *paginatedMenu) pageKey() string {
+	return fmt.Sprintf("paginator:%s:page", pm.menuName)
+}
+
+// shownKey keeps the range of the items on the page shown, as start:end
+func (pm *paginatedMenu) shownKey() string {
+	return fmt.Sprintf("paginator:%s:shown", pm.menuName)
+}
+
+// reserved reports whether an item number would be read as a page, back or home input
+func (pm *paginatedMenu) reserved(number int) bool {
+	input := strconv.Itoa(number)
+	switch input {
+	case pm.nextPageInput, pm.previousPageInput:
+		return true
+	}
+	return pm.app != nil && !pm.app.opt.DisableNavigation && (input == pm.app.opt.BackInput || input == pm.app.opt.HomeInput)
+}
+
+// validate refuses page inputs that leave no number for the first item of a page
+func (pm *paginatedMenu) validate() error {
+	if pm.reserved(1) {
+		return fmt.Errorf("paginated menu %s: item number 1 is a navigation input", pm.menuName)
+	}
+	return nil
+}
+
+func (pm *paginatedMenu) GenerateResponse(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	items := pm.items
+	if pm.itemsFn != nil {
+		var err error
+		items, err = pm.itemsFn(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bs, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal items: %v", err)
+	}
+
+	header, err := pm.header(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rendering afresh always starts from the first page
+	sr, shown := pm.renderPage(header, items, 0)
+
+	err = pm.app.opt.Cache.SetMapField(ctx, pm.app.GetSessionKey(payload),
+		pm.itemsKey(), bs, pm.pageKey(), 0, pm.shownKey(), shown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save paginated items: %v", err)
+	}
+
+	return sr, nil
+}
+
+func (pm *paginatedMenu) Text(lang string, args ...interface{}) string {
+	text := pm.menuContent.Text(lang, pm.defaultLanguage)
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+func (pm *paginatedMenu) translate(content Content) {
+	for lang, text := range content {
+		pm.menuContent[lang] = text
+	}
+}
+
+func (pm *paginatedMenu) setDefaultLanguage(lang string) {
+	pm.defaultLanguage = lang
+}
+
+func (pm *paginatedMenu) ExecuteMenuArgs(key string, args ...interface{}) SessionResponse {
+	return &sessionResponse{
+		response: pm.Text(key, args...),
+		menuName: pm.menuName,
+	}
+}
+
+func (pm *paginatedMenu) ExecuteMenuTemplate(key string, data interface{}) (SessionResponse, error) {
+	text, err := executeTemplate(pm.menuContent.Text(key, pm.defaultLanguage), data)
+	if err != nil {
+		return nil, fmt.Errorf("menu %s: %v", pm.menuName, err)
+	}
+
+	return &sessionResponse{
+		response: text,
+		menuName: pm.menuName,
+	}, nil
+}
+
+func (pm *paginatedMenu) turnPage(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	var delta int
+	switch payload.UssdCurrentParam() {
+	case pm.nextPageInput:
+		delta = 1
+	case pm.previousPageInput:
+		delta = -1
+	default:
+		return nil, false, nil
+	}
+
+	items, page, err := pm.state(ctx, payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	header, err := pm.header(ctx, payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	page += delta
+	if pages := pm.pages(header, items); page >= len(pages) {
+		page = len(pages) - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	sr, shown := pm.renderPage(header, items, page)
+
+	err = pm.app.opt.Cache.SetMapField(ctx, pm.app.GetSessionKey(payload), pm.pageKey(), page, pm.shownKey(), shown)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save page: %v", err)
+	}
+
+	return sr, true, nil
+}
+
+// state reads the items and current page for the session
+func (pm *paginatedMenu) state(ctx context.Context, payload UssdPayload) ([]string, int, error) {
+	items, vals, err := pm.stateFields(ctx, payload, pm.pageKey())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, _ := strconv.Atoi(vals[pm.pageKey()])
+
+	return items, page, nil
+}
+
+// stateFields reads the items for the session together with other fields of the paginator
+func (pm *paginatedMenu) stateFields(ctx context.Context, payload UssdPayload, fields ...string) ([]string, map[string]string, error) {
+	vals, err := pm.app.opt.Cache.GetMapFields(ctx, pm.app.GetSessionKey(payload), append([]string{pm.itemsKey()}, fields...)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get paginated items: %v", err)
+	}
+
+	items := make([]string, 0)
+	err = json.Unmarshal([]byte(vals[pm.itemsKey()]), &items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal paginated items: %v", err)
+	}
+
+	return items, vals, nil
+}
+
+func (pm *paginatedMenu) header(ctx context.Context, payload UssdPayload) (string, error) {
+	lang := pm.app.GetLanguage(ctx, payload)
+
+	if pm.contentFn == nil {
+		return strings.TrimSpace(pm.Text(lang)), nil
+	}
+
+	text, err := pm.contentFn(ctx, payload, lang)
+	if err != nil {
+		return "", fmt.Errorf("failed to get content of menu %s: %w", pm.menuName, err)
+	}
+
+	return strings.TrimSpace(text), nil
+}
+
+// pages splits items into pages of at most page size items that fit in max length, returning [start, end) of each page
+func (pm *paginatedMenu) pages(header string, items []string) [][2]int {
+	var (
+		pages  = make([][2]int, 0)
+		footer = len(pm.navText(pm.nextPageInput, pm.nextPageText)) + len(pm.navText(pm.previousPageInput, pm.previousPageText)) + 2
+		base   = len(conPrefix) + 1 + len(header) + footer
+	)
+
+	for start := 0; start < len(items); {
+		var (
+			end    = start
+			length = base
+		)
+		for end < len(items) && end-start < pm.pageSize {
+			// Items are not given numbers the user enters to navigate
+			if end > start && pm.reserved(end-start+1) {
+				break
+			}
+			length += len(itemText(end-start+1, items[end])) + 1
+			// A page always has at least one item
+			if length > pm.maxLength && end > start {
+				break
+			}
+			end++
+		}
+		pages = append(pages, [2]int{start, end})
+		start = end
+	}
+
+	if len(pages) == 0 {
+		pages = append(pages, [2]int{0, 0})
+	}
+
+	return pages
+}
+
+func (pm *paginatedMenu) navText(input, text string) string {
+	return fmt.Sprintf("%s. %s", input, text)
+}
+
+func itemText(number int, item string) string {
+	return fmt.Sprintf("%d. %s", number, item)
+}
+
+// renderPage renders the page, returning the range of the items shown on it as start:end
+func (pm *paginatedMenu) renderPage(header string, items []string, page int) (SessionResponse, string) {
+	var (
+		pages = pm.pages(header, items)
+		lines = make([]string, 0, pm.pageSize+3)
+	)
+
+	if page >= len(pages) {
+		page = len(pages) - 1
+	}
+
+	if header != "" {
+		lines = append(lines, header)
+	}
+
+	start, end := pages[page][0], pages[page][1]
+	for i := start; i < end; i++ {
+		lines = append(lines, itemText(i-start+1, items[i]))
+	}
+
+	if page < len(pages)-1 {
+		lines = append(lines, pm.navText(pm.nextPageInput, pm.nextPageText))
+	}
+	if page > 0 {
+		lines = append(lines, pm.navText(pm.previousPageInput, pm.previousPageText))
+	}
+
+	return &sessionResponse{
+		response: strings.Join(lines, "\n"),
+		menuName: pm.menuName,
+	}, fmt.Sprintf("%d:%d", start, end)
+}
+
+// turnPage renders the next or previous page when the menu currently shown is paginated.
+//
+// It returns false if the current menu is not paginated or the input is not a page input.
+func (app *UssdApp) turnPage(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	name, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), currentMenuKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to get current menu: %v", err)
+	}
+
+	pt, ok := app.registry().menus[name].(pageTurner)
+	if !ok {
+		return nil, false, nil
+	}
+
+	sr, ok, err := pt.turnPage(ctx, payload)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	SkipSavingPayload(payload)
+
+	return sr, true, nil
+}
+
+// GetPaginatedItem returns the item selected by the user from the paginated menu with the given name.
+//
+// It should be called by the next menu of the paginated menu. It returns the index of the item in all the items.
+// Returns ErrFailedValidation if the input is not the number of an item on the page shown.
+func (app *UssdApp) GetPaginatedItem(ctx context.Context, payload UssdPayload, menuName string) (int, string, error) {
+	pm, ok := app.registry().menus[menuName].(*paginatedMenu)
+	if !ok {
+		return 0, "", fmt.Errorf("%w: paginated menu %s", ErrMenuNotExist, menuName)
+	}
+
+	items, vals, err := pm.stateFields(ctx, payload, pm.shownKey())
+	if err != nil {
+		return 0, "", err
+	}
+
+	var start, end int
+	_, err = fmt.Sscanf(vals[pm.shownKey()], "%d:%d", &start, &end)
+	if err != nil || start < 0 || end > len(items) {
+		return 0, "", fmt.Errorf("failed to get page shown of %s: %q", menuName, vals[pm.shownKey()])
+	}
+
+	number, err := strconv.Atoi(payload.UssdCurrentParam())
+	if err != nil || number < 1 || start+number > end {
+		return 0, "", fmt.Errorf("%w: invalid selection %s", ErrFailedValidation, payload.UssdCurrentParam())
+	}
+
+	return start + number - 1, items[start+number-1], nil
+}
@@ -0,0 +1,85 @@
+package ussdapp_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/gidyon/ussdapp/ussdtest"
+)
+
+func TestPaginatedMenu(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	items := make([]string, 0, 120)
+	for i := 1; i <= 120; i++ {
+		items = append(items, fmt.Sprintf("Item %d", i))
+	}
+
+	err := app.AddMenu(ussdapp.NewPaginatedMenu(app, &ussdapp.PaginatedMenuOptions{
+		MenuName:    "home",
+		NextMenu:    "selected",
+		MenuContent: text("CON Pick an item"),
+		Items:       items,
+		PageSize:    100,
+		MaxLength:   10000,
+	}))
+	if err != nil {
+		t.Fatalf("failed to add paginated menu: %v", err)
+	}
+
+	addMenus(t, app, &ussdapp.MenuOptions{
+		MenuName: "selected",
+		NextMenu: "home",
+		GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+			index, item, err := app.GetPaginatedItem(ctx, payload, "home")
+			if err != nil {
+				return app.PreviousMenuWithError(ctx, payload, m, "Invalid choice")
+			}
+			return m.ExecuteMenuArgs("en", index, item), nil
+		},
+		MenuContent: text("END Picked %d: %s"),
+	})
+
+	tests := []struct {
+		name   string
+		inputs []string
+		expect string
+	}{
+		// Item 98 would be read as the next page input, so the first page ends before it
+		{name: "first page", inputs: []string{"97"}, expect: "Picked 96: Item 97"},
+		{name: "next page", inputs: []string{"98", "1"}, expect: "Picked 97: Item 98"},
+		{name: "last page stays shown", inputs: []string{"98", "98", "3"}, expect: "Picked 99: Item 100"},
+		{name: "previous page", inputs: []string{"98", "99", "2"}, expect: "Picked 1: Item 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := ussdtest.NewSessionTester(t, app).Dial("*123#").Expect("97. Item 97")
+			for _, input := range tt.inputs {
+				st.Send(input)
+			}
+			st.ExpectExact(tt.expect).ExpectEnd()
+		})
+	}
+
+	// Items not on the page shown are refused
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").Send("98").Expect("1. Item 98").
+		Send("50").Expect("Invalid choice").ExpectContinue()
+}
+
+func TestPaginatedMenuReservedInputs(t *testing.T) {
+	app := ussdtest.NewApp(t, nil)
+
+	err := app.AddMenu(ussdapp.NewPaginatedMenu(app, &ussdapp.PaginatedMenuOptions{
+		MenuName:      "home",
+		NextMenu:      "home",
+		Items:         []string{"a", "b"},
+		NextPageInput: "1",
+	}))
+	if err == nil {
+		t.Fatal("expected a next page input of 1 to be refused")
+	}
+}
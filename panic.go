@@ -0,0 +1,68 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicHandlerFn is called with the value and stack of a panic recovered while processing a request, e.g to report
+// it to an error tracking service. The menu name is empty for panics outside of menus, such as in middlewares
+type PanicHandlerFn func(ctx context.Context, payload UssdPayload, menuName string, recovered interface{}, stack []byte)
+
+// PanicError is the error of a request that panicked. The user is shown the error menu, or the error message
+type PanicError struct {
+	// Menu is the menu that panicked. It is empty for panics outside of menus
+	Menu  string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Menu != "" {
+		return fmt.Sprintf("menu %s panicked: %v", e.Menu, e.Value)
+	}
+	return fmt.Sprintf("request panicked: %v", e.Value)
+}
+
+// recoverPanic recovers a panic into err as a PanicError, logging its stack and calling Options.PanicHandler.
+// It must be deferred directly
+func (app *UssdApp) recoverPanic(ctx context.Context, payload UssdPayload, menuName string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	perr := &PanicError{Menu: menuName, Value: r, Stack: debug.Stack()}
+
+	app.opt.Logger.Error("recovered panic", "session_id", payload.SessionId(), "menu", menuName, "panic", r,
+		"stack", string(perr.Stack))
+	app.metrics.panicked(menuName)
+
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.panic = perr.Error()
+	}
+
+	// Panics of menus are reported with the other errors of menus
+	if menuName == "" {
+		app.reportRequestError(ctx, payload, ErrorSourceRequest, "", perr)
+	}
+
+	if app.opt.PanicHandler != nil {
+		app.opt.PanicHandler(ctx, payload, menuName, r, perr.Stack)
+	}
+
+	*err = perr
+}
+
+// generateResponse generates the menu response, recovering panics of the menu
+func (app *UssdApp) generateResponse(ctx context.Context, payload UssdPayload, menu Menu) (sr SessionResponse, err error) {
+	defer app.recoverPanic(ctx, payload, menu.MenuName(), &err)
+	return menu.GenerateResponse(ctx, payload)
+}
+
+// runHandler runs the middlewares and menus of a request, recovering panics outside of menus
+func (app *UssdApp) runHandler(ctx context.Context, payload UssdPayload, handler HandlerFunc) (sr SessionResponse, err error) {
+	defer app.recoverPanic(ctx, payload, "", &err)
+	return handler(ctx, payload)
+}
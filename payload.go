@@ -2,10 +2,10 @@ package ussdapp
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // UssdPayload interface has getters for getting original session data for the ussd request.
@@ -38,6 +38,36 @@ type ussdPayloadInternal struct {
 	ValidationFailed bool   `json:"validation_failed,omitempty"`
 	Time             string `json:"time,omitempty"`
 	skip             bool
+	// incremental is set by gateways that send only the latest input, UssdParams is then joined to the earlier inputs
+	incremental bool
+	// conversation is set by messaging channels whose sessions are keyed by msisdn. The first message starts the
+	// session without being an input and the session is cleared once it ends
+	conversation bool
+	// sessionDuration overrides Options.SessionDuration when set
+	sessionDuration time.Duration
+	// homeMenu overrides the home menu of the app when set
+	homeMenu string
+	// dialedInputs are inputs dialed with the service code on a new session, see fastForward
+	dialedInputs []string
+	// jobs are enqueued once the response is written, see EnqueueJob
+	jobs []*Job
+	// duplicate is set on retries of a request answered with the earlier response, see Options.DedupWindow
+	duplicate bool
+	// traced is set on requests of msisdns traced with TraceMsisdn
+	traced bool
+	// receivedAt is when processing of the request started, for the duration in session logs
+	receivedAt time.Time
+	// httpStatus is the status of the response written by the built-in handler
+	httpStatus int
+	// panic is the panic recovered while processing the request, see Options.PanicHandler
+	panic string
+	// metadata is business context of the request saved in the session log, see SetPayloadMetadata
+	metadata map[string]interface{}
+	// segments of the msisdn, resolved once per request, see Options.SegmentResolver
+	segments         []string
+	segmentsResolved bool
+	// overflowPages is set when pages of an earlier response are saved in the session, see PaginateResponse
+	overflowPages bool
 }
 
 func (p *ussdPayload) SkipSaving() bool {
@@ -126,7 +156,8 @@ func UssdPayloadFromRequest(r *http.Request) UssdPayload {
 		p := &incomingUssd{}
 		err := json.NewDecoder(r.Body).Decode(p)
 		if err != nil {
-			fmt.Println(err)
+			// Invalid bodies give an empty payload
+			p = &incomingUssd{}
 		}
 
 		ussdStr, err := url.QueryUnescape(p.UssdString)
@@ -0,0 +1,200 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	phoneSaveStep         = "save"
+	defaultPhonePrompt    = "CON Enter phone number"
+	defaultPhoneCountry   = "KE"
+	defaultInvalidPhone   = "Enter a valid phone number"
+	minInternationalPhone = 8
+	maxInternationalPhone = 15
+)
+
+// phoneCountry is the calling code and the length of national numbers, without the trunk prefix 0, of a country
+type phoneCountry struct {
+	callingCode    string
+	nationalLength int
+}
+
+// phoneCountries are the countries phone numbers can be normalized for, by ISO 3166 code
+var phoneCountries = map[string]phoneCountry{
+	"BI": {"257", 8},
+	"ET": {"251", 9},
+	"GH": {"233", 9},
+	"KE": {"254", 9},
+	"MW": {"265", 9},
+	"NG": {"234", 10},
+	"RW": {"250", 9},
+	"TZ": {"255", 9},
+	"UG": {"256", 9},
+	"ZA": {"27", 9},
+	"ZM": {"260", 9},
+}
+
+// NormalizeMSISDN returns the phone number entered by a user in E.164 format, reading local formats as numbers of
+// the country, e.g for KE 0712345678, 712345678, 254712345678 and +254712345678 all return +254712345678.
+//
+// Numbers starting with + or 00 are read as international numbers. The country is an ISO 3166 code such as KE, UG,
+// TZ, RW, NG, GH or ZA.
+func NormalizeMSISDN(input, country string) (string, error) {
+	c, ok := phoneCountries[strings.ToUpper(country)]
+	if !ok {
+		return "", fmt.Errorf("unsupported phone number country %s", country)
+	}
+
+	number := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(input))
+
+	international := false
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number, international = number[1:], true
+	case strings.HasPrefix(number, "00"):
+		number, international = number[2:], true
+	}
+
+	if !isDigits(number) {
+		return "", fmt.Errorf("invalid phone number %s", input)
+	}
+
+	switch {
+	case international:
+		if !validInternationalNumber(number) {
+			return "", fmt.Errorf("invalid phone number %s", input)
+		}
+		return "+" + number, nil
+	case strings.HasPrefix(number, c.callingCode) && len(number) == len(c.callingCode)+c.nationalLength:
+		return "+" + number, nil
+	case strings.HasPrefix(number, "0") && len(number) == c.nationalLength+1:
+		return "+" + c.callingCode + number[1:], nil
+	case !strings.HasPrefix(number, "0") && len(number) == c.nationalLength:
+		return "+" + c.callingCode + number, nil
+	}
+
+	return "", fmt.Errorf("invalid phone number %s", input)
+}
+
+// validInternationalNumber checks the length of numbers of known countries, and that other numbers fit E.164
+func validInternationalNumber(number string) bool {
+	if strings.HasPrefix(number, "0") {
+		return false
+	}
+	for _, c := range phoneCountries {
+		if strings.HasPrefix(number, c.callingCode) {
+			return len(number) == len(c.callingCode)+c.nationalLength
+		}
+	}
+	return len(number) >= minInternationalPhone && len(number) <= maxInternationalPhone
+}
+
+// MSISDN validates that input is a phone number of the country in a format NormalizeMSISDN accepts
+func MSISDN(country string) Validator {
+	return func(input string) error {
+		if _, err := NormalizeMSISDN(input, country); err != nil {
+			return NewValidationError(defaultInvalidPhone)
+		}
+		return nil
+	}
+}
+
+// PhoneMenuOptions contains data for a menu that asks for a phone number
+type PhoneMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Prompt asks for the phone number, per language. Defaults to CON Enter phone number
+	Prompt Content
+	// Field is the session field the number is saved in, in E.164 format. Defaults to the menu name
+	Field string
+	// Country local numbers belong to. Defaults to the DefaultCountry of the app
+	Country string
+	// InvalidMessage is shown above the prompt for invalid numbers, per language
+	InvalidMessage Content
+	// NextMenu is rendered once the number is saved. Defaults to the home menu
+	NextMenu string
+}
+
+// AddPhoneMenu registers a menu that asks for a phone number and saves it in the session in E.164 format.
+//
+// Local formats such as 0712345678 are read as numbers of the country. The input is received by a menu named
+// <menu>:save, invalid numbers re-render the prompt with the invalid message.
+func (app *UssdApp) AddPhoneMenu(opt *PhoneMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing phone menu options")
+	case opt.MenuName == "":
+		return errors.New("missing phone menu name")
+	}
+
+	var (
+		saveMenu = fmt.Sprintf("%s:%s", opt.MenuName, phoneSaveStep)
+		nextMenu = firstVal(opt.NextMenu, app.homeMenu)
+		field    = firstVal(opt.Field, opt.MenuName)
+		country  = strings.ToUpper(firstVal(opt.Country, app.opt.DefaultCountry))
+	)
+	if _, ok := phoneCountries[country]; !ok {
+		return fmt.Errorf("phone menu %s has unsupported country %s", opt.MenuName, country)
+	}
+
+	prompt := opt.Prompt
+	if len(prompt) == 0 {
+		prompt = Content{app.opt.DefaultLanguage: defaultPhonePrompt}
+	}
+
+	messages := opt.InvalidMessage.clone()
+	if _, ok := messages[""]; !ok {
+		messages[""] = defaultInvalidPhone
+	}
+	invalid := NewError(CodeInvalidInput, messages, nil)
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName:    opt.MenuName,
+			NextMenu:    saveMenu,
+			ShortCut:    opt.ShortCut,
+			MenuContent: prompt,
+		}),
+		NewMenu(&MenuOptions{
+			MenuName: saveMenu,
+			NextMenu: nextMenu,
+			Validators: []Validator{func(input string) error {
+				if _, err := NormalizeMSISDN(input, country); err != nil {
+					return invalid
+				}
+				return nil
+			}},
+			GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+				msisdn, err := NormalizeMSISDN(payload.UssdCurrentParam(), country)
+				if err != nil {
+					return nil, err
+				}
+
+				err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), field, msisdn)
+				if err != nil {
+					return nil, fmt.Errorf("failed to save phone number: %v", err)
+				}
+
+				return app.ReplaceMenuWithName(ctx, nextMenu, payload)
+			},
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
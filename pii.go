@@ -0,0 +1,160 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	sensitiveInputsKey         = "sensitive_inputs"
+	defaultMsisdnVisibleDigits = 3
+	defaultRedactionText       = "***"
+	hashedMsisdnLength         = 13
+	defaultResponseTextLength  = 500
+	maxResponseTextLength      = 1000
+)
+
+// PIIPolicy protects personal data in session logs. It is applied before logs are queued for the log sinks
+type PIIPolicy struct {
+	// MaskMsisdn replaces the digits of msisdns with *, except the last MsisdnVisibleDigits
+	MaskMsisdn bool
+	// MsisdnVisibleDigits is the number of digits kept at the end of masked msisdns. Defaults to 3
+	MsisdnVisibleDigits int
+	// HashMsisdn replaces msisdns with their HMAC-SHA256 using HashKey, so that sessions of a user can still be
+	// grouped. The hex digest is cut to the length of the msisdn column. It takes precedence over MaskMsisdn
+	HashMsisdn bool
+	HashKey    []byte
+	// RedactInputs replaces all user inputs and ussd params with the redaction text
+	RedactInputs bool
+	// RedactionText replaces redacted inputs. Defaults to ***
+	RedactionText string
+}
+
+// sensitiveMenu is implemented by menus whose input must not be logged
+type sensitiveMenu interface {
+	SensitiveInput() bool
+}
+
+func isSensitive(m Menu) bool {
+	sm, ok := m.(sensitiveMenu)
+	return ok && sm.SensitiveInput()
+}
+
+// markSensitiveInput records the position of the current input in the ussd params of the session so that
+// it is redacted from this and later logs
+func (app *UssdApp) markSensitiveInput(ctx context.Context, payload UssdPayload) error {
+	positions, err := app.sensitiveInputs(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	positions = append(positions, len(strings.Split(payload.UssdParams(), "*"))-1)
+
+	return app.Session(payload).SetJSON(ctx, sensitiveInputsKey, positions)
+}
+
+// sensitiveInputs returns the positions of sensitive inputs in the ussd params of the session
+func (app *UssdApp) sensitiveInputs(ctx context.Context, payload UssdPayload) ([]int, error) {
+	positions := make([]int, 0)
+
+	err := app.Session(payload).GetJSON(ctx, sensitiveInputsKey, &positions)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+// protectLog redacts sensitive inputs in the log and applies the PII policy
+func (app *UssdApp) protectLog(ctx context.Context, payload UssdPayload, log *SessionRequest) {
+	policy := app.opt.PIIPolicy
+	if policy == nil {
+		policy = &PIIPolicy{}
+	}
+
+	redaction := firstVal(policy.RedactionText, defaultRedactionText)
+
+	if policy.RedactInputs {
+		if log.USSDParams != "" {
+			log.USSDParams = redaction
+		}
+		if log.UserInput != "" {
+			log.UserInput = redaction
+		}
+	} else if app.registry().sensitive {
+		positions, err := app.sensitiveInputs(ctx, payload)
+		if err != nil {
+			// Logging inputs that may be sensitive is worse than losing them
+			app.opt.Logger.Warn("failed to get sensitive inputs", "session_id", payload.SessionId(), "error", err)
+			log.USSDParams, log.UserInput = redaction, redaction
+		} else if len(positions) > 0 {
+			params := strings.Split(log.USSDParams, "*")
+			for _, i := range positions {
+				if i >= 0 && i < len(params) {
+					params[i] = redaction
+					if i == len(params)-1 {
+						log.UserInput = redaction
+					}
+				}
+			}
+			log.USSDParams = strings.Join(params, "*")
+		}
+	}
+
+	log.Msisdn = policy.logMsisdn(log.Msisdn)
+}
+
+// logMsisdn returns the msisdn as it is saved in session logs
+func (policy *PIIPolicy) logMsisdn(msisdn string) string {
+	switch {
+	case policy.HashMsisdn:
+		mac := hmac.New(sha256.New, policy.HashKey)
+		mac.Write([]byte(msisdn))
+		return hex.EncodeToString(mac.Sum(nil))[:hashedMsisdnLength]
+	case policy.MaskMsisdn:
+		return maskMsisdn(msisdn, policy.MsisdnVisibleDigits)
+	default:
+		return msisdn
+	}
+}
+
+// maskMsisdn replaces all but the last visible characters with *
+func maskMsisdn(msisdn string, visible int) string {
+	if visible <= 0 {
+		visible = defaultMsisdnVisibleDigits
+	}
+	if len(msisdn) <= visible {
+		return msisdn
+	}
+	return strings.Repeat("*", len(msisdn)-visible) + msisdn[len(msisdn)-visible:]
+}
+
+// logResponseText returns the text of the response saved in the session log when Options.LogResponseText is set,
+// cut to Options.ResponseTextLength characters
+func (app *UssdApp) logResponseText(sr SessionResponse) string {
+	if !app.opt.LogResponseText {
+		return ""
+	}
+
+	max := app.opt.ResponseTextLength
+	switch {
+	case max <= 0:
+		max = defaultResponseTextLength
+	case max > maxResponseTextLength:
+		max = maxResponseTextLength
+	}
+
+	text := []rune(sr.Response())
+	if len(text) > max {
+		text = text[:max]
+	}
+
+	return string(text)
+}
@@ -0,0 +1,153 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	languagePreference      = "language"
+	defaultPreferencesTTL   = 90 * 24 * time.Hour
+	defaultPreferencesTable = "ussd_preferences"
+)
+
+// PreferenceStore keeps preferences of users across sessions, keyed by msisdn
+type PreferenceStore interface {
+	// GetPreference returns the value of the preference. Returns ErrKeyNotFound if it is not set
+	GetPreference(ctx context.Context, msisdn, key string) (string, error)
+	// SavePreference sets the value of the preference
+	SavePreference(ctx context.Context, msisdn, key, value string) error
+}
+
+// loadPreferences copies preferences of a returning user into a new session
+func (app *UssdApp) loadPreferences(ctx context.Context, payload UssdPayload) error {
+	if app.opt.PreferenceStore == nil {
+		return nil
+	}
+
+	lang, err := app.opt.PreferenceStore.GetPreference(ctx, payload.Msisdn(), languagePreference)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil
+	default:
+		return fmt.Errorf("failed to get language preference: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.sessionKey(payload), languageKey, lang)
+	if err != nil {
+		return fmt.Errorf("failed to set session language: %v", err)
+	}
+
+	return nil
+}
+
+// NewCachePreferenceStore creates a preference store that keeps preferences of a msisdn in a hash in cache.
+//
+// The hash expires after ttl from the last update. Defaults to 90 days.
+func NewCachePreferenceStore(cache Cacher, appName string, ttl time.Duration) PreferenceStore {
+	if ttl <= 0 {
+		ttl = defaultPreferencesTTL
+	}
+	return &cachePreferenceStore{
+		cache:   cache,
+		appName: appName,
+		ttl:     ttl,
+	}
+}
+
+type cachePreferenceStore struct {
+	cache   Cacher
+	appName string
+	ttl     time.Duration
+}
+
+func (s *cachePreferenceStore) key(msisdn string) string {
+	return fmt.Sprintf("%s:preferences:%s", s.appName, msisdn)
+}
+
+func (s *cachePreferenceStore) GetPreference(ctx context.Context, msisdn, key string) (string, error) {
+	return s.cache.GetMapField(ctx, s.key(msisdn), key)
+}
+
+func (s *cachePreferenceStore) SavePreference(ctx context.Context, msisdn, key, value string) error {
+	err := s.cache.SetMapField(ctx, s.key(msisdn), key, value)
+	if err != nil {
+		return fmt.Errorf("failed to save preference: %v", err)
+	}
+
+	err = s.cache.Expire(ctx, s.key(msisdn), s.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to set preference expiration: %v", err)
+	}
+
+	return nil
+}
+
+// Preference is a user preference saved in the database
+type Preference struct {
+	Msisdn    string    `gorm:"primaryKey;type:varchar(13)"`
+	Key       string    `gorm:"primaryKey;type:varchar(50)"`
+	Value     string    `gorm:"type:varchar(500);not null"`
+	UpdatedAt time.Time `gorm:"not null;type:datetime(6)"`
+}
+
+func (*Preference) TableName() string {
+	return defaultPreferencesTable
+}
+
+// NewGormPreferenceStore creates a preference store that keeps preferences in the preferences table of the database.
+//
+// The table is created if it does not exist.
+func NewGormPreferenceStore(db *gorm.DB) (PreferenceStore, error) {
+	if db == nil {
+		return nil, errors.New("missing db")
+	}
+
+	err := db.AutoMigrate(&Preference{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to auto migrate %s table: %v", defaultPreferencesTable, err)
+	}
+
+	return &gormPreferenceStore{db: db}, nil
+}
+
+type gormPreferenceStore struct {
+	db *gorm.DB
+}
+
+func (s *gormPreferenceStore) GetPreference(ctx context.Context, msisdn, key string) (string, error) {
+	pref := &Preference{}
+
+	err := s.db.WithContext(ctx).Select("value").Where(&Preference{Msisdn: msisdn, Key: key}).First(pref).Error
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "", ErrKeyNotFound
+	default:
+		return "", fmt.Errorf("failed to get preference: %v", err)
+	}
+
+	return pref.Value, nil
+}
+
+func (s *gormPreferenceStore) SavePreference(ctx context.Context, msisdn, key, value string) error {
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "msisdn"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&Preference{
+		Msisdn: msisdn,
+		Key:    key,
+		Value:  value,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save preference: %v", err)
+	}
+
+	return nil
+}
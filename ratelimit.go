@@ -0,0 +1,83 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultRateLimitMessage = "END Too many requests. Please try again later"
+	rateLimitedMenuName     = "rate_limited"
+)
+
+// RateLimit limits the sessions a msisdn can start, counting them in the cache.
+//
+// A msisdn can start Burst sessions in each window, the time it takes to allow Burst sessions at RequestsPerMinute,
+// e.g 5 sessions every 5 minutes for 1 request a minute and a burst of 5. Only requests that start a session, i.e
+// dials of the service code, are counted. Later requests of a session are not limited, so users are not cut off in
+// the middle of a flow. The cache must implement IncrCacher.
+type RateLimit struct {
+	// RequestsPerMinute is the rate at which sessions are allowed, the number of dials a minute
+	RequestsPerMinute float64
+	// Burst is the number of dials allowed at once. Defaults to RequestsPerMinute, and is at least 1
+	Burst int
+	// Message is sent to msisdns that exceed the limit. Defaults to an END message asking the user to try later
+	Message string
+}
+
+func (app *UssdApp) rateLimitKey(payload UssdPayload) string {
+	return fmt.Sprintf("%s:ratelimit:%s", app.opt.AppName, payload.Msisdn())
+}
+
+// rateLimit counts requests that start a session in the window of the msisdn, returning the rate limit message once
+// the msisdn has started burst sessions in the window
+func (app *UssdApp) rateLimit(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	limit := app.opt.RateLimit
+	if limit == nil || limit.RequestsPerMinute <= 0 {
+		return nil, false, nil
+	}
+
+	// Requests of sessions in progress are not counted
+	_, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), nextMenuKey)
+	switch {
+	case err == nil:
+		return nil, false, nil
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		return nil, false, fmt.Errorf("failed to get session for rate limit: %v", err)
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = int(limit.RequestsPerMinute)
+	}
+	// A msisdn can always start a session once a window
+	if burst < 1 {
+		burst = 1
+	}
+
+	window := time.Duration(float64(burst) / limit.RequestsPerMinute * float64(time.Minute))
+
+	// The counter is incremented atomically, so requests at the same instant are all counted
+	count, err := incr(ctx, app.opt.Cache, app.rateLimitKey(payload), window)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to count sessions for rate limit: %v", err)
+	}
+
+	if count <= int64(burst) {
+		return nil, false, nil
+	}
+
+	app.opt.Logger.Warn("msisdn exceeded the rate limit", "msisdn", payload.Msisdn())
+
+	SkipSavingPayload(payload)
+
+	sr := &sessionResponse{
+		response: firstVal(limit.Message, defaultRateLimitMessage),
+		menuName: rateLimitedMenuName,
+	}
+
+	return sr.End(), true, nil
+}
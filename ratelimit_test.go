@@ -0,0 +1,112 @@
+package ussdapp_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/gidyon/ussdapp/ussdtest"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   *ussdapp.RateLimit
+		dials   int
+		allowed int
+	}{
+		{name: "burst", limit: &ussdapp.RateLimit{RequestsPerMinute: 1, Burst: 3}, dials: 10, allowed: 3},
+		{name: "burst defaults to rate", limit: &ussdapp.RateLimit{RequestsPerMinute: 2}, dials: 10, allowed: 2},
+		{name: "burst is at least one", limit: &ussdapp.RateLimit{RequestsPerMinute: 0.5}, dials: 10, allowed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en", RateLimit: tt.limit})
+
+			addMenus(t, app, &ussdapp.MenuOptions{MenuName: "home", NextMenu: "home", MenuContent: text("CON Welcome")})
+
+			srv := httptest.NewServer(app.GatewayHandler(ussdapp.NewGenericAdapter()))
+			defer srv.Close()
+
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				allowed int
+			)
+
+			// Dials at the same instant are all counted
+			for i := 0; i < tt.dials; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					res := dial(t, srv.URL, "rate-limit-"+strconv.Itoa(i))
+
+					mu.Lock()
+					defer mu.Unlock()
+					if strings.Contains(res, "Welcome") {
+						allowed++
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			if allowed != tt.allowed {
+				t.Fatalf("expected %d dials to be allowed, got %d", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{
+		DefaultLanguage: "en",
+		// A burst of 2 every 500ms
+		RateLimit: &ussdapp.RateLimit{RequestsPerMinute: 240, Burst: 2},
+	})
+
+	addMenus(t, app, &ussdapp.MenuOptions{MenuName: "home", NextMenu: "home", MenuContent: text("CON Welcome")})
+
+	st := ussdtest.NewSessionTester(t, app)
+
+	st.Dial("*123#").Expect("Welcome").Send("1").Expect("Welcome")
+	st.Dial("*123#").Expect("Welcome")
+	st.Dial("*123#").Expect("Too many requests").ExpectEnd()
+
+	// Sessions are allowed again in the next window
+	time.Sleep(600 * time.Millisecond)
+	st.Dial("*123#").Expect("Welcome")
+}
+
+// dial starts a session on the generic gateway handler at srvURL, returning the response text
+func dial(t *testing.T, srvURL, sessionID string) string {
+	query := url.Values{
+		"SESSION_ID":   {sessionID},
+		"SERVICE_CODE": {"*123#"},
+		"MSISDN":       {"254700000000"},
+		"USSD_PARAMS":  {""},
+	}
+
+	res, err := http.Get(srvURL + "?" + query.Encode())
+	if err != nil {
+		t.Errorf("request failed: %v", err)
+		return ""
+	}
+	defer res.Body.Close()
+
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("failed to read response: %v", err)
+		return ""
+	}
+
+	return string(bs)
+}
@@ -0,0 +1,82 @@
+package ussdapp
+
+import (
+	"fmt"
+)
+
+// menuRegistry is a snapshot of the registered menus. Snapshots are not modified once stored, menus are added by
+// storing a new snapshot, so requests read menus without locking while menus are added at runtime.
+type menuRegistry struct {
+	menus map[string]Menu
+	// names are the menu names in registration order
+	names []string
+	// sensitive is set once a menu with sensitive input is added
+	sensitive bool
+	// versions are the versions of menus, see AddMenuVersion
+	versions map[string][]*menuVersion
+	// transitions are the transitions added with AddTransition, by the menu they go from
+	transitions map[string][]*Transition
+	// middlewares are the middlewares added with Use, the first one being the outermost
+	middlewares []Middleware
+	// flows are the names of flows added with AddFlow
+	flows []string
+}
+
+// registry returns the current snapshot of the registered menus
+func (app *UssdApp) registry() *menuRegistry {
+	return app.menuRegistry.Load().(*menuRegistry)
+}
+
+// getMenu returns the registered menu with the name
+func (app *UssdApp) getMenu(name string) (Menu, bool) {
+	m, ok := app.registry().menus[name]
+	return m, ok
+}
+
+// withMenu returns a copy of the registry with the menu added
+func (r *menuRegistry) withMenu(m Menu) (*menuRegistry, error) {
+	if _, ok := r.menus[m.MenuName()]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrMenuExist, m.MenuName())
+	}
+
+	next := &menuRegistry{
+		menus:       make(map[string]Menu, len(r.menus)+1),
+		names:       make([]string, 0, len(r.names)+1),
+		sensitive:   r.sensitive || isSensitive(m),
+		versions:    r.versions,
+		transitions: r.transitions,
+		middlewares: r.middlewares,
+		flows:       r.flows,
+	}
+	for name, menu := range r.menus {
+		next.menus[name] = menu
+	}
+	next.menus[m.MenuName()] = m
+	next.names = append(append(next.names, r.names...), m.MenuName())
+
+	return next, nil
+}
+
+// withMiddlewares returns a copy of the registry with the middlewares added after the existing ones
+func (r *menuRegistry) withMiddlewares(middlewares []Middleware) *menuRegistry {
+	next := *r
+	next.middlewares = append(append(make([]Middleware, 0, len(r.middlewares)+len(middlewares)), r.middlewares...), middlewares...)
+
+	return &next
+}
+
+// withVersions returns a copy of the registry with the versions of the menu replaced
+func (r *menuRegistry) withVersions(menuName string, versions []*menuVersion) *menuRegistry {
+	next := *r
+	next.versions = make(map[string][]*menuVersion, len(r.versions)+1)
+	for name, vs := range r.versions {
+		next.versions[name] = vs
+	}
+	next.versions[menuName] = versions
+
+	for _, v := range versions {
+		next.sensitive = next.sensitive || isSensitive(v.menu)
+	}
+
+	return &next
+}
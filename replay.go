@@ -0,0 +1,130 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReplayScript is a logged session that can be fed back through an app with Replay. It can be saved as json
+type ReplayScript struct {
+	SessionID string        `json:"session_id"`
+	Msisdn    string        `json:"msisdn"`
+	Steps     []*ReplayStep `json:"steps"`
+}
+
+// ReplayStep is a request of the session and the menu the app rendered for it
+type ReplayStep struct {
+	USSDParams string `json:"ussd_params"`
+	UserInput  string `json:"user_input"`
+	MenuName   string `json:"menu_name"`
+	Succeeded  bool   `json:"succeeded"`
+}
+
+// ReplayResult is the outcome of replaying a script
+type ReplayResult struct {
+	// SessionID is the id the session was replayed with
+	SessionID string
+	Steps     []*ReplayStepResult
+	// Mismatches is the number of steps that rendered a different menu or failed differently from the log
+	Mismatches int
+}
+
+// ReplayStepResult compares the response to a replayed request with the log
+type ReplayStepResult struct {
+	Step     *ReplayStep
+	MenuName string
+	Response string
+	Err      error
+	Mismatch bool
+}
+
+// ExportSessionScript reads the logs of a session from Options.SQLDB as a script for Replay
+func (app *UssdApp) ExportSessionScript(ctx context.Context, sessionID string) (*ReplayScript, error) {
+	if app.opt.SQLDB == nil {
+		return nil, errors.New("exporting sessions requires sql database")
+	}
+
+	logs := make([]*SessionRequest, 0)
+
+	err := app.opt.SQLDB.WithContext(ctx).Table(app.logsTable).
+		Select("session_id, msisdn, menu_name, ussd_params, user_input, succeeded").
+		Where("session_id = ?", sessionID).
+		Order("created_at, id").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session logs: %v", err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no logs for session %s", sessionID)
+	}
+
+	script := &ReplayScript{
+		SessionID: sessionID,
+		Msisdn:    logs[0].Msisdn,
+		Steps:     make([]*ReplayStep, 0, len(logs)),
+	}
+
+	for _, log := range logs {
+		script.Steps = append(script.Steps, &ReplayStep{
+			USSDParams: log.USSDParams,
+			UserInput:  log.UserInput,
+			MenuName:   log.MenuName,
+			Succeeded:  log.Succeeded,
+		})
+	}
+
+	return script, nil
+}
+
+// Replay sends the requests in the script through the app in a new session and compares the rendered menus with the log.
+//
+// Replay on an app whose cache is not used in production, since menus run with their side effects. Session logs are not saved.
+func (app *UssdApp) Replay(ctx context.Context, script *ReplayScript) (*ReplayResult, error) {
+	if script == nil || len(script.Steps) == 0 {
+		return nil, errors.New("empty replay script")
+	}
+
+	result := &ReplayResult{
+		SessionID: fmt.Sprintf("replay-%s-%d", script.SessionID, time.Now().UnixNano()),
+		Steps:     make([]*ReplayStepResult, 0, len(script.Steps)),
+	}
+
+	for _, step := range script.Steps {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		payload := &ussdPayload{
+			data: &ussdPayloadInternal{
+				SessionID:        result.SessionID,
+				Msisdn:           script.Msisdn,
+				UssdParams:       step.USSDParams,
+				UssdCurrentParam: step.UserInput,
+			},
+		}
+
+		stepResult := &ReplayStepResult{Step: step}
+
+		sr, err := app.ProcessPayload(ctx, payload)
+		if err != nil {
+			stepResult.Err = err
+			stepResult.Response = app.errorResponse(ctx, payload, err).Response()
+		} else {
+			stepResult.MenuName = sr.MenuName()
+			stepResult.Response = ussdResponseText(sr, payload.ValidationFailed())
+		}
+
+		succeeded := err == nil && !failedStatus(sr.Failed(), payload.ValidationFailed())
+		if stepResult.MenuName != step.MenuName || succeeded != step.Succeeded {
+			stepResult.Mismatch = true
+			result.Mismatches++
+		}
+
+		result.Steps = append(result.Steps, stepResult)
+	}
+
+	return result, nil
+}
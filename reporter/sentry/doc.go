@@ -0,0 +1,5 @@
+/*
+Package sentry implements an error reporter that sends errors of USSD apps to Sentry, or to services that accept
+Sentry events such as GlitchTip, with the sentry-go client.
+*/
+package sentryreporter
@@ -0,0 +1,104 @@
+package sentryreporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gidyon/ussdapp"
+)
+
+const (
+	defaultTimeout    = 5 * time.Second
+	defaultMaxPending = 100
+)
+
+// Options contains data required for the sentry reporter
+type Options struct {
+	// DSN is the client key url of the project, e.g https://<key>@o0.ingest.sentry.io/<project>
+	DSN         string
+	Environment string
+	Release     string
+	ServerName  string
+	// HTTPClient sends events. Defaults to a client with a 5 seconds timeout
+	HTTPClient *http.Client
+	// MaxPending is the number of events waiting to be sent, events reported beyond it are dropped. Defaults to 100
+	MaxPending int
+}
+
+type reporter struct {
+	client *sentry.Client
+}
+
+// NewSentryReporter creates an error reporter that sends errors to the Sentry project of the DSN. Events are sent in
+// the background, so reporting does not slow down requests
+func NewSentryReporter(opt *Options) (ussdapp.ErrorReporter, error) {
+	if opt == nil {
+		return nil, errors.New("missing sentry options")
+	}
+	if opt.DSN == "" {
+		return nil, errors.New("missing sentry dsn")
+	}
+
+	transport := sentry.NewHTTPTransport()
+	transport.Timeout = defaultTimeout
+	transport.BufferSize = opt.MaxPending
+	if transport.BufferSize <= 0 {
+		transport.BufferSize = defaultMaxPending
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         opt.DSN,
+		Environment: opt.Environment,
+		Release:     opt.Release,
+		ServerName:  opt.ServerName,
+		HTTPClient:  opt.HTTPClient,
+		Transport:   transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %v", err)
+	}
+
+	return &reporter{client: client}, nil
+}
+
+func (r *reporter) ReportError(ctx context.Context, report *ussdapp.ErrorReport) {
+	scope := sentry.NewScope()
+	scope.SetLevel(sentry.LevelError)
+	scope.SetTag("source", report.Source)
+
+	if report.Menu != "" {
+		scope.SetTag("menu", report.Menu)
+		scope.SetTransaction(report.Menu)
+	}
+	if report.Operation != "" {
+		scope.SetTag("operation", report.Operation)
+		if report.Menu == "" {
+			scope.SetTransaction(report.Operation)
+		}
+	}
+	if report.ServiceCode != "" {
+		scope.SetTag("service_code", report.ServiceCode)
+	}
+	if report.SessionID != "" {
+		scope.SetExtra("session_id", report.SessionID)
+	}
+	if report.Msisdn != "" {
+		scope.SetUser(sentry.User{ID: report.Msisdn})
+	}
+	if len(report.Stack) > 0 {
+		scope.SetLevel(sentry.LevelFatal)
+		scope.SetExtra("stack", string(report.Stack))
+	}
+	if !report.Time.IsZero() {
+		scope.AddEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			event.Timestamp = report.Time
+			return event
+		})
+	}
+
+	r.client.CaptureException(report.Err, &sentry.EventHint{Context: ctx}, scope)
+}
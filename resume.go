@@ -0,0 +1,265 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	resumeOfferedKey    = "resume_offered"
+	resumeMenuName      = "resume"
+	resumeInput         = "1"
+	defaultResumeWindow = 10 * time.Minute
+	defaultResumePrompt = "CON Continue where you left off?\n1. Yes\n2. No"
+)
+
+// resumeState is the session data kept for a user so that an expired session can be continued
+type resumeState struct {
+	Menu string            `json:"menu"`
+	Data map[string]string `json:"data"`
+}
+
+func (app *UssdApp) resumeKey(payload UssdPayload) string {
+	return fmt.Sprintf("%s:resume:%s", app.opt.AppName, payload.Msisdn())
+}
+
+// resumeFields are the session fields kept to resume a session: the menu the user was on, the menu stack and the
+// answers of flows. Login and lockout state is never kept, so a resumed session logs in again
+func (app *UssdApp) resumeFields() []string {
+	flows := app.registry().flows
+
+	fields := make([]string, 0, len(flows)+3)
+	fields = append(fields, currentMenuKey, currentPayload, menuStackKey)
+	for _, flowName := range flows {
+		fields = append(fields, flowKey(flowName))
+	}
+
+	return fields
+}
+
+// saveResumeState keeps the session data under the msisdn so that the flow can be resumed in a new session.
+//
+// The data is removed once the session ends or goes back to the home menu.
+func (app *UssdApp) saveResumeState(ctx context.Context, payload UssdPayload, sr SessionResponse) error {
+	if !app.opt.ResumeSession {
+		return nil
+	}
+
+	data, err := app.opt.Cache.GetMapFields(ctx, app.GetSessionKey(payload), append(app.resumeFields(), resumeOfferedKey)...)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return fmt.Errorf("failed to get session data: %v", err)
+	}
+
+	// The user has not answered the resume prompt yet
+	if data[resumeOfferedKey] != "" {
+		return nil
+	}
+
+	menu := data[currentMenuKey]
+	if sr.Terminal() || menu == "" || menu == app.homeMenuOf(payload) {
+		err = app.opt.Cache.Delete(ctx, app.resumeKey(payload))
+		if err != nil {
+			return fmt.Errorf("failed to delete resume data: %v", err)
+		}
+		return nil
+	}
+
+	// Fields not set in the session are not kept
+	for field, val := range data {
+		if field == resumeOfferedKey || val == "" {
+			delete(data, field)
+		}
+	}
+
+	bs, err := json.Marshal(&resumeState{Menu: menu, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume data: %v", err)
+	}
+
+	err = app.opt.Cache.Set(ctx, app.resumeKey(payload), string(bs), app.opt.ResumeWindow)
+	if err != nil {
+		return fmt.Errorf("failed to save resume data: %v", err)
+	}
+
+	return nil
+}
+
+// getResumeState reads session data kept for the msisdn. Returns ErrKeyNotFound if there is none
+func (app *UssdApp) getResumeState(ctx context.Context, payload UssdPayload) (*resumeState, error) {
+	val, err := app.opt.Cache.Get(ctx, app.resumeKey(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &resumeState{}
+	err = json.Unmarshal([]byte(val), state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resume data: %v", err)
+	}
+
+	return state, nil
+}
+
+// offerResume asks the user whether to continue an expired session, if the msisdn has one within the resume window.
+//
+// It returns false if there is no session to resume.
+func (app *UssdApp) offerResume(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if !app.opt.ResumeSession {
+		return nil, false, nil
+	}
+
+	state, err := app.getResumeState(ctx, payload)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, err
+	}
+
+	if _, ok := app.getMenu(state.Menu); !ok {
+		return nil, false, nil
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), resumeOfferedKey, "true", nextMenuKey, app.homeMenuOf(payload))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save resume prompt: %v", err)
+	}
+
+	SkipSavingPayload(payload)
+
+	return &sessionResponse{
+		response: app.opt.ResumePrompt,
+		menuName: resumeMenuName,
+	}, true, nil
+}
+
+// resume handles the answer to the resume prompt, restoring the expired session and rendering the menu
+// the user was on, or starting afresh from the home menu.
+//
+// It returns false if the user was not asked to resume.
+func (app *UssdApp) resume(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if !app.opt.ResumeSession {
+		return nil, false, nil
+	}
+
+	sessionKey := app.GetSessionKey(payload)
+
+	_, err := app.opt.Cache.GetMapField(ctx, sessionKey, resumeOfferedKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to get resume prompt: %v", err)
+	}
+
+	err = app.opt.Cache.DeleteMapField(ctx, sessionKey, resumeOfferedKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to clear resume prompt: %v", err)
+	}
+
+	state, err := app.getResumeState(ctx, payload)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		state = nil
+	default:
+		return nil, false, err
+	}
+
+	if payload.UssdCurrentParam() != resumeInput || state == nil {
+		err = app.opt.Cache.Delete(ctx, app.resumeKey(payload))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to delete resume data: %v", err)
+		}
+
+		sr, err := app.navigateHome(ctx, payload)
+		return sr, true, err
+	}
+
+	sr, err := app.restoreSession(ctx, payload, state)
+	if err != nil {
+		return nil, false, err
+	}
+
+	SkipSavingPayload(payload)
+
+	return sr, true, nil
+}
+
+// restoreSession copies the resumed session data into the current session and renders the menu the user was on
+func (app *UssdApp) restoreSession(ctx context.Context, payload UssdPayload, state *resumeState) (SessionResponse, error) {
+	menu, ok := app.getMenu(state.Menu)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMenuNotExist, state.Menu)
+	}
+
+	// Payloads kept in the data belong to the expired session
+	menuPayload, err := rebindPayload([]byte(state.Data[currentPayload]), payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resumed payload: %v", err)
+	}
+
+	stack := make([]*menuStackEntry, 0)
+	if val := state.Data[menuStackKey]; val != "" {
+		err = json.Unmarshal([]byte(val), &stack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal resumed menu stack: %v", err)
+		}
+	}
+	for _, entry := range stack {
+		p, err := rebindPayload(entry.Payload, payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get resumed payload: %v", err)
+		}
+		entry.Payload, err = p.JSON()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the fields that are kept to resume a session are restored, see resumeFields
+	fields := make(map[string]interface{}, len(state.Data))
+	for _, field := range app.resumeFields() {
+		switch val, ok := state.Data[field]; {
+		case !ok, field == currentPayload, field == menuStackKey:
+		default:
+			fields[field] = val
+		}
+	}
+
+	if len(fields) > 0 {
+		err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore session data: %v", err)
+		}
+	}
+
+	err = app.saveMenuStack(ctx, payload, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.ReplaceMenu(ctx, menuPayload, menu)
+}
+
+// rebindPayload reads a payload saved in an earlier session and moves it to the session of payload
+func rebindPayload(bs []byte, payload UssdPayload) (UssdPayload, error) {
+	if len(bs) == 0 {
+		bs = []byte("{}")
+	}
+
+	p, err := UssdPayloadFromJSON(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	p.(*ussdPayload).data.SessionID = payload.SessionId()
+	p.(*ussdPayload).data.Msisdn = payload.Msisdn()
+
+	return p, nil
+}
@@ -0,0 +1,320 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetentionInterval  = time.Hour
+	defaultRetentionBatchSize = 1000
+	defaultPartitionsAhead    = 7
+	logPartitionPrefix        = "p"
+	logPartitionLayout        = "20060102"
+)
+
+// LogRetention prunes session logs in the logs table of Options.SQLDB once they are older than MaxAge
+type LogRetention struct {
+	// MaxAge is how long logs are kept
+	MaxAge time.Duration
+	// Interval is how often old logs are pruned. Defaults to an hour
+	Interval time.Duration
+	// BatchSize is the number of logs read and deleted at a time. Defaults to 1000
+	BatchSize int
+	// Archiver receives logs before they are deleted when set, e.g a file sink from NewFileLogSink. Logs are not
+	// deleted when archiving them fails
+	Archiver LogSink
+	// Partitioned drops daily partitions older than MaxAge instead of deleting rows, which is much cheaper for large
+	// tables. The worker adds partitions for the coming week on each run, see PartitionLogsTable. MySQL only
+	Partitioned bool
+}
+
+func (r *LogRetention) interval() time.Duration {
+	if r.Interval <= 0 {
+		return defaultRetentionInterval
+	}
+	return r.Interval
+}
+
+func (r *LogRetention) batchSize() int {
+	if r.BatchSize <= 0 {
+		return defaultRetentionBatchSize
+	}
+	return r.BatchSize
+}
+
+// logsRetentionWorker prunes logs older than Options.LogRetention until the app is closed
+func (app *UssdApp) logsRetentionWorker(ctx context.Context) {
+	defer app.workers.Done()
+
+	ticker := time.NewTicker(app.opt.LogRetention.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.stop:
+			return
+		case <-ticker.C:
+		}
+
+		n, err := app.PruneLogs(ctx)
+		if err != nil {
+			app.opt.Logger.Error("failed to prune session logs", "error", err)
+			app.reportWorkerError("prune_logs", err)
+		}
+		if n > 0 {
+			app.opt.Logger.Info("pruned session logs", "count", n)
+		}
+	}
+}
+
+// PruneLogs deletes, or archives then deletes, logs older than Options.LogRetention and returns the number of logs
+// pruned. It is called by a background worker and may be called to prune logs at once, e.g from a cron job
+func (app *UssdApp) PruneLogs(ctx context.Context) (int, error) {
+	retention := app.opt.LogRetention
+	switch {
+	case app.opt.SQLDB == nil:
+		return 0, errors.New("pruning logs requires sql database")
+	case retention == nil || retention.MaxAge <= 0:
+		return 0, errors.New("missing log retention max age")
+	}
+
+	cutoff := time.Now().Add(-retention.MaxAge)
+
+	if retention.Partitioned {
+		err := app.PartitionLogsTable(ctx, defaultPartitionsAhead)
+		if err != nil {
+			return 0, err
+		}
+		return app.dropLogPartitions(ctx, cutoff)
+	}
+
+	return app.deleteLogs(ctx, cutoff)
+}
+
+// deleteLogs deletes logs created before cutoff in batches, archiving each batch first
+func (app *UssdApp) deleteLogs(ctx context.Context, cutoff time.Time) (int, error) {
+	var (
+		retention = app.opt.LogRetention
+		batchSize = retention.batchSize()
+		pruned    = 0
+	)
+
+	for {
+		logs := make([]*SessionRequest, 0, batchSize)
+
+		err := app.opt.SQLDB.WithContext(ctx).Table(app.logsTable).
+			Where("created_at < ?", cutoff).
+			Order("created_at, id").
+			Limit(batchSize).
+			Find(&logs).Error
+		if err != nil {
+			return pruned, fmt.Errorf("failed to get aged session logs: %v", err)
+		}
+		if len(logs) == 0 {
+			return pruned, nil
+		}
+
+		if retention.Archiver != nil {
+			err = retention.Archiver.Write(ctx, logs)
+			if err != nil {
+				return pruned, fmt.Errorf("failed to archive session logs: %v", err)
+			}
+		}
+
+		ids := make([]uint, 0, len(logs))
+		for _, log := range logs {
+			ids = append(ids, log.ID)
+		}
+
+		err = app.opt.SQLDB.WithContext(ctx).Table(app.logsTable).
+			Where("id IN ? AND created_at < ?", ids, cutoff).
+			Delete(&SessionRequest{}).Error
+		if err != nil {
+			return pruned, fmt.Errorf("failed to delete aged session logs: %v", err)
+		}
+
+		pruned += len(logs)
+
+		if len(logs) < batchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// logPartitionName is the name of the partition holding logs of the day
+func logPartitionName(day time.Time) string {
+	return logPartitionPrefix + day.Format(logPartitionLayout)
+}
+
+// logPartitionDay is the day of a partition created by PartitionLogsTable
+func logPartitionDay(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, logPartitionPrefix) {
+		return time.Time{}, false
+	}
+	day, err := time.ParseInLocation(logPartitionLayout, strings.TrimPrefix(name, logPartitionPrefix), time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// logPartitions returns the partitions of the logs table, oldest first
+func (app *UssdApp) logPartitions(ctx context.Context) ([]string, error) {
+	names := make([]string, 0)
+
+	err := app.opt.SQLDB.WithContext(ctx).Raw(
+		"SELECT PARTITION_NAME FROM information_schema.PARTITIONS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL "+
+			"ORDER BY PARTITION_ORDINAL_POSITION", app.logsTable,
+	).Scan(&names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions of %s table: %v", app.logsTable, err)
+	}
+
+	return names, nil
+}
+
+// PartitionLogsTable partitions the logs table in Options.SQLDB by day and adds partitions for today and the days
+// ahead. Tables that are not partitioned yet are partitioned on the first call, which rewrites the table and should
+// be done while traffic is low. MySQL only.
+//
+// Logs are partitioned by the UTC day of created_at, and the worker of Options.LogRetention calls it on each run
+// when partitioning is on
+func (app *UssdApp) PartitionLogsTable(ctx context.Context, days int) error {
+	if app.opt.SQLDB == nil {
+		return errors.New("partitioning logs requires sql database")
+	}
+	if days < 0 {
+		days = 0
+	}
+
+	existing, err := app.logPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		today = time.Now().UTC().Truncate(24 * time.Hour)
+		from  = today
+	)
+
+	// Partitions are only added after the newest one since ranges must increase
+	if len(existing) > 0 {
+		newest, ok := logPartitionDay(existing[len(existing)-1])
+		if !ok {
+			return fmt.Errorf("%s table has partitions not created by the app", app.logsTable)
+		}
+		from = newest.AddDate(0, 0, 1)
+	}
+
+	defs := make([]string, 0, days+1)
+	for day := from; !day.After(today.AddDate(0, 0, days)); day = day.AddDate(0, 0, 1) {
+		defs = append(defs, fmt.Sprintf("PARTITION %s VALUES LESS THAN (TO_DAYS('%s'))",
+			logPartitionName(day), day.AddDate(0, 0, 1).Format("2006-01-02")))
+	}
+	if len(defs) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD PARTITION (%s)", app.logsTable, strings.Join(defs, ", "))
+	if len(existing) == 0 {
+		// The partition of today also holds logs older than today
+		query = fmt.Sprintf("ALTER TABLE %s PARTITION BY RANGE (TO_DAYS(created_at)) (%s)", app.logsTable, strings.Join(defs, ", "))
+	}
+
+	err = app.opt.SQLDB.WithContext(ctx).Exec(query).Error
+	if err != nil {
+		return fmt.Errorf("failed to partition %s table: %v", app.logsTable, err)
+	}
+
+	return nil
+}
+
+// dropLogPartitions drops the partitions of days before cutoff, archiving their logs first
+func (app *UssdApp) dropLogPartitions(ctx context.Context, cutoff time.Time) (int, error) {
+	existing, err := app.logPartitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// A partition is dropped once its whole day is older than cutoff. Partitions are listed oldest first
+	days := make([]time.Time, 0, len(existing))
+	for _, name := range existing {
+		day, ok := logPartitionDay(name)
+		if ok && !day.AddDate(0, 0, 1).After(cutoff) {
+			days = append(days, day)
+		}
+	}
+
+	pruned := 0
+	for _, day := range days {
+		n, err := app.archivePartition(ctx, day)
+		if err != nil {
+			return pruned, err
+		}
+
+		err = app.opt.SQLDB.WithContext(ctx).
+			Exec(fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", app.logsTable, logPartitionName(day))).Error
+		if err != nil {
+			return pruned, fmt.Errorf("failed to drop partition %s: %v", logPartitionName(day), err)
+		}
+
+		pruned += n
+	}
+
+	return pruned, nil
+}
+
+// archivePartition writes the logs of the partition of the day to the archiver and returns the number of logs in it
+func (app *UssdApp) archivePartition(ctx context.Context, day time.Time) (int, error) {
+	var (
+		retention = app.opt.LogRetention
+		batchSize = retention.batchSize()
+		count     = 0
+	)
+
+	if retention.Archiver == nil {
+		var n int64
+		err := app.opt.SQLDB.WithContext(ctx).Table(fmt.Sprintf("%s PARTITION (%s)", app.logsTable, logPartitionName(day))).
+			Count(&n).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to count logs of partition %s: %v", logPartitionName(day), err)
+		}
+		return int(n), nil
+	}
+
+	var lastID uint
+	for {
+		logs := make([]*SessionRequest, 0, batchSize)
+
+		err := app.opt.SQLDB.WithContext(ctx).Table(fmt.Sprintf("%s PARTITION (%s)", app.logsTable, logPartitionName(day))).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(batchSize).
+			Find(&logs).Error
+		if err != nil {
+			return count, fmt.Errorf("failed to get logs of partition %s: %v", logPartitionName(day), err)
+		}
+		if len(logs) == 0 {
+			return count, nil
+		}
+
+		err = retention.Archiver.Write(ctx, logs)
+		if err != nil {
+			return count, fmt.Errorf("failed to archive session logs: %v", err)
+		}
+
+		count += len(logs)
+		lastID = logs[len(logs)-1].ID
+
+		if len(logs) < batchSize {
+			return count, nil
+		}
+	}
+}
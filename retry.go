@@ -0,0 +1,225 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultCacheRetryAttempts = 3
+	defaultCacheRetryBackoff  = 20 * time.Millisecond
+	defaultCacheRetryMaxWait  = 200 * time.Millisecond
+)
+
+// CacheRetry retries cache operations that fail with a transient error, waiting longer after each attempt
+type CacheRetry struct {
+	// MaxAttempts is the number of times an operation is tried, including the first. Defaults to 3
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles for each retry after it. Defaults to 20ms
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest wait between retries. Defaults to 200ms
+	MaxBackoff time.Duration
+	// Retryable reports whether an error is transient. Defaults to IsTransientCacheError
+	Retryable func(error) bool
+}
+
+// IsTransientCacheError reports whether a cache error is likely to go away when the operation is tried again,
+// such as network errors, timeouts and redis servers that are loading, failing over or read only.
+//
+// Missing keys, cancelled contexts and other errors are permanent. Cachers can classify their own errors by
+// returning errors with a Transient() bool method.
+func IsTransientCacheError(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrKeyNotFound), errors.Is(err, ErrValueNotFound):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+
+	var te interface{ Transient() bool }
+	if errors.As(err, &te) {
+		return te.Transient()
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	// Redis replies during failovers and restarts
+	msg := err.Error()
+	for _, prefix := range []string{"LOADING ", "READONLY ", "MASTERDOWN ", "CLUSTERDOWN ", "TRYAGAIN"} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryingCacher retries cache operations that fail with a transient error
+type retryingCacher struct {
+	Cacher
+	retry CacheRetry
+}
+
+func newRetryingCacher(cache Cacher, retry *CacheRetry) *retryingCacher {
+	c := &retryingCacher{Cacher: cache, retry: *retry}
+	if c.retry.MaxAttempts <= 0 {
+		c.retry.MaxAttempts = defaultCacheRetryAttempts
+	}
+	if c.retry.InitialBackoff <= 0 {
+		c.retry.InitialBackoff = defaultCacheRetryBackoff
+	}
+	if c.retry.MaxBackoff <= 0 {
+		c.retry.MaxBackoff = defaultCacheRetryMaxWait
+	}
+	if c.retry.Retryable == nil {
+		c.retry.Retryable = IsTransientCacheError
+	}
+	return c
+}
+
+// do runs op until it succeeds, fails with a permanent error or runs out of attempts
+func (c *retryingCacher) do(ctx context.Context, op func() error) error {
+	backoff := c.retry.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= c.retry.MaxAttempts || !c.retry.Retryable(err) {
+			return err
+		}
+
+		// Full jitter between half and the whole backoff spreads out retries of concurrent requests
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+}
+
+func (c *retryingCacher) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.Set(ctx, key, value, dur)
+	})
+}
+
+func (c *retryingCacher) Get(ctx context.Context, key string) (val string, err error) {
+	err = c.do(ctx, func() error {
+		val, err = c.Cacher.Get(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+func (c *retryingCacher) Delete(ctx context.Context, key string) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.Delete(ctx, key)
+	})
+}
+
+func (c *retryingCacher) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.SetMap(ctx, key, fields)
+	})
+}
+
+func (c *retryingCacher) GetMap(ctx context.Context, key string) (val map[string]string, err error) {
+	err = c.do(ctx, func() error {
+		val, err = c.Cacher.GetMap(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+func (c *retryingCacher) DeleteMap(ctx context.Context, key string) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.DeleteMap(ctx, key)
+	})
+}
+
+func (c *retryingCacher) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.SetMapField(ctx, key, values...)
+	})
+}
+
+func (c *retryingCacher) GetMapField(ctx context.Context, key, field string) (val string, err error) {
+	err = c.do(ctx, func() error {
+		val, err = c.Cacher.GetMapField(ctx, key, field)
+		return err
+	})
+	return val, err
+}
+
+func (c *retryingCacher) GetMapFields(ctx context.Context, key string, fields ...string) (val map[string]string, err error) {
+	err = c.do(ctx, func() error {
+		val, err = c.Cacher.GetMapFields(ctx, key, fields...)
+		return err
+	})
+	return val, err
+}
+
+func (c *retryingCacher) DeleteMapField(ctx context.Context, key string, fields ...string) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.DeleteMapField(ctx, key, fields...)
+	})
+}
+
+func (c *retryingCacher) SetUnique(ctx context.Context, key string, value string) (ok bool, err error) {
+	err = c.do(ctx, func() error {
+		ok, err = addToSet(ctx, c.Cacher, key, value)
+		return err
+	})
+	return ok, err
+}
+
+// Incr is not retried, a request that failed may have counted
+func (c *retryingCacher) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	return incr(ctx, c.Cacher, key, dur)
+}
+
+func (c *retryingCacher) ExistInSet(ctx context.Context, key string, value string) (ok bool, err error) {
+	err = c.do(ctx, func() error {
+		ok, err = c.Cacher.ExistInSet(ctx, key, value)
+		return err
+	})
+	return ok, err
+}
+
+func (c *retryingCacher) DeleteSetValue(ctx context.Context, key string, value string) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.DeleteSetValue(ctx, key, value)
+	})
+}
+
+func (c *retryingCacher) Expire(ctx context.Context, key string, dur time.Duration) error {
+	return c.do(ctx, func() error {
+		return c.Cacher.Expire(ctx, key, dur)
+	})
+}
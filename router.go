@@ -0,0 +1,241 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Router serves several apps, or several home menus of an app, from one process. Each request goes to the route
+// whose dial string is the longest prefix of the string dialed by the user.
+//
+// A dial string is a service code optionally followed by inputs, e.g *123# or *123*5#. Dialing *123*5*1# matches
+// both, and is served by the route of *123*5#.
+//
+// Apps are tenants isolated from each other: each has its own menus, cache keys prefixed with its app name and
+// session logs table. Handle refuses apps that would share cache keys or a logs table.
+type Router struct {
+	gateway GatewayAdapter
+	mu      sync.RWMutex
+	// routes are sorted by the number of parts of their dial string, longest first
+	routes []*route
+}
+
+// dialStringKey is the session field with the dial string of the route a session started on
+const dialStringKey = "dial_string"
+
+type route struct {
+	dialString string
+	parts      []string
+	app        *UssdApp
+	homeMenu   string
+}
+
+// NewRouter creates a router for apps whose requests come from the gateway
+func NewRouter(gateway GatewayAdapter) *Router {
+	if gateway == nil {
+		gateway = NewGenericAdapter()
+	}
+	return &Router{gateway: gateway, routes: make([]*route, 0)}
+}
+
+// Handle serves the app for requests matching the dial string, e.g *123#. An app may handle several dial strings.
+//
+// Inputs in the dial string after the service code select the route and are removed from the request, so the
+// app starts on its home menu when *123*5# is dialed.
+func (r *Router) Handle(dialString string, app *UssdApp) error {
+	return r.HandleHomeMenu(dialString, app, "")
+}
+
+// HandleHomeMenu serves the app starting on homeMenu instead of its home menu for requests matching the dial string.
+//
+// Use it to serve separate flows of one app on different service codes.
+func (r *Router) HandleHomeMenu(dialString string, app *UssdApp, homeMenu string) error {
+	parts := dialParts(dialString)
+	switch {
+	case len(parts) == 0:
+		return errors.New("missing dial string")
+	case app == nil:
+		return errors.New("missing app")
+	}
+
+	if homeMenu != "" {
+		if _, ok := app.getMenu(homeMenu); !ok {
+			return fmt.Errorf("%w: home menu %s", ErrMenuNotExist, homeMenu)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.routes {
+		if strings.Join(other.parts, "*") == strings.Join(parts, "*") {
+			return fmt.Errorf("dial string %s is handled", dialString)
+		}
+		err := app.checkIsolation(other.app)
+		if err != nil {
+			return fmt.Errorf("app of %s is not isolated from the app of %s: %v", dialString, other.dialString, err)
+		}
+	}
+
+	rt := &route{dialString: dialString, parts: parts, app: app, homeMenu: homeMenu}
+
+	i := 0
+	for i < len(r.routes) && len(r.routes[i].parts) >= len(parts) {
+		i++
+	}
+	r.routes = append(r.routes[:i], append([]*route{rt}, r.routes[i:]...)...)
+
+	return nil
+}
+
+// App returns the app handling the dial string
+func (r *Router) App(dialString string) (*UssdApp, bool) {
+	routes := r.match(dialParts(dialString))
+	if len(routes) == 0 {
+		return nil, false
+	}
+	return routes[0].app, true
+}
+
+// match returns the routes whose dial string is a prefix of the dialed parts, longest first
+func (r *Router) match(dialed []string) []*route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]*route, 0, 1)
+	for _, rt := range r.routes {
+		if hasPrefixParts(dialed, rt.parts) {
+			routes = append(routes, rt)
+		}
+	}
+
+	return routes
+}
+
+// route finds the route of the payload, removing the inputs in the dial string of the route from the payload
+func (r *Router) route(ctx context.Context, payload UssdPayload) (*UssdApp, bool) {
+	var (
+		codeParts = dialParts(payload.ServiceCode())
+		inputs    = make([]string, 0)
+		p, ok     = payload.(*ussdPayload)
+	)
+	// Gateways that send the latest input only do not repeat the dial string in later requests, so only
+	// their service code is matched
+	if payload.UssdParams() != "" && !(ok && p.data.incremental) {
+		inputs = strings.Split(payload.UssdParams(), "*")
+	}
+
+	routes := r.match(append(append([]string{}, codeParts...), inputs...))
+	if len(routes) == 0 {
+		return nil, false
+	}
+
+	rt := routes[0]
+	if len(routes) > 1 {
+		rt = r.sessionRoute(ctx, payload, routes)
+	}
+
+	if !ok {
+		return rt.app, true
+	}
+
+	p.data.homeMenu = rt.homeMenu
+
+	if consumed := len(rt.parts) - len(codeParts); consumed > 0 && consumed <= len(inputs) {
+		inputs = inputs[consumed:]
+		p.data.UssdParams = strings.Join(inputs, "*")
+		p.data.UssdCurrentParam = ""
+		if len(inputs) > 0 {
+			p.data.UssdCurrentParam = strings.TrimSpace(inputs[len(inputs)-1])
+		}
+	}
+
+	return rt.app, true
+}
+
+// sessionRoute returns the route a session in progress started on, so that its inputs are not mistaken for a
+// longer dial string. New sessions take the longest route, which is saved in the session.
+func (r *Router) sessionRoute(ctx context.Context, payload UssdPayload, routes []*route) *route {
+	for _, rt := range routes {
+		dialString, err := rt.app.opt.Cache.GetMapField(ctx, rt.app.GetSessionKey(payload), dialStringKey)
+		if err == nil && dialString == rt.dialString {
+			return rt
+		}
+	}
+
+	// Sessions that started on the shortest route had no other route to choose from, so it was not saved
+	shortest := routes[len(routes)-1]
+	_, err := shortest.app.opt.Cache.GetMapField(ctx, shortest.app.GetSessionKey(payload), nextMenuKey)
+	if err == nil {
+		return shortest
+	}
+
+	var (
+		rt         = routes[0]
+		sessionKey = rt.app.GetSessionKey(payload)
+	)
+
+	err = rt.app.opt.Cache.SetMapField(ctx, sessionKey, dialStringKey, rt.dialString)
+	if err == nil {
+		err = rt.app.opt.Cache.Expire(ctx, sessionKey, rt.app.sessionDuration(payload))
+	}
+	if err != nil {
+		rt.app.opt.Logger.Error("failed to save route of session", "session_id", payload.SessionId(), "error", err)
+	}
+
+	return rt
+}
+
+// ProcessPayload runs the menus of the app routed to by the payload
+func (r *Router) ProcessPayload(ctx context.Context, payload UssdPayload) (SessionResponse, error) {
+	app, ok := r.route(ctx, payload)
+	if !ok {
+		return nil, fmt.Errorf("no app handles service code %s", payload.ServiceCode())
+	}
+	return app.ProcessPayload(ctx, payload)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := r.gateway.ParseRequest(req)
+	if errors.Is(err, ErrSkipRequest) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		http.Error(w, "bad ussd request", http.StatusBadRequest)
+		return
+	}
+
+	app, ok := r.route(req.Context(), payload)
+	if !ok {
+		http.Error(w, "unknown service code", http.StatusNotFound)
+		return
+	}
+
+	app.servePayload(req.Context(), r.gateway, w, payload)
+}
+
+// dialParts splits a dial string like *123*5# into its parts
+func dialParts(dialString string) []string {
+	dialString = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(dialString), "*"), "#")
+	if dialString == "" {
+		return nil
+	}
+	return strings.Split(dialString, "*")
+}
+
+func hasPrefixParts(parts, prefix []string) bool {
+	if len(prefix) > len(parts) {
+		return false
+	}
+	for i := range prefix {
+		if parts[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	secureInputVerifyStep      = "verify"
+	defaultMaxAttempts         = 3
+	defaultLockoutDuration     = 30 * time.Minute
+	defaultWrongInputMessage   = "Wrong PIN, %d attempts left"
+	defaultLockedOutMessage    = "END Too many wrong attempts. Please try again later"
+	defaultSecureInputPrompt   = "CON Enter PIN"
+	secureInputLockedMenuName  = "locked_out"
+	secureInputAttemptsKeyName = "attempts"
+	secureInputLockKeyName     = "lockout"
+)
+
+// SecureInputCheckFn verifies a PIN or password entered by the user
+type SecureInputCheckFn func(ctx context.Context, payload UssdPayload, input string) (bool, error)
+
+// SecureInputOptions contains data for a menu that collects a PIN or password
+type SecureInputOptions struct {
+	MenuName string
+	ShortCut string
+	// Prompt asks for the input, per language. Defaults to CON Enter PIN
+	Prompt Content
+	// Check verifies the input. Use CheckPIN to compare it with a hash from HashPIN
+	Check SecureInputCheckFn
+	// NextMenu is rendered once the input is verified. Defaults to the home menu
+	NextMenu string
+	// MaxAttempts is the number of wrong inputs after which the msisdn is locked out. Defaults to 3
+	MaxAttempts int
+	// LockoutDuration is how long the msisdn is locked out. Wrong inputs are also forgotten after it. Defaults to 30 minutes
+	LockoutDuration time.Duration
+	// WrongInputMessage is shown above the prompt after a wrong input, per language. A %d verb is replaced with the attempts left
+	WrongInputMessage Content
+	// LockedOutMessage ends the session of locked out msisdns, per language
+	LockedOutMessage Content
+	// Login logs the session in once the input is verified, rendering the menu that required login instead of
+	// the next menu. See Options.LoginMenu
+	Login bool
+}
+
+// AddSecureInputMenu registers a menu that asks for a PIN or password and renders the next menu once it is verified.
+//
+// The input is received by a menu named <menu>:verify and is redacted from session logs. Failed attempts are counted
+// in the cache per msisdn, and the msisdn is locked out after too many. The cache must implement IncrCacher.
+func (app *UssdApp) AddSecureInputMenu(opt *SecureInputOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing secure input options")
+	case opt.MenuName == "":
+		return errors.New("missing secure input menu name")
+	case opt.Check == nil:
+		return fmt.Errorf("secure input menu %s has no check", opt.MenuName)
+	case !isIncrCacher(app.opt.Cache):
+		return fmt.Errorf("secure input menu %s requires a cache that implements IncrCacher", opt.MenuName)
+	}
+
+	si := &secureInput{
+		app:         app,
+		opt:         opt,
+		maxAttempts: opt.MaxAttempts,
+		lockout:     opt.LockoutDuration,
+	}
+	if si.maxAttempts <= 0 {
+		si.maxAttempts = defaultMaxAttempts
+	}
+	if si.lockout <= 0 {
+		si.lockout = defaultLockoutDuration
+	}
+
+	prompt := opt.Prompt
+	if len(prompt) == 0 {
+		prompt = Content{app.opt.DefaultLanguage: defaultSecureInputPrompt}
+	}
+
+	verifyMenu := fmt.Sprintf("%s:%s", opt.MenuName, secureInputVerifyStep)
+
+	menus := []Menu{
+		NewMenu(&MenuOptions{
+			MenuName:       opt.MenuName,
+			NextMenu:       verifyMenu,
+			ShortCut:       opt.ShortCut,
+			MenuContent:    prompt,
+			GenerateMenuFn: si.prompt,
+		}),
+		NewMenu(&MenuOptions{
+			MenuName:       verifyMenu,
+			NextMenu:       firstVal(opt.NextMenu, app.homeMenu),
+			SensitiveInput: true,
+			GenerateMenuFn: si.verify,
+		}),
+	}
+
+	for _, m := range menus {
+		err := app.AddMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type secureInput struct {
+	app         *UssdApp
+	opt         *SecureInputOptions
+	maxAttempts int
+	lockout     time.Duration
+}
+
+func (si *secureInput) key(name string, payload UssdPayload) string {
+	return fmt.Sprintf("%s:%s:%s:%s", si.app.opt.AppName, si.opt.MenuName, name, payload.Msisdn())
+}
+
+func (si *secureInput) lockedOut(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	_, err := si.app.opt.Cache.Get(ctx, si.key(secureInputLockKeyName, payload))
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to get lockout: %v", err)
+	}
+
+	lang := si.app.GetLanguage(ctx, payload)
+
+	// Messages without the END prefix end the session too, so that no more inputs are taken
+	sr := &sessionResponse{
+		response: firstVal(si.opt.LockedOutMessage.Text(lang, si.app.opt.DefaultLanguage), defaultLockedOutMessage),
+		menuName: secureInputLockedMenuName,
+	}
+
+	return sr.End(), true, nil
+}
+
+func (si *secureInput) prompt(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+	sr, locked, err := si.lockedOut(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return sr, nil
+	}
+
+	return m.ExecuteMenuArgs(si.app.GetLanguage(ctx, payload)), nil
+}
+
+func (si *secureInput) verify(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+	sr, locked, err := si.lockedOut(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return sr, nil
+	}
+
+	ok, err := si.opt.Check(ctx, payload, payload.UssdCurrentParam())
+	if err != nil {
+		return nil, err
+	}
+
+	attemptsKey := si.key(secureInputAttemptsKeyName, payload)
+
+	if ok {
+		err = si.app.opt.Cache.Delete(ctx, attemptsKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset attempts: %v", err)
+		}
+		if si.opt.Login {
+			return si.app.Login(ctx, payload, m.NextMenu())
+		}
+		return si.app.ReplaceMenuWithName(ctx, m.NextMenu(), payload)
+	}
+
+	// Attempts are counted atomically, so wrong inputs sent at the same time all count
+	attempts, err := incr(ctx, si.app.opt.Cache, attemptsKey, si.lockout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attempts: %v", err)
+	}
+
+	if attempts >= int64(si.maxAttempts) {
+		err = si.app.opt.Cache.Set(ctx, si.key(secureInputLockKeyName, payload), strconv.FormatInt(attempts, 10), si.lockout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save lockout: %v", err)
+		}
+
+		err = si.app.opt.Cache.Delete(ctx, attemptsKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset attempts: %v", err)
+		}
+	}
+
+	// Another request may have locked the msisdn out since the check above
+	sr, locked, err = si.lockedOut(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return sr, nil
+	}
+
+	msg := firstVal(si.opt.WrongInputMessage.Text(si.app.GetLanguage(ctx, payload), si.app.opt.DefaultLanguage), defaultWrongInputMessage)
+	if strings.Contains(msg, "%d") {
+		msg = fmt.Sprintf(msg, int64(si.maxAttempts)-attempts)
+	}
+
+	return si.app.PreviousMenuWithError(ctx, payload, m, msg)
+}
+
+// HashPIN hashes a PIN or password for storage using bcrypt
+func HashPIN(pin string) (string, error) {
+	bs, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash pin: %v", err)
+	}
+	return string(bs), nil
+}
+
+// CheckPIN checks a PIN or password against its hash from HashPIN
+func CheckPIN(hash, pin string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
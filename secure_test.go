@@ -0,0 +1,49 @@
+package ussdapp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/gidyon/ussdapp/ussdtest"
+)
+
+func TestSecureInputLockout(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "pin", MenuContent: text("CON 1. Balance")},
+		&ussdapp.MenuOptions{MenuName: "balance", NextMenu: "home", MenuContent: text("END Your balance is 100")},
+	)
+
+	err := app.AddSecureInputMenu(&ussdapp.SecureInputOptions{
+		MenuName:    "pin",
+		NextMenu:    "balance",
+		MaxAttempts: 3,
+		Check: func(ctx context.Context, payload ussdapp.UssdPayload, input string) (bool, error) {
+			return input == "1234", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add secure input menu: %v", err)
+	}
+
+	st := ussdtest.NewSessionTester(t, app)
+
+	st.Dial("*123#").Send("1").Expect("Enter PIN").
+		Send("0000").Expect("Wrong PIN, 2 attempts left").Expect("Enter PIN").
+		Send("1234").ExpectExact("Your balance is 100").ExpectEnd()
+
+	// Attempts are forgotten once the PIN is right
+	st.Dial("*123#").Send("1").
+		Send("0000").Expect("2 attempts left").
+		Send("0000").Expect("1 attempts left").
+		Send("0000").Expect("Too many wrong attempts").ExpectEnd()
+
+	// The right PIN is refused while locked out
+	st.Dial("*123#").Send("1").Expect("Too many wrong attempts").ExpectEnd()
+
+	// Other msisdns are not locked out
+	st.WithMsisdn("254711111111").Dial("*123#").Send("1").
+		Send("1234").ExpectExact("Your balance is 100").ExpectEnd()
+}
@@ -0,0 +1,136 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const defaultHiddenMenuMessage = "Invalid choice"
+
+// SegmentResolverFn returns the roles or customer segments of a user, e.g agent, registered or premium
+type SegmentResolverFn func(ctx context.Context, msisdn string) ([]string, error)
+
+// segmentedMenu is implemented by menus seen only by some segments of users
+type segmentedMenu interface {
+	Segments() []string
+}
+
+// userSegments returns the segments of the msisdn, resolving them once per request
+func (app *UssdApp) userSegments(ctx context.Context, payload UssdPayload) ([]string, error) {
+	p, ok := payload.(*ussdPayload)
+	if ok && p.data.segmentsResolved {
+		return p.data.segments, nil
+	}
+
+	segments, err := app.opt.SegmentResolver(ctx, payload.Msisdn())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segments of user: %v", err)
+	}
+
+	if ok {
+		p.data.segments, p.data.segmentsResolved = segments, true
+	}
+
+	return segments, nil
+}
+
+// menuVisible reports whether the user is in one of the segments of the menu.
+//
+// Menus with segments are hidden when the app has no segment resolver.
+func (app *UssdApp) menuVisible(ctx context.Context, payload UssdPayload, m Menu) (bool, error) {
+	sm, ok := m.(segmentedMenu)
+	if !ok || len(sm.Segments()) == 0 {
+		return true, nil
+	}
+
+	if app.opt.SegmentResolver == nil {
+		return false, nil
+	}
+
+	segments, err := app.userSegments(ctx, payload)
+	if err != nil {
+		return false, err
+	}
+
+	for _, segment := range segments {
+		for _, want := range sm.Segments() {
+			if segment == want {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// homeMenuFor returns the home menu in place of a hidden menu opened with a shortcut
+func (app *UssdApp) homeMenuFor(ctx context.Context, payload UssdPayload, hidden Menu) (Menu, error) {
+	home, ok := app.getMenu(app.homeMenuOf(payload))
+	if !ok || home == hidden {
+		return nil, fmt.Errorf("menu %s is hidden from the user and has no home menu to fall back to", hidden.MenuName())
+	}
+
+	visible, err := app.menuVisible(ctx, payload, home)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, fmt.Errorf("home menu %s is hidden from the user", home.MenuName())
+	}
+
+	return home, nil
+}
+
+func (app *UssdApp) hiddenMenuMessage(ctx context.Context, payload UssdPayload) string {
+	return firstVal(app.opt.HiddenMenuMessage.Text(app.GetLanguage(ctx, payload), app.opt.DefaultLanguage), defaultHiddenMenuMessage)
+}
+
+// hideOptions removes the numbered options of the menu text whose routes lead to menus hidden from the user, e.g
+// "2. Agent services" for users who are not agents. Options are not renumbered, so inputs keep their routes
+func (app *UssdApp) hideOptions(ctx context.Context, payload UssdPayload, m Menu, sr SessionResponse) error {
+	if sr == nil || len(menuRoutes(m)) == 0 {
+		return nil
+	}
+
+	hidden := make(map[string]bool)
+	for input, name := range menuRoutes(m) {
+		routed, ok := app.getMenu(name)
+		if !ok {
+			continue
+		}
+		visible, err := app.menuVisible(ctx, payload, routed)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			hidden[input] = true
+		}
+	}
+
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(sr.Response(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !hidden[optionInput(line)] {
+			kept = append(kept, line)
+		}
+	}
+
+	sr.setResponse(strings.Join(kept, "\n"))
+
+	return nil
+}
+
+// optionInput returns the input of a numbered option line, e.g 2 for "2. Agent services" or "2) Agent services"
+func optionInput(line string) string {
+	line = strings.TrimSpace(line)
+	i := strings.IndexAny(line, ".)")
+	if i <= 0 || !isDigits(line[:i]) {
+		return ""
+	}
+	return line[:i]
+}
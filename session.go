@@ -1,5 +1,7 @@
 package ussdapp
 
+import "strings"
+
 // SessionResponse is response for ussd session request
 type SessionResponse interface {
 	// Response return the session response string
@@ -12,6 +14,18 @@ type SessionResponse interface {
 	MenuName() string
 	// SessionId returns the USSD session id
 	SessionId() string
+	// Terminal reports whether the response ends the session. Responses not marked with End or Continue
+	// end the session when they start with END
+	Terminal() bool
+	// End marks the response as ending the session. Gateway adapters add the prefix the gateway expects
+	End() SessionResponse
+	// Continue marks the response as expecting more input from the user
+	Continue() SessionResponse
+	// SetData attaches business context to the response, e.g a transaction reference or product code, which is saved
+	// as JSON in the Data column of the session log
+	SetData(key string, value interface{}) SessionResponse
+	// Data returns the values attached with SetData
+	Data() map[string]interface{}
 
 	// unexposed setters
 	setResponse(string)
@@ -19,14 +33,35 @@ type SessionResponse interface {
 	setStatusMessage(string)
 	setMenu(string)
 	setSessionId(string)
+	setMenuVersion(string)
+	menuVersion() string
+	setVariant(string)
+	variant() string
 }
 
+// responseKind is whether a response ends the session
+type responseKind int
+
+const (
+	// prefixedResponse ends the session when the response starts with END
+	prefixedResponse responseKind = iota
+	continueResponse
+	endResponse
+)
+
 type sessionResponse struct {
 	response      string
 	failed        bool
 	statusMessage string
 	menuName      string
 	sessionId     string
+	kind          responseKind
+	// version is the version of the menu that rendered the response, see AddMenuVersion
+	version string
+	// experimentVariant is the experiment and variant of the menu that rendered the response, see Experiment
+	experimentVariant string
+	// data is business context saved in the session log, see SetData
+	data map[string]interface{}
 }
 
 func (sr *sessionResponse) Response() string {
@@ -49,6 +84,39 @@ func (sr *sessionResponse) SessionId() string {
 	return sr.sessionId
 }
 
+func (sr *sessionResponse) Terminal() bool {
+	switch sr.kind {
+	case endResponse:
+		return true
+	case continueResponse:
+		return false
+	default:
+		return strings.HasPrefix(strings.TrimSpace(sr.response), endPrefix)
+	}
+}
+
+func (sr *sessionResponse) End() SessionResponse {
+	sr.kind = endResponse
+	return sr
+}
+
+func (sr *sessionResponse) Continue() SessionResponse {
+	sr.kind = continueResponse
+	return sr
+}
+
+func (sr *sessionResponse) SetData(key string, value interface{}) SessionResponse {
+	if sr.data == nil {
+		sr.data = make(map[string]interface{})
+	}
+	sr.data[key] = value
+	return sr
+}
+
+func (sr *sessionResponse) Data() map[string]interface{} {
+	return sr.data
+}
+
 func (sr *sessionResponse) setResponse(val string) {
 	sr.response = val
 }
@@ -69,22 +137,44 @@ func (sr *sessionResponse) setSessionId(val string) {
 	sr.sessionId = val
 }
 
+func (sr *sessionResponse) setMenuVersion(val string) {
+	sr.version = val
+}
+
+func (sr *sessionResponse) menuVersion() string {
+	return sr.version
+}
+
+func (sr *sessionResponse) setVariant(val string) {
+	sr.experimentVariant = val
+}
+
+func (sr *sessionResponse) variant() string {
+	return sr.experimentVariant
+}
+
 type SessionData struct {
 	Response      string
 	Failed        bool
 	StatusMessage string
 	MenuName      string
 	SessionId     string
+	// Terminal ends the session, as if End was called on the response
+	Terminal bool
 }
 
 func NewSessionResponse(data *SessionData) SessionResponse {
-	return &sessionResponse{
+	sr := &sessionResponse{
 		response:      data.Response,
 		failed:        data.Failed,
 		statusMessage: data.StatusMessage,
 		menuName:      data.MenuName,
 		sessionId:     data.SessionId,
 	}
+	if data.Terminal {
+		sr.kind = endResponse
+	}
+	return sr
 }
 
 func SetSessionFailed(session SessionResponse, status string) {
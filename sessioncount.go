@@ -0,0 +1,72 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SessionCounter counts the sessions in progress on all instances of the app, e.g with a redis counter. See
+// rediscache.NewSessionCounter
+type SessionCounter interface {
+	// AddSessions adds delta to the number of sessions in progress
+	AddSessions(ctx context.Context, delta int64) error
+	// CountSessions returns the number of sessions in progress
+	CountSessions(ctx context.Context) (int64, error)
+}
+
+// ActiveSessionCount returns the number of sessions in progress. It counts the sessions of all instances with
+// Options.SessionCounter, and the sessions on this instance otherwise.
+//
+// Sessions are counted when they start and until they end, are terminated or their data expires.
+func (app *UssdApp) ActiveSessionCount(ctx context.Context) (int64, error) {
+	if app.opt.SessionCounter == nil {
+		return int64(app.liveSessions()), nil
+	}
+
+	n, err := app.opt.SessionCounter.CountSessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %v", err)
+	}
+
+	// Sessions of instances that stopped before counting their end may leave the count off
+	if n < 0 {
+		n = 0
+	}
+
+	return n, nil
+}
+
+// countSessions adds delta to the session counter when set
+func (app *UssdApp) countSessions(ctx context.Context, delta int64) {
+	if app.opt.SessionCounter == nil {
+		return
+	}
+
+	err := app.opt.SessionCounter.AddSessions(ctx, delta)
+	if err != nil {
+		app.opt.Logger.Warn("failed to count sessions", "error", err)
+	}
+}
+
+// countSessionEnd counts out a session that ended. Requests turned away before the session started, e.g by the
+// blocklist, have no session data and were not counted
+func (app *UssdApp) countSessionEnd(ctx context.Context, sessionKey string) {
+	if app.opt.SessionCounter == nil {
+		return
+	}
+
+	_, err := app.opt.Cache.GetMapField(ctx, sessionKey, "new")
+	switch {
+	case err == nil:
+		app.countSessions(ctx, -1)
+	case errors.Is(err, ErrKeyNotFound):
+	default:
+		app.opt.Logger.Warn("failed to count out session", "session_key", sessionKey, "error", err)
+	}
+}
+
+// markEnded reports whether sessions that end are marked, so that expiry checks do not take them for timeouts
+func (app *UssdApp) markEnded() bool {
+	return app.opt.OnSessionTimeout != nil || app.opt.SessionCounter != nil
+}
@@ -0,0 +1,114 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// SessionGet reads the session field into a value of type T, e.g
+//
+//	amount, err := ussdapp.SessionGet[int64](ctx, app, payload, "amount")
+//	account, err := ussdapp.SessionGet[*Account](ctx, app, payload, "account")
+//
+// Strings, numbers and booleans are parsed from their text, other types from json. Returns ErrKeyNotFound if the
+// field is not set.
+func SessionGet[T any](ctx context.Context, app *UssdApp, payload UssdPayload, field string) (T, error) {
+	var v T
+
+	val, err := app.Session(payload).Get(ctx, field)
+	if err != nil {
+		return v, err
+	}
+
+	err = decodeSessionValue(val, &v)
+	if err != nil {
+		return v, fmt.Errorf("failed to read session field %s: %v", field, err)
+	}
+
+	return v, nil
+}
+
+// SessionGetOr reads the session field like SessionGet, returning def when the field is not set
+func SessionGetOr[T any](ctx context.Context, app *UssdApp, payload UssdPayload, field string, def T) (T, error) {
+	v, err := SessionGet[T](ctx, app, payload, field)
+	if errors.Is(err, ErrKeyNotFound) {
+		return def, nil
+	}
+	return v, err
+}
+
+// SessionSet saves the value in the session field. Strings, numbers and booleans are saved as text, so they can be
+// read with Session.Get and in menu templates, other types as json
+func SessionSet[T any](ctx context.Context, app *UssdApp, payload UssdPayload, field string, value T) error {
+	val, err := encodeSessionValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to save session field %s: %v", field, err)
+	}
+
+	return app.Session(payload).Set(ctx, field, val)
+}
+
+func encodeSessionValue(value interface{}) (string, error) {
+	rv := reflect.ValueOf(value)
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	}
+
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bs), nil
+}
+
+func decodeSessionValue(val string, dest interface{}) error {
+	rv := reflect.ValueOf(dest).Elem()
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(val, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(val, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(v)
+	default:
+		return json.Unmarshal([]byte(val), dest)
+	}
+
+	return nil
+}
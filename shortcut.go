@@ -0,0 +1,107 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func shortCutParamKey(name string) string {
+	return "shortcut:" + name
+}
+
+// shortCutMenu returns the menu whose shortcut matches the ussd string of a new session, with the parameters
+// captured by the shortcut.
+//
+// Shortcuts matching the ussd string exactly take precedence over patterns like 1*2*{amount}. Patterns are tried
+// in the order menus were added.
+func (app *UssdApp) shortCutMenu(payload UssdPayload) (Menu, map[string]string) {
+	ussdString := payload.UssdParams()
+	if ussdString == "" {
+		return nil, nil
+	}
+
+	reg := app.registry()
+
+	for _, name := range reg.names {
+		if m := reg.menus[name]; m.ShortCut() == ussdString {
+			return m, nil
+		}
+	}
+
+	for _, name := range reg.names {
+		m := reg.menus[name]
+		if !strings.Contains(m.ShortCut(), "{") {
+			continue
+		}
+		if params, ok := matchShortCut(m.ShortCut(), ussdString); ok {
+			return m, params
+		}
+	}
+
+	return nil, nil
+}
+
+// matchShortCut matches the inputs of a ussd string against a shortcut pattern, where inputs in braces are
+// captured, e.g 1*2*{amount} matches 1*2*500 capturing amount 500
+func matchShortCut(pattern, ussdString string) (map[string]string, bool) {
+	var (
+		parts  = strings.Split(pattern, "*")
+		inputs = strings.Split(ussdString, "*")
+		params = make(map[string]string)
+	)
+	if len(parts) != len(inputs) {
+		return nil, false
+	}
+
+	for i, part := range parts {
+		input := strings.TrimSpace(inputs[i])
+		if name, ok := shortCutParamName(part); ok {
+			if input == "" {
+				return nil, false
+			}
+			params[name] = input
+			continue
+		}
+		if part != input {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// shortCutParamName returns the name of a parameter part of a shortcut pattern such as {amount}
+func shortCutParamName(part string) (string, bool) {
+	if len(part) < 3 || part[0] != '{' || part[len(part)-1] != '}' {
+		return "", false
+	}
+	return part[1 : len(part)-1], true
+}
+
+// saveShortCutParams keeps the parameters captured by a shortcut in the session
+func (app *UssdApp) saveShortCutParams(ctx context.Context, payload UssdPayload, params map[string]string) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, 2*len(params))
+	for name, val := range params {
+		values = append(values, shortCutParamKey(name), val)
+	}
+
+	err := app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), values...)
+	if err != nil {
+		return fmt.Errorf("failed to save shortcut parameters: %v", err)
+	}
+
+	return nil
+}
+
+// ShortCutParam returns a parameter captured by the shortcut pattern the session was started with.
+//
+// For a menu with shortcut 1*2*{amount}, dialing *123*1*2*500# opens the menu and ShortCutParam returns 500 for
+// amount. Returns ErrKeyNotFound if the session did not capture the parameter.
+func (app *UssdApp) ShortCutParam(ctx context.Context, payload UssdPayload, name string) (string, error) {
+	return app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), shortCutParamKey(name))
+}
@@ -0,0 +1,7 @@
+/*
+Package simulator drives a USSD app the way a gateway does, so that flows can be tested without a gateway or a phone.
+
+A simulator generates session ids, joins the inputs of a session into the USSD string with * and sends them
+to the app handler or to the URL of a running app.
+*/
+package simulator
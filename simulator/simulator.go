@@ -0,0 +1,307 @@
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol is the format of the requests sent to the app
+type Protocol int
+
+const (
+	// Generic sends GET requests with SESSION_ID, SERVICE_CODE, MSISDN and USSD_PARAMS query parameters,
+	// as read by the generic gateway adapter
+	Generic Protocol = iota
+	// AfricasTalking sends form POST requests as the Africa's Talking gateway does
+	AfricasTalking
+)
+
+const (
+	defaultMsisdn      = "254700000000"
+	defaultServiceCode = "*123#"
+)
+
+// Options contains data required for the simulator
+type Options struct {
+	// Handler is the app handler requests are served by. Takes precedence over URL
+	Handler http.Handler
+	// URL of a running app
+	URL string
+	// Msisdn is the phone number sessions are dialed from. Defaults to 254700000000
+	Msisdn string
+	// ServiceCode is the ussd code dialed. Defaults to *123#.
+	//
+	// Inputs appended to the code, e.g *123*1*2#, are sent with the first request of the session as a shortcut
+	ServiceCode string
+	Protocol    Protocol
+	// Client sends requests to URL. Defaults to a client with a 30 second timeout
+	Client *http.Client
+}
+
+// Response is the response of the app to a request
+type Response struct {
+	// Text is the response without the CON or END prefix
+	Text string
+	// End is true when the app ended the session
+	End bool
+}
+
+// Simulator drives ussd sessions against an app
+type Simulator interface {
+	// Dial starts a new session and returns the first response
+	Dial(ctx context.Context) (*Response, error)
+	// Send sends the user input in the current session
+	Send(ctx context.Context, input string) (*Response, error)
+	// SessionID returns the id of the current session
+	SessionID() string
+	// Run reads inputs from in, one per line, and writes responses to out until in is closed or the user enters q
+	Run(ctx context.Context, in io.Reader, out io.Writer) error
+}
+
+// NewSimulator creates a simulator for the app handler or the app at the URL
+func NewSimulator(opt *Options) (Simulator, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.Handler == nil && opt.URL == "":
+		return nil, errors.New("missing app handler or url")
+	}
+
+	switch opt.Protocol {
+	case Generic, AfricasTalking:
+	default:
+		return nil, fmt.Errorf("unknown protocol %d", opt.Protocol)
+	}
+
+	s := &simulator{
+		handler:     opt.Handler,
+		url:         opt.URL,
+		msisdn:      opt.Msisdn,
+		serviceCode: opt.ServiceCode,
+		protocol:    opt.Protocol,
+		client:      opt.Client,
+	}
+	if s.msisdn == "" {
+		s.msisdn = defaultMsisdn
+	}
+	if s.serviceCode == "" {
+		s.serviceCode = defaultServiceCode
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return s, nil
+}
+
+type simulator struct {
+	handler     http.Handler
+	url         string
+	msisdn      string
+	serviceCode string
+	protocol    Protocol
+	client      *http.Client
+	sessionID   string
+	inputs      []string
+}
+
+func (s *simulator) SessionID() string {
+	return s.sessionID
+}
+
+func (s *simulator) Dial(ctx context.Context) (*Response, error) {
+	bs := make([]byte, 8)
+	_, err := rand.Read(bs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %v", err)
+	}
+
+	s.sessionID = hex.EncodeToString(bs)
+	s.inputs = s.inputs[:0]
+
+	_, shortCut := splitServiceCode(s.serviceCode)
+	s.inputs = append(s.inputs, shortCut...)
+
+	return s.do(ctx)
+}
+
+// splitServiceCode separates inputs dialed after the base code, e.g *123*1*2# has code *123# and inputs 1 and 2
+func splitServiceCode(code string) (string, []string) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(code, "*"), "#"), "*")
+	if len(parts) < 2 {
+		return code, nil
+	}
+	return "*" + parts[0] + "#", parts[1:]
+}
+
+func (s *simulator) Send(ctx context.Context, input string) (*Response, error) {
+	if s.sessionID == "" {
+		return nil, errors.New("no session, dial first")
+	}
+
+	s.inputs = append(s.inputs, input)
+
+	return s.do(ctx)
+}
+
+// request builds the request carrying the inputs of the session so far
+func (s *simulator) request(ctx context.Context) (*http.Request, error) {
+	var (
+		ussdString     = strings.Join(s.inputs, "*")
+		serviceCode, _ = splitServiceCode(s.serviceCode)
+	)
+
+	target := s.url
+	if target == "" {
+		target = "http://ussdsim/"
+	}
+
+	switch s.protocol {
+	case AfricasTalking:
+		form := url.Values{
+			"sessionId":   {s.sessionID},
+			"serviceCode": {serviceCode},
+			"phoneNumber": {"+" + s.msisdn},
+			"text":        {ussdString},
+		}
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return r, nil
+	default:
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url: %v", err)
+		}
+
+		query := u.Query()
+		query.Set("SESSION_ID", s.sessionID)
+		query.Set("SERVICE_CODE", serviceCode)
+		query.Set("MSISDN", s.msisdn)
+		query.Set("USSD_PARAMS", ussdString)
+		u.RawQuery = query.Encode()
+
+		return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	}
+}
+
+func (s *simulator) do(ctx context.Context) (*Response, error) {
+	r, err := s.request(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	var (
+		status int
+		body   []byte
+	)
+
+	if s.handler != nil {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, r)
+		status, body = w.Code, w.Body.Bytes()
+	} else {
+		res, err := s.client.Do(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		body, err = io.ReadAll(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %v", err)
+		}
+		status = res.StatusCode
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("app responded with status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+
+	return parseResponse(string(body)), nil
+}
+
+func parseResponse(text string) *Response {
+	text = strings.TrimSpace(text)
+
+	switch {
+	case strings.HasPrefix(text, "END"):
+		return &Response{Text: strings.TrimSpace(text[3:]), End: true}
+	case strings.HasPrefix(text, "CON"):
+		return &Response{Text: strings.TrimSpace(text[3:])}
+	default:
+		return &Response{Text: text}
+	}
+}
+
+func (s *simulator) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	var (
+		scanner = bufio.NewScanner(in)
+		res     *Response
+		err     error
+	)
+
+	dial := func() error {
+		res, err = s.Dial(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "--- session %s\n%s\n", s.sessionID, res.Text)
+		return nil
+	}
+
+	err = dial()
+	if err != nil {
+		return err
+	}
+
+	for {
+		if res.End {
+			fmt.Fprint(out, "--- session ended, press enter to dial again or q to quit\n")
+		}
+		fmt.Fprint(out, "> ")
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		input := strings.TrimSpace(scanner.Text())
+		if input == "q" {
+			return nil
+		}
+
+		if res.End {
+			err = dial()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		res, err = s.Send(ctx, input)
+		if err != nil {
+			fmt.Fprintf(out, "--- %v\n", err)
+			// The input was not handled so it is not part of the session
+			s.inputs = s.inputs[:len(s.inputs)-1]
+			res = &Response{}
+			continue
+		}
+
+		fmt.Fprintln(out, res.Text)
+	}
+}
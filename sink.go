@@ -0,0 +1,125 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LogSink saves session logs to a store
+type LogSink interface {
+	// Write saves the logs. Logs that fail to be written are kept in files and written again later
+	Write(ctx context.Context, logs []*SessionRequest) error
+}
+
+// newLogSinks returns the sinks that session logs are written to.
+//
+// Logs go to the logs table in SQLDB when no sink is set in options.
+func newLogSinks(opt *Options, table string) []LogSink {
+	sinks := make([]LogSink, 0, len(opt.LogSinks)+1)
+	if opt.LogSink != nil {
+		sinks = append(sinks, opt.LogSink)
+	}
+	for _, sink := range opt.LogSinks {
+		if sink != nil {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	if len(sinks) == 0 && opt.SQLDB != nil {
+		sinks = append(sinks, NewGormLogSink(opt.SQLDB, table))
+	}
+
+	return sinks
+}
+
+// NewGormLogSink creates a log sink that inserts session logs in the table of the database, e.g the logs table of
+// the app from UssdApp.LogsTable. Empty table inserts them in the default logs table.
+//
+// The table, or columns missing from it, are created on the first write.
+func NewGormLogSink(db *gorm.DB, table string) LogSink {
+	return &gormLogSink{db: db, table: firstVal(table, defaultSessionsLogsTable)}
+}
+
+type gormLogSink struct {
+	db         *gorm.DB
+	table      string
+	migrate    sync.Once
+	migrateErr error
+}
+
+func (s *gormLogSink) Write(ctx context.Context, logs []*SessionRequest) error {
+	const safeBulkSize = 1000
+
+	s.migrate.Do(func() {
+		s.migrateErr = s.db.Table(s.table).AutoMigrate(&SessionRequest{})
+	})
+	if s.migrateErr != nil {
+		return fmt.Errorf("failed to auto migrate %s table: %v", s.table, s.migrateErr)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx = tx.Table(s.table).Clauses(clause.OnConflict{DoNothing: true})
+
+		for i := 0; i < len(logs); i += safeBulkSize {
+			to := i + safeBulkSize
+			if to > len(logs) {
+				to = len(logs)
+			}
+
+			err := tx.CreateInBatches(logs[i:to], to-i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// NewFileLogSink creates a log sink that appends session logs to the file as json lines
+func NewFileLogSink(fileName string) LogSink {
+	return &fileLogSink{fileName: fileName}
+}
+
+type fileLogSink struct {
+	mu       sync.Mutex
+	fileName string
+}
+
+func (s *fileLogSink) Write(ctx context.Context, logs []*SessionRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open logs file: %v", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, log := range logs {
+		err = enc.Encode(log)
+		if err != nil {
+			return fmt.Errorf("failed to write logs file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// NewNoopLogSink creates a log sink that discards session logs
+func NewNoopLogSink() LogSink {
+	return noopLogSink{}
+}
+
+type noopLogSink struct{}
+
+func (noopLogSink) Write(context.Context, []*SessionRequest) error {
+	return nil
+}
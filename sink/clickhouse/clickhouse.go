@@ -0,0 +1,158 @@
+package clickhousesink
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const (
+	defaultTable     = "ussd_logs"
+	defaultBatchSize = 10000
+)
+
+// Options contains data required for the clickhouse log sink
+type Options struct {
+	DB *sql.DB
+	// Table is the logs table. Defaults to ussd_logs
+	Table string
+	// BatchSize is the maximum number of rows sent in one insert. Defaults to 10000
+	BatchSize int
+	// AsyncInsert lets the server buffer inserts, which is cheaper for many small batches
+	AsyncInsert bool
+	// CreateTable creates the logs table if it does not exist
+	CreateTable bool
+}
+
+// createTableQuery partitions logs by day and orders them by time for time series queries
+const createTableQuery = `CREATE TABLE IF NOT EXISTS %s (
+	session_id String,
+	msisdn String,
+	menu_name LowCardinality(String),
+	menu_version LowCardinality(String),
+	variant LowCardinality(String),
+	ussd_params String,
+	user_input String,
+	response_text String,
+	data String,
+	succeeded Bool,
+	ended Bool,
+	status_message String,
+	duration_ms UInt32,
+	http_status UInt16,
+	created_at DateTime64(6)
+) ENGINE = MergeTree
+PARTITION BY toDate(created_at)
+ORDER BY (created_at, menu_name, session_id)`
+
+const columns = "session_id, msisdn, menu_name, menu_version, variant, ussd_params, user_input, response_text, data, succeeded, ended, status_message, duration_ms, http_status, created_at"
+
+// NewClickHouseLogSink creates a log sink that inserts session logs in a clickhouse table
+func NewClickHouseLogSink(ctx context.Context, opt *Options) (ussdapp.LogSink, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.DB == nil:
+		return nil, errors.New("missing clickhouse db")
+	}
+
+	cs := &clickHouseSink{
+		db:        opt.DB,
+		table:     opt.Table,
+		batchSize: opt.BatchSize,
+	}
+	if cs.table == "" {
+		cs.table = defaultTable
+	}
+	if cs.batchSize <= 0 {
+		cs.batchSize = defaultBatchSize
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s)", cs.table, columns)
+	if opt.AsyncInsert {
+		insert += " SETTINGS async_insert = 1, wait_for_async_insert = 1"
+	}
+	cs.insertQuery = insert + " VALUES (" + strings.TrimSuffix(strings.Repeat("?, ", 15), ", ") + ")"
+
+	if opt.CreateTable {
+		_, err := opt.DB.ExecContext(ctx, fmt.Sprintf(createTableQuery, cs.table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s table: %v", cs.table, err)
+		}
+	}
+
+	return cs, nil
+}
+
+type clickHouseSink struct {
+	db          *sql.DB
+	table       string
+	batchSize   int
+	insertQuery string
+}
+
+func (cs *clickHouseSink) Write(ctx context.Context, logs []*ussdapp.SessionRequest) error {
+	for i := 0; i < len(logs); i += cs.batchSize {
+		to := i + cs.batchSize
+		if to > len(logs) {
+			to = len(logs)
+		}
+
+		err := cs.writeBatch(ctx, logs[i:to])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBatch inserts the logs in one block. Clickhouse drivers buffer rows of a prepared insert
+// in a transaction and send them in columnar form on commit
+func (cs *clickHouseSink) writeBatch(ctx context.Context, logs []*ussdapp.SessionRequest) error {
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, cs.insertQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, log := range logs {
+		_, err = stmt.ExecContext(ctx,
+			log.SessionID,
+			log.Msisdn,
+			log.MenuName,
+			log.MenuVersion,
+			log.Variant,
+			log.USSDParams,
+			log.UserInput,
+			log.ResponseText,
+			log.Data,
+			log.Succeeded,
+			log.Ended,
+			log.StatusMessage,
+			uint32(log.DurationMs),
+			uint16(log.HTTPStatus),
+			log.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to append log to batch: %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to send batch: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+/*
+Package clickhouse implements a USSD session log sink using clickhouse.
+
+The sink uses database/sql, so register a clickhouse driver such as github.com/ClickHouse/clickhouse-go/v2 and pass the opened database.
+*/
+package clickhousesink
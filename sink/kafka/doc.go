@@ -0,0 +1,4 @@
+/*
+Package kafka implements a USSD session log sink that publishes session events to a kafka topic.
+*/
+package kafkasink
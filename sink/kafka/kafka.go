@@ -0,0 +1,194 @@
+package kafkasink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/linkedin/goavro/v2"
+	"github.com/segmentio/kafka-go"
+)
+
+// Encoding is the serialization format of published events
+type Encoding int
+
+const (
+	// JSON publishes events as json objects
+	JSON Encoding = iota
+	// Avro publishes events as avro binary encoded using EventSchema
+	Avro
+)
+
+// EventSchema is the avro schema of published events
+const EventSchema = `{
+	"type": "record",
+	"name": "SessionEvent",
+	"namespace": "ussdapp",
+	"fields": [
+		{"name": "session_id", "type": "string"},
+		{"name": "msisdn", "type": "string"},
+		{"name": "menu_name", "type": "string"},
+		{"name": "menu_version", "type": "string", "default": ""},
+		{"name": "variant", "type": "string", "default": ""},
+		{"name": "ussd_params", "type": "string"},
+		{"name": "user_input", "type": "string"},
+		{"name": "data", "type": "string"},
+		{"name": "succeeded", "type": "boolean"},
+		{"name": "ended", "type": "boolean", "default": false},
+		{"name": "status_message", "type": "string"},
+		{"name": "response_text", "type": "string", "default": ""},
+		{"name": "duration_ms", "type": "long", "default": 0},
+		{"name": "http_status", "type": "int", "default": 0},
+		{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+	]
+}`
+
+// Options contains data required for the kafka log sink
+type Options struct {
+	Brokers  []string
+	Topic    string
+	Encoding Encoding
+	// BatchTimeout is how long the writer waits to fill a batch. Defaults to 10ms
+	BatchTimeout time.Duration
+}
+
+// NewKafkaLogSink creates a log sink that publishes session logs to the topic, keyed by session id
+// so that events of a session are consumed in order.
+//
+// The sink implements io.Closer to flush and close the underlying writer.
+func NewKafkaLogSink(opt *Options) (ussdapp.LogSink, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case len(opt.Brokers) == 0:
+		return nil, errors.New("missing kafka brokers")
+	case opt.Topic == "":
+		return nil, errors.New("missing kafka topic")
+	}
+
+	ks := &kafkaSink{
+		encoding: opt.Encoding,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(opt.Brokers...),
+			Topic:        opt.Topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: opt.BatchTimeout,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+
+	if ks.writer.BatchTimeout == 0 {
+		ks.writer.BatchTimeout = 10 * time.Millisecond
+	}
+
+	switch opt.Encoding {
+	case JSON:
+	case Avro:
+		codec, err := goavro.NewCodec(EventSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create avro codec: %v", err)
+		}
+		ks.codec = codec
+	default:
+		return nil, fmt.Errorf("unknown encoding %d", opt.Encoding)
+	}
+
+	return ks, nil
+}
+
+type kafkaSink struct {
+	writer   *kafka.Writer
+	encoding Encoding
+	codec    *goavro.Codec
+}
+
+type sessionEvent struct {
+	SessionID     string    `json:"session_id"`
+	Msisdn        string    `json:"msisdn"`
+	MenuName      string    `json:"menu_name"`
+	MenuVersion   string    `json:"menu_version,omitempty"`
+	Variant       string    `json:"variant,omitempty"`
+	USSDParams    string    `json:"ussd_params"`
+	UserInput     string    `json:"user_input"`
+	Data          string    `json:"data"`
+	Succeeded     bool      `json:"succeeded"`
+	Ended         bool      `json:"ended"`
+	StatusMessage string    `json:"status_message"`
+	ResponseText  string    `json:"response_text,omitempty"`
+	DurationMs    int64     `json:"duration_ms"`
+	HTTPStatus    int       `json:"http_status,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (ks *kafkaSink) encode(log *ussdapp.SessionRequest) ([]byte, error) {
+	if ks.encoding == Avro {
+		return ks.codec.BinaryFromNative(nil, map[string]interface{}{
+			"session_id":     log.SessionID,
+			"msisdn":         log.Msisdn,
+			"menu_name":      log.MenuName,
+			"menu_version":   log.MenuVersion,
+			"variant":        log.Variant,
+			"ussd_params":    log.USSDParams,
+			"user_input":     log.UserInput,
+			"data":           log.Data,
+			"succeeded":      log.Succeeded,
+			"ended":          log.Ended,
+			"status_message": log.StatusMessage,
+			"response_text":  log.ResponseText,
+			"duration_ms":    log.DurationMs,
+			"http_status":    log.HTTPStatus,
+			"created_at":     log.CreatedAt,
+		})
+	}
+
+	return json.Marshal(&sessionEvent{
+		SessionID:     log.SessionID,
+		Msisdn:        log.Msisdn,
+		MenuName:      log.MenuName,
+		MenuVersion:   log.MenuVersion,
+		Variant:       log.Variant,
+		USSDParams:    log.USSDParams,
+		UserInput:     log.UserInput,
+		Data:          log.Data,
+		Succeeded:     log.Succeeded,
+		Ended:         log.Ended,
+		StatusMessage: log.StatusMessage,
+		ResponseText:  log.ResponseText,
+		DurationMs:    log.DurationMs,
+		HTTPStatus:    log.HTTPStatus,
+		CreatedAt:     log.CreatedAt,
+	})
+}
+
+func (ks *kafkaSink) Write(ctx context.Context, logs []*ussdapp.SessionRequest) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	msgs := make([]kafka.Message, 0, len(logs))
+	for _, log := range logs {
+		bs, err := ks.encode(log)
+		if err != nil {
+			return fmt.Errorf("failed to encode session event: %v", err)
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(log.SessionID),
+			Value: bs,
+			Time:  log.CreatedAt,
+		})
+	}
+
+	err := ks.writer.WriteMessages(ctx, msgs...)
+	if err != nil {
+		return fmt.Errorf("failed to publish session events: %v", err)
+	}
+
+	return nil
+}
+
+func (ks *kafkaSink) Close() error {
+	return ks.writer.Close()
+}
@@ -0,0 +1,4 @@
+/*
+Package mongo implements a USSD session log sink using mongodb.
+*/
+package mongosink
@@ -0,0 +1,91 @@
+package mongosink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoLogSink creates a log sink that inserts session logs as documents in the collection
+func NewMongoLogSink(collection *mongo.Collection) ussdapp.LogSink {
+	return &mongoSink{collection: collection}
+}
+
+type mongoSink struct {
+	collection *mongo.Collection
+}
+
+type sessionRequestDoc struct {
+	// ID is derived from the log so that logs written again are not duplicated
+	ID            string    `bson:"_id"`
+	SessionID     string    `bson:"session_id"`
+	Msisdn        string    `bson:"msisdn"`
+	MenuName      string    `bson:"menu_name"`
+	MenuVersion   string    `bson:"menu_version,omitempty"`
+	Variant       string    `bson:"variant,omitempty"`
+	USSDParams    string    `bson:"ussd_params"`
+	UserInput     string    `bson:"user_input"`
+	Data          string    `bson:"data,omitempty"`
+	Succeeded     bool      `bson:"succeeded"`
+	Ended         bool      `bson:"ended"`
+	StatusMessage string    `bson:"status_message,omitempty"`
+	ResponseText  string    `bson:"response_text,omitempty"`
+	DurationMs    int64     `bson:"duration_ms"`
+	HTTPStatus    int       `bson:"http_status,omitempty"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+func (ms *mongoSink) Write(ctx context.Context, logs []*ussdapp.SessionRequest) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(logs))
+	for _, log := range logs {
+		docs = append(docs, &sessionRequestDoc{
+			ID:            fmt.Sprintf("%s:%d", log.SessionID, log.CreatedAt.UnixNano()),
+			SessionID:     log.SessionID,
+			Msisdn:        log.Msisdn,
+			MenuName:      log.MenuName,
+			MenuVersion:   log.MenuVersion,
+			Variant:       log.Variant,
+			USSDParams:    log.USSDParams,
+			UserInput:     log.UserInput,
+			Data:          log.Data,
+			Succeeded:     log.Succeeded,
+			Ended:         log.Ended,
+			StatusMessage: log.StatusMessage,
+			ResponseText:  log.ResponseText,
+			DurationMs:    log.DurationMs,
+			HTTPStatus:    log.HTTPStatus,
+			CreatedAt:     log.CreatedAt,
+		})
+	}
+
+	// Unordered inserts write the rest of the documents if some fail
+	_, err := ms.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicates(err) {
+		return fmt.Errorf("failed to insert session logs: %v", err)
+	}
+
+	return nil
+}
+
+// onlyDuplicates checks whether all failed inserts were documents that are already saved
+func onlyDuplicates(err error) bool {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return false
+	}
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we) {
+			return false
+		}
+	}
+	return true
+}
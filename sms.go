@@ -0,0 +1,47 @@
+package ussdapp
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SMSSender sends SMS messages. See the sms package for Africa's Talking and Twilio senders
+type SMSSender interface {
+	SendSMS(ctx context.Context, msisdn, message string) error
+}
+
+// EndSMSFn returns the SMS sent to the user when a response ends the session, e.g a summary with a reference
+// number. Session data can still be read. No SMS is sent when the message is empty.
+type EndSMSFn func(ctx context.Context, payload UssdPayload, sr SessionResponse) (string, error)
+
+// sendEndSMS sends the SMS returned by the end SMS hook in the background, once the response has been written
+func (app *UssdApp) sendEndSMS(ctx context.Context, payload UssdPayload, sr SessionResponse) {
+	if app.opt.EndSMS == nil || app.opt.SMSSender == nil {
+		return
+	}
+
+	message, err := app.opt.EndSMS(ctx, payload, sr)
+	if err != nil {
+		app.opt.Logger.Error("failed to get end sms", "session_id", payload.SessionId(), "error", err)
+		return
+	}
+	if message == "" {
+		return
+	}
+
+	if atomic.LoadInt32(&app.closed) == 1 {
+		app.opt.Logger.Error("end sms not sent: app is closed", "session_id", payload.SessionId())
+		return
+	}
+
+	app.workers.Add(1)
+
+	go func(msisdn, sessionID string) {
+		defer app.workers.Done()
+
+		err := app.opt.SMSSender.SendSMS(context.Background(), msisdn, message)
+		if err != nil {
+			app.opt.Logger.Error("failed to send end sms", "session_id", sessionID, "error", err)
+		}
+	}(payload.Msisdn(), payload.SessionId())
+}
@@ -0,0 +1,111 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const (
+	africasTalkingURL        = "https://api.africastalking.com/version1/messaging"
+	africasTalkingSandboxURL = "https://api.sandbox.africastalking.com/version1/messaging"
+)
+
+// AfricasTalkingOptions contains data required for the Africa's Talking SMS sender
+type AfricasTalkingOptions struct {
+	Username string
+	APIKey   string
+	// From is the short code or alphanumeric sender id. Defaults to the account sender id
+	From string
+	// Sandbox sends messages to the sandbox environment
+	Sandbox bool
+	// HTTPClient defaults to a client with a 10 seconds timeout
+	HTTPClient *http.Client
+}
+
+// NewAfricasTalkingSender creates an SMS sender using the Africa's Talking messaging API
+func NewAfricasTalkingSender(opt *AfricasTalkingOptions) (ussdapp.SMSSender, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.Username == "":
+		return nil, errors.New("missing username")
+	case opt.APIKey == "":
+		return nil, errors.New("missing api key")
+	}
+
+	endpoint := africasTalkingURL
+	if opt.Sandbox {
+		endpoint = africasTalkingSandboxURL
+	}
+
+	return &africasTalkingSender{opt: opt, endpoint: endpoint, client: httpClient(opt.HTTPClient)}, nil
+}
+
+type africasTalkingSender struct {
+	opt      *AfricasTalkingOptions
+	endpoint string
+	client   *http.Client
+}
+
+type africasTalkingResponse struct {
+	SMSMessageData struct {
+		Message    string `json:"Message"`
+		Recipients []struct {
+			StatusCode int    `json:"statusCode"`
+			Status     string `json:"status"`
+		} `json:"Recipients"`
+	} `json:"SMSMessageData"`
+}
+
+func (s *africasTalkingSender) SendSMS(ctx context.Context, msisdn, message string) error {
+	form := url.Values{
+		"username": {s.opt.Username},
+		"to":       {e164(msisdn)},
+		"message":  {message},
+	}
+	if s.opt.From != "" {
+		form.Set("from", s.opt.From)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("apiKey", s.opt.APIKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sms: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return responseError(res)
+	}
+
+	resBody := &africasTalkingResponse{}
+	err = json.NewDecoder(res.Body).Decode(resBody)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	// Status codes 100 to 102 are processed, queued and sent
+	recipients := resBody.SMSMessageData.Recipients
+	if len(recipients) == 0 {
+		return fmt.Errorf("sms not sent: %s", resBody.SMSMessageData.Message)
+	}
+	if code := recipients[0].StatusCode; code < 100 || code > 102 {
+		return fmt.Errorf("sms not sent: %s", recipients[0].Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,4 @@
+/*
+Package sms implements SMS senders for the end SMS of USSD sessions using Africa's Talking and Twilio.
+*/
+package sms
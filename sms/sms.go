@@ -0,0 +1,29 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+// e164 adds the leading + that gateways strip from msisdns
+func e164(msisdn string) string {
+	return "+" + strings.TrimPrefix(strings.TrimSpace(msisdn), "+")
+}
+
+// responseError reads the body of a failed request for the error
+func responseError(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
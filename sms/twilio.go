@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gidyon/ussdapp"
+)
+
+const twilioURL = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+
+// TwilioOptions contains data required for the Twilio SMS sender
+type TwilioOptions struct {
+	AccountSID string
+	AuthToken  string
+	// From is the Twilio number messages are sent from
+	From string
+	// MessagingServiceSID sends messages through a messaging service instead of From
+	MessagingServiceSID string
+	// HTTPClient defaults to a client with a 10 seconds timeout
+	HTTPClient *http.Client
+}
+
+// NewTwilioSender creates an SMS sender using the Twilio messages API
+func NewTwilioSender(opt *TwilioOptions) (ussdapp.SMSSender, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.AccountSID == "":
+		return nil, errors.New("missing account sid")
+	case opt.AuthToken == "":
+		return nil, errors.New("missing auth token")
+	case opt.From == "" && opt.MessagingServiceSID == "":
+		return nil, errors.New("missing from number or messaging service sid")
+	}
+
+	return &twilioSender{
+		opt:      opt,
+		endpoint: fmt.Sprintf(twilioURL, opt.AccountSID),
+		client:   httpClient(opt.HTTPClient),
+	}, nil
+}
+
+type twilioSender struct {
+	opt      *TwilioOptions
+	endpoint string
+	client   *http.Client
+}
+
+func (s *twilioSender) SendSMS(ctx context.Context, msisdn, message string) error {
+	form := url.Values{
+		"To":   {e164(msisdn)},
+		"Body": {message},
+	}
+	if s.opt.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", s.opt.MessagingServiceSID)
+	} else {
+		form.Set("From", s.opt.From)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.SetBasicAuth(s.opt.AccountSID, s.opt.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sms: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return responseError(res)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package ussdapp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSpoolInterval      = 30 * time.Second
+	defaultSpoolMemoryBatches = 100
+)
+
+// FailedLogSpool keeps session logs that fail to be written to a sink until they are written again
+type FailedLogSpool struct {
+	// Dir keeps files of failed logs. Defaults to failed-bulk-inserts in the working directory
+	Dir string
+	// Interval is how often failed logs are written again. Defaults to 30 seconds
+	Interval time.Duration
+	// MaxFiles is the most files kept in Dir. The oldest files are removed to make room for new ones. Unlimited when
+	// zero
+	MaxFiles int
+	// Compression gzips the files
+	Compression bool
+	// InMemory keeps failed logs in memory instead of files, e.g for read only file systems. Logs in memory are lost
+	// when the app stops before they are written
+	InMemory bool
+	// MemoryBatches is the most batches of logs kept in memory. The oldest batches are dropped to make room for new
+	// ones. Defaults to 100
+	MemoryBatches int
+}
+
+func (s *FailedLogSpool) dir() string {
+	if s == nil || s.Dir == "" {
+		return failedBulkDir
+	}
+	return s.Dir
+}
+
+func (s *FailedLogSpool) interval() time.Duration {
+	if s == nil || s.Interval <= 0 {
+		return defaultSpoolInterval
+	}
+	return s.Interval
+}
+
+func (s *FailedLogSpool) inMemory() bool {
+	return s != nil && s.InMemory
+}
+
+func (s *FailedLogSpool) memoryBatches() int {
+	if s == nil || s.MemoryBatches <= 0 {
+		return defaultSpoolMemoryBatches
+	}
+	return s.MemoryBatches
+}
+
+// failedBatch is a batch of logs kept in memory until it is written to the sink at index
+type failedBatch struct {
+	index int
+	logs  []*SessionRequest
+}
+
+// keepFailedLogs keeps a copy of the logs in memory, dropping the oldest batch when the queue is full
+func (app *UssdApp) keepFailedLogs(index int, logs []*SessionRequest) {
+	batch := failedBatch{index: index, logs: append([]*SessionRequest(nil), logs...)}
+
+	app.failedMu.Lock()
+	defer app.failedMu.Unlock()
+
+	app.failedBatches = append(app.failedBatches, batch)
+	app.trimFailedBatches()
+}
+
+// trimFailedBatches drops the oldest batches over the limit of the spool. It must be called with failedMu held
+func (app *UssdApp) trimFailedBatches() {
+	max := app.opt.FailedLogSpool.memoryBatches()
+	if len(app.failedBatches) <= max {
+		return
+	}
+
+	dropped := app.failedBatches[:len(app.failedBatches)-max]
+	app.failedBatches = append([]failedBatch(nil), app.failedBatches[len(dropped):]...)
+
+	count := 0
+	for _, batch := range dropped {
+		count += len(batch.logs)
+	}
+	app.opt.Logger.Warn("failed logs queue is full, dropping the oldest logs", "count", count)
+}
+
+// retryFailedBatches writes the batches kept in memory again, keeping the batches that fail
+func (app *UssdApp) retryFailedBatches(ctx context.Context) {
+	app.failedMu.Lock()
+	batches := app.failedBatches
+	app.failedBatches = nil
+	app.failedMu.Unlock()
+
+	retry := make([]failedBatch, 0)
+	for _, batch := range batches {
+		sinks := app.logSinks
+		if batch.index >= 0 && batch.index < len(sinks) {
+			sinks = sinks[batch.index : batch.index+1]
+		}
+
+		var err error
+		for _, sink := range sinks {
+			err = sink.Write(ctx, batch.logs)
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			app.opt.Logger.Warn("failed to save failed logs kept in memory", "count", len(batch.logs), "error", err)
+			retry = append(retry, batch)
+			continue
+		}
+
+		app.opt.Logger.Info("saved failed logs kept in memory", "count", len(batch.logs))
+	}
+
+	if len(retry) == 0 {
+		return
+	}
+
+	// Batches that failed again are older than those added while they were written
+	app.failedMu.Lock()
+	defer app.failedMu.Unlock()
+
+	app.failedBatches = append(retry, app.failedBatches...)
+	app.trimFailedBatches()
+}
+
+// trimFailedLogFiles removes the oldest files in the spool directory over the limit of the spool
+func (app *UssdApp) trimFailedLogFiles() {
+	spool := app.opt.FailedLogSpool
+	if spool == nil || spool.MaxFiles <= 0 {
+		return
+	}
+
+	dir := spool.dir()
+
+	filesInfo, err := ioutil.ReadDir(dir)
+	if err != nil {
+		app.opt.Logger.Warn("failed to read failed logs directory", "dir", dir, "error", err)
+		return
+	}
+	if len(filesInfo) <= spool.MaxFiles {
+		return
+	}
+
+	sort.Slice(filesInfo, func(i, j int) bool {
+		return filesInfo[i].ModTime().Before(filesInfo[j].ModTime())
+	})
+
+	for _, fileInfo := range filesInfo[:len(filesInfo)-spool.MaxFiles] {
+		fileName := filepath.Join(dir, fileInfo.Name())
+
+		err = os.Remove(fileName)
+		if err != nil {
+			app.opt.Logger.Warn("failed to remove failed logs file", "file", fileName, "error", err)
+			continue
+		}
+
+		app.opt.Logger.Warn("failed logs directory is full, removed the oldest file", "file", fileName)
+	}
+}
+
+// failedLogsFileName is the name of a file of failed logs of the sink at index
+func (app *UssdApp) failedLogsFileName(index int, ts int64) string {
+	ext := ".ndjson"
+	if app.opt.FailedLogSpool != nil && app.opt.FailedLogSpool.Compression {
+		ext += ".gz"
+	}
+	return fmt.Sprintf("bulk-%d-%d%s", index, ts, ext)
+}
@@ -0,0 +1,350 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ErrNoTransition is returned by Fire when no transition out of the menu is allowed for the event
+var ErrNoTransition = errors.New("no transition allowed")
+
+// TransitionKind is what moves a session from a menu to another.
+//
+// Menus are the states of the app and inputs of the user, or events fired by menu logic, are the events that move a
+// session between them.
+type TransitionKind int
+
+const (
+	// TransitionNext is taken on any input accepted by the menu, to its next menu
+	TransitionNext TransitionKind = iota
+	// TransitionRoute is taken on the input of a route of the menu
+	TransitionRoute
+	// TransitionGuarded is taken on input when its guard allows it, see AddTransition
+	TransitionGuarded
+	// TransitionDecision is taken when a rule of the decision table of the menu matches
+	TransitionDecision
+	// TransitionEvent is taken when menu logic fires its event, see Fire
+	TransitionEvent
+	// TransitionFallback is taken when the feature flag of the menu is off
+	TransitionFallback
+	// TransitionBack is taken on the back input, to the previous menu set in the menu options
+	TransitionBack
+)
+
+func (k TransitionKind) String() string {
+	switch k {
+	case TransitionNext:
+		return "next"
+	case TransitionRoute:
+		return "route"
+	case TransitionGuarded:
+		return "guarded"
+	case TransitionDecision:
+		return "decision"
+	case TransitionEvent:
+		return "event"
+	case TransitionFallback:
+		return "fallback"
+	case TransitionBack:
+		return "back"
+	default:
+		return "unknown"
+	}
+}
+
+// TransitionGuard tells whether a transition is allowed for the session, e.g whether the user has a loan to repay
+type TransitionGuard func(ctx context.Context, payload UssdPayload) (bool, error)
+
+// TransitionAction is run when a transition is taken, before the menu it goes to is rendered
+type TransitionAction func(ctx context.Context, payload UssdPayload) error
+
+// Transition is a move from a menu to another
+type Transition struct {
+	From string
+	To   string
+	Kind TransitionKind
+	// Input is the input that takes route and guarded transitions. Guarded transitions without input are taken on
+	// any input
+	Input string
+	// Event is the event that takes event transitions
+	Event string
+	// Label describes the transition in menu graphs, e.g the rule of a decision table
+	Label  string
+	Guard  TransitionGuard
+	Action TransitionAction
+}
+
+// TransitionOptions contains data for a transition added to the menus with AddTransition
+type TransitionOptions struct {
+	From string
+	To   string
+	// Input takes the transition when entered on the From menu. Empty takes it on any input. Routes of the menu take
+	// precedence over it
+	Input string
+	// Event takes the transition when fired with Fire instead of on input
+	Event string
+	// Guard allows the transition. Transitions without a guard are always allowed
+	Guard TransitionGuard
+	// Action is run when the transition is taken
+	Action TransitionAction
+}
+
+// AddTransition adds a transition between registered menus, or menus registered later, that is taken on input or on
+// an event fired by menu logic. Transitions out of a menu are tried in the order they are added.
+func (app *UssdApp) AddTransition(opt *TransitionOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing transition options")
+	case opt.From == "":
+		return errors.New("missing transition source menu")
+	case opt.To == "":
+		return fmt.Errorf("transition from %s has no target menu", opt.From)
+	case opt.Input != "" && opt.Event != "":
+		return fmt.Errorf("transition from %s to %s has both input and event", opt.From, opt.To)
+	}
+
+	t := &Transition{
+		From:   opt.From,
+		To:     opt.To,
+		Kind:   TransitionGuarded,
+		Input:  opt.Input,
+		Event:  opt.Event,
+		Label:  opt.Input,
+		Guard:  opt.Guard,
+		Action: opt.Action,
+	}
+	if opt.Event != "" {
+		t.Kind = TransitionEvent
+		t.Label = opt.Event
+	}
+
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	app.menuRegistry.Store(app.registry().withTransition(t))
+
+	return nil
+}
+
+// Transitions returns the transitions out of the menu: to its next menu, routes by input, guarded and event
+// transitions, rules of its decision table, the fallback of its feature flag and its previous menu
+func (app *UssdApp) Transitions(menuName string) []*Transition {
+	reg := app.registry()
+
+	m, ok := reg.menus[menuName]
+	if !ok {
+		return nil
+	}
+
+	ts := make([]*Transition, 0)
+
+	if m.NextMenu() != "" {
+		ts = append(ts, &Transition{From: menuName, To: m.NextMenu(), Kind: TransitionNext})
+	}
+
+	routes := menuRoutes(m)
+	inputs := make([]string, 0, len(routes))
+	for input := range routes {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		ts = append(ts, &Transition{From: menuName, To: routes[input], Kind: TransitionRoute, Input: input, Label: input})
+	}
+
+	ts = append(ts, reg.transitions[menuName]...)
+
+	if dm, ok := m.(decisionMenu); ok && dm.decisionTable() != nil {
+		table := dm.decisionTable()
+		for i, rule := range table.Rules {
+			ts = append(ts, &Transition{
+				From:  menuName,
+				To:    rule.Menu,
+				Kind:  TransitionDecision,
+				Label: firstVal(rule.Name, strconv.Itoa(i+1)),
+			})
+		}
+		if table.Default != "" {
+			ts = append(ts, &Transition{From: menuName, To: table.Default, Kind: TransitionDecision, Label: "default"})
+		}
+	}
+
+	if fm, ok := m.(flaggedMenu); ok {
+		if flag, fallback := fm.featureFlag(); flag != "" && fallback != "" {
+			ts = append(ts, &Transition{From: menuName, To: fallback, Kind: TransitionFallback, Label: flag})
+		}
+	}
+
+	if pm, ok := m.(interface{ PreviousMenu() string }); ok && pm.PreviousMenu() != "" {
+		ts = append(ts, &Transition{From: menuName, To: pm.PreviousMenu(), Kind: TransitionBack, Label: "back"})
+	}
+
+	return ts
+}
+
+// AllowedTransitions returns the transitions out of the menu shown to the user that the guards allow for the
+// session. Transitions taken on input are all listed, as they depend on what the user enters next.
+func (app *UssdApp) AllowedTransitions(ctx context.Context, payload UssdPayload) ([]*Transition, error) {
+	curr, err := app.shownMenu(ctx, payload)
+	if err != nil || curr == nil {
+		return nil, err
+	}
+
+	allowed := make([]*Transition, 0)
+	for _, t := range app.Transitions(curr.MenuName()) {
+		ok, err := t.allowed(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, t)
+		}
+	}
+
+	return allowed, nil
+}
+
+// Fire takes the first allowed transition for the event out of the menu, running its action and rendering the menu
+// it goes to. Call it from menu logic for internal triggers, e.g a declined payment.
+func (app *UssdApp) Fire(ctx context.Context, payload UssdPayload, from Menu, event string) (SessionResponse, error) {
+	for _, t := range app.registry().transitions[from.MenuName()] {
+		if t.Kind != TransitionEvent || t.Event != event {
+			continue
+		}
+
+		ok, err := t.allowed(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = t.run(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		app.debug(payload, "event transition", "from", t.From, "to", t.To, "event", event)
+
+		return app.ReplaceMenuWithName(ctx, t.To, payload)
+	}
+
+	return nil, fmt.Errorf("%w: event %s on %s menu", ErrNoTransition, event, from.MenuName())
+}
+
+// nextState returns the menu the input of the user moves the session to from the menu shown to them: the menu of
+// a route, an allowed guarded transition or a matching decision rule, in that order.
+//
+// Returns nil when the input takes none of them, so the menu saved as next is rendered.
+func (app *UssdApp) nextState(ctx context.Context, payload UssdPayload) (Menu, error) {
+	curr, err := app.shownMenu(ctx, payload)
+	if err != nil || curr == nil {
+		return nil, err
+	}
+
+	routed, err := app.routedMenu(curr, payload)
+	if err != nil || routed != nil {
+		return routed, err
+	}
+
+	guarded, err := app.guardedMenu(ctx, curr, payload)
+	if err != nil || guarded != nil {
+		return guarded, err
+	}
+
+	return app.decidedMenu(ctx, curr, payload)
+}
+
+// guardedMenu takes the first allowed guarded transition out of the menu for the input
+func (app *UssdApp) guardedMenu(ctx context.Context, curr Menu, payload UssdPayload) (Menu, error) {
+	for _, t := range app.registry().transitions[curr.MenuName()] {
+		if t.Kind != TransitionGuarded || (t.Input != "" && t.Input != payload.UssdCurrentParam()) {
+			continue
+		}
+
+		ok, err := t.allowed(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		next, ok := app.getMenu(t.To)
+		if !ok {
+			return nil, fmt.Errorf("%v: %s", ErrMenuNotExist, t.To)
+		}
+
+		err = t.run(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		app.debug(payload, "guarded transition", "from", t.From, "to", t.To)
+
+		return next, nil
+	}
+
+	return nil, nil
+}
+
+// shownMenu returns the menu shown to the user, whose transitions the input takes. Returns nil for new sessions
+func (app *UssdApp) shownMenu(ctx context.Context, payload UssdPayload) (Menu, error) {
+	curr, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), currentMenuKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to get current menu: %v", err)
+	}
+
+	m, ok := app.getMenu(curr)
+	if !ok {
+		return nil, nil
+	}
+
+	return m, nil
+}
+
+func (t *Transition) allowed(ctx context.Context, payload UssdPayload) (bool, error) {
+	if t.Guard == nil {
+		return true, nil
+	}
+
+	ok, err := t.Guard(ctx, payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to check transition from %s to %s: %w", t.From, t.To, err)
+	}
+
+	return ok, nil
+}
+
+func (t *Transition) run(ctx context.Context, payload UssdPayload) error {
+	if t.Action == nil {
+		return nil
+	}
+
+	err := t.Action(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("transition from %s to %s: %w", t.From, t.To, err)
+	}
+
+	return nil
+}
+
+// withTransition returns a copy of the registry with the transition added
+func (r *menuRegistry) withTransition(t *Transition) *menuRegistry {
+	next := *r
+	next.transitions = make(map[string][]*Transition, len(r.transitions)+1)
+	for from, ts := range r.transitions {
+		next.transitions[from] = ts
+	}
+	next.transitions[t.From] = append(append([]*Transition{}, r.transitions[t.From]...), t)
+
+	return &next
+}
@@ -0,0 +1,145 @@
+package ussdapp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+// LogStorage keeps objects of session logs in a bucket of an object store such as S3, GCS or MinIO. See the
+// storage/s3 package
+type LogStorage interface {
+	// Put writes the object, replacing it when it exists
+	Put(ctx context.Context, key string, data []byte) error
+	// Get reads the object
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys of objects starting with the prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the object
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorageLogSink creates a log sink that uploads each batch of session logs to the storage as a gzipped file of
+// json lines, under the prefix and the day the batch was written, e.g archive/2022/11/03/1667467200000000000.ndjson.gz.
+//
+// It suits archiving logs pruned by Options.LogRetention rather than logs that are queried.
+func NewStorageLogSink(storage LogStorage, prefix string) LogSink {
+	return &storageLogSink{storage: storage, prefix: prefix}
+}
+
+type storageLogSink struct {
+	storage LogStorage
+	prefix  string
+	seq     uint32
+}
+
+func (s *storageLogSink) Write(ctx context.Context, logs []*SessionRequest) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	data, err := encodeLogs(logs, true)
+	if err != nil {
+		return err
+	}
+
+	// The sequence keeps keys of batches written at the same time apart
+	now := time.Now().UTC()
+	key := path.Join(s.prefix, now.Format("2006/01/02"),
+		fmt.Sprintf("%d-%d.ndjson.gz", now.UnixNano(), atomic.AddUint32(&s.seq, 1)))
+
+	err = s.storage.Put(ctx, key, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload session logs: %v", err)
+	}
+
+	return nil
+}
+
+// encodeLogs writes the logs as json lines, gzipped when compress is set
+func encodeLogs(logs []*SessionRequest, compress bool) ([]byte, error) {
+	var (
+		buf = &bytes.Buffer{}
+		w   io.Writer
+		gz  *gzip.Writer
+	)
+
+	w = buf
+	if compress {
+		gz = gzip.NewWriter(buf)
+		w = gz
+	}
+
+	enc := json.NewEncoder(w)
+	for _, log := range logs {
+		err := enc.Encode(log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode session logs: %v", err)
+		}
+	}
+
+	if gz != nil {
+		err := gz.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress session logs: %v", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeLogs reads logs written by encodeLogs, gzipped or not. A json array of logs, which older versions wrote to
+// failed logs files, is read as well
+func decodeLogs(data []byte) ([]*SessionRequest, error) {
+	// Gzip streams start with the magic bytes 0x1f 0x8b
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress session logs: %v", err)
+		}
+		defer gz.Close()
+
+		data, err = io.ReadAll(gz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress session logs: %v", err)
+		}
+	}
+
+	logs := make([]*SessionRequest, 0)
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		err := json.Unmarshal(data, &logs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session logs: %v", err)
+		}
+		return logs, nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		log := &SessionRequest{}
+		err := json.Unmarshal(line, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session logs: %v", err)
+		}
+		logs = append(logs, log)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode session logs: %v", err)
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,5 @@
+/*
+Package s3 implements storage of USSD session logs in buckets of S3 compatible object stores, such as Amazon S3, MinIO
+and Google Cloud Storage through its XML API with HMAC keys.
+*/
+package s3storage
@@ -0,0 +1,129 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gidyon/ussdapp"
+)
+
+// API is the part of the S3 client used by the storage
+type API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// Options contains data required for the s3 storage
+type Options struct {
+	// Client is the S3 client, e.g s3.NewFromConfig(cfg). Clients of other S3 compatible stores resolve their endpoint
+	// with s3.EndpointResolverFromURL and set UsePathStyle
+	Client API
+	// Bucket keeps the objects
+	Bucket string
+	// Prefix is added to the keys of objects, e.g ussd/
+	Prefix string
+}
+
+// NewS3Storage creates a storage that keeps objects in the bucket
+func NewS3Storage(opt *Options) (ussdapp.LogStorage, error) {
+	switch {
+	case opt == nil:
+		return nil, errors.New("missing options")
+	case opt.Client == nil:
+		return nil, errors.New("missing s3 client")
+	case opt.Bucket == "":
+		return nil, errors.New("missing bucket")
+	}
+
+	return &s3Storage{
+		client: opt.Client,
+		bucket: opt.Bucket,
+		prefix: opt.Prefix,
+	}, nil
+}
+
+type s3Storage struct {
+	client API
+	bucket string
+	prefix string
+}
+
+func (ss *s3Storage) Put(ctx context.Context, key string, data []byte) error {
+	contentType := "application/x-ndjson"
+	if strings.HasSuffix(key, ".gz") {
+		contentType = "application/gzip"
+	}
+
+	_, err := ss.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(ss.bucket),
+		Key:           aws.String(ss.prefix + key),
+		Body:          bytes.NewReader(data),
+		ContentLength: int64(len(data)),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object %s: %v", key, err)
+	}
+
+	return nil
+}
+
+func (ss *s3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := ss.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(ss.prefix + key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %v", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %v", key, err)
+	}
+
+	return data, nil
+}
+
+func (ss *s3Storage) Delete(ctx context.Context, key string) error {
+	_, err := ss.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(ss.prefix + key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %v", key, err)
+	}
+
+	return nil
+}
+
+func (ss *s3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+
+	pages := s3.NewListObjectsV2Paginator(ss.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(ss.bucket),
+		Prefix: aws.String(ss.prefix + prefix),
+	})
+	for pages.HasMorePages() {
+		out, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+
+		// Keys are returned without the prefix of the storage, as they were put
+		for _, obj := range out.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.ToString(obj.Key), ss.prefix))
+		}
+	}
+
+	return keys, nil
+}
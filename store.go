@@ -0,0 +1,116 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Session reads and writes data for a ussd session. Data is kept in the session hash in cache and expires with the session.
+type Session interface {
+	// Key returns the cache key of the session hash
+	Key() string
+	// Get returns the value of the field. Returns ErrKeyNotFound if the field is not set
+	Get(ctx context.Context, field string) (string, error)
+	// Set sets the value of the field
+	Set(ctx context.Context, field string, value interface{}) error
+	// Del removes the fields
+	Del(ctx context.Context, fields ...string) error
+	// GetInt returns the value of the field as an integer
+	GetInt(ctx context.Context, field string) (int, error)
+	// GetBool returns the value of the field as a boolean
+	GetBool(ctx context.Context, field string) (bool, error)
+	// SetJSON saves value in the field as json
+	SetJSON(ctx context.Context, field string, value interface{}) error
+	// GetJSON reads json in the field into dest
+	GetJSON(ctx context.Context, field string, dest interface{}) error
+}
+
+// Session returns a store for data of the payload session
+func (app *UssdApp) Session(payload UssdPayload) Session {
+	return &session{
+		cache: app.opt.Cache,
+		key:   app.GetSessionKey(payload),
+	}
+}
+
+type session struct {
+	cache Cacher
+	key   string
+}
+
+func (s *session) Key() string {
+	return s.key
+}
+
+func (s *session) Get(ctx context.Context, field string) (string, error) {
+	return s.cache.GetMapField(ctx, s.key, field)
+}
+
+func (s *session) Set(ctx context.Context, field string, value interface{}) error {
+	err := s.cache.SetMapField(ctx, s.key, field, value)
+	if err != nil {
+		return fmt.Errorf("failed to set session field %s: %v", field, err)
+	}
+	return nil
+}
+
+func (s *session) Del(ctx context.Context, fields ...string) error {
+	err := s.cache.DeleteMapField(ctx, s.key, fields...)
+	if err != nil {
+		return fmt.Errorf("failed to delete session fields: %v", err)
+	}
+	return nil
+}
+
+func (s *session) GetInt(ctx context.Context, field string) (int, error) {
+	val, err := s.Get(ctx, field)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("session field %s is not an integer: %v", field, err)
+	}
+
+	return v, nil
+}
+
+func (s *session) GetBool(ctx context.Context, field string) (bool, error) {
+	val, err := s.Get(ctx, field)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("session field %s is not a boolean: %v", field, err)
+	}
+
+	return v, nil
+}
+
+func (s *session) SetJSON(ctx context.Context, field string, value interface{}) error {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session field %s: %v", field, err)
+	}
+
+	return s.Set(ctx, field, bs)
+}
+
+func (s *session) GetJSON(ctx context.Context, field string, dest interface{}) error {
+	val, err := s.Get(ctx, field)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal([]byte(val), dest)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal session field %s: %v", field, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package ussdapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTaskTTL        = time.Hour
+	defaultPendingContent = "CON Your request is still being processed\n1. Check again"
+	defaultSuccessContent = "END Your request was successful"
+	defaultFailureContent = "END Your request failed. Please try again later"
+)
+
+// TaskStatus is the state of a task started with StartTask
+type TaskStatus string
+
+const (
+	TaskPending   TaskStatus = "pending"
+	TaskSucceeded TaskStatus = "succeeded"
+	TaskFailed    TaskStatus = "failed"
+)
+
+// TaskFn is backend work that runs after the response is sent, returning a result for the success screen
+type TaskFn func(ctx context.Context) (string, error)
+
+// Task is work started by a user with StartTask
+type Task struct {
+	ID     string     `json:"id"`
+	Name   string     `json:"name"`
+	Status TaskStatus `json:"status"`
+	// Result is returned by the task when it succeeds
+	Result string `json:"result,omitempty"`
+	// Error is the error of a failed task
+	Error string `json:"error,omitempty"`
+}
+
+func (app *UssdApp) taskKey(id string) string {
+	return fmt.Sprintf("%s:tasks:%s", app.opt.AppName, id)
+}
+
+// userTaskKey holds the id of the latest task of the name started by the msisdn, for later sessions to find it
+func (app *UssdApp) userTaskKey(payload UssdPayload, name string) string {
+	return fmt.Sprintf("%s:user_tasks:%s:%s", app.opt.AppName, name, payload.Msisdn())
+}
+
+func (app *UssdApp) taskTTL() time.Duration {
+	if app.opt.TaskTTL > 0 {
+		return app.opt.TaskTTL
+	}
+	return defaultTaskTTL
+}
+
+func (app *UssdApp) saveTask(ctx context.Context, task *Task) error {
+	bs, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %v", err)
+	}
+
+	err = app.opt.Cache.Set(ctx, app.taskKey(task.ID), string(bs), app.taskTTL())
+	if err != nil {
+		return fmt.Errorf("failed to save task %s: %v", task.ID, err)
+	}
+
+	return nil
+}
+
+// StartTask runs fn in the background and returns the id of the task, for work that takes longer than the gateway
+// waits for a response, e.g opening an account.
+//
+// The task id is kept in the session and for the msisdn, so a poll menu shows the outcome in the same session or
+// when the user dials again. Tasks are kept for Options.TaskTTL and Close waits for running tasks.
+func (app *UssdApp) StartTask(ctx context.Context, payload UssdPayload, name string, fn TaskFn) (string, error) {
+	if atomic.LoadInt32(&app.closed) == 1 {
+		return "", errors.New("app is closed")
+	}
+
+	bs := make([]byte, 16)
+	_, err := rand.Read(bs)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate task id: %v", err)
+	}
+
+	task := &Task{ID: hex.EncodeToString(bs), Name: name, Status: TaskPending}
+
+	err = app.saveTask(ctx, task)
+	if err != nil {
+		return "", err
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), taskField(name), task.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to save task in session: %v", err)
+	}
+
+	err = app.opt.Cache.Set(ctx, app.userTaskKey(payload, name), task.ID, app.taskTTL())
+	if err != nil {
+		return "", fmt.Errorf("failed to save task of msisdn: %v", err)
+	}
+
+	app.workers.Add(1)
+	go app.runTask(task, fn)
+
+	return task.ID, nil
+}
+
+func taskField(name string) string {
+	return "task:" + name
+}
+
+// runTask runs the task, saving its outcome. The task outlives the request that started it
+func (app *UssdApp) runTask(task *Task, fn TaskFn) {
+	defer app.workers.Done()
+
+	ctx := context.Background()
+
+	result, err := func() (result string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("task panicked: %v", r)
+			}
+		}()
+		return fn(ctx)
+	}()
+
+	if err != nil {
+		app.opt.Logger.Error("task failed", "task", task.Name, "task_id", task.ID, "error", err)
+		task.Status, task.Error = TaskFailed, err.Error()
+	} else {
+		task.Status, task.Result = TaskSucceeded, result
+	}
+
+	err = app.saveTask(ctx, task)
+	if err != nil {
+		app.opt.Logger.Error("failed to save task outcome", "task", task.Name, "task_id", task.ID, "error", err)
+	}
+}
+
+// GetTask returns the latest task of the name started in the session, or by the msisdn in an earlier session.
+//
+// Returns ErrKeyNotFound if there is no such task or it expired.
+func (app *UssdApp) GetTask(ctx context.Context, payload UssdPayload, name string) (*Task, error) {
+	id, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), taskField(name))
+	switch {
+	case err == nil && id != "":
+	case err == nil, errors.Is(err, ErrKeyNotFound):
+		id, err = app.opt.Cache.Get(ctx, app.userTaskKey(payload, name))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to get task of session: %v", err)
+	}
+
+	val, err := app.opt.Cache.Get(ctx, app.taskKey(id))
+	if err != nil {
+		return nil, err
+	}
+
+	task := &Task{}
+	err = json.Unmarshal([]byte(val), task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %v", err)
+	}
+
+	return task, nil
+}
+
+// clearTask forgets the task of the name once its outcome has been shown
+func (app *UssdApp) clearTask(ctx context.Context, payload UssdPayload, name string) error {
+	err := app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), taskField(name))
+	if err != nil {
+		return fmt.Errorf("failed to clear task of session: %v", err)
+	}
+
+	err = app.opt.Cache.Delete(ctx, app.userTaskKey(payload, name))
+	if err != nil {
+		return fmt.Errorf("failed to clear task of msisdn: %v", err)
+	}
+
+	return nil
+}
+
+// PollMenuOptions contains data for a menu that shows the status of a task started with StartTask
+type PollMenuOptions struct {
+	MenuName string
+	ShortCut string
+	// Task is the name of the task
+	Task string
+	// Pending is shown while the task runs, per language. Any input checks again
+	Pending Content
+	// Success is shown once the task succeeds, per language. {{.result}} is replaced with the task result
+	Success Content
+	// SuccessMenu is rendered instead of Success when set
+	SuccessMenu string
+	// Failure is shown when the task fails, per language. {{.error}} is replaced with the task error
+	Failure Content
+	// FailureMenu is rendered instead of Failure when set
+	FailureMenu string
+	// NoTaskMenu is rendered when the user has no task. Defaults to the home menu
+	NoTaskMenu string
+}
+
+// AddPollMenu registers a menu that shows whether a task started with StartTask is pending, succeeded or failed.
+//
+// Once the outcome is shown the task is forgotten. Users who dial again can be sent to the poll menu from the home
+// menu:
+//
+//	if _, err := app.GetTask(ctx, payload, "open_account"); err == nil {
+//		return app.ReplaceMenuWithName(ctx, "open_account_status", payload)
+//	}
+func (app *UssdApp) AddPollMenu(opt *PollMenuOptions) error {
+	switch {
+	case opt == nil:
+		return errors.New("missing poll menu options")
+	case opt.MenuName == "":
+		return errors.New("missing poll menu name")
+	case opt.Task == "":
+		return fmt.Errorf("poll menu %s is missing a task", opt.MenuName)
+	}
+
+	m := NewMenu(&MenuOptions{
+		MenuName: opt.MenuName,
+		NextMenu: opt.MenuName,
+		ShortCut: opt.ShortCut,
+		GenerateMenuFn: func(ctx context.Context, payload UssdPayload, m Menu) (SessionResponse, error) {
+			task, err := app.GetTask(ctx, payload, opt.Task)
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrKeyNotFound):
+				return app.ReplaceMenuWithName(ctx, firstVal(opt.NoTaskMenu, app.homeMenuOf(payload)), payload)
+			default:
+				return nil, err
+			}
+
+			var (
+				content  = opt.Pending
+				fallback = defaultPendingContent
+				menu     string
+			)
+
+			switch task.Status {
+			case TaskSucceeded:
+				content, fallback, menu = opt.Success, defaultSuccessContent, opt.SuccessMenu
+			case TaskFailed:
+				content, fallback, menu = opt.Failure, defaultFailureContent, opt.FailureMenu
+			}
+
+			if task.Status != TaskPending {
+				err = app.clearTask(ctx, payload, opt.Task)
+				if err != nil {
+					return nil, err
+				}
+				if menu != "" {
+					return app.ReplaceMenuWithName(ctx, menu, payload)
+				}
+			}
+
+			text := firstVal(content.Text(app.GetLanguage(ctx, payload), app.opt.DefaultLanguage), fallback)
+			if strings.Contains(text, "{{") {
+				text, err = executeTemplate(text, map[string]string{"result": task.Result, "error": task.Error})
+				if err != nil {
+					return nil, fmt.Errorf("menu %s: %v", opt.MenuName, err)
+				}
+			}
+
+			return &sessionResponse{response: text, menuName: m.MenuName()}, nil
+		},
+	})
+
+	return app.AddMenu(m)
+}
@@ -0,0 +1,41 @@
+package ussdapp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// templates caches parsed menu templates by their text
+var templates sync.Map
+
+// executeTemplate applies data to the named placeholders in text, e.g "Welcome {{.Name}}".
+//
+// Text without placeholders is returned as it is.
+func executeTemplate(text string, data interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	var tmpl *template.Template
+	if v, ok := templates.Load(text); ok {
+		tmpl = v.(*template.Template)
+	} else {
+		var err error
+		tmpl, err = template.New("menu").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse menu template: %v", err)
+		}
+		templates.Store(text, tmpl)
+	}
+
+	sb := &strings.Builder{}
+
+	err := tmpl.Execute(sb, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute menu template: %v", err)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,21 @@
+package ussdapp
+
+import "fmt"
+
+// checkIsolation returns an error if the apps would share cache keys or session logs when run in one process.
+//
+// Apps are tenants of the process they run in. Each app keeps its own menu registry, its cache keys are prefixed
+// with Options.AppName and its session logs go to its own table, see LogsTable. Apps served together, e.g by a
+// Router, must not share a name nor a logs table in the same database.
+func (app *UssdApp) checkIsolation(other *UssdApp) error {
+	switch {
+	case app == other:
+		return nil
+	case app.opt.AppName == other.opt.AppName:
+		return fmt.Errorf("apps are named %s, cache keys would be shared", app.opt.AppName)
+	case app.opt.SQLDB != nil && app.opt.SQLDB == other.opt.SQLDB && app.logsTable == other.logsTable:
+		return fmt.Errorf("apps %s and %s save logs to table %s, set a table name", app.opt.AppName, other.opt.AppName, app.logsTable)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	terminatedMenuName      = "terminated"
+	terminatedStatusMessage = "terminated by operator"
+)
+
+// ActiveSession is a session in progress on this instance
+type ActiveSession struct {
+	SessionID   string `json:"session_id"`
+	Msisdn      string `json:"msisdn"`
+	ServiceCode string `json:"service_code"`
+	// MenuName is the last menu sent to the user
+	MenuName  string    `json:"menu_name"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ActiveSessions returns the sessions in progress on this instance, oldest first. Sessions whose data expired or was
+// deleted are left out
+func (app *UssdApp) ActiveSessions(ctx context.Context) ([]*ActiveSession, error) {
+	now := time.Now()
+
+	app.trackedMu.Lock()
+	sessions := make(map[string]*ActiveSession, len(app.tracked))
+	for key, ts := range app.tracked {
+		if ts.expiresAt.Before(now) {
+			continue
+		}
+		sessions[key] = &ActiveSession{
+			SessionID:   ts.event.SessionID,
+			Msisdn:      ts.event.Msisdn,
+			ServiceCode: ts.event.ServiceCode,
+			MenuName:    ts.event.MenuName,
+			StartedAt:   ts.event.Time,
+			ExpiresAt:   ts.expiresAt,
+		}
+	}
+	app.trackedMu.Unlock()
+
+	active := make([]*ActiveSession, 0, len(sessions))
+	for key, session := range sessions {
+		_, err := app.opt.Cache.GetMapField(ctx, key, "new")
+		switch {
+		case err == nil:
+			active = append(active, session)
+		case errors.Is(err, ErrKeyNotFound):
+		default:
+			return nil, fmt.Errorf("failed to get session %s: %v", session.SessionID, err)
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].StartedAt.Before(active[j].StartedAt)
+	})
+
+	return active, nil
+}
+
+// TerminateSession ends a session in progress on this instance, e.g one that is stuck or abusive, by deleting its
+// data. The next request of the session starts a new session on the home menu.
+//
+// A session log with the status "terminated by operator" is saved when the app saves logs.
+func (app *UssdApp) TerminateSession(ctx context.Context, sessionID string) error {
+	key, ok := app.trackedSessionKey(sessionID)
+	if !ok {
+		return fmt.Errorf("session %s is not on this instance: %w", sessionID, ErrKeyNotFound)
+	}
+
+	_, msisdn, _ := app.ParseSessionKey(key)
+
+	return app.terminateSession(ctx, sessionID, msisdn)
+}
+
+// terminateSession deletes the data of the session of the msisdn, which may be on any instance
+func (app *UssdApp) terminateSession(ctx context.Context, sessionID, msisdn string) error {
+	sessionKey := app.sessionKeyOf(sessionID, msisdn)
+
+	_, err := app.opt.Cache.GetMapField(ctx, sessionKey, "new")
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return fmt.Errorf("session %s: %w", sessionID, ErrKeyNotFound)
+	default:
+		return fmt.Errorf("failed to get session: %v", err)
+	}
+
+	err = app.opt.Cache.DeleteMap(ctx, sessionKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+
+	var menuName string
+
+	app.trackedMu.Lock()
+	if ts, ok := app.tracked[sessionKey]; ok {
+		menuName = ts.event.MenuName
+		delete(app.tracked, sessionKey)
+	}
+	app.trackedMu.Unlock()
+
+	app.countSessions(ctx, -1)
+
+	// The session was ended, not abandoned by the user
+	if app.markEnded() {
+		err = app.opt.Cache.Set(ctx, app.sessionEndedKey(sessionID, msisdn), "true", app.opt.SessionDuration)
+		if err != nil {
+			app.opt.Logger.Warn("failed to mark session as ended", "session_id", sessionID, "error", err)
+		}
+	}
+
+	app.opt.Logger.Info("session terminated by operator", "session_id", sessionID, "msisdn", msisdn)
+
+	if !app.savingLogs() || atomic.LoadInt32(&app.closed) == 1 {
+		return nil
+	}
+
+	app.persistLog(ctx, &SessionRequest{
+		SessionID:     sessionID,
+		Msisdn:        msisdn,
+		MenuName:      firstVal(menuName, terminatedMenuName),
+		Succeeded:     false,
+		Ended:         true,
+		StatusMessage: terminatedStatusMessage,
+		CreatedAt:     time.Now(),
+	})
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/gidyon/ussdapp"
+
+// newTracer returns the tracer for the app. Spans are not recorded when the provider is nil
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = trace.NewNoopTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// endSpan records the error in the span if any and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingCacher creates a span for each cache operation
+type tracingCacher struct {
+	Cacher
+	tracer trace.Tracer
+}
+
+func (c *tracingCacher) start(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "cache."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("cache.key_kind", keyKind(key)),
+	))
+}
+
+// keyKind returns the app name and kind of a cache key, e.g myapp:sessions. Session ids and msisdns in the rest of
+// the key are left out of spans
+func keyKind(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) < 3 {
+		return "other"
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+func (c *tracingCacher) end(span trace.Span, err error) {
+	if errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrValueNotFound) {
+		err = nil
+	}
+	endSpan(span, err)
+}
+
+func (c *tracingCacher) Set(ctx context.Context, key, value string, dur time.Duration) error {
+	ctx, span := c.start(ctx, "Set", key)
+	err := c.Cacher.Set(ctx, key, value, dur)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := c.start(ctx, "Get", key)
+	val, err := c.Cacher.Get(ctx, key)
+	c.end(span, err)
+	return val, err
+}
+
+func (c *tracingCacher) Delete(ctx context.Context, key string) error {
+	ctx, span := c.start(ctx, "Delete", key)
+	err := c.Cacher.Delete(ctx, key)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) SetMap(ctx context.Context, key string, fields map[string]interface{}) error {
+	ctx, span := c.start(ctx, "SetMap", key)
+	err := c.Cacher.SetMap(ctx, key, fields)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) GetMap(ctx context.Context, key string) (map[string]string, error) {
+	ctx, span := c.start(ctx, "GetMap", key)
+	val, err := c.Cacher.GetMap(ctx, key)
+	c.end(span, err)
+	return val, err
+}
+
+func (c *tracingCacher) DeleteMap(ctx context.Context, key string) error {
+	ctx, span := c.start(ctx, "DeleteMap", key)
+	err := c.Cacher.DeleteMap(ctx, key)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) SetMapField(ctx context.Context, key string, values ...interface{}) error {
+	ctx, span := c.start(ctx, "SetMapField", key)
+	err := c.Cacher.SetMapField(ctx, key, values...)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) GetMapField(ctx context.Context, key, field string) (string, error) {
+	ctx, span := c.start(ctx, "GetMapField", key)
+	span.SetAttributes(attribute.String("cache.field", field))
+	val, err := c.Cacher.GetMapField(ctx, key, field)
+	c.end(span, err)
+	return val, err
+}
+
+func (c *tracingCacher) GetMapFields(ctx context.Context, key string, fields ...string) (map[string]string, error) {
+	ctx, span := c.start(ctx, "GetMapFields", key)
+	span.SetAttributes(attribute.StringSlice("cache.fields", fields))
+	val, err := c.Cacher.GetMapFields(ctx, key, fields...)
+	c.end(span, err)
+	return val, err
+}
+
+func (c *tracingCacher) DeleteMapField(ctx context.Context, key string, fields ...string) error {
+	ctx, span := c.start(ctx, "DeleteMapField", key)
+	span.SetAttributes(attribute.StringSlice("cache.fields", fields))
+	err := c.Cacher.DeleteMapField(ctx, key, fields...)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) SetUnique(ctx context.Context, key string, value string) (bool, error) {
+	ctx, span := c.start(ctx, "SetUnique", key)
+	ok, err := addToSet(ctx, c.Cacher, key, value)
+	c.end(span, err)
+	return ok, err
+}
+
+func (c *tracingCacher) Incr(ctx context.Context, key string, dur time.Duration) (int64, error) {
+	ctx, span := c.start(ctx, "Incr", key)
+	n, err := incr(ctx, c.Cacher, key, dur)
+	c.end(span, err)
+	return n, err
+}
+
+func (c *tracingCacher) ExistInSet(ctx context.Context, key string, value string) (bool, error) {
+	ctx, span := c.start(ctx, "ExistInSet", key)
+	ok, err := c.Cacher.ExistInSet(ctx, key, value)
+	c.end(span, err)
+	return ok, err
+}
+
+func (c *tracingCacher) DeleteSetValue(ctx context.Context, key string, value string) error {
+	ctx, span := c.start(ctx, "DeleteSetValue", key)
+	err := c.Cacher.DeleteSetValue(ctx, key, value)
+	c.end(span, err)
+	return err
+}
+
+func (c *tracingCacher) Expire(ctx context.Context, key string, dur time.Duration) error {
+	ctx, span := c.start(ctx, "Expire", key)
+	err := c.Cacher.Expire(ctx, key, dur)
+	c.end(span, err)
+	return err
+}
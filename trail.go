@@ -0,0 +1,118 @@
+package ussdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoSessionLogs is returned when a session has no logs, e.g the id is wrong or the logs were pruned
+var ErrNoSessionLogs = errors.New("no logs for session")
+
+// SessionTrail is what a user typed and what the app responded in a session, for support tooling
+type SessionTrail struct {
+	SessionID string
+	// Msisdn is the msisdn as saved in the logs, so it is hashed or masked under a PIIPolicy
+	Msisdn    string
+	StartedAt time.Time
+	Steps     []*SessionTrailStep
+}
+
+// SessionTrailStep is a request of the session and the response of the app
+type SessionTrailStep struct {
+	Time       time.Time
+	USSDParams string
+	UserInput  string
+	// MenuName is the menu rendered for the request
+	MenuName string
+	// MenuVersion is the version of the menu rendered, see AddMenuVersion
+	MenuVersion   string
+	Succeeded     bool
+	Ended         bool
+	StatusMessage string
+	// ResponseText is the text shown to the user, saved with Options.LogResponseText
+	ResponseText string
+	// DurationMs is the time taken to answer the request
+	DurationMs int64
+}
+
+// GetSessionTrail returns the requests and responses of a session in the order they happened, read from Options.SQLDB
+func (app *UssdApp) GetSessionTrail(ctx context.Context, sessionID string) (*SessionTrail, error) {
+	trails, err := app.sessionTrails(ctx, "session_id = ?", sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(trails) == 0 {
+		return nil, fmt.Errorf("%w %s", ErrNoSessionLogs, sessionID)
+	}
+
+	return trails[0], nil
+}
+
+// GetMsisdnSessions returns the requests of the msisdn made between from and to grouped by session, oldest first.
+//
+// Msisdns hashed by the PIIPolicy are hashed before the query. Masked msisdns cannot be queried since masks are shared by many msisdns.
+func (app *UssdApp) GetMsisdnSessions(ctx context.Context, msisdn string, from, to time.Time) ([]*SessionTrail, error) {
+	policy := app.opt.PIIPolicy
+	if policy == nil {
+		policy = &PIIPolicy{}
+	}
+	if policy.MaskMsisdn && !policy.HashMsisdn {
+		return nil, errors.New("sessions cannot be queried by masked msisdn")
+	}
+
+	return app.sessionTrails(ctx, "msisdn = ? AND created_at BETWEEN ? AND ?", policy.logMsisdn(msisdn), from, to)
+}
+
+// sessionTrails reads the logs matching the query, grouped by session in the order the sessions started
+func (app *UssdApp) sessionTrails(ctx context.Context, query string, args ...interface{}) ([]*SessionTrail, error) {
+	if app.opt.SQLDB == nil {
+		return nil, errors.New("session trails require sql database")
+	}
+
+	logs := make([]*SessionRequest, 0)
+
+	err := app.opt.SQLDB.WithContext(ctx).Table(app.logsTable).
+		Select("session_id, msisdn, menu_name, menu_version, ussd_params, user_input, response_text, succeeded, ended, status_message, duration_ms, created_at").
+		Where(query, args...).
+		Order("created_at, id").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session logs: %v", err)
+	}
+
+	var (
+		trails    = make([]*SessionTrail, 0)
+		bySession = make(map[string]*SessionTrail)
+	)
+
+	for _, log := range logs {
+		trail, ok := bySession[log.SessionID]
+		if !ok {
+			trail = &SessionTrail{
+				SessionID: log.SessionID,
+				Msisdn:    log.Msisdn,
+				StartedAt: log.CreatedAt,
+				Steps:     make([]*SessionTrailStep, 0),
+			}
+			bySession[log.SessionID] = trail
+			trails = append(trails, trail)
+		}
+
+		trail.Steps = append(trail.Steps, &SessionTrailStep{
+			Time:          log.CreatedAt,
+			USSDParams:    log.USSDParams,
+			UserInput:     log.UserInput,
+			MenuName:      log.MenuName,
+			MenuVersion:   log.MenuVersion,
+			Succeeded:     log.Succeeded,
+			Ended:         log.Ended,
+			StatusMessage: log.StatusMessage,
+			ResponseText:  log.ResponseText,
+			DurationMs:    log.DurationMs,
+		})
+	}
+
+	return trails, nil
+}
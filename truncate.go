@@ -0,0 +1,213 @@
+package ussdapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const overflowPagesKey = "overflow_pages"
+
+// ErrResponseTooLong is returned when a response exceeds the maximum length and the truncation strategy is RejectResponse
+var ErrResponseTooLong = errors.New("response too long")
+
+// TruncationStrategy decides what happens to responses longer than Options.MaxResponseLength
+type TruncationStrategy int
+
+const (
+	// TruncateResponse cuts the response at the maximum length
+	TruncateResponse TruncationStrategy = iota
+	// PaginateResponse splits the response into pages that the user moves through using the next page input.
+	//
+	// Responses that end the session are truncated since the user cannot request the next page.
+	PaginateResponse
+	// RejectResponse fails the request with ErrResponseTooLong
+	RejectResponse
+)
+
+// ResponseTooLongFn is called when a menu renders a response longer than the maximum length
+type ResponseTooLongFn func(ctx context.Context, payload UssdPayload, sr SessionResponse, length int)
+
+// splitPrefix separates the CON/END/UPR prefix from the response text
+func splitPrefix(res string) (string, string) {
+	for _, prefix := range []string{conPrefix, endPrefix, uprPrefix} {
+		if strings.HasPrefix(res, prefix) {
+			return prefix, strings.TrimSpace(res[len(prefix):])
+		}
+	}
+	return "", res
+}
+
+// truncateRunes cuts text to at most n characters
+func truncateRunes(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n])
+}
+
+// splitPages splits text into pages of at most n characters, breaking at new lines where possible
+func splitPages(text string, n int) []string {
+	var (
+		pages = make([]string, 0)
+		curr  = ""
+	)
+
+	if n < 1 {
+		n = 1
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		for utf8.RuneCountInString(line) > n {
+			if curr != "" {
+				pages = append(pages, curr)
+				curr = ""
+			}
+			pages = append(pages, string([]rune(line)[:n]))
+			line = string([]rune(line)[n:])
+		}
+
+		switch {
+		case curr == "":
+			curr = line
+		case utf8.RuneCountInString(curr)+1+utf8.RuneCountInString(line) > n:
+			pages = append(pages, curr)
+			curr = line
+		default:
+			curr = curr + "\n" + line
+		}
+	}
+
+	if curr != "" || len(pages) == 0 {
+		pages = append(pages, curr)
+	}
+
+	return pages
+}
+
+// limitResponse applies the truncation strategy to responses longer than the maximum length
+func (app *UssdApp) limitResponse(ctx context.Context, payload UssdPayload, sr SessionResponse) (SessionResponse, error) {
+	maxLen := app.opt.MaxResponseLength
+	if maxLen <= 0 {
+		return sr, nil
+	}
+
+	// Pages from an earlier response are stale
+	if p, ok := payload.(*ussdPayload); ok && p.data.overflowPages {
+		err := app.opt.Cache.DeleteMapField(ctx, app.GetSessionKey(payload), overflowPagesKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear overflow pages: %v", err)
+		}
+		p.data.overflowPages = false
+	}
+
+	length := utf8.RuneCountInString(ussdResponseText(sr, false))
+	if length <= maxLen {
+		return sr, nil
+	}
+
+	app.opt.Logger.Warn("response exceeds the length limit", "menu", sr.MenuName(), "length", length, "limit", maxLen)
+	if app.opt.OnResponseTooLong != nil {
+		app.opt.OnResponseTooLong(ctx, payload, sr, length)
+	}
+
+	prefix, body := responseParts(sr)
+
+	// Characters left for the body after the prefix and status message
+	budget := maxLen - len(prefix) - 1
+	if sr.Failed() {
+		budget -= utf8.RuneCountInString(sr.StatusMessage()) + 1
+	}
+
+	switch {
+	case app.opt.TruncationStrategy == RejectResponse:
+		return nil, fmt.Errorf("%w: menu %s rendered %d characters", ErrResponseTooLong, sr.MenuName(), length)
+	case app.opt.TruncationStrategy == PaginateResponse && prefix == conPrefix:
+		more := fmt.Sprintf("%s. %s", app.opt.NextPageInput, defaultNextPageText)
+
+		pages := splitPages(body, budget-len(more)-1)
+
+		bs, err := json.Marshal(pages[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal overflow pages: %v", err)
+		}
+
+		err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), overflowPagesKey, bs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save overflow pages: %v", err)
+		}
+
+		sr.setResponse(fmt.Sprintf("%s %s\n%s", prefix, pages[0], more))
+	default:
+		sr.setResponse(fmt.Sprintf("%s %s", prefix, truncateRunes(body, budget)))
+	}
+
+	return sr, nil
+}
+
+// nextOverflowPage renders the next page of a response split by the PaginateResponse strategy.
+//
+// It returns false if the input is not the next page input or there are no pages left.
+func (app *UssdApp) nextOverflowPage(ctx context.Context, payload UssdPayload) (SessionResponse, bool, error) {
+	if app.opt.MaxResponseLength <= 0 || app.opt.TruncationStrategy != PaginateResponse {
+		return nil, false, nil
+	}
+
+	val, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), overflowPagesKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to get overflow pages: %v", err)
+	}
+
+	// The pages are cleared by limitResponse once another menu is rendered
+	if p, ok := payload.(*ussdPayload); ok {
+		p.data.overflowPages = true
+	}
+
+	if payload.UssdCurrentParam() != app.opt.NextPageInput {
+		return nil, false, nil
+	}
+
+	pages := make([]string, 0)
+	err = json.Unmarshal([]byte(val), &pages)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal overflow pages: %v", err)
+	}
+
+	if len(pages) == 0 {
+		return nil, false, nil
+	}
+
+	res := pages[0]
+	if len(pages) > 1 {
+		res = fmt.Sprintf("%s\n%s. %s", res, app.opt.NextPageInput, defaultNextPageText)
+	}
+
+	bs, err := json.Marshal(pages[1:])
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal overflow pages: %v", err)
+	}
+
+	err = app.opt.Cache.SetMapField(ctx, app.GetSessionKey(payload), overflowPagesKey, bs)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save overflow pages: %v", err)
+	}
+
+	menuName, _ := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), currentMenuKey)
+
+	SkipSavingPayload(payload)
+
+	return &sessionResponse{
+		response: fmt.Sprintf("%s %s", conPrefix, res),
+		menuName: menuName,
+	}, true, nil
+}
@@ -0,0 +1,130 @@
+package ussdapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultTwilioSessionDuration = 24 * time.Hour
+
+// TwilioOptions configures the Twilio SMS adapter
+type TwilioOptions struct {
+	// AuthToken of the Twilio account. When set, requests without a valid X-Twilio-Signature are rejected
+	AuthToken string
+	// WebhookURL is the url configured on the Twilio number. It is needed to check signatures behind proxies,
+	// otherwise the url is read from the request
+	WebhookURL string
+	// SessionDuration is how long a conversation is kept without replies, defaults to 24 hours
+	SessionDuration time.Duration
+}
+
+// TwiMLResponse is the response written to Twilio
+type TwiMLResponse struct {
+	XMLName xml.Name `xml:"Response"`
+	Message string   `xml:"Message"`
+}
+
+// NewTwilioAdapter returns a gateway adapter that serves the menus over SMS using Twilio messaging webhooks.
+//
+// Each SMS of the sender is an input to the session of its msisdn, see NewConversationPayload.
+// Menus are sent back as TwiML messages without the CON/END prefix.
+func NewTwilioAdapter(opt *TwilioOptions) GatewayAdapter {
+	if opt == nil {
+		opt = &TwilioOptions{}
+	}
+	if opt.SessionDuration <= 0 {
+		opt.SessionDuration = defaultTwilioSessionDuration
+	}
+	return &twilioAdapter{opt: opt}
+}
+
+type twilioAdapter struct {
+	opt *TwilioOptions
+}
+
+func (a *twilioAdapter) ParseRequest(r *http.Request) (UssdPayload, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse form: %v", err)
+	}
+
+	if a.opt.AuthToken != "" && !a.validSignature(r) {
+		return nil, errors.New("invalid twilio signature")
+	}
+
+	msisdn := strings.TrimPrefix(r.PostForm.Get("From"), "+")
+	if msisdn == "" {
+		return nil, errors.New("missing sender")
+	}
+
+	payload := NewConversationPayload(&ConversationMessage{
+		Channel:         r.PostForm.Get("To"),
+		Sender:          msisdn,
+		Text:            r.PostForm.Get("Body"),
+		SessionDuration: a.opt.SessionDuration,
+	})
+
+	return payload, nil
+}
+
+// validSignature checks the request signature, which is the HMAC-SHA1 of the url followed by the sorted form fields
+func (a *twilioAdapter) validSignature(r *http.Request) bool {
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get("X-Twilio-Signature"))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	webhookURL := a.opt.WebhookURL
+	if webhookURL == "" {
+		scheme := "https"
+		if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+			scheme = "http"
+		}
+		webhookURL = scheme + "://" + r.Host + r.URL.RequestURI()
+	}
+
+	fields := make([]string, 0, len(r.PostForm))
+	for field := range r.PostForm {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	mac := hmac.New(sha1.New, []byte(a.opt.AuthToken))
+	io.WriteString(mac, webhookURL)
+	for _, field := range fields {
+		for _, val := range r.PostForm[field] {
+			io.WriteString(mac, field+val)
+		}
+	}
+
+	return hmac.Equal(mac.Sum(nil), signature)
+}
+
+func (*twilioAdapter) WriteResponse(w http.ResponseWriter, sr SessionResponse) error {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return fmt.Errorf("failed to write response: %v", err)
+	}
+
+	err = xml.NewEncoder(w).Encode(&TwiMLResponse{Message: ResponseMessage(sr)})
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return nil
+}
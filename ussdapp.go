@@ -8,9 +8,13 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/grpc/grpclog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -19,28 +23,191 @@ const (
 	currentMenuKey = "current_menu"
 	currentPayload = "current_payload"
 	languageKey    = "language"
+	ussdParamsKey  = "ussd_params"
 )
 
 type UssdApp struct {
 	homeMenu string
-	allmenus map[string]Menu
-	menus    []string
-	logsChan chan *SessionRequest
-	opt      *Options
+	// menuRegistry holds the *menuRegistry snapshot of registered menus
+	menuRegistry atomic.Value
+	// registryMu serializes adding menus, handlers and middlewares
+	registryMu   sync.Mutex
+	handlers     map[string]MenuHandlerFn
+	translations Translations
+	logSinks     []LogSink
+	// logsTable is the table of session logs in Options.SQLDB
+	logsTable string
+	logsChan  chan *SessionRequest
+	flushReqs chan chan error
+	// failedBatches are logs kept in memory by Options.FailedLogSpool until they are written
+	failedBatches []failedBatch
+	failedMu      sync.Mutex
+	workers       sync.WaitGroup
+	stop          chan struct{}
+	closed        int32
+	closeCtx      context.Context
+	metrics       *metrics
+	tracer        trace.Tracer
+	// tracked are sessions in progress on this instance, keyed by session key
+	tracked   map[string]*trackedSession
+	trackedMu sync.Mutex
+	// breakers are the circuit breakers of backend dependencies of menus, see AddCircuitBreaker
+	breakers   map[string]*CircuitBreaker
+	breakersMu sync.RWMutex
+	opt        *Options
 }
 
 // Options contains data required for ussd app
 type Options struct {
-	AppName         string
-	HomeMenu        string
-	SQLDB           *gorm.DB
-	Cache           Cacher
-	Logger          grpclog.LoggerV2
-	TableName       string
-	DefaultLanguage string
-	SaveLogs        bool
-	Handler         http.Handler
-	SessionDuration time.Duration
+	AppName            string
+	HomeMenu           string
+	SQLDB              *gorm.DB
+	Cache              Cacher
+	Logger             Logger
+	TableName          string
+	DefaultLanguage    string
+	SaveLogs           bool
+	Handler            http.Handler
+	SessionDuration    time.Duration
+	Gateway            GatewayAdapter
+	SessionHook        SessionHookFn
+	ErrorMessage       string
+	BackInput          string
+	HomeInput          string
+	DisableNavigation  bool
+	MaxResponseLength  int
+	TruncationStrategy TruncationStrategy
+	OnResponseTooLong  ResponseTooLongFn
+	NextPageInput      string
+	BeforeRender       BeforeRenderFn
+	AfterRender        AfterRenderFn
+	// MetricsRegistry enables prometheus metrics for the app when set. Serve them using MetricsHandler
+	MetricsRegistry *prometheus.Registry
+	// TracerProvider enables opentelemetry tracing for the app when set
+	TracerProvider trace.TracerProvider
+	// ResumeSession offers users whose session expired in the middle of a flow to continue where they left off
+	ResumeSession bool
+	// ResumeWindow is how long after the last request a flow can be resumed. Defaults to 10 minutes
+	ResumeWindow time.Duration
+	// ResumePrompt asks the user whether to resume. Entering 1 resumes the flow, any other input goes to the home menu
+	ResumePrompt string
+	// LogSink receives session logs instead of the logs table in SQLDB when set
+	LogSink LogSink
+	// LogSinks receive session logs together with LogSink. Logs go to the logs table in SQLDB when no sink is set
+	LogSinks []LogSink
+	// RateLimit limits the sessions a msisdn can start when set
+	RateLimit *RateLimit
+	// GuardConcurrentSessions detects sessions started by a msisdn that has another session in progress
+	GuardConcurrentSessions bool
+	// OnConcurrentSession decides whether to end the session in progress or refuse the new one. Defaults to ending
+	// the session in progress, since gateways do not tell the app about sessions dropped by the network
+	OnConcurrentSession ConcurrentSessionFn
+	// ConcurrentSessionMessage is sent when a new session is refused
+	ConcurrentSessionMessage string
+	// PIIPolicy masks personal data in session logs when set
+	PIIPolicy *PIIPolicy
+	// PreferenceStore remembers the language a user selects across sessions when set
+	PreferenceStore PreferenceStore
+	// TranslationFiles are JSON or TOML files with the content of menus in each language. See LoadTranslations
+	TranslationFiles []string
+	// SessionEncryptionKey encrypts values written to the cache with AES-GCM when set. It must be 16, 24 or 32 bytes
+	SessionEncryptionKey []byte
+	// OnSessionStart is called when a new session starts
+	OnSessionStart SessionEventFn
+	// OnSessionEnd is called when a session ends with an END response
+	OnSessionEnd SessionEventFn
+	// OnSessionTimeout is called when the data of a session expires before the session ends, e.g the user abandoned it
+	OnSessionTimeout SessionEventFn
+	// SessionSweepInterval is how often sessions are checked for timeouts. Defaults to 30 seconds
+	SessionSweepInterval time.Duration
+	// DisableSessionSweeper stops the app from checking sessions for timeouts, for apps whose timeouts are reported
+	// by a listener of cache expiry events, such as rediscache.ListenSessionExpiry
+	DisableSessionSweeper bool
+	// FlagProvider turns menus with a feature flag on per user. Menus with a flag are off when it is not set
+	FlagProvider FlagProvider
+	// AdminAddr serves the admin API on the address when set, e.g localhost:9090. It requires AdminAuth. See
+	// AdminHandler
+	AdminAddr string
+	// AdminAuth authenticates requests to the admin API served on AdminAddr, e.g AdminTokenAuth
+	AdminAuth AdminAuthFn
+	// ErrorMenu is rendered as an END response when a request fails with an unexpected error, instead of ErrorMessage
+	ErrorMenu string
+	// PanicHandler is called with panics recovered in menus and middlewares, e.g to report them to an error tracking
+	// service. Panics are always recovered and logged, and the request fails as with an error
+	PanicHandler PanicHandlerFn
+	// ErrorReporter receives errors of menus, panics and failures of background workers, e.g to send them to Sentry
+	ErrorReporter ErrorReporter
+	// CacheRetry retries cache operations that fail with a transient error when set
+	CacheRetry *CacheRetry
+	// DefaultCountry is the ISO 3166 code of the country local phone numbers belong to. Defaults to KE
+	DefaultCountry string
+	// DedupWindow answers gateway retries of a request, with the same session id and ussd string, with the response
+	// sent to the first request for the duration instead of running the menus again. Off when zero. For gateways that
+	// send only the latest input, the same input entered twice within the window is taken for a retry
+	DedupWindow time.Duration
+	// MenuTimeout is how long a menu has to generate its response, through the deadline of the context it gets. A
+	// menu whose backend calls overrun it ends the session with MenuTimeoutMessage. Off when zero
+	MenuTimeout time.Duration
+	// MenuTimeoutMessage is sent when a menu overruns MenuTimeout. Defaults to an END message asking to dial again
+	MenuTimeoutMessage string
+	// ServiceBusyMessage is sent for menus whose dependency has an open circuit breaker, see AddCircuitBreaker.
+	// Defaults to an END message asking to try again later
+	ServiceBusyMessage string
+	// TaskTTL is how long tasks started with StartTask and their outcome are kept. Defaults to an hour
+	TaskTTL time.Duration
+	// JobEnqueuer sends jobs added by menus with EnqueueJob to a queue once the response is written
+	JobEnqueuer JobEnqueuer
+	// SMSSender sends the SMS returned by EndSMS
+	SMSSender SMSSender
+	// EndSMS returns an SMS sent to the user when a response ends the session, e.g a summary of a registration
+	EndSMS EndSMSFn
+	// LoginMenu is rendered instead of menus with RequiresAuth for sessions that are not logged in. The requested
+	// menu is rendered once the login flow calls Login
+	LoginMenu string
+	// Authenticator tells whether a session that did not call Login is logged in, e.g from a token of the user.
+	// Sessions that called Login are always logged in
+	Authenticator Authenticator
+	// SegmentResolver returns the roles or customer segments of users, e.g agent or premium, for menus with
+	// Segments. Menus with segments are hidden when it is not set
+	SegmentResolver SegmentResolverFn
+	// HiddenMenuMessage is shown, per language, with the current menu when users choose a menu hidden from them
+	HiddenMenuMessage Content
+	// HomeMenuFn returns the menu a new session starts on, e.g a login menu for registered users. It is called once
+	// per session and HomeMenu is used when it returns an empty name
+	HomeMenuFn HomeMenuFn
+	// Blocklist turns away msisdns blocked for fraud or abuse before any menu runs when set
+	Blocklist Blocklist
+	// BlockedMessage is sent to blocked msisdns. Defaults to an END message telling the user the number is blocked
+	BlockedMessage string
+	// SessionCounter counts sessions in progress on all instances when set, see ActiveSessionCount. Sessions whose
+	// data expires are counted out by the session sweeper, or by rediscache.ListenSessionExpiry when the sweeper is
+	// disabled
+	SessionCounter SessionCounter
+	// LogRetention prunes, or archives then prunes, session logs in the logs table of SQLDB once they are older than
+	// the retention when set
+	LogRetention *LogRetention
+	// FailedLogStorage keeps logs that fail to be written to a sink in an object store, such as S3, instead of local
+	// files that are lost when the container restarts. Logs are kept in files when uploading them fails
+	FailedLogStorage LogStorage
+	// FailedLogSpool sets where and how logs that fail to be written are kept until they are written again. Defaults
+	// to files in the failed-bulk-inserts directory retried every 30 seconds
+	FailedLogSpool *FailedLogSpool
+	// LogBuffer sets the size of the buffer of logs waiting to be written, how often they are written and what happens
+	// to logs saved while the buffer is full
+	LogBuffer *LogBuffer
+	// LogMode writes logs in batches in the background, in the request path, or not at all. Defaults to LogModeAsync
+	LogMode LogMode
+	// SyncLogTimeout is how long writing the log of a request may take with LogModeSync. Defaults to 2 seconds
+	SyncLogTimeout time.Duration
+	// LogResponseText saves the text of responses in session logs, so support can see the screens users were shown
+	// when menus have dynamic content. Responses often hold personal data such as names and balances, so it is off
+	// by default
+	LogResponseText bool
+	// ResponseTextLength is the most characters of a response saved with LogResponseText. Defaults to 500, at most 1000
+	ResponseTextLength int
+	// Debug writes debug logs of each request, such as the menu resolved and the response. See TraceMsisdn to debug
+	// the requests of one user
+	Debug bool
 }
 
 // NewUssdApp returns a ussd application to be configured
@@ -55,39 +222,114 @@ func NewUssdApp(ctx context.Context, opt *Options) (*UssdApp, error) {
 		return nil, errors.New("missing home menu")
 	case opt.Cache == nil:
 		return nil, errors.New("missing redis db")
-	case opt.SQLDB == nil:
-		return nil, errors.New("missing sql db")
 	case opt.Logger == nil:
 		return nil, errors.New("missing logger")
+	case opt.LogRetention != nil && opt.LogRetention.MaxAge <= 0:
+		return nil, errors.New("missing log retention max age")
+	case opt.AdminAddr != "" && opt.AdminAuth == nil:
+		return nil, errors.New("missing admin auth for admin api")
+	case opt.GuardConcurrentSessions && !isSetCacher(opt.Cache):
+		return nil, errors.New("guarding concurrent sessions requires a cache that implements SetCacher")
+	case opt.RateLimit != nil && !isIncrCacher(opt.Cache):
+		return nil, errors.New("rate limits require a cache that implements IncrCacher")
 	default:
 		if opt.SessionDuration == 0 {
 			opt.SessionDuration = time.Minute * 5
 		}
+		if opt.Gateway == nil {
+			opt.Gateway = NewGenericAdapter()
+		}
+		if opt.ErrorMessage == "" {
+			opt.ErrorMessage = defaultErrorMessage
+		}
+		if opt.BackInput == "" {
+			opt.BackInput = defaultBackInput
+		}
+		if opt.HomeInput == "" {
+			opt.HomeInput = defaultHomeInput
+		}
+		if opt.NextPageInput == "" {
+			opt.NextPageInput = defaultNextPageInput
+		}
+		if opt.ResumeWindow == 0 {
+			opt.ResumeWindow = defaultResumeWindow
+		}
+		if opt.ResumePrompt == "" {
+			opt.ResumePrompt = defaultResumePrompt
+		}
+		if opt.DefaultCountry == "" {
+			opt.DefaultCountry = defaultPhoneCountry
+		}
 	}
 
-	if opt.TableName != "" {
-		sessionLogsTable = opt.TableName
-	} else {
-		sessionLogsTable = os.Getenv("USSD_LOGS_TABLE")
-	}
+	// Each app keeps its own table, so that apps in one process do not share logs
+	logsTable := firstVal(opt.TableName, os.Getenv("USSD_LOGS_TABLE"), defaultSessionsLogsTable)
 
 	app := &UssdApp{
-		homeMenu: opt.HomeMenu,
-		allmenus: make(map[string]Menu),
-		menus:    []string{},
-		logsChan: make(chan *SessionRequest, bulkInsertSize),
-		opt:      opt,
+		homeMenu:     opt.HomeMenu,
+		handlers:     make(map[string]MenuHandlerFn),
+		breakers:     make(map[string]*CircuitBreaker),
+		tracked:      make(map[string]*trackedSession),
+		translations: make(Translations),
+		logsChan:     make(chan *SessionRequest, opt.LogBuffer.size()),
+		flushReqs:    make(chan chan error),
+		logSinks:     newLogSinks(opt, logsTable),
+		logsTable:    logsTable,
+		stop:         make(chan struct{}),
+		tracer:       newTracer(opt.TracerProvider),
+		opt:          opt,
+	}
+
+	app.menuRegistry.Store(&menuRegistry{menus: make(map[string]Menu), names: []string{}})
+
+	for _, fileName := range opt.TranslationFiles {
+		err := app.AddTranslationsFromFile(fileName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Auto migration
-	if !app.opt.SQLDB.Migrator().HasTable(&SessionRequest{}) {
-		err := app.opt.SQLDB.AutoMigrate(&SessionRequest{})
+	if opt.CacheRetry != nil {
+		app.opt.Cache = newRetryingCacher(opt.Cache, opt.CacheRetry)
+	}
+
+	if len(opt.SessionEncryptionKey) > 0 {
+		cache, err := newEncryptingCacher(opt.Cache, opt.SessionEncryptionKey)
 		if err != nil {
-			return nil, fmt.Errorf("failed to auto migrate %s table", (&SessionRequest{}).TableName())
+			return nil, err
 		}
+		app.opt.Cache = cache
 	}
 
-	if opt.SaveLogs {
+	if opt.MetricsRegistry != nil {
+		m, err := newMetrics(opt.AppName, opt.MetricsRegistry)
+		if err != nil {
+			return nil, err
+		}
+		app.metrics = m
+		app.opt.Cache = &metricsCacher{Cacher: opt.Cache, metrics: m}
+
+		err = m.watchActiveSessions(app.ActiveSessionCount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.TracerProvider != nil {
+		app.opt.Cache = &tracingCacher{Cacher: opt.Cache, tracer: app.tracer}
+	}
+
+	// Auto migration adds the table or columns missing from it
+	if app.opt.SQLDB != nil {
+		err := app.opt.SQLDB.Table(app.logsTable).AutoMigrate(&SessionRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to auto migrate %s table", app.logsTable)
+		}
+	}
+
+	if app.savingLogs() {
+		app.workers.Add(2)
+
 		// Start insert worker
 		go app.saveLogsWorker(ctx)
 
@@ -95,23 +337,77 @@ func NewUssdApp(ctx context.Context, opt *Options) (*UssdApp, error) {
 		go app.saveFailedLogsWorker(ctx)
 	}
 
+	if opt.LogRetention != nil && opt.SQLDB != nil {
+		app.workers.Add(1)
+
+		// Start logs retention worker
+		go app.logsRetentionWorker(ctx)
+	}
+
+	app.workers.Add(1)
+
+	// Start session timeout worker
+	go app.sessionsSweeper(ctx)
+
+	if opt.AdminAddr != "" {
+		app.workers.Add(1)
+
+		// Start admin server
+		go app.serveAdmin(ctx)
+	}
+
 	return app, nil
 }
 
 func ValidateAppMenus(app *UssdApp) error {
+	menus := app.registry().menus
+
+	if _, ok := menus[app.opt.ErrorMenu]; !ok && app.opt.ErrorMenu != "" {
+		return fmt.Errorf("error menu %s is not registered", app.opt.ErrorMenu)
+	}
+
+	if _, ok := menus[app.opt.LoginMenu]; !ok && app.opt.LoginMenu != "" {
+		return fmt.Errorf("login menu %s is not registered", app.opt.LoginMenu)
+	}
+
+	for _, val := range menus {
+		if dm, ok := val.(decisionMenu); ok && dm.decisionTable() != nil {
+			err := dm.decisionTable().Validate()
+			if err != nil {
+				return fmt.Errorf("decision table of %s menu: %v", val.MenuName(), err)
+			}
+		}
+		if requiresAuth(val) && app.opt.LoginMenu == "" {
+			return fmt.Errorf("menu %s requires auth but the app has no login menu", val.MenuName())
+		}
+		if dm, ok := val.(dependentMenu); ok {
+			for _, dep := range dm.Dependencies() {
+				if app.CircuitBreaker(dep) == nil {
+					return fmt.Errorf("dependency %s of %s menu has no circuit breaker", dep, val.MenuName())
+				}
+			}
+		}
 
-	for _, val := range app.allmenus {
-		_, ok := app.allmenus[val.MenuName()]
-		if !ok {
-			return fmt.Errorf("menu %s not registered", val.MenuName())
+		for _, t := range app.Transitions(val.MenuName()) {
+			if _, ok := menus[t.To]; ok {
+				continue
+			}
+			switch t.Kind {
+			case TransitionNext:
+				return fmt.Errorf("next menu %s for %s menu is not registered", t.To, t.From)
+			case TransitionRoute:
+				return fmt.Errorf("route menu %s for input %s on %s menu is not registered", t.To, t.Input, t.From)
+			case TransitionBack:
+				// Previous menus may be outside the app, e.g menus of another app on the same router
+			default:
+				return fmt.Errorf("%s menu %s on %s menu is not registered", t.Kind, t.To, t.From)
+			}
 		}
-		// _, ok = app.allmenus[val.PreviousMenu()]
-		// if !ok && val.PreviousMenu() != "" && app.homeMenu != val.MenuName() {
-		// 	return fmt.Errorf("previous menu %s for %s menu is not registered", val.PreviousMenu(), val.MenuName())
-		// }
-		_, ok = app.allmenus[val.NextMenu()]
-		if !ok && val.NextMenu() != "" {
-			return fmt.Errorf("next menu %s for %s menu is not registered", val.NextMenu(), val.MenuName())
+	}
+
+	for from := range app.registry().transitions {
+		if _, ok := menus[from]; !ok {
+			return fmt.Errorf("transitions from %s menu which is not registered", from)
 		}
 	}
 
@@ -130,38 +426,88 @@ func ValidateMenu(m Menu) error {
 	case m.NextMenu() == "":
 		return fmt.Errorf("next menu for %s is missing", m.MenuName())
 	}
+	if cm, ok := m.(checkedMenu); ok {
+		return cm.validate()
+	}
 	return nil
 }
 
-func (app *UssdApp) AddMenu(m Menu) error {
-	err := ValidateMenu(m)
-	if err != nil {
-		return err
+// checkedMenu is implemented by menus that check their own options when they are registered
+type checkedMenu interface {
+	validate() error
+}
+
+// prepareMenu sets up translations and language lookup of a menu being registered
+func (app *UssdApp) prepareMenu(m Menu) {
+	if t, ok := m.(translatable); ok {
+		t.setDefaultLanguage(app.opt.DefaultLanguage)
+		t.translate(app.translations[m.MenuName()])
 	}
 
-	_, ok := app.allmenus[m.MenuName()]
-	if ok {
-		return fmt.Errorf("%w: %s", ErrMenuExist, m.MenuName())
+	if la, ok := m.(languageAware); ok {
+		la.setLanguageFn(app.GetLanguage)
 	}
 
-	app.allmenus[m.MenuName()] = m
+	if em, ok := m.(experimentMenu); ok {
+		em.setVariantFn(app.assignVariant)
+	}
+}
 
-	app.menus = append(app.menus, m.MenuName())
+// AddMenu registers the menu. It is safe to call while the app serves requests, e.g to load menus of plugins
+func (app *UssdApp) AddMenu(m Menu) error {
+	return app.addMenus(m)
+}
 
-	app.opt.Logger.Infof("Registered %s menu", m.MenuName())
+// addMenus registers the menus together. None of them is registered if any is invalid or already registered
+func (app *UssdApp) addMenus(menus ...Menu) error {
+	for _, m := range menus {
+		err := ValidateMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	reg := app.registry()
+	for _, m := range menus {
+		app.prepareMenu(m)
+
+		var err error
+		reg, err = reg.withMenu(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	app.menuRegistry.Store(reg)
+
+	for _, m := range menus {
+		app.opt.Logger.Info("registered menu", "menu", m.MenuName())
+	}
 
 	return nil
 }
 
+// Handler returns the http handler set in options, defaulting to the built-in handler
 func (app *UssdApp) Handler() http.Handler {
-	return app.opt.Handler
+	if app.opt.Handler != nil {
+		return app.opt.Handler
+	}
+	return app
+}
+
+// LogsTable returns the name of the table session logs are saved to in Options.SQLDB
+func (app *UssdApp) LogsTable() string {
+	return app.logsTable
 }
 
 func (app *UssdApp) Cache() Cacher {
 	return app.opt.Cache
 }
 
-func (app *UssdApp) Logger() grpclog.LoggerV2 {
+func (app *UssdApp) Logger() Logger {
 	return app.opt.Logger
 }
 
@@ -170,17 +516,49 @@ func (app *UssdApp) SqlDB() *gorm.DB {
 }
 
 func (app *UssdApp) sessionKey(payload UssdPayload) string {
-	return fmt.Sprintf("%s:sessions:%s:%s", app.opt.AppName, payload.SessionId(), payload.Msisdn())
+	return app.sessionKeyOf(payload.SessionId(), payload.Msisdn())
+}
+
+// homeMenuOf returns the home menu for the payload, which is set per route by a Router or per session by
+// Options.HomeMenuFn
+func (app *UssdApp) homeMenuOf(payload UssdPayload) string {
+	if p, ok := payload.(*ussdPayload); ok && p.data.homeMenu != "" {
+		return p.data.homeMenu
+	}
+	return app.homeMenu
+}
+
+func (app *UssdApp) sessionKeyOf(sessionID, msisdn string) string {
+	return fmt.Sprintf("%s:sessions:%s:%s", app.opt.AppName, sessionID, msisdn)
+}
+
+// ParseSessionKey returns the session id and msisdn in a key returned by GetSessionKey.
+//
+// It returns false for keys that are not session keys of the app.
+func (app *UssdApp) ParseSessionKey(key string) (sessionID, msisdn string, ok bool) {
+	prefix := fmt.Sprintf("%s:sessions:", app.opt.AppName)
+	if !strings.HasPrefix(key, prefix) {
+		return "", "", false
+	}
+
+	rest := strings.TrimPrefix(key, prefix)
+
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+
+	return rest[:i], rest[i+1:], true
 }
 
 // GetMenuNames will return all menu names registered as a slice of strings
 func (app *UssdApp) GetMenuNames() []string {
-	return app.menus
+	return app.registry().names
 }
 
 // GetNextMenu will attempt to get the highest matching menu to be saved or/and rendered
 func (app *UssdApp) GetNextMenu(currentMenu Menu, payload UssdPayload) (Menu, error) {
-	next, ok := app.allmenus[currentMenu.NextMenu()]
+	next, ok := app.getMenu(currentMenu.NextMenu())
 	if !ok {
 		return nil, fmt.Errorf("%v: %s", ErrMenuNotExist, currentMenu.NextMenu())
 	}
@@ -188,18 +566,48 @@ func (app *UssdApp) GetNextMenu(currentMenu Menu, payload UssdPayload) (Menu, er
 	return next, nil
 }
 
-// GetPreviousMenu will attempt to get the previous menu for the session
+// GetRoutedMenu will get the menu routed from the user input on the menu currently shown to the user
+//
+// Returns nil if the current menu has no route for the input
+func (app *UssdApp) GetRoutedMenu(ctx context.Context, payload UssdPayload) (Menu, error) {
+	curr, err := app.shownMenu(ctx, payload)
+	if err != nil || curr == nil {
+		return nil, err
+	}
+
+	return app.routedMenu(curr, payload)
+}
+
+// routedMenu returns the menu routed from the input on the menu, or nil if the menu has no route for the input
+func (app *UssdApp) routedMenu(curr Menu, payload UssdPayload) (Menu, error) {
+	route, ok := menuRoutes(curr)[payload.UssdCurrentParam()]
+	if !ok {
+		return nil, nil
+	}
+
+	routed, ok := app.getMenu(route)
+	if !ok {
+		return nil, fmt.Errorf("%v: %s", ErrMenuNotExist, route)
+	}
+
+	return routed, nil
+}
+
+// GetPreviousMenu will attempt to get the menu shown before the current menu in the session
+//
+// It defaults to the home menu when the session has no earlier menu.
 func (app *UssdApp) GetPreviousMenu(ctx context.Context, payload UssdPayload) (Menu, error) {
-	prev, err := app.Cache().GetMapField(ctx, app.GetSessionKey(payload), currentMenuKey)
-	switch {
-	case err == nil:
-	case errors.Is(err, ErrKeyNotFound):
-		prev = app.homeMenu
-	default:
+	history, err := app.MenuHistory(ctx, payload)
+	if err != nil {
 		return nil, fmt.Errorf("failed to get previous menu: %v", err)
 	}
 
-	prevMenu, ok := app.allmenus[prev]
+	prev := app.homeMenuOf(payload)
+	if len(history) > 1 {
+		prev = history[len(history)-2]
+	}
+
+	prevMenu, ok := app.getMenu(prev)
 	if !ok {
 		return nil, fmt.Errorf("%v: %s", ErrMenuNotExist, prev)
 	}
@@ -209,7 +617,7 @@ func (app *UssdApp) GetPreviousMenu(ctx context.Context, payload UssdPayload) (M
 
 // SaveMenuNameAsCurrent will save the menu with given name as current.
 func (app *UssdApp) SaveMenuNameAsCurrent(ctx context.Context, menuName string, payload UssdPayload) (Menu, error) {
-	menu, ok := app.allmenus[menuName]
+	menu, ok := app.getMenu(menuName)
 	if !ok {
 		return nil, ErrMenuNotExist
 	}
@@ -260,20 +668,25 @@ func (app *UssdApp) GetCurrentMenu(ctx context.Context, payload UssdPayload) (Me
 	switch {
 	case err == nil:
 	case errors.Is(err, ErrKeyNotFound):
-		return app.allmenus[app.homeMenu], nil
+		return app.registry().menus[app.homeMenuOf(payload)], nil
 	default:
 		return nil, fmt.Errorf("failed to get current_menu from map: %v", err)
 	}
 
-	menu, ok := app.allmenus[res]
+	menu, ok := app.getMenu(res)
 	if !ok {
-		return app.allmenus[app.homeMenu], nil
+		return app.registry().menus[app.homeMenuOf(payload)], nil
 	}
 
 	return menu, nil
 }
 
-func (app *UssdApp) GetSessionMenu(ctx context.Context, payload UssdPayload) (Menu, bool, error) {
+func (app *UssdApp) GetSessionMenu(ctx context.Context, payload UssdPayload) (_ Menu, _ bool, err error) {
+	ctx, span := app.tracer.Start(ctx, "ussdapp.GetSessionMenu", trace.WithAttributes(
+		attribute.String("ussd.session_id", payload.SessionId()),
+	))
+	defer func() { endSpan(span, err) }()
+
 	var (
 		isNew      bool
 		sessionKey = app.GetSessionKey(payload)
@@ -282,6 +695,10 @@ func (app *UssdApp) GetSessionMenu(ctx context.Context, payload UssdPayload) (Me
 	res, err := app.opt.Cache.GetMapField(ctx, sessionKey, nextMenuKey)
 	switch {
 	case err == nil:
+		err = app.loadHomeMenu(ctx, payload)
+		if err != nil {
+			return nil, false, err
+		}
 	case errors.Is(err, ErrKeyNotFound):
 		// Session is new so we set some data
 		err = app.opt.Cache.SetMapField(ctx, sessionKey, "new", "true")
@@ -290,28 +707,29 @@ func (app *UssdApp) GetSessionMenu(ctx context.Context, payload UssdPayload) (Me
 		}
 
 		// Set expiration for key
-		err = app.Cache().Expire(ctx, sessionKey, app.opt.SessionDuration)
+		err = app.Cache().Expire(ctx, sessionKey, app.sessionDuration(payload))
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to set session expiration: %v", err)
 		}
 
 		isNew = true
+
+		err = app.resolveHomeMenu(ctx, payload)
+		if err != nil {
+			return nil, false, err
+		}
 	default:
 		return nil, false, fmt.Errorf("failed to get current_menu from map: %v", err)
 	}
 
-	menu, ok := app.allmenus[res]
+	menu, ok := app.getMenu(res)
 	if !ok {
-		return app.allmenus[app.homeMenu], isNew, nil
+		return app.registry().menus[app.homeMenuOf(payload)], isNew, nil
 	}
 
 	return menu, isNew, nil
 }
 
-func sessionSetKey(appId string) string {
-	return fmt.Sprintf("%s:sessions", appId)
-}
-
 // IsNewSession will check if incoming ussd session is new
 //
 // # If session is new, it will be saved and automatically be cleared after session duration
@@ -325,9 +743,9 @@ func (app *UssdApp) IsNewSession(ctx context.Context, payload UssdPayload) (bool
 	_, err := app.opt.Cache.GetMapField(ctx, app.GetSessionKey(payload), "new")
 	switch {
 	case err == nil:
-		fmt.Println("not a new session")
+		app.debug(payload, "session in progress")
 	case errors.Is(err, ErrKeyNotFound):
-		fmt.Println("new session")
+		app.debug(payload, "new session")
 		// New session
 		isNew = true
 		// Set value for the map so next time is not new session
@@ -342,21 +760,20 @@ func (app *UssdApp) IsNewSession(ctx context.Context, payload UssdPayload) (bool
 	return isNew, nil
 }
 
-// deleteSessionSetKey will remove session key from cache
-func (app *UssdApp) deleteSessionSetKey(ctx context.Context, sessionID, msisdn string) error {
-	err := app.opt.Cache.DeleteSetValue(ctx, sessionSetKey(app.opt.AppName), fmt.Sprintf("%s:%s", msisdn, sessionID))
-	if err != nil {
-		return fmt.Errorf("failed to remove session: %v", err)
-	}
-	return nil
-}
-
 // SaveLanguage will save user language for the ussd session
+//
+// The language is also saved in the preference store when set, so that it is used in later sessions of the user.
 func (app *UssdApp) SaveLanguage(ctx context.Context, payload UssdPayload, language string) error {
 	err := app.opt.Cache.SetMapField(ctx, app.sessionKey(payload), languageKey, language)
 	if err != nil {
 		return fmt.Errorf("failed to save language")
 	}
+	if app.opt.PreferenceStore != nil {
+		err = app.opt.PreferenceStore.SavePreference(ctx, payload.Msisdn(), languagePreference, language)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -384,7 +801,7 @@ func firstVal(vals ...string) string {
 }
 
 func (app *UssdApp) ReplaceMenuWithName(ctx context.Context, menuName string, payload UssdPayload) (SessionResponse, error) {
-	menu, ok := app.allmenus[menuName]
+	menu, ok := app.getMenu(menuName)
 	if !ok {
 		return nil, ErrMenuNotExist
 	}
@@ -393,14 +810,20 @@ func (app *UssdApp) ReplaceMenuWithName(ctx context.Context, menuName string, pa
 }
 
 func (app *UssdApp) ReplaceMenu(ctx context.Context, payload UssdPayload, menu Menu) (SessionResponse, error) {
+	// Protected menus of sessions that are not logged in
+	menu, err := app.authorizeMenu(ctx, payload, menu)
+	if err != nil {
+		return nil, err
+	}
+
 	// Save menu as current
-	err := app.SaveMenuAsCurrent(ctx, menu, payload)
+	err = app.SaveMenuAsCurrent(ctx, menu, payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save current menu: %v", err)
 	}
 
 	// Generate response
-	sr, err := menu.GenerateResponse(ctx, payload)
+	sr, err := app.renderMenu(ctx, payload, menu)
 	if err != nil {
 		return nil, err
 	}
@@ -445,14 +868,14 @@ func (app *UssdApp) PreviousMenuWithError(ctx context.Context, payload UssdPaylo
 		return nil, err
 	}
 
-	fmt.Println("Previous payload: ", payloadPrev.UssdCurrentParam(), val[currentMenuKey])
+	app.debug(payload, "rendering previous menu with error", "menu", val[currentMenuKey], "status", erroText)
 
-	prevMenu, ok := app.allmenus[val[currentMenuKey]]
+	prevMenu, ok := app.registry().menus[val[currentMenuKey]]
 	if !ok {
 		return nil, fmt.Errorf("previous menu does not exist %s: %w", val[currentMenuKey], ErrMenuNotExist)
 	}
 
-	sr, err := prevMenu.GenerateResponse(ctx, payloadPrev)
+	sr, err := app.renderMenu(ctx, payloadPrev, prevMenu)
 	if err != nil {
 		return nil, err
 	}
@@ -469,21 +892,11 @@ func (app *UssdApp) PreviousMenuWithError(ctx context.Context, payload UssdPaylo
 //
 // # A shortcut in this case is the ussd string data that comes during first session
 //
+// Shortcuts may be patterns that capture inputs, see ShortCutParam.
 // The method should only be called for new sessions as ongoing session cannot be deemed as shortcut
 func (app *UssdApp) GetShortCutMenu(ctx context.Context, payload UssdPayload) Menu {
-	shortCut := payload.UssdParams()
-
-	if shortCut == "" {
-		return nil
-	}
-
-	for _, v := range app.allmenus {
-		if v.ShortCut() == shortCut {
-			return v
-		}
-	}
-
-	return nil
+	m, _ := app.shortCutMenu(payload)
+	return m
 }
 
 const (
@@ -493,31 +906,41 @@ const (
 )
 
 func WriteUSSDResponse(w http.ResponseWriter, up UssdPayload, sr SessionResponse) error {
-	res := strings.TrimSpace(sr.Response())
-
-	if sr.Failed() || up.ValidationFailed() {
-		valErr := sr.StatusMessage()
-		switch {
-		case strings.HasPrefix(res, conPrefix):
-			res = fmt.Sprintf("CON %s\n%s", valErr, res[4:])
-		case strings.HasPrefix(res, endPrefix):
-			res = fmt.Sprintf("END %s\n%s", valErr, res[4:])
-		case strings.HasPrefix(res, uprPrefix):
-		default:
-			res = fmt.Sprintf("CON %s\n%s", valErr, res)
-		}
+	_, err := io.WriteString(w, ussdResponseText(sr, up.ValidationFailed()))
+	if err != nil {
+		return err
 	}
 
-	if !strings.HasPrefix(res, "CON ") && !strings.HasPrefix(res, "UPR") && !strings.HasPrefix(res, "END") {
-		res = fmt.Sprintf("CON %s", res)
+	return nil
+}
+
+// ussdResponseText formats the session response using the CON/END prefix convention.
+//
+// The prefix is derived from Terminal, so responses marked with End or Continue may leave it out of the text.
+func ussdResponseText(sr SessionResponse, validationFailed bool) string {
+	prefix, body := responseParts(sr)
+	if prefix == uprPrefix {
+		return strings.TrimSpace(sr.Response())
 	}
 
-	_, err := io.WriteString(w, res)
-	if err != nil {
-		return err
+	if sr.Failed() || validationFailed {
+		body = fmt.Sprintf("%s\n%s", sr.StatusMessage(), body)
 	}
 
-	return nil
+	return fmt.Sprintf("%s %s", prefix, body)
+}
+
+// responseParts returns the prefix for the response and the text without the prefix
+func responseParts(sr SessionResponse) (string, string) {
+	prefix, body := splitPrefix(strings.TrimSpace(sr.Response()))
+	switch {
+	case prefix == uprPrefix:
+		return prefix, body
+	case sr.Terminal():
+		return endPrefix, body
+	default:
+		return conPrefix, body
+	}
 }
 
 // UpdateNextMenu will get the next menu for current menu and save it as current menu
@@ -532,8 +955,13 @@ func (app *UssdApp) UpdateNextMenu(ctx context.Context, payload UssdPayload, cur
 		return err
 	}
 
+	return app.saveNextMenu(ctx, payload, currMenu, m)
+}
+
+// saveNextMenu saves next as the menu that receives the input on currMenu, keeping currMenu in the navigation history
+func (app *UssdApp) saveNextMenu(ctx context.Context, payload UssdPayload, currMenu, next Menu) error {
 	// Save menu as current
-	err = app.SaveMenuAsCurrent(ctx, m, payload)
+	err := app.SaveMenuAsCurrent(ctx, next, payload)
 	if err != nil {
 		return err
 	}
@@ -544,6 +972,18 @@ func (app *UssdApp) UpdateNextMenu(ctx context.Context, payload UssdPayload, cur
 		return fmt.Errorf("failed to save previous menu: %v", err)
 	}
 
+	// Save menu to navigation history
+	err = app.PushMenu(ctx, payload, currMenu)
+	if err != nil {
+		return err
+	}
+
+	// Save payload for menus reading earlier inputs
+	err = app.pushPayload(ctx, payload)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -560,7 +1000,7 @@ func failedStatus(failed ...bool) bool {
 //
 // If saving logs is disabled, the method has no effect
 func (app *UssdApp) SaveLog(ctx context.Context, payload UssdPayload, sr SessionResponse) {
-	if !app.opt.SaveLogs {
+	if !app.savingLogs() || isDuplicate(payload) {
 		return
 	}
 
@@ -568,19 +1008,40 @@ func (app *UssdApp) SaveLog(ctx context.Context, payload UssdPayload, sr Session
 		sr = &sessionResponse{}
 	}
 
-	t := time.Now()
+	if atomic.LoadInt32(&app.closed) == 1 {
+		app.opt.Logger.Warn("session log not saved: app is closed", "session_id", payload.SessionId())
+		return
+	}
 
-	select {
-	case <-ctx.Done():
-	case app.logsChan <- &SessionRequest{
+	log := &SessionRequest{
 		SessionID:     payload.SessionId(),
 		Msisdn:        payload.Msisdn(),
 		USSDParams:    payload.UssdParams(),
 		UserInput:     payload.UssdCurrentParam(),
 		MenuName:      sr.MenuName(),
+		MenuVersion:   sr.menuVersion(),
+		Variant:       sr.variant(),
 		Succeeded:     !failedStatus(sr.Failed(), payload.ValidationFailed()),
+		Ended:         sr.Terminal(),
 		StatusMessage: sr.StatusMessage(),
-		CreatedAt:     t,
-	}:
+		Data:          app.logData(payload, sr),
+		ResponseText:  app.logResponseText(sr),
+		CreatedAt:     time.Now(),
+	}
+
+	if p, ok := payload.(*ussdPayload); ok {
+		if !p.data.receivedAt.IsZero() {
+			log.DurationMs = log.CreatedAt.Sub(p.data.receivedAt).Milliseconds()
+		}
+		log.HTTPStatus = p.data.httpStatus
+		// Requests that panicked are answered with the error menu, which is logged as failed with the panic
+		if p.data.panic != "" {
+			log.Succeeded = false
+			log.StatusMessage = p.data.panic
+		}
 	}
+
+	app.protectLog(ctx, payload, log)
+
+	app.persistLog(ctx, log)
 }
@@ -0,0 +1,489 @@
+package ussdapp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	"github.com/gidyon/ussdapp/ussdtest"
+)
+
+func addMenus(t *testing.T, app *ussdapp.UssdApp, menus ...*ussdapp.MenuOptions) {
+	t.Helper()
+
+	for _, opt := range menus {
+		err := app.AddMenu(ussdapp.NewMenu(opt))
+		if err != nil {
+			t.Fatalf("failed to add menu %s: %v", opt.MenuName, err)
+		}
+	}
+}
+
+func text(s string) ussdapp.Content {
+	return ussdapp.Content{"en": s}
+}
+
+func TestNavigation(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "name", MenuContent: text("CON Welcome\n1. Register")},
+		&ussdapp.MenuOptions{MenuName: "name", NextMenu: "age", MenuContent: text("CON What is your name")},
+		&ussdapp.MenuOptions{MenuName: "age", NextMenu: "done", MenuContent: text("CON How old are you")},
+		&ussdapp.MenuOptions{MenuName: "done", NextMenu: "home", MenuContent: text("END Registered")},
+	)
+
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").Expect("Welcome").ExpectContinue().
+		Send("1").Expect("What is your name").
+		Send("Jane").Expect("How old are you").
+		Send("0").Expect("What is your name").
+		Send("Jane").Expect("How old are you").
+		Send("00").Expect("Welcome").
+		Send("1").Expect("What is your name").
+		Send("Jane").Send("30").ExpectExact("Registered").ExpectEnd()
+}
+
+func TestRoutes(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{
+			MenuName:    "home",
+			NextMenu:    "help",
+			MenuContent: text("CON Welcome\n1. Balance\n2. Statement"),
+			Routes:      map[string]string{"1": "balance", "2": "statement"},
+		},
+		&ussdapp.MenuOptions{MenuName: "balance", NextMenu: "home", MenuContent: text("END Your balance is 100")},
+		&ussdapp.MenuOptions{MenuName: "statement", NextMenu: "home", MenuContent: text("END Statement sent")},
+		&ussdapp.MenuOptions{MenuName: "help", NextMenu: "home", MenuContent: text("END Call 100 for help")},
+	)
+
+	st := ussdtest.NewSessionTester(t, app)
+
+	st.Dial("*123#").Send("1").ExpectExact("Your balance is 100").ExpectEnd()
+	st.Dial("*123#").Send("2").ExpectExact("Statement sent").ExpectEnd()
+	// Inputs without a route go to the next menu
+	st.Dial("*123#").Send("9").ExpectExact("Call 100 for help").ExpectEnd()
+}
+
+func TestValidation(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "amount", MenuContent: text("CON Enter amount")},
+		&ussdapp.MenuOptions{
+			MenuName:          "amount",
+			NextMenu:          "home",
+			Validators:        []ussdapp.Validator{ussdapp.Numeric()},
+			ValidationMessage: text("Amount must be a number"),
+			MenuContent:       text("END Sent"),
+		},
+	)
+
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").Expect("Enter amount").
+		Send("ten").Expect("Amount must be a number").Expect("Enter amount").ExpectContinue().
+		Send("10").ExpectExact("Sent").ExpectEnd()
+}
+
+func TestDedup(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		renders int
+	)
+
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en", DedupWindow: time.Minute})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "pay", MenuContent: text("CON 1. Pay")},
+		&ussdapp.MenuOptions{
+			MenuName: "pay",
+			NextMenu: "home",
+			GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+				mu.Lock()
+				renders++
+				mu.Unlock()
+				return m.ExecuteMenuArgs("en"), nil
+			},
+			MenuContent: text("END Payment sent"),
+		},
+	)
+
+	srv := httptest.NewServer(app.GatewayHandler(ussdapp.NewGenericAdapter()))
+	defer srv.Close()
+
+	send := func(params string) string {
+		t.Helper()
+
+		query := url.Values{
+			"SESSION_ID":   {"dedup-session"},
+			"SERVICE_CODE": {"*123#"},
+			"MSISDN":       {"254700000000"},
+			"USSD_PARAMS":  {params},
+		}
+
+		res, err := http.Get(srv.URL + "?" + query.Encode())
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer res.Body.Close()
+
+		bs, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+
+		return strings.TrimSpace(string(bs))
+	}
+
+	send("")
+	first := send("1")
+	// The gateway retries the request
+	retry := send("1")
+
+	if first != "END Payment sent" {
+		t.Fatalf("expected payment response, got %q", first)
+	}
+	if retry != first {
+		t.Fatalf("expected retry to get %q, got %q", first, retry)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if renders != 1 {
+		t.Fatalf("expected pay menu to run once, ran %d times", renders)
+	}
+}
+
+func TestOTPMenu(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "otp", MenuContent: text("CON 1. Withdraw")},
+		&ussdapp.MenuOptions{MenuName: "withdraw", NextMenu: "home", MenuContent: text("END Withdrawal approved")},
+	)
+
+	err := app.AddOTPMenu(&ussdapp.OTPMenuOptions{
+		MenuName:    "otp",
+		Digits:      4,
+		MaxAttempts: 2,
+		NextMenu:    "withdraw",
+		Send: func(ctx context.Context, payload ussdapp.UssdPayload, code string) error {
+			mu.Lock()
+			sent = append(sent, code)
+			mu.Unlock()
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add otp menu: %v", err)
+	}
+
+	lastCode := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(sent) == 0 {
+			t.Fatal("no code was sent")
+		}
+		return sent[len(sent)-1]
+	}
+
+	wrong := func(code string) string {
+		if code == "0000" {
+			return "1111"
+		}
+		return "0000"
+	}
+
+	st := ussdtest.NewSessionTester(t, app)
+
+	st.Dial("*123#").Send("1").Expect("Enter the code sent to you")
+	code := lastCode()
+
+	st.Send("12").Expect("Enter the 4 digit code").ExpectContinue()
+	// A wrong code renders the prompt again without sending another code
+	st.Send(wrong(code)).Expect("Wrong code").ExpectContinue()
+	if lastCode() != code {
+		t.Fatal("expected the code not to be sent again")
+	}
+	st.Send(code).ExpectExact("Withdrawal approved").ExpectEnd()
+
+	// The session ends after too many wrong codes
+	st.Dial("*123#").Send("1")
+	code = lastCode()
+	st.Send(wrong(code)).Expect("Wrong code").
+		Send(wrong(code)).Expect("Too many wrong codes").ExpectEnd()
+}
+
+func TestLoginRequired(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en", LoginMenu: "login"})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{
+			MenuName:    "home",
+			NextMenu:    "help",
+			MenuContent: text("CON 1. Balance"),
+			Routes:      map[string]string{"1": "balance"},
+		},
+		&ussdapp.MenuOptions{MenuName: "help", NextMenu: "home", MenuContent: text("END Call 100 for help")},
+		&ussdapp.MenuOptions{MenuName: "balance", NextMenu: "home", RequiresAuth: true, MenuContent: text("END Your balance is 100")},
+		&ussdapp.MenuOptions{MenuName: "login", NextMenu: "login:pin", MenuContent: text("CON Enter your PIN")},
+		&ussdapp.MenuOptions{
+			MenuName:       "login:pin",
+			NextMenu:       "home",
+			SensitiveInput: true,
+			GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+				if payload.UssdCurrentParam() != "1234" {
+					return app.PreviousMenuWithError(ctx, payload, m, "Wrong PIN")
+				}
+				return app.Login(ctx, payload, "home")
+			},
+		},
+	)
+
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").
+		Send("1").Expect("Enter your PIN").
+		Send("0000").Expect("Wrong PIN").Expect("Enter your PIN").
+		Send("1234").ExpectExact("Your balance is 100").ExpectEnd()
+}
+
+type recordingSink struct {
+	mu   sync.Mutex
+	logs []*ussdapp.SessionRequest
+}
+
+func (s *recordingSink) Write(ctx context.Context, logs []*ussdapp.SessionRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, logs...)
+	return nil
+}
+
+func TestCloseFlushesLogs(t *testing.T) {
+	sink := &recordingSink{}
+
+	app := ussdtest.NewApp(t, &ussdapp.Options{
+		DefaultLanguage: "en",
+		SaveLogs:        true,
+		LogSink:         sink,
+		// Logs are only written on close within the test
+		LogBuffer: &ussdapp.LogBuffer{FlushInterval: time.Hour},
+	})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "done", MenuContent: text("CON Welcome")},
+		&ussdapp.MenuOptions{MenuName: "done", NextMenu: "home", MenuContent: text("END Bye")},
+	)
+
+	st := ussdtest.NewSessionTester(t, app)
+	st.Dial("*123#").Send("1").ExpectEnd()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := app.Close(ctx)
+	if err != nil {
+		t.Fatalf("failed to close app: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if len(sink.logs) != 2 {
+		t.Fatalf("expected 2 session logs, got %d", len(sink.logs))
+	}
+	for _, log := range sink.logs {
+		if log.SessionID != st.SessionID() {
+			t.Fatalf("expected logs of session %s, got %s", st.SessionID(), log.SessionID)
+		}
+	}
+	if !sink.logs[1].Ended {
+		t.Fatal("expected last log to end the session")
+	}
+}
+
+func TestPaginateResponse(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{
+		DefaultLanguage:    "en",
+		MaxResponseLength:  30,
+		TruncationStrategy: ussdapp.PaginateResponse,
+	})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{
+			MenuName:    "home",
+			NextMenu:    "short",
+			MenuContent: text("CON Terms\nLine one of terms\nLine two of terms"),
+		},
+		&ussdapp.MenuOptions{MenuName: "short", NextMenu: "done", MenuContent: text("CON Accept?")},
+		&ussdapp.MenuOptions{MenuName: "done", NextMenu: "home", MenuContent: text("END Done")},
+	)
+
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").Expect("Terms").Expect("98. Next").
+		Send("98").Expect("Line one of terms").
+		Send("1").ExpectExact("Accept?").
+		// Pages of the terms are not shown once another menu is rendered
+		Send("98").ExpectExact("Done").ExpectEnd()
+}
+
+func TestAmount(t *testing.T) {
+	validate := ussdapp.Amount(10, 1000)
+
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{input: "10", valid: true},
+		{input: "1,000", valid: true},
+		{input: "99.50", valid: true},
+		{input: "9.99"},
+		{input: "1000.01"},
+		{input: "1e2"},
+		{input: "NaN"},
+		{input: "Inf"},
+		{input: "-50"},
+	}
+
+	for _, tt := range tests {
+		err := validate(tt.input)
+		if tt.valid && err != nil {
+			t.Errorf("expected %q to be valid, got %v", tt.input, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("expected %q to be invalid", tt.input)
+		}
+	}
+}
+
+func TestResumeSession(t *testing.T) {
+	app := ussdtest.NewApp(t, &ussdapp.Options{DefaultLanguage: "en", LoginMenu: "login", ResumeSession: true})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{
+			MenuName:    "home",
+			NextMenu:    "account",
+			MenuContent: text("CON Welcome\n1. Register\n2. Account"),
+			Routes:      map[string]string{"1": "register", "2": "account"},
+		},
+		&ussdapp.MenuOptions{MenuName: "account", NextMenu: "statement", RequiresAuth: true, MenuContent: text("CON Account\n1. Statement")},
+		&ussdapp.MenuOptions{MenuName: "statement", NextMenu: "home", RequiresAuth: true, MenuContent: text("END Statement sent")},
+		&ussdapp.MenuOptions{MenuName: "login", NextMenu: "login:pin", MenuContent: text("CON Enter your PIN")},
+		&ussdapp.MenuOptions{
+			MenuName: "login:pin",
+			NextMenu: "home",
+			GenerateMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload, m ussdapp.Menu) (ussdapp.SessionResponse, error) {
+				return app.Login(ctx, payload, "home")
+			},
+		},
+	)
+
+	err := app.AddFlow(&ussdapp.FlowOptions{
+		Name: "register",
+		Steps: []*ussdapp.FlowStep{
+			{Field: "name", Prompt: text("CON What is your name")},
+			{Field: "age", Prompt: text("CON How old are you")},
+		},
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			return ussdapp.NewMenu(&ussdapp.MenuOptions{
+				MenuName:    "registered",
+				NextMenu:    "home",
+				MenuContent: text("END Registered %s aged %s"),
+			}).ExecuteMenuArgs("en", fields["name"], fields["age"]), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add flow: %v", err)
+	}
+
+	st := ussdtest.NewSessionTester(t, app)
+
+	// Answers of a flow are kept
+	st.Dial("*123#").Send("1").Expect("What is your name").Send("Jane").Expect("How old are you")
+	st.Dial("*123#").Expect("Continue where you left off").
+		Send("1").Expect("How old are you").
+		Send("30").ExpectExact("Registered Jane aged 30").ExpectEnd()
+
+	// Logins are not kept
+	st.Dial("*123#").Send("2").Expect("Enter your PIN").Send("1234").Expect("Account")
+	st.Dial("*123#").Expect("Continue where you left off").
+		Send("1").Expect("Enter your PIN").ExpectContinue()
+}
+
+func TestFlow(t *testing.T) {
+	const vip = "254711111111"
+
+	app := ussdtest.NewApp(t, &ussdapp.Options{
+		DefaultLanguage: "en",
+		HomeMenuFn: func(ctx context.Context, payload ussdapp.UssdPayload) (string, error) {
+			if payload.Msisdn() == vip {
+				return "vip", nil
+			}
+			return "", nil
+		},
+	})
+
+	addMenus(t, app,
+		&ussdapp.MenuOptions{MenuName: "home", NextMenu: "register", MenuContent: text("CON Welcome\n1. Register")},
+		&ussdapp.MenuOptions{MenuName: "vip", NextMenu: "register", MenuContent: text("CON Welcome back\n1. Register")},
+		&ussdapp.MenuOptions{MenuName: "survey:age", NextMenu: "home", MenuContent: text("CON How old are you")},
+	)
+
+	registered := ussdapp.NewMenu(&ussdapp.MenuOptions{MenuName: "registered", NextMenu: "home", MenuContent: text("CON Registered %s\n1. Continue")})
+
+	err := app.AddFlow(&ussdapp.FlowOptions{
+		Name:  "register",
+		Steps: []*ussdapp.FlowStep{{Field: "name", Prompt: text("CON What is your name")}},
+		OnComplete: func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+			return registered.ExecuteMenuArgs("en", fields["name"]), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add flow: %v", err)
+	}
+
+	// Completed flows go to the home menu of the session
+	st := ussdtest.NewSessionTester(t, app)
+	st.Dial("*123#").Send("1").Send("Jane").Expect("Registered Jane").Send("1").Expect("Welcome\n")
+	st.WithMsisdn(vip).Dial("*123#").Send("1").Send("Jane").Expect("Registered Jane").Send("1").Expect("Welcome back")
+
+	complete := func(ctx context.Context, payload ussdapp.UssdPayload, fields map[string]string) (ussdapp.SessionResponse, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name  string
+		steps []*ussdapp.FlowStep
+	}{
+		{name: "pay", steps: []*ussdapp.FlowStep{{Field: "complete"}}},
+		{name: "survey", steps: []*ussdapp.FlowStep{{Field: "name"}, {Field: "age"}}},
+	}
+
+	for _, tt := range tests {
+		err := app.AddFlow(&ussdapp.FlowOptions{Name: tt.name, Steps: tt.steps, OnComplete: complete})
+		if err == nil {
+			t.Fatalf("expected flow %s to be refused", tt.name)
+		}
+		// Menus of refused flows are not registered
+		for _, name := range app.GetMenuNames() {
+			if name == tt.name {
+				t.Fatalf("expected menu %s of refused flow not to be registered", name)
+			}
+		}
+	}
+}
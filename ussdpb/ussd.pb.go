@@ -0,0 +1,282 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.21.12
+// source: ussdpb/ussd.proto
+
+package ussdpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// UssdRequest is a ussd request received by a gateway
+type UssdRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Id of the session assigned by the gateway
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// Code dialed by the user e.g *123#
+	ServiceCode string `protobuf:"bytes,2,opt,name=service_code,json=serviceCode,proto3" json:"service_code,omitempty"`
+	// Phone number of the user
+	Msisdn string `protobuf:"bytes,3,opt,name=msisdn,proto3" json:"msisdn,omitempty"`
+	// Inputs of the user in the session separated by *
+	UssdParams string `protobuf:"bytes,4,opt,name=ussd_params,json=ussdParams,proto3" json:"ussd_params,omitempty"`
+}
+
+func (x *UssdRequest) Reset() {
+	*x = UssdRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ussdpb_ussd_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UssdRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UssdRequest) ProtoMessage() {}
+
+func (x *UssdRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ussdpb_ussd_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UssdRequest.ProtoReflect.Descriptor instead.
+func (*UssdRequest) Descriptor() ([]byte, []int) {
+	return file_ussdpb_ussd_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UssdRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UssdRequest) GetServiceCode() string {
+	if x != nil {
+		return x.ServiceCode
+	}
+	return ""
+}
+
+func (x *UssdRequest) GetMsisdn() string {
+	if x != nil {
+		return x.Msisdn
+	}
+	return ""
+}
+
+func (x *UssdRequest) GetUssdParams() string {
+	if x != nil {
+		return x.UssdParams
+	}
+	return ""
+}
+
+// UssdResponse is the response to show the user
+type UssdResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Id of the session
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// Text of the menu without the CON or END prefix
+	Text string `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	// Whether the session ends with this response
+	End bool `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	// Name of the menu that was rendered
+	MenuName string `protobuf:"bytes,4,opt,name=menu_name,json=menuName,proto3" json:"menu_name,omitempty"`
+}
+
+func (x *UssdResponse) Reset() {
+	*x = UssdResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ussdpb_ussd_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UssdResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UssdResponse) ProtoMessage() {}
+
+func (x *UssdResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ussdpb_ussd_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UssdResponse.ProtoReflect.Descriptor instead.
+func (*UssdResponse) Descriptor() ([]byte, []int) {
+	return file_ussdpb_ussd_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UssdResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UssdResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *UssdResponse) GetEnd() bool {
+	if x != nil {
+		return x.End
+	}
+	return false
+}
+
+func (x *UssdResponse) GetMenuName() string {
+	if x != nil {
+		return x.MenuName
+	}
+	return ""
+}
+
+var File_ussdpb_ussd_proto protoreflect.FileDescriptor
+
+var file_ussdpb_ussd_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x75, 0x73, 0x73, 0x64, 0x70, 0x62, 0x2f, 0x75, 0x73, 0x73, 0x64, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x75, 0x73, 0x73, 0x64, 0x61, 0x70, 0x70, 0x2e, 0x76, 0x31, 0x22,
+	0x88, 0x01, 0x0a, 0x0b, 0x55, 0x73, 0x73, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x21,
+	0x0a, 0x0c, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x43, 0x6f, 0x64,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x73, 0x69, 0x73, 0x64, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x6d, 0x73, 0x69, 0x73, 0x64, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x75, 0x73, 0x73,
+	0x64, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x75, 0x73, 0x73, 0x64, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22, 0x70, 0x0a, 0x0c, 0x55, 0x73,
+	0x73, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12,
+	0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x6e, 0x75, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x6e, 0x75, 0x4e, 0x61, 0x6d, 0x65, 0x32, 0x4f, 0x0a, 0x0b,
+	0x55, 0x73, 0x73, 0x64, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x40, 0x0a, 0x0b, 0x50,
+	0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x55, 0x73, 0x73, 0x64, 0x12, 0x17, 0x2e, 0x75, 0x73, 0x73,
+	0x64, 0x61, 0x70, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x73, 0x73, 0x64, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x75, 0x73, 0x73, 0x64, 0x61, 0x70, 0x70, 0x2e, 0x76, 0x31,
+	0x2e, 0x55, 0x73, 0x73, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x22, 0x5a,
+	0x20, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x69, 0x64, 0x79,
+	0x6f, 0x6e, 0x2f, 0x75, 0x73, 0x73, 0x64, 0x61, 0x70, 0x70, 0x2f, 0x75, 0x73, 0x73, 0x64, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_ussdpb_ussd_proto_rawDescOnce sync.Once
+	file_ussdpb_ussd_proto_rawDescData = file_ussdpb_ussd_proto_rawDesc
+)
+
+func file_ussdpb_ussd_proto_rawDescGZIP() []byte {
+	file_ussdpb_ussd_proto_rawDescOnce.Do(func() {
+		file_ussdpb_ussd_proto_rawDescData = protoimpl.X.CompressGZIP(file_ussdpb_ussd_proto_rawDescData)
+	})
+	return file_ussdpb_ussd_proto_rawDescData
+}
+
+var file_ussdpb_ussd_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_ussdpb_ussd_proto_goTypes = []interface{}{
+	(*UssdRequest)(nil),  // 0: ussdapp.v1.UssdRequest
+	(*UssdResponse)(nil), // 1: ussdapp.v1.UssdResponse
+}
+var file_ussdpb_ussd_proto_depIdxs = []int32{
+	0, // 0: ussdapp.v1.UssdService.ProcessUssd:input_type -> ussdapp.v1.UssdRequest
+	1, // 1: ussdapp.v1.UssdService.ProcessUssd:output_type -> ussdapp.v1.UssdResponse
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ussdpb_ussd_proto_init() }
+func file_ussdpb_ussd_proto_init() {
+	if File_ussdpb_ussd_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_ussdpb_ussd_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UssdRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_ussdpb_ussd_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UssdResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_ussdpb_ussd_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ussdpb_ussd_proto_goTypes,
+		DependencyIndexes: file_ussdpb_ussd_proto_depIdxs,
+		MessageInfos:      file_ussdpb_ussd_proto_msgTypes,
+	}.Build()
+	File_ussdpb_ussd_proto = out.File
+	file_ussdpb_ussd_proto_rawDesc = nil
+	file_ussdpb_ussd_proto_goTypes = nil
+	file_ussdpb_ussd_proto_depIdxs = nil
+}
@@ -0,0 +1,14 @@
+/*
+Package ussdtest provides helpers for testing ussd apps.
+
+A SessionTester drives a session through the app handler and checks each response:
+
+	app := ussdtest.NewApp(t, &ussdapp.Options{HomeMenu: "home"})
+	// register menus
+
+	ussdtest.NewSessionTester(t, app).
+		Dial("*123#").Expect("Welcome").
+		Send("1").Expect("What is your name").
+		Send("Jane").ExpectEnd()
+*/
+package ussdtest
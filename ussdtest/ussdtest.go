@@ -0,0 +1,185 @@
+package ussdtest
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gidyon/ussdapp"
+	memorycache "github.com/gidyon/ussdapp/cache/memory"
+	"github.com/gidyon/ussdapp/simulator"
+)
+
+const (
+	defaultAppName = "ussdtest"
+	defaultMsisdn  = "254700000000"
+)
+
+// NewApp creates an app for tests. Unset options default to an in-memory cache, a discarding logger,
+// app name ussdtest and home menu home.
+//
+// The app is closed when the test ends.
+func NewApp(t testing.TB, opt *ussdapp.Options) *ussdapp.UssdApp {
+	t.Helper()
+
+	if opt == nil {
+		opt = &ussdapp.Options{}
+	}
+	if opt.AppName == "" {
+		opt.AppName = defaultAppName
+	}
+	if opt.HomeMenu == "" {
+		opt.HomeMenu = "home"
+	}
+	if opt.Cache == nil {
+		opt.Cache = memorycache.NewMemoryCache()
+	}
+	if opt.Logger == nil {
+		opt.Logger = ussdapp.NewStdLogger(log.New(io.Discard, "", 0))
+	}
+
+	app, err := ussdapp.NewUssdApp(context.Background(), opt)
+	if err != nil {
+		t.Fatalf("failed to create ussd app: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = app.Close(ctx)
+	})
+
+	return app
+}
+
+// SessionTester sends inputs of a session to an app and fails the test when a response is not as expected.
+//
+// Methods return the tester so that calls can be chained.
+type SessionTester struct {
+	t      testing.TB
+	app    *ussdapp.UssdApp
+	msisdn string
+	sim    simulator.Simulator
+	res    *simulator.Response
+}
+
+// NewSessionTester creates a tester for the app.
+//
+// Requests are read with the generic gateway adapter whatever the gateway set in the app options.
+func NewSessionTester(t testing.TB, app *ussdapp.UssdApp) *SessionTester {
+	return &SessionTester{
+		t:      t,
+		app:    app,
+		msisdn: defaultMsisdn,
+	}
+}
+
+// WithMsisdn sets the phone number of sessions dialed afterwards. Defaults to 254700000000
+func (st *SessionTester) WithMsisdn(msisdn string) *SessionTester {
+	st.msisdn = msisdn
+	return st
+}
+
+// Dial starts a new session with the ussd code. Inputs appended to the code, e.g *123*1#, are sent as a shortcut
+func (st *SessionTester) Dial(serviceCode string) *SessionTester {
+	st.t.Helper()
+
+	sim, err := simulator.NewSimulator(&simulator.Options{
+		Handler:     st.app.GatewayHandler(ussdapp.NewGenericAdapter()),
+		Msisdn:      st.msisdn,
+		ServiceCode: serviceCode,
+		Protocol:    simulator.Generic,
+	})
+	if err != nil {
+		st.t.Fatalf("failed to create simulator: %v", err)
+	}
+
+	st.sim = sim
+
+	st.res, err = sim.Dial(context.Background())
+	if err != nil {
+		st.t.Fatalf("dial %s failed: %v", serviceCode, err)
+	}
+
+	return st
+}
+
+// Send sends the input in the current session
+func (st *SessionTester) Send(input string) *SessionTester {
+	st.t.Helper()
+
+	if st.sim == nil {
+		st.t.Fatalf("send %q before dial", input)
+	}
+
+	var err error
+	st.res, err = st.sim.Send(context.Background(), input)
+	if err != nil {
+		st.t.Fatalf("send %q failed: %v", input, err)
+	}
+
+	return st
+}
+
+// Expect checks that the last response contains text
+func (st *SessionTester) Expect(text string) *SessionTester {
+	st.t.Helper()
+
+	if !strings.Contains(st.Response(), text) {
+		st.t.Fatalf("expected response to contain %q, got %q", text, st.Response())
+	}
+
+	return st
+}
+
+// ExpectExact checks that the last response, without the CON or END prefix, is text
+func (st *SessionTester) ExpectExact(text string) *SessionTester {
+	st.t.Helper()
+
+	if st.Response() != text {
+		st.t.Fatalf("expected response %q, got %q", text, st.Response())
+	}
+
+	return st
+}
+
+// ExpectEnd checks that the last response ended the session
+func (st *SessionTester) ExpectEnd() *SessionTester {
+	st.t.Helper()
+
+	if st.res == nil || !st.res.End {
+		st.t.Fatalf("expected session to end, got %q", st.Response())
+	}
+
+	return st
+}
+
+// ExpectContinue checks that the last response expects more input
+func (st *SessionTester) ExpectContinue() *SessionTester {
+	st.t.Helper()
+
+	if st.res == nil || st.res.End {
+		st.t.Fatalf("expected session to continue, got %q", st.Response())
+	}
+
+	return st
+}
+
+// Response returns the text of the last response without the CON or END prefix
+func (st *SessionTester) Response() string {
+	if st.res == nil {
+		return ""
+	}
+	return st.res.Text
+}
+
+// SessionID returns the id of the current session
+func (st *SessionTester) SessionID() string {
+	if st.sim == nil {
+		return ""
+	}
+	return st.sim.SessionID()
+}
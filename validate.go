@@ -0,0 +1,137 @@
+package ussdapp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator checks the user input received by a menu. The returned error message is shown to the user.
+type Validator func(input string) error
+
+// validationError is a failed validation with a message for the user
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+func (e *validationError) Unwrap() error {
+	return ErrFailedValidation
+}
+
+// NewValidationError returns an error for invalid input that shows message to the user.
+//
+// The error matches ErrFailedValidation when checked with errors.Is.
+func NewValidationError(message string) error {
+	return &validationError{message: message}
+}
+
+// runValidators returns the first validation error for the input.
+//
+// The message in messages for the language, or a fallback language, replaces the validator message when set.
+func runValidators(validators []Validator, messages Content, input, lang string, fallbacks ...string) error {
+	for _, validator := range validators {
+		err := validator(input)
+		if err == nil {
+			continue
+		}
+		if msg := messages.Text(lang, fallbacks...); msg != "" {
+			return NewValidationError(msg)
+		}
+		// Errors with messages per language are shown in the session language
+		if _, ok := userMessage(err, lang, fallbacks...); ok {
+			return err
+		}
+		return NewValidationError(err.Error())
+	}
+	return nil
+}
+
+// Numeric validates that input has digits only
+func Numeric() Validator {
+	return func(input string) error {
+		if input == "" {
+			return NewValidationError("Enter numbers only")
+		}
+		for _, r := range input {
+			if r < '0' || r > '9' {
+				return NewValidationError("Enter numbers only")
+			}
+		}
+		return nil
+	}
+}
+
+// MinLen validates that input has at least n characters
+func MinLen(n int) Validator {
+	return func(input string) error {
+		if utf8.RuneCountInString(input) < n {
+			return NewValidationError(fmt.Sprintf("Enter at least %d characters", n))
+		}
+		return nil
+	}
+}
+
+// MaxLen validates that input has at most n characters
+func MaxLen(n int) Validator {
+	return func(input string) error {
+		if utf8.RuneCountInString(input) > n {
+			return NewValidationError(fmt.Sprintf("Enter at most %d characters", n))
+		}
+		return nil
+	}
+}
+
+// Regexp validates that input matches the regular expression. It panics if the expression does not compile
+func Regexp(expr string) Validator {
+	re := regexp.MustCompile(expr)
+	return func(input string) error {
+		if !re.MatchString(input) {
+			return NewValidationError("Invalid input")
+		}
+		return nil
+	}
+}
+
+// OneOf validates that input is one of the values
+func OneOf(values ...string) Validator {
+	return func(input string) error {
+		for _, val := range values {
+			if input == val {
+				return nil
+			}
+		}
+		return NewValidationError("Invalid selection")
+	}
+}
+
+var phoneNumberRe = regexp.MustCompile(`^\+?[0-9]{9,15}$`)
+
+// PhoneNumber validates that input looks like a phone number, with an optional leading +
+func PhoneNumber() Validator {
+	return func(input string) error {
+		if !phoneNumberRe.MatchString(strings.ReplaceAll(input, " ", "")) {
+			return NewValidationError("Enter a valid phone number")
+		}
+		return nil
+	}
+}
+
+// Amount validates that input is an amount between min and max inclusive, with at most 2 decimals.
+//
+// The input is read by ParseAmount, so exponents and values such as NaN or Inf are rejected.
+func Amount(min, max float64) Validator {
+	return func(input string) error {
+		minor, err := ParseAmount(input, 2)
+		if v := float64(minor) / 100; err != nil || v < min || v > max {
+			return NewValidationError(fmt.Sprintf("Enter an amount between %s and %s",
+				strconv.FormatFloat(min, 'f', -1, 64), strconv.FormatFloat(max, 'f', -1, 64)))
+		}
+		return nil
+	}
+}
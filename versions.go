@@ -0,0 +1,140 @@
+package ussdapp
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// MenuRollout decides which msisdns are served a version of a menu
+type MenuRollout struct {
+	// Percent of msisdns served the version, from 0 to 100. Msisdns are picked by a hash of the msisdn and the
+	// menu name, so users keep the version they were served as the percentage grows
+	Percent int
+	// Msisdns are always served the version, e.g testers
+	Msisdns []string
+}
+
+// menuVersion is a version of a registered menu. Versions are not modified once registered
+type menuVersion struct {
+	version string
+	menu    Menu
+	percent int
+	msisdns map[string]struct{}
+}
+
+func newMenuVersion(version string, m Menu, rollout *MenuRollout) (*menuVersion, error) {
+	if rollout == nil {
+		rollout = &MenuRollout{}
+	}
+	if rollout.Percent < 0 || rollout.Percent > 100 {
+		return nil, fmt.Errorf("rollout percent %d for version %s of %s menu is not between 0 and 100", rollout.Percent, version, m.MenuName())
+	}
+
+	mv := &menuVersion{
+		version: version,
+		menu:    m,
+		percent: rollout.Percent,
+		msisdns: make(map[string]struct{}, len(rollout.Msisdns)),
+	}
+	for _, msisdn := range rollout.Msisdns {
+		mv.msisdns[msisdn] = struct{}{}
+	}
+
+	return mv, nil
+}
+
+// serves reports whether the msisdn is served the version
+func (mv *menuVersion) serves(msisdn string) bool {
+	if _, ok := mv.msisdns[msisdn]; ok {
+		return true
+	}
+	if mv.percent <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(mv.menu.MenuName() + ":" + msisdn))
+
+	return int(h.Sum32()%100) < mv.percent
+}
+
+// AddMenuVersion registers a version of a registered menu that is served instead of the menu to the msisdns picked
+// by the rollout. The version must have the name of the menu, its next menu and routes may differ.
+//
+// Versions are checked in the order they are added and the first that serves the msisdn is used. The version served
+// is saved in the menu_version column of session logs.
+func (app *UssdApp) AddMenuVersion(version string, m Menu, rollout *MenuRollout) error {
+	err := ValidateMenu(m)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("missing version for %s menu", m.MenuName())
+	}
+
+	mv, err := newMenuVersion(version, m, rollout)
+	if err != nil {
+		return err
+	}
+
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	reg := app.registry()
+
+	if _, ok := reg.menus[m.MenuName()]; !ok {
+		return fmt.Errorf("%w: %s", ErrMenuNotExist, m.MenuName())
+	}
+	for _, v := range reg.versions[m.MenuName()] {
+		if v.version == version {
+			return fmt.Errorf("version %s of %s menu is registered", version, m.MenuName())
+		}
+	}
+
+	app.prepareMenu(m)
+
+	app.menuRegistry.Store(reg.withVersions(m.MenuName(), append(append([]*menuVersion{}, reg.versions[m.MenuName()]...), mv)))
+
+	app.opt.Logger.Info("registered menu version", "menu", m.MenuName(), "version", version)
+
+	return nil
+}
+
+// SetMenuRollout changes the msisdns served a version of a menu, e.g to widen the rollout or to roll the version
+// back with a zero percentage
+func (app *UssdApp) SetMenuRollout(menuName, version string, rollout *MenuRollout) error {
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	reg := app.registry()
+
+	versions := append([]*menuVersion{}, reg.versions[menuName]...)
+	for i, v := range versions {
+		if v.version != version {
+			continue
+		}
+
+		mv, err := newMenuVersion(version, v.menu, rollout)
+		if err != nil {
+			return err
+		}
+		versions[i] = mv
+
+		app.menuRegistry.Store(reg.withVersions(menuName, versions))
+
+		return nil
+	}
+
+	return fmt.Errorf("version %s of %s menu is not registered", version, menuName)
+}
+
+// servedVersion returns the version of the menu served to the msisdn of the payload. It returns the menu and
+// an empty version when no version serves the msisdn
+func (app *UssdApp) servedVersion(payload UssdPayload, m Menu) (Menu, string) {
+	for _, v := range app.registry().versions[m.MenuName()] {
+		if v.serves(payload.Msisdn()) {
+			return v.menu, v.version
+		}
+	}
+	return m, ""
+}
@@ -2,106 +2,71 @@ package ussdapp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	tickerInterval = 5 * time.Second
 	failedBulkDir  = "failed-bulk-inserts"
 	bulkInsertSize = 1000
+	// failedLogsUploadTimeout is how long uploading logs to Options.FailedLogStorage may take
+	failedLogsUploadTimeout = 30 * time.Second
 )
 
 func (app *UssdApp) saveLogsWorker(ctx context.Context) {
-	if !app.opt.SaveLogs {
+	defer app.workers.Done()
+
+	if !app.savingLogs() || app.opt.LogMode == LogModeSync {
 		return
 	}
 
-	ticker := time.NewTicker(tickerInterval)
-	defer ticker.Stop()
+	var (
+		buffer    = app.opt.LogBuffer
+		interval  = buffer.flushInterval()
+		batchSize = buffer.batchSize()
+	)
 
-	if !app.opt.SQLDB.Migrator().HasTable(&SessionRequest{}) {
-		err := app.opt.SQLDB.Migrator().AutoMigrate(&SessionRequest{})
-		if err != nil {
-			app.Logger().Fatal(err)
-		}
-	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	var (
-		currCap = bulkInsertSize
-		logs    = make([]*SessionRequest, 0, bulkInsertSize)
-		err     error
-
-		updateCap = func() {
-			// Check that channel if filled
-			if len(app.logsChan) == cap(app.logsChan) {
-				currCap = currCap + (currCap / 2)
-				app.opt.Logger.Infof("INSERT USSD LOGS: channel is filled, draining and expanding channel to capacity %d", currCap)
-
-				// Drain the channel
-				for v := range app.logsChan {
+		logs = make([]*SessionRequest, 0, batchSize)
+		err  error
+
+		drain = func() {
+			for {
+				select {
+				case v := <-app.logsChan:
 					logs = append(logs, v)
+				default:
+					return
 				}
-
-				// Update channel capacity
-				app.logsChan = make(chan *SessionRequest, currCap)
 			}
 		}
 
-		callback = func() error {
-			// We update logs and capacity regardless
+		callback = func(ctx context.Context) (err error) {
+			spanCtx, span := app.tracer.Start(ctx, "ussdapp.SaveLogs", trace.WithAttributes(
+				attribute.Int("ussd.logs", len(logs)),
+			))
+			defer func() { endSpan(span, err) }()
+
+			// We update logs regardless
 			defer func() {
 				logs = logs[0:0]
-				updateCap()
 			}()
 
-			return app.opt.SQLDB.Transaction(func(tx *gorm.DB) error {
-				err1 := tx.CreateInBatches(logs, len(logs)+1).Error
-				if err1 != nil {
-					tx.Rollback()
-					app.opt.Logger.Errorf("INSERT USSD LOGS FAILED (SAVING LOGS IN FILE ...): %v", err1)
-
-					// Get directory
-					_, err := os.Stat(failedBulkDir)
-					switch {
-					case err == nil:
-					case os.IsNotExist(err):
-						err := os.Mkdir(failedBulkDir, 0755)
-						if err != nil {
-							return err
-						}
-					default:
-						return err
-					}
-
-					fileName := fmt.Sprintf("%s/bulk-%d.json", failedBulkDir, time.Now().UnixNano())
-
-					// Save logs locally in file
-					f, err := os.Create(fileName)
-					if err != nil {
-						app.opt.Logger.Errorf("FAILED TO CREATE FILE: %v", err)
-						return err
-					}
-					defer f.Close()
-
-					err = json.NewEncoder(f).Encode(logs)
-					if err != nil {
-						app.opt.Logger.Errorf("FAILED TO ADD JSON DATA TO FILE: %v", err)
-						return err
-					}
-
-					return err1
-				}
-
-				return nil
-			})
+			return app.writeLogs(spanCtx, logs)
 		}
 	)
 
@@ -109,28 +74,59 @@ func (app *UssdApp) saveLogsWorker(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-app.stop:
+			// Save logs that are yet to be written before exiting
+			drain()
+			logsLen := len(logs)
+			if logsLen > 0 {
+				err = callback(app.closeCtx)
+				if err == nil {
+					app.opt.Logger.Info("bulk inserted session logs on close", "count", logsLen)
+				} else {
+					app.metrics.logFlushFailed()
+					app.opt.Logger.Error("failed to bulk insert session logs on close", "error", err)
+				}
+			}
+			return
 		case <-ticker.C:
 			logsLen := len(logs)
 			if logsLen > 0 {
-				err = callback()
+				err = callback(ctx)
+				if err == nil {
+					app.opt.Logger.Info("bulk inserted session logs from ticker", "count", logsLen)
+					ticker.Reset(interval)
+				} else {
+					app.metrics.logFlushFailed()
+					app.opt.Logger.Error("failed to bulk insert session logs", "error", err)
+				}
+			}
+
+		case res := <-app.flushReqs:
+			drain()
+			logsLen := len(logs)
+			if logsLen > 0 {
+				err = callback(ctx)
 				if err == nil {
-					app.opt.Logger.Infof("INSERT USSD LOGS: bulk inserted %d ussd logs from ticker", logsLen)
-					ticker.Reset(tickerInterval)
+					app.opt.Logger.Info("bulk inserted session logs on flush", "count", logsLen)
+					ticker.Reset(interval)
 				} else {
-					app.opt.Logger.Errorf("INSERT USSD LOGS: failed to bulk insert: %v", err)
+					app.metrics.logFlushFailed()
+					app.opt.Logger.Error("failed to bulk insert session logs on flush", "error", err)
 				}
 			}
+			res <- err
 
 		case logDB := <-app.logsChan:
 			logs = append(logs, logDB)
 			logsLen := len(logs)
-			if logsLen > currCap-1 {
-				err = callback()
+			if logsLen >= batchSize {
+				err = callback(ctx)
 				if err == nil {
-					app.opt.Logger.Infof("INSERT USSD LOGS: bulk inserted %d ussd logs from channel", logsLen)
-					ticker.Reset(tickerInterval)
+					app.opt.Logger.Info("bulk inserted session logs from channel", "count", logsLen)
+					ticker.Reset(interval)
 				} else {
-					app.opt.Logger.Errorf("INSERT USSD LOGS: failed to bulk insert: %v", err)
+					app.metrics.logFlushFailed()
+					app.opt.Logger.Error("failed to bulk insert session logs", "error", err)
 				}
 			}
 		}
@@ -138,103 +134,265 @@ func (app *UssdApp) saveLogsWorker(ctx context.Context) {
 
 }
 
+// writeLogs writes the logs to each sink, keeping logs that fail to be written to a sink so that they are written
+// later. Sinks are written separately so that logs are written again only to sinks that failed
+func (app *UssdApp) writeLogs(ctx context.Context, logs []*SessionRequest) error {
+	var err error
+
+	for i, sink := range app.logSinks {
+		werr := sink.Write(ctx, logs)
+		if werr == nil {
+			continue
+		}
+
+		app.opt.Logger.Error("failed to save session logs, keeping them to be saved later", "error", werr)
+
+		ferr := app.saveFailedLogs(i, logs)
+		if ferr != nil {
+			// The logs are lost unless the sink comes back before the app stops
+			app.reportWorkerError("keep_failed_logs", fmt.Errorf("failed to keep logs that failed to be saved: %v: %w", werr, ferr))
+			werr = ferr
+		}
+		err = werr
+	}
+
+	return err
+}
+
 func (app *UssdApp) saveFailedLogsWorker(ctx context.Context) {
-	timer := time.NewTicker(30 * time.Second)
+	defer app.workers.Done()
+
+	spool := app.opt.FailedLogSpool
+
+	timer := time.NewTicker(spool.interval())
 	defer timer.Stop()
 
-	_, err := os.Stat(failedBulkDir)
+	if !spool.inMemory() {
+		err := app.createFailedLogsDir()
+		if err != nil {
+			app.opt.Logger.Error("failed to create failed logs directory", "dir", spool.dir(), "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.stop:
+			return
+		case <-timer.C:
+		}
+
+		if spool.inMemory() {
+			app.retryFailedBatches(ctx)
+		} else {
+			app.retryFailedLogFiles(ctx)
+		}
+
+		if app.opt.FailedLogStorage != nil {
+			app.retryFailedLogObjects(ctx)
+		}
+	}
+}
+
+// createFailedLogsDir creates the directory of failed logs files if it does not exist
+func (app *UssdApp) createFailedLogsDir() error {
+	dir := app.opt.FailedLogSpool.dir()
+
+	_, err := os.Stat(dir)
 	switch {
 	case err == nil:
+		return nil
 	case os.IsNotExist(err):
-		err := os.Mkdir(failedBulkDir, 0755)
+		return os.MkdirAll(dir, 0755)
+	default:
+		return err
+	}
+}
+
+// retryFailedLogFiles writes logs of files in the failed logs directory again, removing the files written
+func (app *UssdApp) retryFailedLogFiles(ctx context.Context) {
+	dir := app.opt.FailedLogSpool.dir()
+
+	// Read from directories and try to save logs that have failed
+	filesInfo, err := ioutil.ReadDir(dir)
+	if err != nil {
+		app.opt.Logger.Warn("failed to read failed logs directory", "dir", dir, "error", err)
+		return
+	}
+
+	for _, fileInfo := range filesInfo {
+		fileName := filepath.Join(dir, fileInfo.Name())
+
+		// Read file content
+		buf, err := ioutil.ReadFile(fileName)
 		if err != nil {
-			app.opt.Logger.Errorf("failed to create directory: %v", err)
+			app.opt.Logger.Warn("failed to read failed logs file", "file", fileName, "error", err)
+			continue
 		}
-	default:
-		app.opt.Logger.Errorf("failed to create directory: %v", err)
+
+		err = app.writeFailedLogs(ctx, fileInfo.Name(), buf)
+		if err != nil {
+			app.opt.Logger.Warn("failed to save logs of failed logs file", "file", fileName, "error", err)
+			continue
+		}
+
+		// Delete the file
+		err = os.Remove(fileName)
+		if err != nil {
+			app.opt.Logger.Warn("failed to remove failed logs file", "file", fileName, "error", err)
+			return
+		}
+
+		app.opt.Logger.Info("saved logs of failed logs file", "file", fileName)
+	}
+}
+
+// retryFailedLogObjects writes logs of objects uploaded to Options.FailedLogStorage again, deleting the objects written
+func (app *UssdApp) retryFailedLogObjects(ctx context.Context) {
+	keys, err := app.opt.FailedLogStorage.List(ctx, failedBulkDir+"/")
+	if err != nil {
+		app.opt.Logger.Warn("failed to list failed logs objects", "prefix", failedBulkDir, "error", err)
+		return
 	}
 
-loop:
-	for range timer.C {
-		// Read from directories and try to save logs that have failed
-		filesInfo, err := ioutil.ReadDir(failedBulkDir)
+	for _, key := range keys {
+		buf, err := app.opt.FailedLogStorage.Get(ctx, key)
 		if err != nil {
-			app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to read directory: %v", err)
+			app.opt.Logger.Warn("failed to download failed logs object", "key", key, "error", err)
 			continue
 		}
 
-	loopIn:
-		for _, fileInfo := range filesInfo {
-			fileName := filepath.Join(failedBulkDir, fileInfo.Name())
+		err = app.writeFailedLogs(ctx, path.Base(key), buf)
+		if err != nil {
+			app.opt.Logger.Warn("failed to save logs of failed logs object", "key", key, "error", err)
+			continue
+		}
 
-			// Read file content
-			buf, err := ioutil.ReadFile(fileName)
-			if err != nil {
-				app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to read file contents: %v", err)
-				continue
-			}
+		err = app.opt.FailedLogStorage.Delete(ctx, key)
+		if err != nil {
+			app.opt.Logger.Warn("failed to delete failed logs object", "key", key, "error", err)
+			return
+		}
 
-			logs := make([]*SessionRequest, 0, bulkInsertSize)
+		app.opt.Logger.Info("saved logs of failed logs object", "key", key)
+	}
+}
 
-			err = json.Unmarshal(buf, &logs)
-			if err != nil {
-				app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to unmarshal file contents: %v", err)
-				continue
-			}
+// writeFailedLogs writes the logs of a failed logs file again, to the sink whose index is in the name of the file
+func (app *UssdApp) writeFailedLogs(ctx context.Context, name string, buf []byte) error {
+	logs, err := decodeLogs(buf)
+	if err != nil {
+		return err
+	}
 
-			const safeBulkSize = 1000
+	sinks := app.logSinks
 
-			tx := app.opt.SQLDB.Begin()
-			if tx.Error != nil {
-				app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to begin transaction: %v", err)
-				continue
-			}
+	// Files without a sink index were saved for all sinks
+	var index, ts int
+	n, _ := fmt.Sscanf(name, "bulk-%d-%d", &index, &ts)
+	if n == 2 && index >= 0 && index < len(sinks) {
+		sinks = sinks[index : index+1]
+	}
 
-			tx = tx.Clauses(clause.OnConflict{DoNothing: true})
-
-			if len(logs) > safeBulkSize {
-				for i, j := 0, safeBulkSize; i < len(logs); i, j = i+safeBulkSize, j+safeBulkSize {
-					to := j
-					if j > len(logs) {
-						to = len(logs)
-					}
-					logsIn := logs[i:to]
-
-					// Create batches of the logs
-					err = tx.CreateInBatches(logsIn, len(logs)+1).Error
-					if err != nil {
-						tx.Rollback()
-						app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to save file batch: %v", err)
-						goto loopIn
-					}
-				}
-			} else {
-				err = tx.CreateInBatches(logs, len(logs)+1).Error
-				if err != nil {
-					tx.Rollback()
-					app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to save file batch: %v", err)
-					goto loop
-				}
-			}
+	for _, sink := range sinks {
+		err = sink.Write(ctx, logs)
+		if err != nil {
+			return err
+		}
+	}
 
-			// Commit transaction
-			err = tx.Commit().Error
-			if err != nil {
-				tx.Rollback()
-				app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to commit transaction: %v", err)
-				continue
-			}
+	return nil
+}
 
-			logs = logs[0:0]
+// saveFailedLogs keeps logs that could not be written to the sink at index so that they are written later. They are
+// uploaded to Options.FailedLogStorage when set, and kept by Options.FailedLogSpool when there is no storage or the
+// upload fails
+func (app *UssdApp) saveFailedLogs(index int, logs []*SessionRequest) error {
+	ts := time.Now().UnixNano()
 
-			// Delete the file
-			err = os.Remove(fileName)
-			if err != nil {
-				app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: failed to remove file: %v", err)
-				goto loop
-			}
+	if app.opt.FailedLogStorage != nil {
+		data, err := encodeLogs(logs, true)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s/bulk-%d-%d.ndjson.gz", failedBulkDir, index, ts)
 
-			app.opt.Logger.Warningf("SAVE FAILED LOGS WORKER: successfully saved contents of file: %s", fileName)
+		ctx, cancel := context.WithTimeout(context.Background(), failedLogsUploadTimeout)
+		defer cancel()
+
+		err = app.opt.FailedLogStorage.Put(ctx, key, data)
+		if err == nil {
+			return nil
 		}
+
+		app.opt.Logger.Error("failed to upload failed logs, keeping them in the spool", "key", key, "error", err)
 	}
+
+	spool := app.opt.FailedLogSpool
+
+	if spool.inMemory() {
+		app.keepFailedLogs(index, logs)
+		return nil
+	}
+
+	err := app.createFailedLogsDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeLogs(logs, spool != nil && spool.Compression)
+	if err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(spool.dir(), app.failedLogsFileName(index, ts))
+
+	// Save logs locally in file
+	err = ioutil.WriteFile(fileName, data, 0644)
+	if err != nil {
+		app.opt.Logger.Error("failed to write failed logs file", "file", fileName, "error", err)
+		return err
+	}
+
+	app.trimFailedLogFiles()
+
+	return nil
+}
+
+// Close stops the background workers after writing session logs that are yet to be saved.
+//
+// It waits for the logs to be written until ctx is done, then closes log sinks that implement io.Closer.
+func (app *UssdApp) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&app.closed, 0, 1) {
+		return errors.New("app is closed")
+	}
+
+	app.closeCtx = ctx
+	close(app.stop)
+
+	done := make(chan struct{})
+	go func() {
+		app.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to save pending session logs: %w", ctx.Err())
+	}
+
+	var err error
+	for _, sink := range app.logSinks {
+		if c, ok := sink.(io.Closer); ok {
+			cerr := c.Close()
+			if cerr != nil {
+				err = fmt.Errorf("failed to close log sink: %v", cerr)
+			}
+		}
+	}
+
+	return err
 }